@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// deliveryExecutable returns the binary started to deliver webhooks in the background; tests
+// replace it with a stand-in
+var deliveryExecutable = os.Executable
+
+// webhookDelivery is a report and the settings needed to deliver it, handed to the background
+// process in async mode
+type webhookDelivery struct {
+	Endpoints    []string      `json:"endpoints"`
+	Payload      Payload       `json:"payload"`
+	MaxAttempts  int           `json:"maxAttempts"`
+	SuccessCodes []int         `json:"successCodes,omitempty"`
+	Timeout      time.Duration `json:"timeout"`
+	BacklogFile  string        `json:"backlogFile,omitempty"`
+}
+
+// deliverWebhook sends the report to every endpoint, saving it to the backlog when any of them did
+// not receive it. Endpoints that did receive it ignore the replayed copy as stale.
+func deliverWebhook(delivery webhookDelivery) {
+	failed := sendWebhooks(delivery.Endpoints, delivery.Payload, delivery.MaxAttempts, delivery.SuccessCodes, delivery.Timeout)
+	if len(failed) == 0 || delivery.BacklogFile == "" {
+		return
+	}
+
+	if err := appendBacklog(delivery.BacklogFile, delivery.Payload); err != nil {
+		fmt.Fprintf(output, "Could not save undelivered webhook to backlog: %v\n", err)
+	} else {
+		fmt.Fprintf(output, "Saved undelivered webhook to backlog %s\n", delivery.BacklogFile)
+	}
+}
+
+// dispatchWebhook delivers the report, from a background process when async is set
+func dispatchWebhook(delivery webhookDelivery, async bool) {
+	if !async {
+		deliverWebhook(delivery)
+	} else if err := startAsyncDelivery(delivery); err != nil {
+		fmt.Fprintf(output, "Could not deliver webhook in the background, sending it now: %v\n", err)
+		deliverWebhook(delivery)
+	} else {
+		fmt.Fprintf(output, "Webhook delivery continues in the background\n")
+	}
+}
+
+// startAsyncDelivery hands the report to a background drift-guardian process so the terraform exit
+// code is returned without waiting for the webhook and its retries. The background process has no
+// job log to write to, so a failed delivery is only recorded in the backlog file, and a runner that
+// tears down the job environment on exit may stop it before it delivers.
+func startAsyncDelivery(delivery webhookDelivery) error {
+	executable, err := deliveryExecutable()
+	if err != nil {
+		return fmt.Errorf("error locating drift-guardian binary: %w", err)
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook delivery: %w", err)
+	}
+
+	// CreateTemp makes the file private to the user, since plan output may contain sensitive values
+	f, err := os.CreateTemp("", "drift-guardian-delivery-*.json")
+	if err != nil {
+		return fmt.Errorf("error creating webhook delivery file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("error writing webhook delivery file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("error writing webhook delivery file: %w", err)
+	}
+
+	cmd := exec.Command(executable, "--deliver-webhook", f.Name())
+	if err := cmd.Start(); err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("error starting background webhook delivery: %w", err)
+	}
+	debugLog("Started background webhook delivery, pid %d\n", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// runAsyncDelivery delivers a report saved by startAsyncDelivery and returns the process exit code
+func runAsyncDelivery(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(output, "Could not read webhook delivery: %v\n", err)
+		return 1
+	}
+	_ = os.Remove(path)
+
+	var delivery webhookDelivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		fmt.Fprintf(output, "Could not parse webhook delivery: %v\n", err)
+		return 1
+	}
+
+	deliverWebhook(delivery)
+	return 0
+}
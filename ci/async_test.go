@@ -0,0 +1,139 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDelivery saves a webhook delivery file as startAsyncDelivery would
+func writeDelivery(t *testing.T, delivery webhookDelivery) string {
+	data, err := json.Marshal(delivery)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "delivery.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// TestStartAsyncDelivery tests that the report is handed to a background process without waiting for it
+func TestStartAsyncDelivery(t *testing.T) {
+	originalExecutable := deliveryExecutable
+	defer func() { deliveryExecutable = originalExecutable }()
+
+	// The stand-in records its arguments and the delivery file it was handed
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "drift-guardian")
+	argsFile := filepath.Join(dir, "args.log")
+	copyFile := filepath.Join(dir, "delivery.json")
+	script := "#!/bin/sh\ncp \"$2\" " + copyFile + " && rm \"$2\"\necho \"$1\" > " + argsFile + ".tmp\nmv " + argsFile + ".tmp " + argsFile + "\n"
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o700))
+	deliveryExecutable = func() (string, error) { return binary, nil }
+
+	delivery := webhookDelivery{
+		Endpoints:   []string{"https://drift.example.com"},
+		Payload:     Payload{RepoName: "infra", Environment: "production", Operation: "plan", ExitCode: 2},
+		MaxAttempts: 3,
+		Timeout:     90 * time.Second,
+		BacklogFile: "backlog.jsonl",
+	}
+	require.NoError(t, startAsyncDelivery(delivery))
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(argsFile)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "--deliver-webhook", strings.TrimSpace(string(args)))
+
+	data, err := os.ReadFile(copyFile)
+	require.NoError(t, err)
+	var handed webhookDelivery
+	require.NoError(t, json.Unmarshal(data, &handed))
+	assert.Equal(t, delivery, handed)
+}
+
+// TestStartAsyncDelivery_NoExecutable tests that a failure to start the background process is reported
+func TestStartAsyncDelivery_NoExecutable(t *testing.T) {
+	originalExecutable := deliveryExecutable
+	defer func() { deliveryExecutable = originalExecutable }()
+
+	deliveryExecutable = func() (string, error) { return "", errors.New("not found") }
+	assert.Error(t, startAsyncDelivery(webhookDelivery{}))
+
+	deliveryExecutable = func() (string, error) { return filepath.Join(t.TempDir(), "missing"), nil }
+	assert.Error(t, startAsyncDelivery(webhookDelivery{}))
+}
+
+// TestRunAsyncDelivery tests that the background process delivers the saved report and removes it
+func TestRunAsyncDelivery(t *testing.T) {
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := writeDelivery(t, webhookDelivery{
+		Endpoints:   []string{server.URL},
+		Payload:     Payload{RepoName: "infra", Environment: "production", Operation: "plan", ExitCode: 2},
+		MaxAttempts: 1,
+		Timeout:     time.Second,
+	})
+
+	assert.Equal(t, 0, runAsyncDelivery(path))
+	assert.Equal(t, "infra", received.RepoName)
+	assert.Equal(t, 2, received.ExitCode)
+	assert.NoFileExists(t, path)
+}
+
+// TestRunAsyncDelivery_Backlog tests that a report the background process cannot deliver is backlogged
+func TestRunAsyncDelivery_Backlog(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	output = &bytes.Buffer{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	backlogFile := filepath.Join(t.TempDir(), "backlog.jsonl")
+	path := writeDelivery(t, webhookDelivery{
+		Endpoints:   []string{server.URL},
+		Payload:     Payload{RepoName: "infra", Environment: "production", Operation: "apply"},
+		MaxAttempts: 1,
+		Timeout:     time.Second,
+		BacklogFile: backlogFile,
+	})
+
+	assert.Equal(t, 0, runAsyncDelivery(path))
+
+	data, err := os.ReadFile(backlogFile)
+	require.NoError(t, err)
+	var backlogged Payload
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(data), &backlogged))
+	assert.Equal(t, "apply", backlogged.Operation)
+}
+
+// TestRunAsyncDelivery_Unreadable tests that a missing delivery file fails the background process
+func TestRunAsyncDelivery_Unreadable(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	output = &bytes.Buffer{}
+
+	assert.Equal(t, 1, runAsyncDelivery(filepath.Join(t.TempDir(), "missing.json")))
+}
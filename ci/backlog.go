@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// appendBacklog saves an undelivered payload to the backlog file, one JSON object per line. The file
+// is private to the user since plan output may contain sensitive values.
+func appendBacklog(path string, payload Payload) error {
+	line, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshaling payload: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening backlog file: %w", err)
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing backlog file: %w", err)
+	}
+	return f.Close()
+}
+
+// replayBacklog resends backlogged payloads oldest first, marked as replayed so the server can
+// ignore any that are older than what it has since recorded. Replay stops at the first failed
+// delivery so later reports never arrive before earlier ones, and undelivered payloads are kept for
+// the next replay. A payload counts as delivered once every endpoint accepts it; endpoints that
+// already had it ignore the resent copy as stale. It returns how many payloads were delivered and how
+// many remain.
+func replayBacklog(path string, endpoints []string, maxAttempts int, successCodes []int, timeout time.Duration) (int, int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading backlog file: %w", err)
+	}
+
+	delivered := 0
+	var remaining []string
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(remaining) > 0 {
+			remaining = append(remaining, line)
+			continue
+		}
+
+		var payload Payload
+		if err := json.Unmarshal([]byte(line), &payload); err != nil {
+			fmt.Fprintf(output, "Dropping unreadable backlog entry on line %d: %v\n", i+1, err)
+			continue
+		}
+
+		payload.Replayed = true
+		debugLog("Replaying %s report for %s/%s from %s\n", payload.Operation, payload.RepoName, payload.Environment, payload.Timestamp)
+		if failed := sendWebhooks(endpoints, payload, maxAttempts, successCodes, timeout); len(failed) > 0 {
+			remaining = append(remaining, line)
+			continue
+		}
+		delivered++
+	}
+
+	if err := rewriteBacklog(path, remaining); err != nil {
+		return delivered, len(remaining), err
+	}
+	return delivered, len(remaining), nil
+}
+
+// rewriteBacklog replaces the backlog with the remaining lines, removing it once nothing is left
+func rewriteBacklog(path string, lines []string) error {
+	if len(lines) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing backlog file: %w", err)
+		}
+		return nil
+	}
+
+	// Write to a temporary file and rename so an interrupted replay never truncates the backlog
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error writing backlog file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error replacing backlog file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAppendBacklog tests that undelivered payloads are appended as JSON lines
+func TestAppendBacklog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backlog.jsonl")
+
+	require.NoError(t, appendBacklog(path, Payload{RepoName: "test-repo", Environment: "production", ExitCode: 2}))
+	require.NoError(t, appendBacklog(path, Payload{RepoName: "test-repo", Environment: "staging", ExitCode: 0}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &payload))
+	assert.Equal(t, "staging", payload.Environment)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "Plan output must not be readable by other users")
+}
+
+// TestReplayBacklog tests that replay resends payloads in order and keeps those not delivered
+func TestReplayBacklog(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	output = &bytes.Buffer{}
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name              string
+		acceptedCalls     int
+		expectedDelivered []string
+		expectedRemaining []string
+	}{
+		{name: "all delivered", acceptedCalls: 3, expectedDelivered: []string{"first", "second", "third"}},
+		{name: "replay stops at the first failure", acceptedCalls: 1, expectedDelivered: []string{"first"}, expectedRemaining: []string{"second", "third"}},
+		{name: "server unreachable", acceptedCalls: 0, expectedRemaining: []string{"first", "second", "third"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delivered []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload Payload
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+				if len(delivered) >= tt.acceptedCalls {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				assert.True(t, payload.Replayed, "Replayed payloads must be marked")
+				delivered = append(delivered, payload.Environment)
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "backlog.jsonl")
+			for _, environment := range []string{"first", "second", "third"} {
+				require.NoError(t, appendBacklog(path, Payload{RepoName: "test-repo", Environment: environment, Timestamp: "2025-01-31T10:30:00Z"}))
+			}
+
+			sent, remaining, err := replayBacklog(path, []string{server.URL}, 1, nil, time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDelivered, delivered)
+			assert.Equal(t, len(tt.expectedDelivered), sent)
+			assert.Equal(t, len(tt.expectedRemaining), remaining)
+
+			data, err := os.ReadFile(path)
+			if len(tt.expectedRemaining) == 0 {
+				assert.True(t, os.IsNotExist(err), "An empty backlog should be removed")
+				return
+			}
+			require.NoError(t, err)
+
+			var kept []string
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var payload Payload
+				require.NoError(t, json.Unmarshal([]byte(line), &payload))
+				assert.False(t, payload.Replayed, "Kept payloads are stored as originally saved")
+				kept = append(kept, payload.Environment)
+			}
+			assert.Equal(t, tt.expectedRemaining, kept)
+		})
+	}
+}
+
+// TestReplayBacklog_MissingAndUnreadable tests replay of a missing backlog and one with corrupt entries
+func TestReplayBacklog_MissingAndUnreadable(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	var buffer bytes.Buffer
+	output = &buffer
+
+	dir := t.TempDir()
+	sent, remaining, err := replayBacklog(filepath.Join(dir, "missing.jsonl"), []string{"http://localhost"}, 1, nil, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Zero(t, remaining)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	path := filepath.Join(dir, "backlog.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{not json\n{\"repoName\":\"test-repo\"}\n"), 0o600))
+
+	sent, remaining, err = replayBacklog(path, []string{server.URL}, 1, nil, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Zero(t, remaining)
+	assert.Contains(t, buffer.String(), "Dropping unreadable backlog entry on line 1")
+}
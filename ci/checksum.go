@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// refreshMarkers identify terraform's refresh progress lines, which carry IDs and timings that change
+// between runs without the drift changing
+var refreshMarkers = []string{"Refreshing state...", "Reading...", "Read complete after"}
+
+// planChecksum returns a hex SHA256 of plan output, ignoring color codes and refresh progress so the
+// same drift always has the same checksum
+func planChecksum(planOutput string) string {
+	planOutput = ansiPattern.ReplaceAllString(planOutput, "")
+
+	hash := sha256.New()
+	for _, line := range strings.Split(planOutput, "\n") {
+		if isRefreshLine(line) {
+			continue
+		}
+		hash.Write([]byte(line + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isRefreshLine reports whether a plan output line is refresh progress
+func isRefreshLine(line string) bool {
+	for _, marker := range refreshMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
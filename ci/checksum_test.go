@@ -0,0 +1,28 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPlanChecksum tests that the checksum follows the planned changes, not refresh progress or colors
+func TestPlanChecksum(t *testing.T) {
+	plan := "aws_s3_bucket.logs: Refreshing state... [id=logs]\n" +
+		"\n  # aws_s3_bucket.logs will be updated in-place\n" +
+		"Plan: 0 to add, 1 to change, 0 to destroy.\n"
+	rerun := "aws_s3_bucket.logs: Refreshing state... [id=logs]\n" +
+		"data.aws_caller_identity.current: Read complete after 1s [id=123456789012]\n" +
+		"\n  # aws_s3_bucket.logs will be updated in-place\n" +
+		"\x1b[1mPlan:\x1b[0m 0 to add, 1 to change, 0 to destroy.\n"
+	changed := "aws_s3_bucket.logs: Refreshing state... [id=logs]\n" +
+		"\n  # aws_s3_bucket.logs will be destroyed\n" +
+		"Plan: 0 to add, 0 to change, 1 to destroy.\n"
+
+	checksum := planChecksum(plan)
+	assert.Len(t, checksum, 64)
+	assert.Equal(t, checksum, planChecksum(rerun), "Refresh progress and colors should not change the checksum")
+	assert.NotEqual(t, checksum, planChecksum(changed), "Different changes should change the checksum")
+}
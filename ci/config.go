@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWebhookMaxAttempts is used when no webhook attempt limit is configured
+const defaultWebhookMaxAttempts = 3
+
+// fileConfig holds CLI settings read from the --config file (YAML or JSON)
+type fileConfig struct {
+	Endpoint            string            `yaml:"endpoint"`
+	Endpoints           []string          `yaml:"endpoints"` // Additional instances that also receive reports
+	TerraformVersion    string            `yaml:"terraform-version"`
+	Scheduled           bool              `yaml:"scheduled"`
+	WebhookMaxAttempts  int               `yaml:"webhook-max-attempts"`
+	WebhookSuccessCodes []int             `yaml:"webhook-success-codes"`
+	WebhookTimeout      string            `yaml:"webhook-timeout"`
+	PushgatewayURL      string            `yaml:"pushgateway-url"`
+	Criticality         map[string]int    `yaml:"criticality"` // Resource type -> drift weight
+	BacklogFile         string            `yaml:"backlog-file"`
+	AutoInit            bool              `yaml:"auto-init"`
+	InitArgs            []string          `yaml:"init-args"`
+	ResultFile          string            `yaml:"result-file"`
+	AsyncWebhook        bool              `yaml:"async-webhook"`
+	PlanChecksum        bool              `yaml:"plan-checksum"`
+	ResourceLabels      map[string]string `yaml:"resource-labels"` // Resource type -> drift issue label
+	SendHeartbeat       bool              `yaml:"send-heartbeat"`
+}
+
+// cliSettings holds the resolved CLI settings
+type cliSettings struct {
+	Endpoint         string
+	Endpoints        []string // Additional instances that also receive reports
+	TerraformVersion string
+	Scheduled        bool
+	MaxAttempts      int
+	SuccessCodes     []int // Empty accepts any 2xx status
+	WebhookTimeout   time.Duration
+	PushgatewayURL   string
+	Criticality      map[string]int    // Empty leaves drift unweighted
+	BacklogFile      string            // Empty drops undelivered reports
+	AutoInit         bool              // Run terraform init before plan and apply
+	InitArgs         []string          // Extra arguments for terraform init
+	ResultFile       string            // Empty skips writing the drift result artifact
+	AsyncWebhook     bool              // Deliver webhooks from a background process instead of waiting for them
+	PlanChecksum     bool              // Report a checksum of drifted plans so the server can tell unchanged drift
+	ResourceLabels   map[string]string // Empty leaves drift issues unrouted
+	SendHeartbeat    bool              // Report runs of operations that send no report so the server sees the job is alive
+}
+
+// loadFileConfig reads CLI settings from a YAML or JSON file; an empty path returns no settings
+func loadFileConfig(path string) (fileConfig, error) {
+	var cfg fileConfig
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// resolveSettings combines flags, environment variables, and file settings with the precedence
+// flag > env > file > default
+func resolveSettings(fs *flag.FlagSet, file fileConfig) cliSettings {
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	value := func(flagName, envName, fileValue string) string {
+		if setFlags[flagName] {
+			return fs.Lookup(flagName).Value.String()
+		}
+		if envValue := os.Getenv(envName); envValue != "" {
+			return envValue
+		}
+		return fileValue
+	}
+
+	settings := cliSettings{
+		Endpoint:         value("drift-endpoint", "DRIFT_GUARDIAN_ENDPOINT", file.Endpoint),
+		TerraformVersion: value("terraform-version", "TERRAFORM_VERSION", file.TerraformVersion),
+		PushgatewayURL:   value("pushgateway-url", "PUSHGATEWAY_URL", file.PushgatewayURL),
+		BacklogFile:      value("backlog-file", "DRIFT_GUARDIAN_BACKLOG_FILE", file.BacklogFile),
+		ResultFile:       value("result-file", "DRIFT_RESULT_FILE", file.ResultFile),
+		Endpoints:        parseEndpoints(value("drift-endpoints", "DRIFT_GUARDIAN_ENDPOINTS", strings.Join(file.Endpoints, ","))),
+		MaxAttempts:      defaultWebhookMaxAttempts,
+		WebhookTimeout:   defaultWebhookTimeout,
+		Criticality:      file.Criticality,
+		ResourceLabels:   file.ResourceLabels,
+		InitArgs:         file.InitArgs,
+	}
+
+	if autoInit, err := strconv.ParseBool(value("auto-init", "AUTO_INIT", strconv.FormatBool(file.AutoInit))); err == nil {
+		settings.AutoInit = autoInit
+	}
+
+	if asyncWebhook, err := strconv.ParseBool(value("async-webhook", "DRIFT_GUARDIAN_ASYNC_WEBHOOK", strconv.FormatBool(file.AsyncWebhook))); err == nil {
+		settings.AsyncWebhook = asyncWebhook
+	}
+
+	if planChecksum, err := strconv.ParseBool(value("plan-checksum", "PLAN_CHECKSUM", strconv.FormatBool(file.PlanChecksum))); err == nil {
+		settings.PlanChecksum = planChecksum
+	}
+
+	if sendHeartbeat, err := strconv.ParseBool(value("send-heartbeat", "SEND_HEARTBEAT", strconv.FormatBool(file.SendHeartbeat))); err == nil {
+		settings.SendHeartbeat = sendHeartbeat
+	}
+
+	if initArgs := value("init-args", "INIT_ARGS", ""); initArgs != "" {
+		settings.InitArgs = strings.Fields(initArgs)
+	}
+
+	if criticality := os.Getenv("DRIFT_CRITICALITY"); criticality != "" {
+		settings.Criticality = parseCriticality(criticality)
+	}
+
+	if resourceLabels := os.Getenv("DRIFT_RESOURCE_LABELS"); resourceLabels != "" {
+		settings.ResourceLabels = parseResourceLabels(resourceLabels)
+	}
+
+	if scheduled, err := strconv.ParseBool(value("drift-scheduled", "SCHEDULED", strconv.FormatBool(file.Scheduled))); err == nil {
+		settings.Scheduled = scheduled
+	}
+
+	fileAttempts := ""
+	if file.WebhookMaxAttempts > 0 {
+		fileAttempts = strconv.Itoa(file.WebhookMaxAttempts)
+	}
+	if attempts, err := strconv.Atoi(value("webhook-max-attempts", "DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS", fileAttempts)); err == nil && attempts > 0 {
+		settings.MaxAttempts = attempts
+	}
+
+	fileCodes := make([]string, 0, len(file.WebhookSuccessCodes))
+	for _, code := range file.WebhookSuccessCodes {
+		fileCodes = append(fileCodes, strconv.Itoa(code))
+	}
+	if codes := value("webhook-success-codes", "DRIFT_GUARDIAN_WEBHOOK_SUCCESS_CODES", strings.Join(fileCodes, ",")); codes != "" {
+		parsed, err := parseStatusCodes(codes)
+		if err != nil {
+			fmt.Fprintf(output, "Invalid webhook success codes %q, accepting any 2xx status: %v\n", codes, err)
+		} else {
+			settings.SuccessCodes = parsed
+		}
+	}
+
+	if timeout := value("webhook-timeout", "WEBHOOK_TIMEOUT", file.WebhookTimeout); timeout != "" {
+		parsed, err := parseTimeout(timeout)
+		if err != nil {
+			fmt.Fprintf(output, "Invalid webhook timeout %q, using %v: %v\n", timeout, defaultWebhookTimeout, err)
+		} else {
+			settings.WebhookTimeout = parsed
+		}
+	}
+
+	return settings
+}
+
+// parseEndpoints parses a comma-separated list of Drift Guardian URLs
+func parseEndpoints(value string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(value, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// webhookEndpoints returns every endpoint that receives reports, the primary endpoint first and
+// without duplicates
+func webhookEndpoints(settings cliSettings) []string {
+	var endpoints []string
+	for _, endpoint := range append([]string{settings.Endpoint}, settings.Endpoints...) {
+		if endpoint != "" && !slices.Contains(endpoints, endpoint) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// parseTimeout parses a positive Go duration such as 90s, treating a bare number as seconds
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive")
+	}
+	return timeout, nil
+}
+
+// parseStatusCodes parses a comma-separated list of HTTP status codes
+func parseStatusCodes(value string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid HTTP status code %q", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// parseMetadata parses comma-separated key=value pairs; malformed entries are reported and skipped,
+// leaving the server to validate the keys and values it accepts
+func parseMetadata(value string) map[string]string {
+	metadata := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, val, ok := strings.Cut(entry, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			fmt.Fprintf(output, "Ignoring metadata entry %q: expected key=value\n", entry)
+			continue
+		}
+		metadata[key] = val
+	}
+
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
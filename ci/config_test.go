@@ -0,0 +1,294 @@
+//go:build unit
+
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFlagSet mirrors the CLI flags so precedence can be tested without the global flag set
+func newTestFlagSet(t *testing.T, args ...string) *flag.FlagSet {
+	fs := flag.NewFlagSet("drift-guardian", flag.ContinueOnError)
+	fs.String("terraform-version", "", "")
+	fs.String("drift-endpoint", "", "")
+	fs.String("drift-endpoints", "", "")
+	fs.Bool("drift-scheduled", false, "")
+	fs.Int("webhook-max-attempts", 0, "")
+	fs.String("webhook-success-codes", "", "")
+	fs.String("webhook-timeout", "", "")
+	fs.String("pushgateway-url", "", "")
+	fs.String("backlog-file", "", "")
+	fs.Bool("auto-init", false, "")
+	fs.String("init-args", "", "")
+	fs.String("result-file", "", "")
+	fs.Bool("async-webhook", false, "")
+	fs.Bool("plan-checksum", false, "")
+	fs.Bool("send-heartbeat", false, "")
+	require.NoError(t, fs.Parse(args))
+	return fs
+}
+
+// TestLoadFileConfig tests reading YAML and JSON config files
+func TestLoadFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	expected := fileConfig{
+		Endpoint:           "https://drift.example.com",
+		TerraformVersion:   "1.9.0",
+		Scheduled:          true,
+		WebhookMaxAttempts: 5,
+	}
+
+	yamlPath := filepath.Join(dir, "drift-guardian.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte("endpoint: https://drift.example.com\nterraform-version: 1.9.0\nscheduled: true\nwebhook-max-attempts: 5\n"), 0o600))
+	cfg, err := loadFileConfig(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+
+	jsonPath := filepath.Join(dir, "drift-guardian.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"endpoint": "https://drift.example.com", "terraform-version": "1.9.0", "scheduled": true, "webhook-max-attempts": 5}`), 0o600))
+	cfg, err = loadFileConfig(jsonPath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, cfg)
+
+	cfg, err = loadFileConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, fileConfig{}, cfg, "No path should return empty settings")
+
+	_, err = loadFileConfig(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("webhook-max-attempts: many\n"), 0o600))
+	_, err = loadFileConfig(invalidPath)
+	assert.Error(t, err)
+}
+
+// TestResolveSettings tests the flag > env > file > default precedence
+func TestResolveSettings(t *testing.T) {
+	file := fileConfig{
+		Endpoint:           "https://file.example.com",
+		TerraformVersion:   "1.5.0",
+		Scheduled:          true,
+		WebhookMaxAttempts: 5,
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     fileConfig
+		expected cliSettings
+	}{
+		{
+			name:     "Defaults",
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "File overrides defaults",
+			file:     file,
+			expected: cliSettings{Endpoint: "https://file.example.com", TerraformVersion: "1.5.0", Scheduled: true, MaxAttempts: 5, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name: "Env overrides file",
+			env: map[string]string{
+				"DRIFT_GUARDIAN_ENDPOINT":             "https://env.example.com",
+				"TERRAFORM_VERSION":                   "1.6.0",
+				"SCHEDULED":                           "false",
+				"DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS": "7",
+			},
+			file:     file,
+			expected: cliSettings{Endpoint: "https://env.example.com", TerraformVersion: "1.6.0", Scheduled: false, MaxAttempts: 7, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name: "Flags override env and file",
+			args: []string{"-drift-endpoint", "https://flag.example.com", "-terraform-version", "1.7.0", "-drift-scheduled=false", "-webhook-max-attempts", "2"},
+			env: map[string]string{
+				"DRIFT_GUARDIAN_ENDPOINT":             "https://env.example.com",
+				"TERRAFORM_VERSION":                   "1.6.0",
+				"SCHEDULED":                           "true",
+				"DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS": "7",
+			},
+			file:     file,
+			expected: cliSettings{Endpoint: "https://flag.example.com", TerraformVersion: "1.7.0", Scheduled: false, MaxAttempts: 2, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Success codes from file",
+			file:     fileConfig{WebhookSuccessCodes: []int{200, 202}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, SuccessCodes: []int{200, 202}, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Success codes flag overrides env",
+			args:     []string{"-webhook-success-codes", "200, 207"},
+			env:      map[string]string{"DRIFT_GUARDIAN_WEBHOOK_SUCCESS_CODES": "202"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, SuccessCodes: []int{200, 207}, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Invalid success codes accept any 2xx",
+			env:      map[string]string{"DRIFT_GUARDIAN_WEBHOOK_SUCCESS_CODES": "200,abc"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Pushgateway URL env overrides file",
+			env:      map[string]string{"PUSHGATEWAY_URL": "http://env-gateway:9091"},
+			file:     fileConfig{PushgatewayURL: "http://file-gateway:9091"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, PushgatewayURL: "http://env-gateway:9091", WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Invalid attempts fall back to default",
+			env:      map[string]string{"DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS": "0"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Webhook timeout env overrides file",
+			env:      map[string]string{"WEBHOOK_TIMEOUT": "2m"},
+			file:     fileConfig{WebhookTimeout: "90s"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: 2 * time.Minute},
+		},
+		{
+			name:     "Webhook timeout in bare seconds",
+			args:     []string{"-webhook-timeout", "45"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: 45 * time.Second},
+		},
+		{
+			name:     "Invalid webhook timeout falls back to default",
+			env:      map[string]string{"WEBHOOK_TIMEOUT": "-5s"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout},
+		},
+		{
+			name:     "Criticality from file",
+			file:     fileConfig{Criticality: map[string]int{"aws_iam_*": 10}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, Criticality: map[string]int{"aws_iam_*": 10}},
+		},
+		{
+			name:     "Criticality env overrides file",
+			env:      map[string]string{"DRIFT_CRITICALITY": "aws_security_group=5"},
+			file:     fileConfig{Criticality: map[string]int{"aws_iam_*": 10}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, Criticality: map[string]int{"aws_security_group": 5}},
+		},
+		{
+			name:     "Backlog file flag overrides env",
+			args:     []string{"-backlog-file", "/cache/flag-backlog.jsonl"},
+			env:      map[string]string{"DRIFT_GUARDIAN_BACKLOG_FILE": "/cache/env-backlog.jsonl"},
+			file:     fileConfig{BacklogFile: "/cache/file-backlog.jsonl"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, BacklogFile: "/cache/flag-backlog.jsonl"},
+		},
+		{
+			name:     "Additional endpoints from file",
+			file:     fileConfig{Endpoints: []string{"https://team.example.com"}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, Endpoints: []string{"https://team.example.com"}},
+		},
+		{
+			name:     "Additional endpoints env overrides file",
+			env:      map[string]string{"DRIFT_GUARDIAN_ENDPOINTS": "https://a.example.com, ,https://b.example.com"},
+			file:     fileConfig{Endpoints: []string{"https://team.example.com"}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, Endpoints: []string{"https://a.example.com", "https://b.example.com"}},
+		},
+		{
+			name:     "Result file env overrides file",
+			env:      map[string]string{"DRIFT_RESULT_FILE": "artifacts/drift-result.json"},
+			file:     fileConfig{ResultFile: "drift-result.json"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, ResultFile: "artifacts/drift-result.json"},
+		},
+		{
+			name:     "Auto init from file",
+			file:     fileConfig{AutoInit: true, InitArgs: []string{"-input=false"}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, AutoInit: true, InitArgs: []string{"-input=false"}},
+		},
+		{
+			name:     "Auto init env overrides file",
+			env:      map[string]string{"AUTO_INIT": "false", "INIT_ARGS": "-input=false  -upgrade"},
+			file:     fileConfig{AutoInit: true, InitArgs: []string{"-reconfigure"}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, InitArgs: []string{"-input=false", "-upgrade"}},
+		},
+		{
+			name:     "Auto init flag overrides env",
+			args:     []string{"-auto-init", "-init-args", "-backend=false"},
+			env:      map[string]string{"AUTO_INIT": "false", "INIT_ARGS": "-upgrade"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, AutoInit: true, InitArgs: []string{"-backend=false"}},
+		},
+		{
+			name:     "Async webhook from file",
+			file:     fileConfig{AsyncWebhook: true},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, AsyncWebhook: true},
+		},
+		{
+			name:     "Async webhook flag overrides env",
+			args:     []string{"-async-webhook"},
+			env:      map[string]string{"DRIFT_GUARDIAN_ASYNC_WEBHOOK": "false"},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, AsyncWebhook: true},
+		},
+		{
+			name:     "Plan checksum env overrides file",
+			env:      map[string]string{"PLAN_CHECKSUM": "true"},
+			file:     fileConfig{PlanChecksum: false},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, PlanChecksum: true},
+		},
+		{
+			name:     "Send heartbeat env overrides file",
+			env:      map[string]string{"SEND_HEARTBEAT": "true"},
+			file:     fileConfig{SendHeartbeat: false},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, SendHeartbeat: true},
+		},
+		{
+			name:     "Resource labels env overrides file",
+			env:      map[string]string{"DRIFT_RESOURCE_LABELS": "aws_iam_*=drift:iam"},
+			file:     fileConfig{ResourceLabels: map[string]string{"aws_vpc": "drift:network"}},
+			expected: cliSettings{MaxAttempts: defaultWebhookMaxAttempts, WebhookTimeout: defaultWebhookTimeout, ResourceLabels: map[string]string{"aws_iam_*": "drift:iam"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DRIFT_GUARDIAN_ENDPOINT", "TERRAFORM_VERSION", "SCHEDULED", "DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS", "DRIFT_GUARDIAN_WEBHOOK_SUCCESS_CODES", "WEBHOOK_TIMEOUT", "PUSHGATEWAY_URL", "DRIFT_CRITICALITY", "DRIFT_GUARDIAN_BACKLOG_FILE", "AUTO_INIT", "INIT_ARGS", "DRIFT_GUARDIAN_ENDPOINTS", "DRIFT_RESULT_FILE", "DRIFT_GUARDIAN_ASYNC_WEBHOOK", "PLAN_CHECKSUM", "DRIFT_RESOURCE_LABELS", "SEND_HEARTBEAT"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			settings := resolveSettings(newTestFlagSet(t, tt.args...), tt.file)
+			assert.Equal(t, tt.expected, settings)
+		})
+	}
+}
+
+// TestWebhookEndpoints tests combining the primary and additional endpoints
+func TestWebhookEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings cliSettings
+		expected []string
+	}{
+		{name: "no endpoints", settings: cliSettings{}},
+		{name: "primary only", settings: cliSettings{Endpoint: "https://central.example.com"}, expected: []string{"https://central.example.com"}},
+		{
+			name:     "primary first without duplicates",
+			settings: cliSettings{Endpoint: "https://central.example.com", Endpoints: []string{"https://team.example.com", "https://central.example.com"}},
+			expected: []string{"https://central.example.com", "https://team.example.com"},
+		},
+		{name: "additional only", settings: cliSettings{Endpoints: []string{"https://team.example.com"}}, expected: []string{"https://team.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, webhookEndpoints(tt.settings))
+		})
+	}
+}
+
+// TestParseMetadata tests parsing of DRIFT_METADATA key=value pairs
+func TestParseMetadata(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	output = io.Discard
+
+	assert.Equal(t, map[string]string{"team": "payments", "region": "eu"}, parseMetadata("team=payments, region=eu"))
+	assert.Equal(t, map[string]string{"team": "payments"}, parseMetadata("team=payments,invalid,=eu,region="), "Malformed entries should be skipped")
+	assert.Equal(t, map[string]string{"url": "a=b"}, parseMetadata("url=a=b"), "Only the first '=' separates key and value")
+	assert.Nil(t, parseMetadata(""))
+}
@@ -0,0 +1,30 @@
+package main
+
+// reportsOperation reports whether runs of the terraform operation send a drift report
+func reportsOperation(operation string) bool {
+	return operation == "plan" || operation == "apply" || operation == "destroy"
+}
+
+// heartbeatDelivery turns a report into a heartbeat with SEND_HEARTBEAT, so runs that send no report
+// still tell the server the job is alive. Only the fields identifying the environment are kept, and
+// an undelivered heartbeat is dropped rather than saved to the backlog, since a replayed one would
+// claim the job was seen later than it was.
+func heartbeatDelivery(delivery webhookDelivery) webhookDelivery {
+	report := delivery.Payload
+	delivery.Payload = Payload{
+		RepoName:        report.RepoName,
+		Branch:          report.Branch,
+		RefType:         report.RefType,
+		Environment:     report.Environment,
+		EnvironmentTier: report.EnvironmentTier,
+		ProjectID:       report.ProjectID,
+		Operation:       report.Operation,
+		ExitCode:        report.ExitCode,
+		Scheduled:       report.Scheduled,
+		Timestamp:       report.Timestamp,
+		PipelineSource:  report.PipelineSource,
+		Heartbeat:       true,
+	}
+	delivery.BacklogFile = ""
+	return delivery
+}
@@ -0,0 +1,64 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReportsOperation tests which terraform operations send a drift report
+func TestReportsOperation(t *testing.T) {
+	for _, operation := range []string{"plan", "apply", "destroy"} {
+		assert.True(t, reportsOperation(operation), operation)
+	}
+	for _, operation := range []string{"init", "validate", "output", "import"} {
+		assert.False(t, reportsOperation(operation), operation)
+	}
+}
+
+// TestHeartbeatDelivery tests that a heartbeat keeps only the environment's identity and skips the backlog
+func TestHeartbeatDelivery(t *testing.T) {
+	delivery := webhookDelivery{
+		Endpoints:   []string{"https://drift.example.com"},
+		MaxAttempts: 3,
+		BacklogFile: "/cache/backlog.jsonl",
+		Payload: Payload{
+			RepoName:        "infra",
+			Branch:          "main",
+			RefType:         "branch",
+			Environment:     "production",
+			EnvironmentTier: "production",
+			DriftThreshold:  "3",
+			ProjectID:       "42",
+			IssueProjectID:  "43",
+			Operation:       "validate",
+			Scheduled:       true,
+			Timestamp:       "2024-01-01T00:00:00Z",
+			CommitSHA:       "abc123",
+			PipelineSource:  "schedule",
+			Metadata:        map[string]string{"team": "platform"},
+		},
+	}
+
+	heartbeat := heartbeatDelivery(delivery)
+
+	assert.Equal(t, Payload{
+		RepoName:        "infra",
+		Branch:          "main",
+		RefType:         "branch",
+		Environment:     "production",
+		EnvironmentTier: "production",
+		ProjectID:       "42",
+		Operation:       "validate",
+		Scheduled:       true,
+		Timestamp:       "2024-01-01T00:00:00Z",
+		PipelineSource:  "schedule",
+		Heartbeat:       true,
+	}, heartbeat.Payload)
+	assert.Equal(t, delivery.Endpoints, heartbeat.Endpoints)
+	assert.Equal(t, 3, heartbeat.MaxAttempts)
+	assert.Empty(t, heartbeat.BacklogFile)
+	assert.False(t, delivery.Payload.Heartbeat, "the original report is unchanged")
+}
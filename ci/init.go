@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// initOperations are the terraform commands AUTO_INIT runs terraform init before
+var initOperations = []string{"plan", "apply"}
+
+// needsInit reports whether terraform init should run before operation
+func needsInit(autoInit bool, operation string) bool {
+	return autoInit && slices.Contains(initOperations, operation)
+}
+
+// runInit runs terraform init with the configured arguments, streaming its output, and returns
+// its exit code
+func runInit(terraformBinary string, initArgs []string) int {
+	args := append([]string{"init"}, initArgs...)
+	cmd := exec.Command(terraformBinary, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	debugLog("Executing: %s %s\n", terraformBinary, strings.Join(args, " "))
+	exitCode := commandExitCode(cmd.Run())
+	debugLog("Terraform init exited with code: %d\n", exitCode)
+	return exitCode
+}
+
+// commandExitCode returns the exit code of a finished command, treating a failure to start it as 1
+func commandExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
@@ -0,0 +1,64 @@
+//go:build unit
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTerraform writes a terraform stand-in that logs each invocation to a file and exits with
+// initExitCode from init and 0 otherwise
+func fakeTerraform(t *testing.T, initExitCode string) (binary, logFile string) {
+	dir := t.TempDir()
+	binary = filepath.Join(dir, "terraform")
+	logFile = filepath.Join(dir, "calls.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + logFile + "\nif [ \"$1\" = init ]; then exit " + initExitCode + "; fi\nexit 0\n"
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0o700))
+	return binary, logFile
+}
+
+// calls returns the logged invocations of a fake terraform binary
+func calls(t *testing.T, logFile string) []string {
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// TestNeedsInit tests which operations AUTO_INIT runs terraform init before
+func TestNeedsInit(t *testing.T) {
+	assert.True(t, needsInit(true, "plan"))
+	assert.True(t, needsInit(true, "apply"))
+	assert.False(t, needsInit(true, "destroy"))
+	assert.False(t, needsInit(true, "init"))
+	assert.False(t, needsInit(false, "plan"))
+}
+
+// TestRunInit_ThenPlan tests that a successful init is followed by the plan
+func TestRunInit_ThenPlan(t *testing.T) {
+	binary, logFile := fakeTerraform(t, "0")
+
+	require.Equal(t, 0, runInit(binary, []string{"-input=false", "-upgrade"}))
+	require.NoError(t, exec.Command(binary, "plan", "-detailed-exitcode").Run())
+
+	assert.Equal(t, []string{"init -input=false -upgrade", "plan -detailed-exitcode"}, calls(t, logFile))
+}
+
+// TestRunInit_Failure tests that init's exit code is returned when it fails
+func TestRunInit_Failure(t *testing.T) {
+	binary, logFile := fakeTerraform(t, "3")
+
+	assert.Equal(t, 3, runInit(binary, nil))
+	assert.Equal(t, []string{"init"}, calls(t, logFile))
+}
+
+// TestRunInit_MissingBinary tests that a terraform binary that cannot start counts as a failed init
+func TestRunInit_MissingBinary(t *testing.T) {
+	assert.Equal(t, 1, runInit(filepath.Join(t.TempDir(), "missing"), nil))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultResourceLabel labels drift whose resources match no DRIFT_RESOURCE_LABELS pattern
+const defaultResourceLabel = "drift:other"
+
+// resourceLabelPattern matches the labels the server accepts for routing drift issues
+var resourceLabelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/-]{0,63}$`)
+
+// parseResourceLabels parses comma-separated type=label pairs such as aws_iam_policy=drift:iam; a
+// trailing * matches a type prefix, e.g. aws_iam_*=drift:iam, and * alone replaces the fallback label.
+// Malformed entries are reported and skipped.
+func parseResourceLabels(value string) map[string]string {
+	labels := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		resourceType, label, ok := strings.Cut(entry, "=")
+		resourceType, label = strings.TrimSpace(resourceType), strings.TrimSpace(label)
+		if !ok || resourceType == "" || !resourceLabelPattern.MatchString(label) {
+			fmt.Fprintf(output, "Ignoring resource label entry %q: expected type=label with a label of letters, digits, '_', '.', ':', '/' or '-'\n", entry)
+			continue
+		}
+		labels[resourceType] = label
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// driftLabel classifies a plan by the label covering most of its changed resources, so a plan that
+// mostly drifts security groups routes to the network team; ties go to the alphabetically first label
+func driftLabel(planData []byte, labels map[string]string) (string, error) {
+	var plan planJSON
+	if err := json.Unmarshal(planData, &plan); err != nil {
+		return "", fmt.Errorf("error parsing plan JSON: %w", err)
+	}
+
+	fallback := defaultResourceLabel
+	if label, ok := labels["*"]; ok {
+		fallback = label
+	}
+
+	counts := make(map[string]int)
+	for _, change := range plan.ResourceChanges {
+		if !isResourceChange(change.Change.Actions) {
+			continue
+		}
+		label, ok := matchResourceType(change.Type, labels)
+		if !ok {
+			label = fallback
+		}
+		counts[label]++
+	}
+
+	dominant := fallback
+	for label, count := range counts {
+		if count > counts[dominant] || (count == counts[dominant] && label < dominant) {
+			dominant = label
+		}
+	}
+	return dominant, nil
+}
+
+// labelPlan classifies plan JSON from showPlan; failures are reported and fall back to the generic label
+func labelPlan(planData []byte, labels map[string]string) string {
+	label, err := driftLabel(planData, labels)
+	if err != nil {
+		fmt.Fprintf(output, "Could not classify drift, labelling it %s: %v\n", defaultResourceLabel, err)
+		return defaultResourceLabel
+	}
+	return label
+}
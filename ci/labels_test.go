@@ -0,0 +1,76 @@
+//go:build unit
+
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNetworkPlanJSON is trimmed `terraform show -json` output that mostly drifts network resources
+const testNetworkPlanJSON = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{"address": "aws_security_group.web", "type": "aws_security_group", "change": {"actions": ["update"]}},
+		{"address": "aws_security_group_rule.ssh", "type": "aws_security_group_rule", "change": {"actions": ["delete", "create"]}},
+		{"address": "aws_iam_policy.deploy", "type": "aws_iam_policy", "change": {"actions": ["update"]}},
+		{"address": "aws_vpc.main", "type": "aws_vpc", "change": {"actions": ["no-op"]}}
+	]
+}`
+
+// TestDriftLabel tests classifying sample plans by their dominant changed resource type
+func TestDriftLabel(t *testing.T) {
+	labels := map[string]string{"aws_security_group*": "drift:network", "aws_vpc": "drift:network", "aws_iam_*": "drift:iam"}
+
+	tests := []struct {
+		name     string
+		plan     string
+		labels   map[string]string
+		expected string
+	}{
+		{name: "dominant type wins", plan: testNetworkPlanJSON, labels: labels, expected: "drift:network"},
+		{name: "ties go to the first label", plan: testPlanJSON, labels: labels, expected: "drift:iam"},
+		{name: "unmatched resources fall back", plan: testPlanJSON, labels: map[string]string{"aws_security_group*": "drift:network"}, expected: defaultResourceLabel},
+		{name: "configured fallback", plan: testPlanJSON, labels: map[string]string{"*": "drift:platform", "aws_vpc": "drift:network"}, expected: "drift:platform"},
+		{name: "no changes fall back", plan: `{"resource_changes": []}`, labels: labels, expected: defaultResourceLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label, err := driftLabel([]byte(tt.plan), tt.labels)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, label)
+		})
+	}
+
+	t.Run("invalid plan JSON", func(t *testing.T) {
+		_, err := driftLabel([]byte("Plan: 1 to change"), labels)
+		assert.Error(t, err)
+	})
+}
+
+// TestParseResourceLabels tests parsing of DRIFT_RESOURCE_LABELS type=label pairs
+func TestParseResourceLabels(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	output = io.Discard
+
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+	}{
+		{name: "pairs with spaces", value: "aws_iam_*=drift:iam, aws_security_group = drift:network", expected: map[string]string{"aws_iam_*": "drift:iam", "aws_security_group": "drift:network"}},
+		{name: "malformed entries skipped", value: "aws_iam_policy=drift:iam,aws_s3_bucket,aws_kms_key=,aws_vpc=drift network", expected: map[string]string{"aws_iam_policy": "drift:iam"}},
+		{name: "empty", value: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseResourceLabels(tt.value))
+		})
+	}
+}
@@ -14,17 +14,31 @@ import (
 
 // Payload represents the JSON structure expected by the environment endpoint
 type Payload struct {
-	RepoName        string `json:"repoName"`
-	Branch          string `json:"branchName"`
-	Environment     string `json:"environment"`
-	EnvironmentTier string `json:"environmentTier"`
-	DriftThreshold  string `json:"driftThreshold"`
-	ProjectID       string `json:"projectId"`
-	Operation       string `json:"operation"`
-	ExitCode        int    `json:"exitCode"`
-	Scheduled       bool   `json:"scheduled"`
-	Timestamp       string `json:"timestamp"`            // Added to match server-side Payload
-	PlanOutput      string `json:"planOutput,omitempty"` // Terraform plan output
+	RepoName        string            `json:"repoName"`
+	Branch          string            `json:"branchName"`
+	RefType         string            `json:"refType,omitempty"` // branch or tag, so tag pipelines can be compared against COMPARISON_REF_TYPE=tag
+	Environment     string            `json:"environment"`
+	EnvironmentTier string            `json:"environmentTier"`
+	DriftThreshold  string            `json:"driftThreshold"`
+	ProjectID       string            `json:"projectId"`
+	IssueProjectID  string            `json:"issueProjectId,omitempty"` // Project that receives drift issues
+	Operation       string            `json:"operation"`
+	MergeRequestIID string            `json:"mergeRequestIid,omitempty"` // Merge request for plan preview notes
+	ExitCode        int               `json:"exitCode"`
+	Scheduled       bool              `json:"scheduled"`
+	Timestamp       string            `json:"timestamp"`                // Added to match server-side Payload
+	PlanOutput      string            `json:"planOutput,omitempty"`     // Terraform plan output
+	CommitSHA       string            `json:"commitSha,omitempty"`      // Commit that was planned
+	StateLockError  bool              `json:"stateLockError,omitempty"` // Terraform failed to acquire the state lock
+	Metadata        map[string]string `json:"metadata,omitempty"`       // Labels such as team or region from DRIFT_METADATA
+	DriftWeight     int               `json:"driftWeight,omitempty"`    // Criticality of the drifted resources from DRIFT_CRITICALITY
+	ResourceLabel   string            `json:"resourceLabel,omitempty"`  // Issue label for the dominant drifted resources from DRIFT_RESOURCE_LABELS
+	Replayed        bool              `json:"replayed,omitempty"`       // Resent from the backlog by --replay-backlog
+	PipelineSource  string            `json:"pipelineSource,omitempty"` // What triggered the pipeline, from CI_PIPELINE_SOURCE
+	InitFailed      bool              `json:"initFailed,omitempty"`     // AUTO_INIT's terraform init failed, so the operation never ran
+	CommitAuthor    string            `json:"commitAuthor,omitempty"`   // Author of the applied commit, from CI_COMMIT_AUTHOR
+	PlanChecksum    string            `json:"planChecksum,omitempty"`   // SHA256 of the drifted plan, from PLAN_CHECKSUM
+	Heartbeat       bool              `json:"heartbeat,omitempty"`      // Only marks the environment as seen, from SEND_HEARTBEAT
 }
 
 // debugLog prints messages only when GUARDIAN_DEBUG is set to true
@@ -39,19 +53,63 @@ func debugLog(format string, args ...interface{}) {
 	}
 
 	if debugMode {
-		fmt.Printf(format, args...)
+		fmt.Fprintf(output, format, args...)
 	}
 }
 
+// planArgs appends -detailed-exitcode to terraform plan arguments that do not already have it, so the
+// plan exits with code 2 when there is drift; with autoDetailedExitcode false the arguments are trusted as given
+func planArgs(tfArgs []string, autoDetailedExitcode bool) []string {
+	if !autoDetailedExitcode {
+		debugLog("Not adding -detailed-exitcode flag, --no-auto-detailed-exitcode is set\n")
+		return tfArgs
+	}
+
+	for _, arg := range tfArgs[1:] {
+		if arg == "-detailed-exitcode" {
+			return tfArgs
+		}
+	}
+
+	debugLog("Added -detailed-exitcode flag to terraform plan command\n")
+	return append(tfArgs, "-detailed-exitcode")
+}
+
 func main() {
 	// Define command line flags for Drift Guardian configuration
-	terraformPtr := flag.String("terraform-version", "", "The version of Terraform used for operations")
-	endpointPtr := flag.String("drift-endpoint", "", "The URL of the Drift Guardian service (can also be set via DRIFT_GUARDIAN_ENDPOINT environment variable)")
-	scheduledPtr := flag.Bool("drift-scheduled", false, "Whether this is a scheduled run (can also be set via SCHEDULED environment variable)")
+	flag.String("terraform-version", "", "The version of Terraform used for operations (can also be set via TERRAFORM_VERSION environment variable)")
+	flag.String("drift-endpoint", "", "The URL of the Drift Guardian service (can also be set via DRIFT_GUARDIAN_ENDPOINT environment variable)")
+	flag.String("drift-endpoints", "", "Comma-separated URLs of additional Drift Guardian services that also receive reports (can also be set via DRIFT_GUARDIAN_ENDPOINTS environment variable)")
+	flag.Bool("drift-scheduled", false, "Whether this is a scheduled run (can also be set via SCHEDULED environment variable)")
+	flag.Int("webhook-max-attempts", 0, "Maximum webhook delivery attempts (can also be set via DRIFT_GUARDIAN_WEBHOOK_MAX_ATTEMPTS environment variable, default 3, max 10)")
+	flag.String("webhook-success-codes", "", "Comma-separated HTTP status codes treated as webhook success (can also be set via DRIFT_GUARDIAN_WEBHOOK_SUCCESS_CODES environment variable, default any 2xx)")
+	flag.String("webhook-timeout", "", "Total time allowed for webhook delivery including retries, e.g. 90s (can also be set via WEBHOOK_TIMEOUT environment variable, default 1m)")
+	flag.String("pushgateway-url", "", "Prometheus Pushgateway URL to push run metrics to (can also be set via PUSHGATEWAY_URL environment variable)")
+	flag.String("backlog-file", "", "File undelivered webhooks are saved to for --replay-backlog, e.g. in the runner cache (can also be set via DRIFT_GUARDIAN_BACKLOG_FILE environment variable)")
+	flag.String("result-file", "", "Path to write the run outcome as JSON for CI job artifacts, e.g. drift-result.json (can also be set via DRIFT_RESULT_FILE environment variable)")
+	flag.Bool("auto-init", false, "Run terraform init before plan and apply (can also be set via AUTO_INIT environment variable)")
+	flag.String("init-args", "", "Space-separated arguments for the automatic terraform init, e.g. \"-input=false -upgrade\" (can also be set via INIT_ARGS environment variable)")
+	flag.Bool("async-webhook", false, "Deliver webhooks from a background process so the terraform exit code is returned without waiting; failed deliveries are only recorded in the backlog file (can also be set via DRIFT_GUARDIAN_ASYNC_WEBHOOK environment variable)")
+	flag.Bool("plan-checksum", false, "Send a SHA256 checksum of drifted plans so the server can report drift unchanged since the last run (can also be set via PLAN_CHECKSUM environment variable)")
+	flag.Bool("send-heartbeat", false, "Send a heartbeat for operations other than plan, apply and destroy so the server's staleness detection sees the job is still running (can also be set via SEND_HEARTBEAT environment variable)")
+	replayPtr := flag.Bool("replay-backlog", false, "Resend webhooks saved to the backlog file and exit without running terraform")
+	configPtr := flag.String("config", "", "Path to a YAML or JSON file with Drift Guardian settings; flags and environment variables override file values")
+	noAutoExitcodePtr := flag.Bool("no-auto-detailed-exitcode", false, "Pass terraform plan arguments through without adding -detailed-exitcode; drift is only detected if the plan exits with code 2")
+	deliverPtr := flag.String("deliver-webhook", "", "Internal: deliver the webhook saved in this file by --async-webhook and exit")
 
 	// Parse command line flags
 	flag.Parse()
 
+	// Replay mode only resends saved webhooks
+	if *replayPtr {
+		os.Exit(runReplay(*configPtr))
+	}
+
+	// The background half of --async-webhook only delivers the saved report
+	if *deliverPtr != "" {
+		os.Exit(runAsyncDelivery(*deliverPtr))
+	}
+
 	// Get remaining arguments (these will be passed to terraform)
 	tfArgs := flag.Args()
 
@@ -69,56 +127,60 @@ func main() {
 	// Extract the terraform operation (first argument)
 	operation := tfArgs[0]
 
-	// For 'plan' operation, ensure -detailed-exitcode is included
+	// For 'plan' operation, ensure -detailed-exitcode is included unless the user manages it
 	if operation == "plan" {
-		// Check if -detailed-exitcode is already in the arguments
-		hasDetailedExitcode := false
-		for _, arg := range tfArgs[1:] {
-			if arg == "-detailed-exitcode" {
-				hasDetailedExitcode = true
-				break
-			}
-		}
-
-		// If not present, add it
-		if !hasDetailedExitcode {
-			tfArgs = append(tfArgs, "-detailed-exitcode")
-			debugLog("Added -detailed-exitcode flag to terraform plan command\n")
-		}
+		tfArgs = planArgs(tfArgs, !*noAutoExitcodePtr)
 	}
 
-	// Check for endpoint in environment variable if not provided as flag
-	endpoint := *endpointPtr
-	if endpoint == "" {
-		endpoint = os.Getenv("DRIFT_GUARDIAN_ENDPOINT")
+	// Resolve settings from flags, environment variables, and the optional config file
+	file, err := loadFileConfig(*configPtr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
-
-	terraformVersion := *terraformPtr
-	if terraformVersion == "" {
-		terraformVersion = os.Getenv("TERRAFORM_VERSION")
+	settings := resolveSettings(flag.CommandLine, file)
+	endpoints := webhookEndpoints(settings)
+	terraformVersion := settings.TerraformVersion
+	scheduled := settings.Scheduled
+	maxAttempts := settings.MaxAttempts
+	successCodes := settings.SuccessCodes
+	webhookTimeout := settings.WebhookTimeout
+	pushgatewayURL := settings.PushgatewayURL
+	criticality := settings.Criticality
+	backlogFile := settings.BacklogFile
+	autoInit := settings.AutoInit
+	initArgs := settings.InitArgs
+	resultFile := settings.ResultFile
+	asyncWebhook := settings.AsyncWebhook
+	planChecksumEnabled := settings.PlanChecksum
+	resourceLabels := settings.ResourceLabels
+	sendHeartbeat := settings.SendHeartbeat
+
+	// Weighing and labelling drift need a saved plan, so write one when the command does not already
+	var planFile, tempPlanFile string
+	if operation == "plan" && (len(criticality) > 0 || len(resourceLabels) > 0) {
+		planFile = planOutFile(tfArgs[1:])
+		if planFile == "" {
+			if f, err := os.CreateTemp("", "drift-guardian-*.tfplan"); err != nil {
+				fmt.Fprintf(output, "Could not create a plan file, drift will not be weighted or labelled: %v\n", err)
+			} else {
+				_ = f.Close()
+				tempPlanFile = f.Name()
+				planFile = tempPlanFile
+				tfArgs = append(tfArgs, "-out="+planFile)
+				debugLog("Added -out flag to terraform plan command to weigh and label drift\n")
+			}
+		}
 	}
 
 	// Set TFENV_TERRAFORM_VERSION to the endpoint value
 	_ = os.Setenv("TFENV_TERRAFORM_VERSION", terraformVersion)
 
-	// Check if the scheduled flag was set in environment variable
-	scheduled := *scheduledPtr
-	if !scheduled {
-		scheduledEnv := os.Getenv("SCHEDULED")
-		if scheduledEnv != "" {
-			parsedValue, err := strconv.ParseBool(scheduledEnv)
-			if err == nil {
-				scheduled = parsedValue
-			}
-		}
-	}
-
 	// Get GitLab environment variables
-	projectID := os.Getenv("CI_PROJECT_ID")
-	if projectID == "" {
-		debugLog("Warning: CI_PROJECT_ID environment variable not set\n")
-		projectID = "default"
-	}
+	projectID := ciProjectID()
+
+	// Optionally file drift issues in a central project instead of this one
+	issueProjectID := os.Getenv("DRIFT_GUARDIAN_ISSUE_PROJECT_ID")
 
 	repoName := os.Getenv("CI_PROJECT_NAME")
 	if repoName == "" {
@@ -147,21 +209,73 @@ func main() {
 		debugLog("Drift Threshold Override not setting, using 'default'\n")
 	}
 
-	branchName := os.Getenv("CI_COMMIT_BRANCH")
-	if branchName == "" {
-		debugLog("Warning: CI_COMMIT_BRANCH environment variable not set, using 'default'\n")
-		branchName = "default"
+	// Only set in merge request pipelines
+	mergeRequestIID := os.Getenv("CI_MERGE_REQUEST_IID")
+
+	// Optional; recorded on drift issues for traceability
+	commitSHA := os.Getenv("CI_COMMIT_SHA")
+
+	// Optional; e.g. schedule, push or web, recorded for the audit trail
+	pipelineSource := os.Getenv("CI_PIPELINE_SOURCE")
+
+	// Optional; "Name <email>", only sent with applies so drift can be routed to the last applier
+	var commitAuthor string
+	if operation == "apply" {
+		commitAuthor = os.Getenv("CI_COMMIT_AUTHOR")
 	}
 
+	// Optional key=value pairs, e.g. team=payments,region=eu, shown on drift issues
+	metadata := parseMetadata(os.Getenv("DRIFT_METADATA"))
+
+	branchName, refType := commitRef()
+
 	// Log the configuration values
 	debugLog("Drift Guardian CLI configured with:\n")
-	debugLog("  Endpoint: %s\n", endpoint)
+	debugLog("  Endpoints: %v\n", endpoints)
 	debugLog("  Repository Name: %s\n", repoName)
 	debugLog("  Project ID: %s\n", projectID)
-	debugLog("  Branch Name: %s\n", branchName)
+	if issueProjectID != "" {
+		debugLog("  Issue Project ID: %s\n", issueProjectID)
+	}
+	debugLog("  Branch Name: %s (%s)\n", branchName, refType)
+	if commitSHA != "" {
+		debugLog("  Commit SHA: %s\n", commitSHA)
+	}
+	if mergeRequestIID != "" {
+		debugLog("  Merge Request IID: %s\n", mergeRequestIID)
+	}
+	if pipelineSource != "" {
+		debugLog("  Pipeline Source: %s\n", pipelineSource)
+	}
+	if commitAuthor != "" {
+		debugLog("  Commit Author: %s\n", commitAuthor)
+	}
+	if len(metadata) > 0 {
+		debugLog("  Metadata: %v\n", metadata)
+	}
 	debugLog("  Environment Tier: %s\n", environmentTier)
 	debugLog("  Environment: %s\n", environment)
 	debugLog("  Scheduled: %t\n", scheduled)
+	debugLog("  Webhook Max Attempts: %d\n", maxAttempts)
+	if len(successCodes) > 0 {
+		debugLog("  Webhook Success Codes: %v\n", successCodes)
+	}
+	debugLog("  Webhook Timeout: %v\n", webhookTimeout)
+	if pushgatewayURL != "" {
+		debugLog("  Pushgateway URL: %s\n", pushgatewayURL)
+	}
+	if len(criticality) > 0 {
+		debugLog("  Criticality: %v\n", criticality)
+	}
+	if len(resourceLabels) > 0 {
+		debugLog("  Resource Labels: %v\n", resourceLabels)
+	}
+	if backlogFile != "" {
+		debugLog("  Backlog File: %s\n", backlogFile)
+	}
+	if autoInit {
+		debugLog("  Auto Init Args: %v\n", initArgs)
+	}
 	debugLog("  Operation: %s\n", operation)
 	debugLog("  Terraform Args: %v\n", tfArgs)
 
@@ -174,15 +288,28 @@ func main() {
 	// Create and execute the terraform command
 	cmd := exec.Command(terraformBinary, tfArgs...)
 
-	// Declare exitCode and err in the outer scope
+	// Declare exitCode in the outer scope
 	var exitCode int
-	var err error
+
+	// Ephemeral runners need an init first; if it fails the operation cannot run, and its exit code is
+	// reported instead of drift
+	initFailed := false
+	if needsInit(autoInit, operation) {
+		if initExitCode := runInit(terraformBinary, initArgs); initExitCode != 0 {
+			initFailed = true
+			exitCode = initExitCode
+		}
+	}
 
 	// For plan operations, capture the output to include in the payload
 	var planOutput string
-	if operation == "plan" {
+	// Captured for every operation so state lock failures can be recognized
+	var stderr bytes.Buffer
+	if initFailed {
+		fmt.Fprintf(output, "Terraform init failed with exit code %d, skipping terraform %s\n", exitCode, operation)
+	} else if operation == "plan" {
 		// Create a buffer to capture the output
-		var stdout, stderr bytes.Buffer
+		var stdout bytes.Buffer
 		cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
 		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 		cmd.Stdin = os.Stdin
@@ -209,7 +336,7 @@ func main() {
 		// For non-plan operations, just connect to parent process
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 
 		// Run the terraform command
 		debugLog("Executing: %s %s\n", terraformBinary, strings.Join(tfArgs, " "))
@@ -228,36 +355,116 @@ func main() {
 		debugLog("Terraform command exited with code: %d\n", exitCode)
 	}
 
-	// If endpoint is configured, send webhook to track drift
-	if endpoint != "" {
+	// Write the outcome for the job's artifacts before the plan output is truncated for the webhook
+	if resultFile != "" {
+		result := driftResult{
+			RepoName:      repoName,
+			Environment:   environment,
+			Operation:     operation,
+			ExitCode:      exitCode,
+			DriftDetected: operation == "plan" && exitCode == 2 && !initFailed,
+			InitFailed:    initFailed,
+			Timestamp:     time.Now().Format(time.RFC3339),
+		}
+		if operation == "plan" && !initFailed {
+			result.Resources = parseResourceCounts(planOutput)
+		}
+		if err := writeResult(resultFile, result); err != nil {
+			fmt.Fprintf(output, "Could not write drift result: %v\n", err)
+		} else {
+			debugLog("Wrote drift result to %s\n", resultFile)
+		}
+	}
+
+	// If endpoints are configured, send webhooks to track drift
+	if len(endpoints) > 0 {
 		// Create payload
 		payload := Payload{
 			RepoName:        repoName,
 			Branch:          branchName,
+			RefType:         refType,
 			Environment:     environment,
 			EnvironmentTier: environmentTier,
 			DriftThreshold:  driftThreshold,
 			ProjectID:       projectID,
+			IssueProjectID:  issueProjectID,
 			Operation:       operation,
+			MergeRequestIID: mergeRequestIID,
 			ExitCode:        exitCode,
 			Scheduled:       scheduled,
 			Timestamp:       time.Now().Format(time.RFC3339),
+			CommitSHA:       commitSHA,
+			PipelineSource:  pipelineSource,
+			CommitAuthor:    commitAuthor,
+			Metadata:        metadata,
+			StateLockError:  exitCode == 1 && isStateLockError(stderr.String()),
+			InitFailed:      initFailed,
+		}
+
+		if payload.StateLockError {
+			fmt.Fprintf(output, "Terraform could not acquire the state lock; reporting it without counting drift\n")
 		}
 
 		// Add plan output for plan operations with drift detected
-		if operation == "plan" && exitCode == 2 {
+		if operation == "plan" && exitCode == 2 && !initFailed {
+			// Checksum the full output, since truncation could hide a change
+			if planChecksumEnabled {
+				payload.PlanChecksum = planChecksum(planOutput)
+			}
+
 			// Limit the size of the plan output to avoid very large payloads
 			const maxOutputSize = 50000 // 50KB limit
 			if len(planOutput) > maxOutputSize {
 				planOutput = planOutput[:maxOutputSize] + "\n... [output truncated due to size]\n"
 			}
 			payload.PlanOutput = planOutput
+
+			if planFile != "" {
+				if planData, err := showPlan(terraformBinary, planFile); err != nil {
+					fmt.Fprintf(output, "Could not read the plan, drift will not be weighted or labelled: %v\n", err)
+				} else {
+					if len(criticality) > 0 {
+						payload.DriftWeight = weighPlan(planData, criticality)
+						fmt.Fprintf(output, "Weighted drift score: %d\n", max(payload.DriftWeight, 1))
+					}
+					if len(resourceLabels) > 0 {
+						payload.ResourceLabel = labelPlan(planData, resourceLabels)
+						fmt.Fprintf(output, "Drift issue label: %s\n", payload.ResourceLabel)
+					}
+				}
+			}
 		}
 
-		// Send webhook
-		if operation == "plan" || operation == "apply" || operation == "destroy" {
-			sendWebhook(endpoint, payload)
+		delivery := webhookDelivery{
+			Endpoints:    endpoints,
+			Payload:      payload,
+			MaxAttempts:  maxAttempts,
+			SuccessCodes: successCodes,
+			Timeout:      webhookTimeout,
+			BacklogFile:  backlogFile,
 		}
+
+		// Send webhook, or a heartbeat for operations that are not reported
+		if reportsOperation(operation) {
+			dispatchWebhook(delivery, asyncWebhook)
+		} else if sendHeartbeat {
+			debugLog("Sending heartbeat for terraform %s\n", operation)
+			dispatchWebhook(heartbeatDelivery(delivery), asyncWebhook)
+		}
+	}
+
+	if tempPlanFile != "" {
+		_ = os.Remove(tempPlanFile)
+	}
+
+	// Push run metrics too, so they are recorded even when the server is unavailable
+	if pushgatewayURL != "" && reportsOperation(operation) {
+		pushMetrics(pushgatewayURL, repoName, environment, operation, exitCode)
+	}
+
+	// The requested operation never ran, so fail the job rather than let it pass silently
+	if initFailed {
+		os.Exit(exitCode)
 	}
 
 	// Exit with the same exit code as the terraform command
@@ -271,3 +478,28 @@ func main() {
 		}
 	}
 }
+
+// runReplay resends the backlog and returns the process exit code. Reports still undelivered are kept
+// for the next replay without failing the job, so an unreachable server never blocks terraform runs.
+func runReplay(configPath string) int {
+	file, err := loadFileConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	settings := resolveSettings(flag.CommandLine, file)
+	endpoints := webhookEndpoints(settings)
+	if len(endpoints) == 0 || settings.BacklogFile == "" {
+		fmt.Fprintf(os.Stderr, "--replay-backlog requires a drift endpoint and a backlog file\n")
+		return 1
+	}
+
+	delivered, remaining, err := replayBacklog(settings.BacklogFile, endpoints, settings.MaxAttempts, settings.SuccessCodes, settings.WebhookTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error replaying backlog: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(output, "Replayed %d backlogged webhooks, %d remaining\n", delivered, remaining)
+	return 0
+}
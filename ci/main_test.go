@@ -0,0 +1,29 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPlanArgs tests adding -detailed-exitcode to terraform plan arguments
+func TestPlanArgs(t *testing.T) {
+	tests := []struct {
+		name                 string
+		args                 []string
+		autoDetailedExitcode bool
+		expected             []string
+	}{
+		{name: "added", args: []string{"plan", "-input=false"}, autoDetailedExitcode: true, expected: []string{"plan", "-input=false", "-detailed-exitcode"}},
+		{name: "already present", args: []string{"plan", "-detailed-exitcode", "-out=tfplan"}, autoDetailedExitcode: true, expected: []string{"plan", "-detailed-exitcode", "-out=tfplan"}},
+		{name: "disabled", args: []string{"plan", "-out=tfplan"}, autoDetailedExitcode: false, expected: []string{"plan", "-out=tfplan"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, planArgs(tt.args, tt.autoDetailedExitcode))
+		})
+	}
+}
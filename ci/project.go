@@ -0,0 +1,17 @@
+package main
+
+import "os"
+
+// ciProjectID returns the ID of the project the pipeline runs in: GitLab's CI_PROJECT_ID, or on
+// GitHub Actions the numeric GITHUB_REPOSITORY_ID that ISSUE_PROVIDER=github files issues against
+func ciProjectID() string {
+	if projectID := os.Getenv("CI_PROJECT_ID"); projectID != "" {
+		return projectID
+	}
+	if projectID := os.Getenv("GITHUB_REPOSITORY_ID"); projectID != "" {
+		return projectID
+	}
+
+	debugLog("Warning: neither CI_PROJECT_ID nor GITHUB_REPOSITORY_ID is set, using 'default'\n")
+	return "default"
+}
@@ -0,0 +1,33 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCIProjectID tests reading the project ID on GitLab CI and GitHub Actions
+func TestCIProjectID(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{name: "gitlab", env: map[string]string{"CI_PROJECT_ID": "123"}, expected: "123"},
+		{name: "github actions", env: map[string]string{"GITHUB_REPOSITORY_ID": "456789"}, expected: "456789"},
+		{name: "gitlab preferred", env: map[string]string{"CI_PROJECT_ID": "123", "GITHUB_REPOSITORY_ID": "456789"}, expected: "123"},
+		{name: "neither", expected: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CI_PROJECT_ID", "GITHUB_REPOSITORY_ID"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			assert.Equal(t, tt.expected, ciProjectID())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pushgatewayJob is the Pushgateway job name that groups Drift Guardian metrics
+const pushgatewayJob = "drift_guardian"
+
+// groupingLabel encodes a Pushgateway grouping key label; values are base64 encoded so
+// repository and environment names containing '/' stay intact
+func groupingLabel(name, value string) string {
+	if value == "" {
+		return name + "@base64/=" // Pushgateway's representation of an empty value
+	}
+	return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// formatRunMetrics renders the run result in the Prometheus text exposition format
+func formatRunMetrics(exitCode int, timestamp time.Time) string {
+	driftDetected := 0
+	if exitCode == 2 {
+		driftDetected = 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP drift_guardian_exit_code Exit code of the last terraform run.\n")
+	fmt.Fprintf(&b, "# TYPE drift_guardian_exit_code gauge\n")
+	fmt.Fprintf(&b, "drift_guardian_exit_code %d\n", exitCode)
+	fmt.Fprintf(&b, "# HELP drift_guardian_drift_detected Whether the last terraform plan detected drift.\n")
+	fmt.Fprintf(&b, "# TYPE drift_guardian_drift_detected gauge\n")
+	fmt.Fprintf(&b, "drift_guardian_drift_detected %d\n", driftDetected)
+	fmt.Fprintf(&b, "# HELP drift_guardian_last_run_timestamp_seconds Unix time of the last terraform run.\n")
+	fmt.Fprintf(&b, "# TYPE drift_guardian_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "drift_guardian_last_run_timestamp_seconds %d\n", timestamp.Unix())
+	return b.String()
+}
+
+// pushMetrics pushes the run result to a Prometheus Pushgateway, grouped by repository,
+// environment, and operation. Failures are reported but never fail the pipeline.
+func pushMetrics(gatewayURL, repoName, environment, operation string, exitCode int) {
+	url := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + pushgatewayJob + "/" +
+		groupingLabel("repo", repoName) + "/" +
+		groupingLabel("environment", environment) + "/" +
+		groupingLabel("operation", operation)
+
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBufferString(formatRunMetrics(exitCode, time.Now())))
+	if err != nil {
+		fmt.Fprintf(output, "Error creating pushgateway request: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(output, "Error pushing metrics to pushgateway: %v\n", err)
+		return
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		fmt.Fprintf(output, "Pushgateway returned non-success status code: %d\n", resp.StatusCode)
+		return
+	}
+
+	debugLog("Metrics pushed to pushgateway at %s\n", url)
+}
@@ -0,0 +1,68 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPushMetrics tests that run metrics are pushed to the grouping key for the environment
+func TestPushMetrics(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	pushMetrics(server.URL+"/", "group/test-repo", "production", "plan", 2)
+
+	encode := base64.RawURLEncoding.EncodeToString
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/drift_guardian/repo@base64/"+encode([]byte("group/test-repo"))+"/environment@base64/"+encode([]byte("production"))+"/operation@base64/"+encode([]byte("plan")), path)
+	assert.Contains(t, body, "drift_guardian_exit_code 2\n")
+	assert.Contains(t, body, "drift_guardian_drift_detected 1\n")
+	assert.Contains(t, body, "drift_guardian_last_run_timestamp_seconds ")
+	assert.Empty(t, buf.String(), "Successful push should not print errors")
+}
+
+// TestPushMetrics_NonFatal tests that pushgateway failures are reported without failing
+func TestPushMetrics_NonFatal(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	pushMetrics(server.URL, "test-repo", "production", "apply", 0)
+	assert.Contains(t, buf.String(), "Pushgateway returned non-success status code: 503")
+
+	buf.Reset()
+	server.Close()
+	pushMetrics(server.URL, "test-repo", "production", "apply", 0)
+	assert.Contains(t, buf.String(), "Error pushing metrics to pushgateway")
+}
+
+// TestGroupingLabel tests grouping key encoding, including empty values
+func TestGroupingLabel(t *testing.T) {
+	assert.Equal(t, "repo@base64/Z3JvdXAvcmVwbw", groupingLabel("repo", "group/repo"))
+	assert.Equal(t, "environment@base64/=", groupingLabel("environment", ""))
+}
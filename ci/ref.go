@@ -0,0 +1,21 @@
+package main
+
+import "os"
+
+// commitRef returns the git ref the pipeline runs on and whether it is a branch or a tag. Merge
+// request pipelines report their source branch; tag pipelines have no branch and report the tag.
+func commitRef() (name, refType string) {
+	if branch := os.Getenv("CI_COMMIT_BRANCH"); branch != "" {
+		return branch, "branch"
+	}
+	// Merge request pipelines expose the branch under a different variable
+	if branch := os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"); branch != "" {
+		return branch, "branch"
+	}
+	if tag := os.Getenv("CI_COMMIT_TAG"); tag != "" {
+		return tag, "tag"
+	}
+
+	debugLog("Warning: neither CI_COMMIT_BRANCH nor CI_COMMIT_TAG is set, using 'default'\n")
+	return "default", "branch"
+}
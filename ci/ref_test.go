@@ -0,0 +1,36 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCommitRef tests detecting the branch or tag a pipeline runs on
+func TestCommitRef(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		expectedName    string
+		expectedRefType string
+	}{
+		{name: "branch pipeline", env: map[string]string{"CI_COMMIT_BRANCH": "main"}, expectedName: "main", expectedRefType: "branch"},
+		{name: "merge request pipeline", env: map[string]string{"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature"}, expectedName: "feature", expectedRefType: "branch"},
+		{name: "tag pipeline", env: map[string]string{"CI_COMMIT_TAG": "v1.2.0"}, expectedName: "v1.2.0", expectedRefType: "tag"},
+		{name: "no ref", expectedName: "default", expectedRefType: "branch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CI_COMMIT_BRANCH", "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_TAG"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			name, refType := commitRef()
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedRefType, refType)
+		})
+	}
+}
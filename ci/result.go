@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// planSummaryPattern matches terraform's plan summary line, e.g. "Plan: 1 to add, 2 to change, 0 to destroy."
+var planSummaryPattern = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+// ansiPattern matches the color codes terraform adds to its output
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// driftResult is the outcome of a run, written as a CI job artifact
+type driftResult struct {
+	RepoName      string          `json:"repoName"`
+	Environment   string          `json:"environment"`
+	Operation     string          `json:"operation"`
+	ExitCode      int             `json:"exitCode"`
+	DriftDetected bool            `json:"driftDetected"`
+	InitFailed    bool            `json:"initFailed,omitempty"`
+	Resources     *resourceCounts `json:"resources,omitempty"` // Omitted when the plan summary was not found
+	Timestamp     string          `json:"timestamp"`
+}
+
+// resourceCounts are the planned resource changes from terraform's plan summary
+type resourceCounts struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// parseResourceCounts reads the resource counts from plan output; a plan without changes counts
+// nothing, and output without a summary returns nil
+func parseResourceCounts(planOutput string) *resourceCounts {
+	planOutput = ansiPattern.ReplaceAllString(planOutput, "")
+	match := planSummaryPattern.FindStringSubmatch(planOutput)
+	if match == nil {
+		if strings.Contains(planOutput, "No changes.") {
+			return &resourceCounts{}
+		}
+		return nil
+	}
+
+	add, _ := strconv.Atoi(match[1])
+	change, _ := strconv.Atoi(match[2])
+	destroy, _ := strconv.Atoi(match[3])
+	return &resourceCounts{Add: add, Change: change, Destroy: destroy}
+}
+
+// writeResult writes the run outcome as indented JSON to path
+func writeResult(path string, result driftResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling drift result: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing drift result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+//go:build unit
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseResourceCounts tests reading resource counts from terraform plan output
+func TestParseResourceCounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected *resourceCounts
+	}{
+		{
+			name:     "plan with changes",
+			output:   "  # aws_s3_bucket.logs will be updated in-place\n\nPlan: 1 to add, 2 to change, 3 to destroy.\n",
+			expected: &resourceCounts{Add: 1, Change: 2, Destroy: 3},
+		},
+		{
+			name:     "colored output",
+			output:   "\x1b[0m\x1b[1mPlan:\x1b[0m 0 to add, 1 to change, 0 to destroy.\n",
+			expected: &resourceCounts{Change: 1},
+		},
+		{
+			name:     "no changes",
+			output:   "\nNo changes. Your infrastructure matches the configuration.\n",
+			expected: &resourceCounts{},
+		},
+		{
+			name:   "failed plan",
+			output: "╷\n│ Error: Invalid provider configuration\n╵\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseResourceCounts(tt.output))
+		})
+	}
+}
+
+// TestWriteResult tests the artifact contents for drifted and failed runs
+func TestWriteResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   driftResult
+		expected string
+	}{
+		{
+			name: "drift detected",
+			result: driftResult{
+				RepoName:      "shop",
+				Environment:   "production",
+				Operation:     "plan",
+				ExitCode:      2,
+				DriftDetected: true,
+				Resources:     &resourceCounts{Add: 1, Change: 2},
+				Timestamp:     "2024-05-01T10:00:00Z",
+			},
+			expected: `{"repoName":"shop","environment":"production","operation":"plan","exitCode":2,"driftDetected":true,` +
+				`"resources":{"add":1,"change":2,"destroy":0},"timestamp":"2024-05-01T10:00:00Z"}`,
+		},
+		{
+			name: "init failed without resource counts",
+			result: driftResult{
+				RepoName:    "shop",
+				Environment: "production",
+				Operation:   "plan",
+				ExitCode:    1,
+				InitFailed:  true,
+				Timestamp:   "2024-05-01T10:00:00Z",
+			},
+			expected: `{"repoName":"shop","environment":"production","operation":"plan","exitCode":1,"driftDetected":false,` +
+				`"initFailed":true,"timestamp":"2024-05-01T10:00:00Z"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "drift-result.json")
+			require.NoError(t, writeResult(path, tt.result))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+
+			var decoded driftResult
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, tt.result, decoded)
+		})
+	}
+}
+
+// TestWriteResult_UnwritablePath tests that a missing directory is reported as an error
+func TestWriteResult_UnwritablePath(t *testing.T) {
+	err := writeResult(filepath.Join(t.TempDir(), "missing", "drift-result.json"), driftResult{})
+	assert.ErrorContains(t, err, "error writing drift result")
+}
@@ -0,0 +1,19 @@
+package main
+
+import "strings"
+
+// stateLockMessages are the errors terraform prints when another run holds the state lock
+var stateLockMessages = []string{
+	"Error acquiring the state lock",
+	"Error locking state",
+}
+
+// isStateLockError reports whether terraform's output shows it failed to acquire the state lock
+func isStateLockError(output string) bool {
+	for _, message := range stateLockMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+//go:build unit
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIsStateLockError tests the state lock detection heuristic against terraform output
+func TestIsStateLockError(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected bool
+	}{
+		{
+			name: "lock held by another run",
+			output: "╷\n│ Error: Error acquiring the state lock\n│ \n│ Error message: ConditionalCheckFailedException: The conditional request failed\n" +
+				"│ Lock Info:\n│   ID:        4b7e3f1a-0c2d-4e5f-8a9b-1c2d3e4f5a6b\n╵\n",
+			expected: true,
+		},
+		{
+			name:     "colored output",
+			output:   "\x1b[31m│\x1b[0m \x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mError acquiring the state lock\x1b[0m\n",
+			expected: true,
+		},
+		{
+			name:     "older terraform message",
+			output:   "Error locking state: Error acquiring the state lock: resource temporarily unavailable\n",
+			expected: true,
+		},
+		{
+			name:     "unrelated error",
+			output:   "╷\n│ Error: Invalid provider configuration\n╵\n",
+			expected: false,
+		},
+		{
+			name:     "lock acquired successfully",
+			output:   "Acquiring state lock. This may take a few moments...\nReleasing state lock. This may take a few moments...\n",
+			expected: false,
+		},
+		{
+			name:     "empty output",
+			output:   "",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isStateLockError(tt.output))
+		})
+	}
+}
@@ -2,67 +2,186 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"slices"
 	"time"
 )
 
-// sendWebhook sends a webhook to the environment endpoint
-func sendWebhook(endpoint string, payload Payload) {
+// version is the CLI version reported to the server, set at build time with
+// -ldflags "-X main.version=1.4.0"
+var version = "dev"
+
+// cliVersionHeader carries the CLI version so the server can flag outdated CLIs
+const cliVersionHeader = "X-Drift-Guardian-CLI-Version"
+
+// minCLIVersionHeader is set by the server when this CLI is older than the version it expects
+const minCLIVersionHeader = "X-Drift-Guardian-Min-CLI-Version"
+
+// output is where webhook progress messages are written
+var output io.Writer = os.Stdout
+
+// retryBackoff is the base delay between webhook attempts, doubled after each failure
+var retryBackoff = time.Second
+
+// maxRetryBackoff caps the delay between webhook attempts
+const maxRetryBackoff = 30 * time.Second
+
+// maxDebugResponseBytes limits how much of a webhook response body is read for debug logging
+const maxDebugResponseBytes = 4096
+
+// maxWebhookAttempts caps the configured number of webhook attempts
+const maxWebhookAttempts = 10
+
+// defaultWebhookTimeout bounds webhook delivery, including retries, when no timeout is configured
+const defaultWebhookTimeout = time.Minute
+
+// retryDelay returns the backoff before the given retry (1 for the first retry), capped at maxRetryBackoff
+func retryDelay(retry int) time.Duration {
+	delay := retryBackoff
+	for i := 1; i < retry && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// redactedPayloadJSON returns the payload as JSON for debug logging, with the plan output redacted
+// since it may contain sensitive values
+func redactedPayloadJSON(payload Payload) string {
+	if payload.PlanOutput != "" {
+		payload.PlanOutput = fmt.Sprintf("[redacted %d bytes]", len(payload.PlanOutput))
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf("<unable to marshal payload: %v>", err)
+	}
+	return string(body)
+}
+
+// isSuccessStatus reports whether a webhook response status counts as delivered; an empty
+// successCodes accepts any 2xx status
+func isSuccessStatus(code int, successCodes []int) bool {
+	if len(successCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(successCodes, code)
+}
+
+// sendWebhook sends a webhook to the environment endpoint, trying up to maxAttempts times and
+// treating the statuses in successCodes (any 2xx when empty) as delivered. timeout bounds the whole
+// delivery, so a slow attempt uses up the budget rather than being abandoned and re-sent. It reports
+// whether the payload was delivered.
+func sendWebhook(endpoint string, payload Payload, maxAttempts int, successCodes []int, timeout time.Duration) bool {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if maxAttempts > maxWebhookAttempts {
+		fmt.Fprintf(output, "Webhook max attempts %d exceeds limit, using %d\n", maxAttempts, maxWebhookAttempts)
+		maxAttempts = maxWebhookAttempts
+	}
+
 	// Convert payload to JSON
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		fmt.Printf("Error marshaling payload: %v\n", err)
-		return // Don't exit on webhook error
+		fmt.Fprintf(output, "Error marshaling payload: %v\n", err)
+		return false // Don't exit on webhook error
 	}
 
-	// Create request
-	req, err := http.NewRequest("POST", endpoint+"/environments", bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		return
+	url := endpoint + "/environments"
+	debugLog("Webhook payload for %s: %s\n", url, redactedPayloadJSON(payload))
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := &http.Client{}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	// Retry with exponential backoff
+	attempts := 0
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			backoff := retryDelay(i)
+			if deadline, _ := ctx.Deadline(); time.Until(deadline) <= backoff {
+				fmt.Fprintf(output, "Webhook timeout of %v leaves no time for attempt %d/%d\n", timeout, i+1, maxAttempts)
+				break
+			}
+			debugLog("Retrying in %v...\n", backoff)
+			time.Sleep(backoff)
+		}
+		attempts++
 
-	// Send request with retry logic
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+		// Create a fresh request per attempt so the body is re-sent
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+		if err != nil {
+			fmt.Fprintf(output, "Error creating request: %v\n", err)
+			return false
+		}
+
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(cliVersionHeader, version)
 
-	// Try up to 3 times with exponential backoff
-	for i := 0; i < 3; i++ {
 		resp, err := client.Do(req)
 		if err != nil {
-			fmt.Printf("Error sending webhook (attempt %d/3): %v\n", i+1, err)
-			if i < 2 {
-				// Wait before retrying (exponential backoff)
-				backoff := time.Duration(1<<uint(i)) * time.Second
-				debugLog("Retrying in %v...\n", backoff)
-				time.Sleep(backoff)
-				continue
+			fmt.Fprintf(output, "Error sending webhook (attempt %d/%d): %v\n", i+1, maxAttempts, err)
+			if ctx.Err() != nil {
+				break // The server may still process this attempt, so do not send it again
 			}
-			return // Don't exit on webhook error
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDebugResponseBytes))
+		_ = resp.Body.Close()
+
+		if minimum := resp.Header.Get(minCLIVersionHeader); minimum != "" {
+			fmt.Fprintf(output, "Drift Guardian CLI %s is older than the minimum %s expected by %s, please upgrade\n", version, minimum, endpoint)
+		}
+
+		// An outdated CLI is rejected the same way on every attempt
+		if resp.StatusCode == http.StatusUpgradeRequired && !isSuccessStatus(resp.StatusCode, successCodes) {
+			fmt.Fprintf(output, "Webhook rejected by %s: the CLI must be upgraded\n", endpoint)
+			break
 		}
-		defer func() { _ = resp.Body.Close() }()
 
 		// Check response status
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			fmt.Printf("Received non-success status code: %d (attempt %d/3)\n", resp.StatusCode, i+1)
-			if i < 2 {
-				// Wait before retrying
-				backoff := time.Duration(1<<uint(i)) * time.Second
-				debugLog("Retrying in %v...\n", backoff)
-				time.Sleep(backoff)
-				continue
-			}
-			return // Don't exit on webhook error
+		if !isSuccessStatus(resp.StatusCode, successCodes) {
+			fmt.Fprintf(output, "Received non-success status code: %d (attempt %d/%d)\n", resp.StatusCode, i+1, maxAttempts)
+			continue
+		}
+
+		// Success; async and batch responses are not retried since the report was received
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			debugLog("Drift tracking webhook accepted for asynchronous processing by %s\n", url)
+		case http.StatusMultiStatus:
+			debugLog("Drift tracking webhook returned multi-status from %s: %s\n", url, body)
+		default:
+			debugLog("Drift tracking webhook sent successfully to %s, status: %s\n", url, resp.Status)
+		}
+		return true
+	}
+
+	// Don't exit on webhook error, but leave a single line log scrapers can alert on
+	fmt.Fprintf(output, "webhook delivery failed after %d attempts to %s\n", attempts, url)
+	return false
+}
+
+// sendWebhooks delivers the payload to each endpoint in turn, each with its own attempts and timeout,
+// so an unreachable instance never keeps the others from receiving the report. It returns the
+// endpoints that did not accept it.
+func sendWebhooks(endpoints []string, payload Payload, maxAttempts int, successCodes []int, timeout time.Duration) []string {
+	var failed []string
+	for _, endpoint := range endpoints {
+		if !sendWebhook(endpoint, payload, maxAttempts, successCodes, timeout) {
+			failed = append(failed, endpoint)
 		}
+	}
 
-		// Success
-		debugLog("Drift tracking webhook sent successfully to %s/environments, status: %s\n", endpoint, resp.Status)
-		return
+	if len(endpoints) > 1 {
+		fmt.Fprintf(output, "Webhook delivered to %d/%d endpoints\n", len(endpoints)-len(failed), len(endpoints))
 	}
+	return failed
 }
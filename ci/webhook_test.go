@@ -0,0 +1,355 @@
+//go:build unit
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSendWebhook_RetryMessaging tests that attempt counts and the summary line follow the configured limit
+func TestSendWebhook_RetryMessaging(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name            string
+		maxAttempts     int
+		failures        int
+		expectedLines   []string
+		expectedSummary bool
+	}{
+		{
+			name:        "exhausted attempts emit summary",
+			maxAttempts: 2,
+			failures:    2,
+			expectedLines: []string{
+				"Received non-success status code: 500 (attempt 1/2)",
+				"Received non-success status code: 500 (attempt 2/2)",
+			},
+			expectedSummary: true,
+		},
+		{
+			name:        "success after retry has no summary",
+			maxAttempts: 5,
+			failures:    1,
+			expectedLines: []string{
+				"Received non-success status code: 500 (attempt 1/5)",
+			},
+			expectedSummary: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			var buffer bytes.Buffer
+			output = &buffer
+
+			sendWebhook(server.URL, Payload{RepoName: "test-repo"}, tt.maxAttempts, nil, time.Minute)
+
+			for _, line := range tt.expectedLines {
+				assert.Contains(t, buffer.String(), line)
+			}
+
+			summary := fmt.Sprintf("webhook delivery failed after %d attempts to %s/environments", tt.maxAttempts, server.URL)
+			if tt.expectedSummary {
+				assert.Contains(t, buffer.String(), summary)
+				assert.Equal(t, tt.maxAttempts, calls)
+			} else {
+				assert.False(t, strings.Contains(buffer.String(), "webhook delivery failed"))
+			}
+		})
+	}
+}
+
+// TestRetryDelay tests that the webhook backoff doubles and is capped
+func TestRetryDelay(t *testing.T) {
+	originalBackoff := retryBackoff
+	defer func() { retryBackoff = originalBackoff }()
+	retryBackoff = time.Second
+
+	assert.Equal(t, time.Second, retryDelay(1))
+	assert.Equal(t, 2*time.Second, retryDelay(2))
+	assert.Equal(t, 16*time.Second, retryDelay(5))
+	assert.Equal(t, maxRetryBackoff, retryDelay(6), "Backoff should be capped")
+	assert.Equal(t, maxRetryBackoff, retryDelay(100), "Large retry counts must not overflow")
+}
+
+// TestSendWebhook_MaxAttemptsCapped tests that excessive attempt counts are clamped
+func TestSendWebhook_MaxAttemptsCapped(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	retryBackoff = time.Millisecond
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	output = &buffer
+
+	sendWebhook(server.URL, Payload{RepoName: "test-repo"}, 1000, nil, time.Minute)
+
+	assert.Equal(t, maxWebhookAttempts, calls)
+	assert.Contains(t, buffer.String(), "Webhook max attempts 1000 exceeds limit, using 10")
+	assert.Contains(t, buffer.String(), fmt.Sprintf("webhook delivery failed after %d attempts", maxWebhookAttempts))
+}
+
+// TestSendWebhook_DebugPayload tests that the debug log shows the payload with the plan output redacted
+func TestSendWebhook_DebugPayload(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	t.Setenv("GUARDIAN_DEBUG", "true")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	sendWebhook(server.URL, Payload{
+		RepoName:    "test-repo",
+		Environment: "production",
+		Operation:   "plan",
+		ExitCode:    2,
+		PlanOutput:  "password = \"hunter2\"",
+	}, 1, nil, time.Minute)
+
+	logged := buf.String()
+	assert.Contains(t, logged, `"repoName":"test-repo"`)
+	assert.Contains(t, logged, `"environment":"production"`)
+	assert.Contains(t, logged, `"exitCode":2`)
+	assert.Contains(t, logged, `"planOutput":"[redacted 20 bytes]"`)
+	assert.NotContains(t, logged, "hunter2", "Plan output must not be logged")
+}
+
+// TestSendWebhook_DebugPayloadDisabled tests that the payload is not logged without GUARDIAN_DEBUG
+func TestSendWebhook_DebugPayloadDisabled(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	t.Setenv("GUARDIAN_DEBUG", "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	sendWebhook(server.URL, Payload{RepoName: "test-repo"}, 1, nil, time.Minute)
+
+	assert.NotContains(t, buf.String(), "Webhook payload")
+}
+
+// TestSendWebhook_SuccessCodes tests that 202 and 207 responses are treated as delivered and not retried
+func TestSendWebhook_SuccessCodes(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name          string
+		status        int
+		successCodes  []int
+		expectedCalls int
+	}{
+		{name: "202 accepted by default", status: http.StatusAccepted, expectedCalls: 1},
+		{name: "207 multi-status by default", status: http.StatusMultiStatus, expectedCalls: 1},
+		{name: "202 in configured set", status: http.StatusAccepted, successCodes: []int{200, 202}, expectedCalls: 1},
+		{name: "207 outside configured set is retried", status: http.StatusMultiStatus, successCodes: []int{200, 202}, expectedCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			var buf bytes.Buffer
+			output = &buf
+			sendWebhook(server.URL, Payload{RepoName: "test-repo"}, 3, tt.successCodes, time.Minute)
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedCalls == 3, strings.Contains(buf.String(), "webhook delivery failed"))
+		})
+	}
+}
+
+// TestSendWebhooks_PartialFailure tests that a failing endpoint does not keep the others from
+// receiving the report, and that each endpoint is retried independently
+func TestSendWebhooks_PartialFailure(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	retryBackoff = time.Millisecond
+
+	var centralCalls, brokenCalls, teamCalls int
+	central := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		centralCalls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer central.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	team := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		teamCalls++
+		if teamCalls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer team.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	failed := sendWebhooks([]string{central.URL, broken.URL, team.URL}, Payload{RepoName: "test-repo"}, 3, nil, time.Minute)
+
+	assert.Equal(t, []string{broken.URL}, failed)
+	assert.Equal(t, 1, centralCalls)
+	assert.Equal(t, 3, brokenCalls)
+	assert.Equal(t, 2, teamCalls)
+	assert.Contains(t, buf.String(), "webhook delivery failed after 3 attempts to "+broken.URL+"/environments")
+	assert.Contains(t, buf.String(), "Webhook delivered to 2/3 endpoints")
+}
+
+// TestSendWebhooks_SingleEndpoint tests that a single endpoint gets no aggregate summary
+func TestSendWebhooks_SingleEndpoint(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	output = &buf
+	assert.Empty(t, sendWebhooks([]string{server.URL}, Payload{RepoName: "test-repo"}, 1, nil, time.Minute))
+	assert.NotContains(t, buf.String(), "endpoints")
+}
+
+// TestSendWebhook_Timeout tests that the timeout bounds the whole delivery, so a slow-but-working
+// server is not sent the report again
+func TestSendWebhook_Timeout(t *testing.T) {
+	originalOutput, originalBackoff := output, retryBackoff
+	defer func() { output, retryBackoff = originalOutput, originalBackoff }()
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name          string
+		delay         time.Duration
+		timeout       time.Duration
+		expectedCalls int32
+		expectFailure bool
+	}{
+		{name: "slow server within timeout", delay: 100 * time.Millisecond, timeout: time.Second, expectedCalls: 1},
+		{name: "timed out attempt is not re-sent", delay: 500 * time.Millisecond, timeout: 100 * time.Millisecond, expectedCalls: 1, expectFailure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			var buf bytes.Buffer
+			output = &buf
+			start := time.Now()
+			sendWebhook(server.URL, Payload{RepoName: "test-repo"}, 3, nil, tt.timeout)
+
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+			assert.Less(t, time.Since(start), tt.timeout+time.Second, "Delivery should stop at the timeout")
+			assert.Equal(t, tt.expectFailure, strings.Contains(buf.String(), "webhook delivery failed after 1 attempts"))
+		})
+	}
+}
+
+// TestSendWebhook_CLIVersion tests that the CLI version is sent and an outdated CLI is told to upgrade
+func TestSendWebhook_CLIVersion(t *testing.T) {
+	originalOutput, originalBackoff, originalVersion := output, retryBackoff, version
+	defer func() { output, retryBackoff, version = originalOutput, originalBackoff, originalVersion }()
+	retryBackoff = time.Millisecond
+	version = "1.2.0"
+
+	tests := []struct {
+		name          string
+		status        int
+		minimum       string
+		expectedCalls int32
+		delivered     bool
+	}{
+		{name: "current CLI", status: http.StatusOK, expectedCalls: 1, delivered: true},
+		{name: "outdated CLI is warned", status: http.StatusOK, minimum: "1.4.0", expectedCalls: 1, delivered: true},
+		{name: "outdated CLI is rejected without retrying", status: http.StatusUpgradeRequired, minimum: "1.4.0", expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				assert.Equal(t, "1.2.0", r.Header.Get(cliVersionHeader))
+				if tt.minimum != "" {
+					w.Header().Set(minCLIVersionHeader, tt.minimum)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			var buf bytes.Buffer
+			output = &buf
+			delivered := sendWebhook(server.URL, Payload{RepoName: "test-repo"}, 3, nil, time.Minute)
+
+			assert.Equal(t, tt.delivered, delivered)
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+			assert.Equal(t, tt.minimum != "", strings.Contains(buf.String(), "Drift Guardian CLI 1.2.0 is older than the minimum 1.4.0"))
+		})
+	}
+}
+
+// TestIsSuccessStatus tests the default and configured success status sets
+func TestIsSuccessStatus(t *testing.T) {
+	assert.True(t, isSuccessStatus(http.StatusOK, nil))
+	assert.True(t, isSuccessStatus(http.StatusAccepted, nil))
+	assert.True(t, isSuccessStatus(http.StatusMultiStatus, nil))
+	assert.False(t, isSuccessStatus(http.StatusNotModified, nil))
+	assert.False(t, isSuccessStatus(http.StatusOK, []int{http.StatusAccepted}))
+	assert.True(t, isSuccessStatus(http.StatusAccepted, []int{http.StatusAccepted}))
+}
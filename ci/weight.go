@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxDriftWeight matches the largest weight the server accepts for a single detection
+const maxDriftWeight = 100
+
+// planJSON holds the parts of `terraform show -json` output used to weigh drift
+type planJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// parseCriticality parses comma-separated type=weight pairs such as aws_iam_policy=10; a trailing *
+// matches a type prefix, e.g. aws_iam_*=10. Malformed entries are reported and skipped.
+func parseCriticality(value string) map[string]int {
+	criticality := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		resourceType, weightValue, ok := strings.Cut(entry, "=")
+		resourceType = strings.TrimSpace(resourceType)
+		weight, err := strconv.Atoi(strings.TrimSpace(weightValue))
+		if !ok || resourceType == "" || err != nil || weight < 1 || weight > maxDriftWeight {
+			fmt.Fprintf(output, "Ignoring criticality entry %q: expected type=weight with a weight between 1 and %d\n", entry, maxDriftWeight)
+			continue
+		}
+		criticality[resourceType] = weight
+	}
+
+	if len(criticality) == 0 {
+		return nil
+	}
+	return criticality
+}
+
+// resourceWeight returns the weight of a resource type: an exact match first, then the longest
+// matching prefix pattern, then 1
+func resourceWeight(resourceType string, criticality map[string]int) int {
+	if weight, ok := matchResourceType(resourceType, criticality); ok {
+		return weight
+	}
+	return 1
+}
+
+// matchResourceType looks up a resource type in patterns keyed by type, where a trailing * matches a
+// type prefix: an exact match first, then the longest matching prefix pattern
+func matchResourceType[V any](resourceType string, patterns map[string]V) (V, bool) {
+	if value, ok := patterns[resourceType]; ok {
+		return value, true
+	}
+
+	var value V
+	matched := -1
+	for pattern, patternValue := range patterns {
+		prefix, isPrefix := strings.CutSuffix(pattern, "*")
+		if isPrefix && strings.HasPrefix(resourceType, prefix) && len(prefix) > matched {
+			value, matched = patternValue, len(prefix)
+		}
+	}
+	return value, matched >= 0
+}
+
+// driftWeight scores a plan by its most critical changed resource, so a single drifted IAM policy
+// weighs more than any number of drifted tags
+func driftWeight(planData []byte, criticality map[string]int) (int, error) {
+	var plan planJSON
+	if err := json.Unmarshal(planData, &plan); err != nil {
+		return 0, fmt.Errorf("error parsing plan JSON: %w", err)
+	}
+
+	weight := 1
+	for _, change := range plan.ResourceChanges {
+		if !isResourceChange(change.Change.Actions) {
+			continue
+		}
+		if changeWeight := resourceWeight(change.Type, criticality); changeWeight > weight {
+			debugLog("Resource %s weighs %d\n", change.Address, changeWeight)
+			weight = changeWeight
+		}
+	}
+	return weight, nil
+}
+
+// showPlan reads a saved plan file as JSON with `terraform show -json`
+func showPlan(terraformBinary, planFile string) ([]byte, error) {
+	planData, err := exec.Command(terraformBinary, "show", "-json", planFile).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan %s: %w", planFile, err)
+	}
+	return planData, nil
+}
+
+// weighPlan scores plan JSON from showPlan; failures are reported and leave the detection unweighted
+func weighPlan(planData []byte, criticality map[string]int) int {
+	weight, err := driftWeight(planData, criticality)
+	if err != nil {
+		fmt.Fprintf(output, "Could not weigh drift, counting it once: %v\n", err)
+		return 0
+	}
+	return weight
+}
+
+// isResourceChange reports whether plan actions modify the resource rather than leave or read it
+func isResourceChange(actions []string) bool {
+	for _, action := range actions {
+		if action != "no-op" && action != "read" {
+			return true
+		}
+	}
+	return false
+}
+
+// planOutFile returns the plan file passed to terraform plan via -out, if any
+func planOutFile(args []string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "-out="); ok {
+			return value
+		}
+		if arg == "-out" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
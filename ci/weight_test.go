@@ -0,0 +1,81 @@
+//go:build unit
+
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlanJSON is trimmed `terraform show -json` output with a tag change and an IAM policy change
+const testPlanJSON = `{
+	"format_version": "1.2",
+	"resource_changes": [
+		{"address": "aws_s3_bucket.logs", "type": "aws_s3_bucket", "change": {"actions": ["update"]}},
+		{"address": "aws_iam_policy.deploy", "type": "aws_iam_policy", "change": {"actions": ["update"]}},
+		{"address": "aws_security_group.web", "type": "aws_security_group", "change": {"actions": ["no-op"]}},
+		{"address": "data.aws_caller_identity.current", "type": "aws_caller_identity", "change": {"actions": ["read"]}}
+	]
+}`
+
+// TestDriftWeight tests scoring a plan by its most critical changed resource
+func TestDriftWeight(t *testing.T) {
+	tests := []struct {
+		name        string
+		criticality map[string]int
+		expected    int
+	}{
+		{name: "exact type match", criticality: map[string]int{"aws_iam_policy": 10}, expected: 10},
+		{name: "prefix match", criticality: map[string]int{"aws_iam_*": 8}, expected: 8},
+		{name: "exact match beats prefix", criticality: map[string]int{"aws_iam_*": 8, "aws_iam_policy": 3, "aws_s3_bucket": 2}, expected: 3},
+		{name: "longest prefix wins", criticality: map[string]int{"aws_*": 2, "aws_iam_*": 6}, expected: 6},
+		{name: "unchanged resources are ignored", criticality: map[string]int{"aws_security_group": 9, "aws_caller_identity": 9}, expected: 1},
+		{name: "unmapped resources weigh one", criticality: map[string]int{"google_project_iam_member": 10}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight, err := driftWeight([]byte(testPlanJSON), tt.criticality)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, weight)
+		})
+	}
+
+	t.Run("invalid plan JSON", func(t *testing.T) {
+		_, err := driftWeight([]byte("Plan: 1 to change"), map[string]int{"aws_iam_policy": 10})
+		assert.Error(t, err)
+	})
+}
+
+// TestParseCriticality tests parsing of DRIFT_CRITICALITY type=weight pairs
+func TestParseCriticality(t *testing.T) {
+	originalOutput := output
+	defer func() { output = originalOutput }()
+	output = io.Discard
+
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]int
+	}{
+		{name: "pairs with spaces", value: "aws_iam_*=10, aws_security_group = 5", expected: map[string]int{"aws_iam_*": 10, "aws_security_group": 5}},
+		{name: "malformed entries skipped", value: "aws_iam_policy=10,aws_s3_bucket,aws_kms_key=high,aws_vpc=0,aws_eip=500", expected: map[string]int{"aws_iam_policy": 10}},
+		{name: "empty", value: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseCriticality(tt.value))
+		})
+	}
+}
+
+// TestPlanOutFile tests finding the saved plan path in terraform plan arguments
+func TestPlanOutFile(t *testing.T) {
+	assert.Equal(t, "tfplan", planOutFile([]string{"-input=false", "-out=tfplan"}))
+	assert.Equal(t, "plan.out", planOutFile([]string{"-out", "plan.out", "-detailed-exitcode"}))
+	assert.Empty(t, planOutFile([]string{"-detailed-exitcode"}))
+}
@@ -0,0 +1,93 @@
+//go:build unit
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriterLogger tests that entries are written as JSON lines stamped with the context's principal
+func TestWriterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf)
+
+	ctx := WithPrincipal(context.Background(), "token:abc123")
+	logger.Record(ctx, Entry{Action: ActionDriftReset, RepoName: "infra", Environment: "prod", Details: map[string]string{"operation": "apply"}})
+	logger.Record(context.Background(), Entry{Action: ActionIssueClose, RepoName: "infra", Environment: "prod"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.Equal(t, "token:abc123", first.Principal)
+	assert.Equal(t, ActionDriftReset, first.Action)
+	assert.Equal(t, "apply", first.Details["operation"])
+	assert.False(t, first.Time.IsZero(), "Entries are timestamped")
+	assert.Equal(t, SystemPrincipal, second.Principal, "Operations without a caller are attributed to the system")
+}
+
+// TestFileLogger tests that entries are appended to the audit log file
+func TestFileLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		logger, err := NewFileLogger(path)
+		require.NoError(t, err)
+		logger.Record(context.Background(), Entry{Action: ActionDriftIncrement})
+	}
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(contents), ActionDriftIncrement), "Reopening the file appends to it")
+
+	_, err = NewFileLogger(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
+
+// TestRedisStreamLogger tests that entries are appended to the configured Redis stream
+func TestRedisStreamLogger(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	logger := NewRedisStreamLogger(db, "drift-guardian:audit")
+
+	entry := Entry{Action: ActionAcknowledge, RepoName: "infra", Environment: "prod"}
+	stamped := stamp(WithPrincipal(context.Background(), "scoped:0a1b2c"), entry)
+	encoded, err := json.Marshal(stamped)
+	require.NoError(t, err)
+
+	mock.ExpectXAdd(&redis.XAddArgs{Stream: "drift-guardian:audit", Values: map[string]interface{}{"entry": string(encoded)}}).SetVal("1-0")
+	logger.Record(context.Background(), stamped)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestTokenPrincipal tests that token principals identify tokens without revealing them
+func TestTokenPrincipal(t *testing.T) {
+	principal := TokenPrincipal("token", "super-secret-token")
+	assert.True(t, strings.HasPrefix(principal, "token:"))
+	assert.NotContains(t, principal, "super-secret-token")
+	assert.Equal(t, principal, TokenPrincipal("token", "super-secret-token"), "The same token maps to the same principal")
+	assert.NotEqual(t, principal, TokenPrincipal("token", "other-token"))
+}
+
+// TestFromRequest tests carrying the request's principal over to the server context
+func TestFromRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/environments", nil)
+	assert.Equal(t, SystemPrincipal, PrincipalFrom(FromRequest(context.Background(), req)))
+
+	req = req.WithContext(WithPrincipal(req.Context(), AnonymousPrincipal))
+	assert.Equal(t, AnonymousPrincipal, PrincipalFrom(FromRequest(context.Background(), req)))
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Audited actions
+const (
+	ActionDriftIncrement = "drift.increment"
+	ActionDriftReset     = "drift.reset"
+	ActionIssueCreate    = "issue.create"
+	ActionIssueClose     = "issue.close"
+	ActionIssueEscalate  = "issue.escalate"
+	ActionAcknowledge    = "drift.acknowledge"
+)
+
+// Entry is the audit record of one mutating operation: who did what, to which environment, and when
+type Entry struct {
+	Time        time.Time         `json:"time"`
+	Principal   string            `json:"principal"`
+	Action      string            `json:"action"`
+	RepoName    string            `json:"repoName,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// Logger defines the interface for recording audit entries, separately from operational logs
+type Logger interface {
+	// Record writes entry, filling in its time and the principal carried by ctx; failures are logged
+	// so the audited operation is not undone
+	Record(ctx context.Context, entry Entry)
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SystemPrincipal is recorded for operations no authenticated caller triggered, such as escalation checks
+const SystemPrincipal = "system"
+
+// AnonymousPrincipal is recorded for requests accepted while authentication is disabled
+const AnonymousPrincipal = "anonymous"
+
+// principalKey marks a context carrying the caller an operation is audited against
+type principalKey struct{}
+
+// WithPrincipal returns a context whose audited operations are attributed to principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal carried by ctx, or SystemPrincipal when there is none
+func PrincipalFrom(ctx context.Context) string {
+	if principal, ok := ctx.Value(principalKey{}).(string); ok && principal != "" {
+		return principal
+	}
+	return SystemPrincipal
+}
+
+// FromRequest returns ctx carrying the principal the auth middleware attached to r, for handlers
+// that process requests with the server context
+func FromRequest(ctx context.Context, r *http.Request) context.Context {
+	if principal, ok := r.Context().Value(principalKey{}).(string); ok {
+		return WithPrincipal(ctx, principal)
+	}
+	return ctx
+}
+
+// TokenPrincipal identifies a bearer token without revealing it; kind distinguishes bearer from scoped tokens
+func TokenPrincipal(kind, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return kind + ":" + hex.EncodeToString(sum[:6])
+}
+
+// stamp fills in the entry's time and principal
+func stamp(ctx context.Context, entry Entry) Entry {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if entry.Principal == "" {
+		entry.Principal = PrincipalFrom(ctx)
+	}
+	return entry
+}
+
+// NopLogger implements Logger by discarding entries, for when AUDIT_LOG is not set
+type NopLogger struct{}
+
+// Record discards the entry
+func (NopLogger) Record(ctx context.Context, entry Entry) {}
+
+// WriterLogger implements Logger by writing one JSON entry per line
+type WriterLogger struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterLogger creates a logger writing JSON lines to w, such as stdout
+func NewWriterLogger(w io.Writer) *WriterLogger {
+	return &WriterLogger{writer: w}
+}
+
+// NewFileLogger creates a logger appending JSON lines to the file at path, creating it if needed
+func NewFileLogger(path string) (*WriterLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log file %s: %w", path, err)
+	}
+	return NewWriterLogger(file), nil
+}
+
+// Record writes the entry as a JSON line
+func (l *WriterLogger) Record(ctx context.Context, entry Entry) {
+	line, err := json.Marshal(stamp(ctx, entry))
+	if err != nil {
+		slog.Error("Failed to encode audit entry", "error", err, "action", entry.Action)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(append(line, '\n')); err != nil {
+		slog.Error("Failed to write audit entry", "error", err, "action", entry.Action)
+	}
+}
+
+// RedisStreamLogger implements Logger by appending entries to a Redis stream, whose entries cannot
+// be modified once added
+type RedisStreamLogger struct {
+	client *redis.Client
+	stream string
+}
+
+// NewRedisStreamLogger creates a logger appending entries to stream
+func NewRedisStreamLogger(client *redis.Client, stream string) *RedisStreamLogger {
+	return &RedisStreamLogger{client: client, stream: stream}
+}
+
+// Record appends the entry to the stream as a JSON entry field
+func (l *RedisStreamLogger) Record(ctx context.Context, entry Entry) {
+	encoded, err := json.Marshal(stamp(ctx, entry))
+	if err != nil {
+		slog.Error("Failed to encode audit entry", "error", err, "action", entry.Action)
+		return
+	}
+
+	err = l.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: l.stream,
+		Values: map[string]interface{}{"entry": string(encoded)},
+	}).Err()
+	if err != nil {
+		slog.Error("Failed to append audit entry to Redis stream", "error", err, "stream", l.stream, "action", entry.Action)
+	}
+}
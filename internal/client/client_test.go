@@ -5,11 +5,17 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"drift-guardian/internal/config"
 
@@ -241,7 +247,7 @@ func TestGitLabClient_CreateDriftIssue(t *testing.T) {
 
 			// Create client and call function
 			client := NewGitLabClient(getTestConfig(mockServer.URL, tt.gitlabToken))
-			response, err := client.CreateDriftIssue(context.Background(), tt.projectID, tt.repoName, tt.environment, tt.driftIncrement, tt.threshold, tt.planOutput)
+			response, err := client.CreateDriftIssue(context.Background(), tt.projectID, DriftDetails{RepoName: tt.repoName, Environment: tt.environment, DriftIncrement: tt.driftIncrement, Threshold: tt.threshold, PlanOutput: tt.planOutput})
 
 			if tt.expectSuccess {
 				assert.NoError(t, err)
@@ -263,6 +269,403 @@ func TestGitLabClient_CreateDriftIssue(t *testing.T) {
 	}
 }
 
+// TestGitLabClient_AddPlanPreviewNote tests posting a plan preview on a merge request
+func TestGitLabClient_AddPlanPreviewNote(t *testing.T) {
+	tests := []struct {
+		name             string
+		driftDetected    bool
+		planOutput       string
+		mockResponseCode int
+		expectedSummary  string
+		expectError      bool
+	}{
+		{
+			name:             "plan with changes",
+			driftDetected:    true,
+			planOutput:       "Plan: 1 to add, 0 to change, 0 to destroy.",
+			mockResponseCode: http.StatusCreated,
+			expectedSummary:  "shows **changes** against",
+		},
+		{
+			name:             "clean plan",
+			mockResponseCode: http.StatusCreated,
+			expectedSummary:  "shows **no changes** against",
+		},
+		{
+			name:             "merge request not found",
+			mockResponseCode: http.StatusNotFound,
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/projects/123/merge_requests/42/notes", r.URL.Path)
+				assert.Equal(t, "test-token", r.Header.Get("PRIVATE-TOKEN"))
+
+				var requestBody map[string]string
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+				body = requestBody["body"]
+
+				w.WriteHeader(tt.mockResponseCode)
+				_, _ = w.Write([]byte(`{"id": 1}`))
+			}))
+			defer mockServer.Close()
+
+			client := NewGitLabClient(getTestConfig(mockServer.URL, "test-token"))
+			err := client.AddPlanPreviewNote(context.Background(), 123, 42, "production", "feature/vpc", tt.driftDetected, tt.planOutput)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Contains(t, body, "`feature/vpc`")
+			assert.Contains(t, body, tt.expectedSummary)
+			if tt.planOutput != "" {
+				assert.Contains(t, body, tt.planOutput)
+			} else {
+				assert.NotContains(t, body, "Terraform Plan Output")
+			}
+		})
+	}
+}
+
+// TestLimitLines tests line-based truncation of plan output
+func TestLimitLines(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines = append(lines, "Plan: 3 to add, 0 to change, 0 to destroy.")
+	plan := strings.Join(lines, "\n")
+
+	t.Run("long plan keeps head and summary tail", func(t *testing.T) {
+		limited := limitLines(plan, 10)
+		assert.Equal(t, strings.Join([]string{
+			"line 1", "line 2", "line 3", "line 4", "line 5",
+			"... (991 lines omitted) ...",
+			"line 997", "line 998", "line 999", "line 1000", "Plan: 3 to add, 0 to change, 0 to destroy.",
+		}, "\n"), limited)
+	})
+
+	t.Run("short plan is unchanged", func(t *testing.T) {
+		assert.Equal(t, "line 1\nline 2", limitLines("line 1\nline 2", 10))
+	})
+
+	t.Run("zero disables the limit", func(t *testing.T) {
+		assert.Equal(t, plan, limitLines(plan, 0))
+	})
+}
+
+// TestGitLabClient_CreateDriftIssue_PlanLineLimit tests that issue descriptions honour the plan line limit
+func TestGitLabClient_CreateDriftIssue_PlanLineLimit(t *testing.T) {
+	var description string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+		description = requestBody["description"].(string)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+	}))
+	defer mockServer.Close()
+
+	cfg := getTestConfig(mockServer.URL, "test-token")
+	cfg.IssuePlanMaxLines = 4
+	client := NewGitLabClient(cfg)
+
+	plan := strings.Repeat("  + resource\n", 500) + "Plan: 500 to add, 0 to change, 0 to destroy."
+	_, err := client.CreateDriftIssue(context.Background(), 123, DriftDetails{RepoName: "test-repo", Environment: "production", DriftIncrement: 3, Threshold: 1, PlanOutput: plan})
+	require.NoError(t, err)
+
+	assert.Contains(t, description, "(497 lines omitted)")
+	assert.Contains(t, description, "Plan: 500 to add, 0 to change, 0 to destroy.")
+	assert.Equal(t, 3, strings.Count(description, "+ resource"))
+}
+
+// TestGitLabClient_CreateDriftIssue_RemediationCommand tests that the remediation template is rendered
+// for the drifted environment
+func TestGitLabClient_CreateDriftIssue_RemediationCommand(t *testing.T) {
+	var description string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+		description = requestBody["description"].(string)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+	}))
+	defer mockServer.Close()
+
+	t.Run("template is rendered", func(t *testing.T) {
+		cfg := getTestConfig(mockServer.URL, "test-token")
+		cfg.RemediationCommand = "terraform -chdir={repo}/envs/{environment} plan"
+		client := NewGitLabClient(cfg)
+
+		_, err := client.CreateDriftIssue(context.Background(), 123, DriftDetails{RepoName: "infra", Environment: "production", DriftIncrement: 3, Threshold: 1})
+		require.NoError(t, err)
+
+		assert.Contains(t, description, "## Investigate\n\n```shell\nterraform -chdir=infra/envs/production plan\n```\n")
+	})
+
+	t.Run("empty template omits the section", func(t *testing.T) {
+		client := NewGitLabClient(getTestConfig(mockServer.URL, "test-token"))
+
+		_, err := client.CreateDriftIssue(context.Background(), 123, DriftDetails{RepoName: "infra", Environment: "production", DriftIncrement: 3, Threshold: 1})
+		require.NoError(t, err)
+
+		assert.NotContains(t, description, "## Investigate")
+	})
+}
+
+// TestGitLabClient_CreateDriftIssue_Milestone tests that new drift issues are assigned to the
+// configured or active milestone
+func TestGitLabClient_CreateDriftIssue_Milestone(t *testing.T) {
+	today := time.Now().Format(time.DateOnly)
+	tests := []struct {
+		name              string
+		milestone         string
+		milestones        string
+		milestoneStatus   int
+		expectedMilestone interface{}
+	}{
+		{name: "unset omits milestone"},
+		{name: "configured milestone", milestone: "42", expectedMilestone: float64(42)},
+		{
+			name:              "active milestone is detected",
+			milestone:         "auto",
+			milestones:        `[{"id": 7, "title": "Sprint 1", "start_date": "2000-01-01", "due_date": "2000-01-14"}, {"id": 8, "title": "Sprint 2", "start_date": "` + today + `", "due_date": "` + today + `"}]`,
+			milestoneStatus:   http.StatusOK,
+			expectedMilestone: float64(8),
+		},
+		{name: "no active milestone omits milestone", milestone: "auto", milestones: `[]`, milestoneStatus: http.StatusOK},
+		{name: "failed detection omits milestone", milestone: "auto", milestoneStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody map[string]interface{}
+			var milestoneQuery string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/projects/123/milestones" {
+					milestoneQuery = r.URL.RawQuery
+					w.WriteHeader(tt.milestoneStatus)
+					_, _ = w.Write([]byte(tt.milestones))
+					return
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+			}))
+			defer mockServer.Close()
+
+			cfg := getTestConfig(mockServer.URL, "test-token")
+			cfg.DriftMilestone = tt.milestone
+			client := NewGitLabClient(cfg)
+
+			_, err := client.CreateDriftIssue(context.Background(), 123, DriftDetails{RepoName: "infra", Environment: "production", DriftIncrement: 3, Threshold: 1})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedMilestone, requestBody["milestone_id"])
+			if tt.milestone == "auto" {
+				assert.Equal(t, "state=active&include_ancestors=true", milestoneQuery)
+			}
+		})
+	}
+}
+
+// TestGitLabClient_CreateDriftIssue_ApplyAuthor tests routing new drift issues to the last apply author
+func TestGitLabClient_CreateDriftIssue_ApplyAuthor(t *testing.T) {
+	tests := []struct {
+		name                string
+		applyAuthor         string
+		author              string
+		users               string
+		expectedDescription string
+		expectedAssignees   interface{}
+		expectLookup        bool
+	}{
+		{
+			name:                "unset names the author without looking them up",
+			author:              "Jane Doe <jane@example.com>",
+			expectedDescription: "Last applied by Jane Doe <jane@example.com>.",
+		},
+		{name: "no author data", applyAuthor: "assign"},
+		{
+			name:                "mention resolves the user",
+			applyAuthor:         "mention",
+			author:              "Jane Doe <jane@example.com>",
+			users:               `[{"id": 42, "username": "jdoe"}]`,
+			expectedDescription: "Last applied by @jdoe.",
+			expectLookup:        true,
+		},
+		{
+			name:                "assign also assigns the user",
+			applyAuthor:         "assign",
+			author:              "Jane Doe <jane@example.com>",
+			users:               `[{"id": 42, "username": "jdoe"}]`,
+			expectedDescription: "Last applied by @jdoe.",
+			expectedAssignees:   []interface{}{float64(42)},
+			expectLookup:        true,
+		},
+		{
+			name:                "unknown user is named without a mention",
+			applyAuthor:         "assign",
+			author:              "Jane Doe <jane@example.com>",
+			users:               `[]`,
+			expectedDescription: "Last applied by Jane Doe <jane@example.com>.",
+			expectLookup:        true,
+		},
+		{
+			name:                "author without email is not looked up",
+			applyAuthor:         "mention",
+			author:              "Jane Doe",
+			expectedDescription: "Last applied by Jane Doe.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody map[string]interface{}
+			var userSearch string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/users" {
+					userSearch = r.URL.Query().Get("search")
+					_, _ = w.Write([]byte(tt.users))
+					return
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+			}))
+			defer mockServer.Close()
+
+			cfg := getTestConfig(mockServer.URL, "test-token")
+			cfg.ApplyAuthor = tt.applyAuthor
+			client := NewGitLabClient(cfg)
+
+			details := DriftDetails{RepoName: "infra", Environment: "production", DriftIncrement: 3, Threshold: 1, LastApplyAuthor: tt.author}
+			_, err := client.CreateDriftIssue(context.Background(), 123, details)
+			require.NoError(t, err)
+
+			description := requestBody["description"].(string)
+			if tt.expectedDescription != "" {
+				assert.Contains(t, description, tt.expectedDescription)
+			} else {
+				assert.NotContains(t, description, "Last applied by")
+			}
+			assert.Equal(t, tt.expectedAssignees, requestBody["assignee_ids"])
+			if tt.expectLookup {
+				assert.Equal(t, "jane@example.com", userSearch)
+			} else {
+				assert.Empty(t, userSearch)
+			}
+		})
+	}
+}
+
+// TestGitLabClient_GetIssueStatuses tests checking many issues with batched list requests
+func TestGitLabClient_GetIssueStatuses(t *testing.T) {
+	var queries [][]string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/projects/123/issues", r.URL.Path)
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+		queries = append(queries, r.URL.Query()["iids[]"])
+		// Issue 12 was deleted, so the list does not return it
+		_, _ = w.Write([]byte(`[{"iid": 10, "state": "opened"}, {"iid": 11, "state": "closed"}, {"iid": 13, "state": "opened"}]`))
+	}))
+	defer mockServer.Close()
+
+	client := NewGitLabClient(getTestConfig(mockServer.URL, "test-token"))
+
+	statuses, err := client.GetIssueStatuses(context.Background(), 123, []int{10, 11, 12, 13}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{10: true, 11: false, 12: false, 13: true}, statuses)
+	assert.Equal(t, [][]string{{"10", "11"}, {"12", "13"}}, queries, "Each request should carry at most the batch size")
+
+	t.Run("error status", func(t *testing.T) {
+		errorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer errorServer.Close()
+
+		_, err := NewGitLabClient(getTestConfig(errorServer.URL, "test-token")).GetIssueStatuses(context.Background(), 123, []int{10}, 100)
+		assert.ErrorContains(t, err, "502")
+	})
+}
+
+// TestGitLabClient_ScopedLabels tests that scoped labels are sent and transitioned on update and close
+func TestGitLabClient_ScopedLabels(t *testing.T) {
+	var requests []map[string]interface{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+		if !strings.HasSuffix(r.URL.Path, "/notes") {
+			requests = append(requests, requestBody)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+	}))
+	defer mockServer.Close()
+
+	cfg := getTestConfig(mockServer.URL, "test-token")
+	cfg.IssueLabels = []string{"drift::alert", "automation"}
+	cfg.ResolvedLabel = "drift::resolved"
+	client := NewGitLabClient(cfg)
+	ctx := context.Background()
+
+	_, err := client.CreateDriftIssue(ctx, 123, DriftDetails{RepoName: "test-repo", Environment: "production", DriftIncrement: 3, Threshold: 1})
+	require.NoError(t, err)
+	require.NoError(t, client.UpdateIssueDescription(ctx, 123, 10, DriftDetails{RepoName: "test-repo", Environment: "production", DriftIncrement: 4, Threshold: 1}))
+	require.NoError(t, client.CloseIssue(ctx, 123, 10, "apply", 0))
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, []interface{}{"drift::alert", "automation"}, requests[0]["labels"], "Create should send the scoped alert label")
+	assert.Equal(t, "drift::alert,automation", requests[1]["add_labels"], "Update should re-apply the alert label")
+	assert.Equal(t, "drift::resolved", requests[1]["remove_labels"], "Update should drop the resolved label in the same scope")
+	assert.Equal(t, "close", requests[2]["state_event"])
+	assert.Equal(t, "drift::resolved", requests[2]["add_labels"], "Close should apply the resolved label")
+	assert.Equal(t, "drift::alert", requests[2]["remove_labels"], "Close should replace the alert label rather than accumulate")
+}
+
+// TestGitLabClient_NoIssueLabels tests that an empty label config removes the default labels entirely
+func TestGitLabClient_NoIssueLabels(t *testing.T) {
+	var requestBody map[string]interface{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&requestBody))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123}`))
+	}))
+	defer mockServer.Close()
+
+	cfg := getTestConfig(mockServer.URL, "test-token")
+	cfg.IssueLabels = []string{}
+	client := NewGitLabClient(cfg)
+
+	_, err := client.CreateIssue(context.Background(), 123, "Drift detected", "Drift report")
+	require.NoError(t, err)
+
+	assert.NotContains(t, requestBody, "labels", "No labels should be sent")
+}
+
+// TestFormatDriftDuration tests rendering drift durations in close comments
+func TestFormatDriftDuration(t *testing.T) {
+	assert.Equal(t, "under a minute", formatDriftDuration(20*time.Second))
+	assert.Equal(t, "45m", formatDriftDuration(45*time.Minute))
+	assert.Equal(t, "1h 30m", formatDriftDuration(90*time.Minute+10*time.Second))
+	assert.Equal(t, "2d 3h 0m", formatDriftDuration(51*time.Hour))
+}
+
+// TestScopeConflicts tests detection of labels sharing a GitLab label scope
+func TestScopeConflicts(t *testing.T) {
+	assert.Equal(t, []string{"drift::alert"}, scopeConflicts([]string{"drift::resolved"}, []string{"drift::alert", "automation"}))
+	assert.Empty(t, scopeConflicts([]string{"drift::alert"}, []string{"drift::alert"}), "A label never conflicts with itself")
+	assert.Empty(t, scopeConflicts([]string{"drift-resolved"}, []string{"drift-alert"}), "Unscoped labels never conflict")
+	assert.Equal(t, []string{"team::drift::alert"}, scopeConflicts([]string{"team::drift::resolved"}, []string{"team::drift::alert", "team::owner"}), "Nested scopes use the last separator")
+}
+
 // TestGitLabClient_GetIssueStatus tests GitLab issue status checking
 func TestGitLabClient_GetIssueStatus(t *testing.T) {
 	originalToken := os.Getenv("GITLAB_API_TOKEN")
@@ -379,12 +782,17 @@ func TestGitLabClient_GetIssueStatus(t *testing.T) {
 // TestGitLabClient_IssueDescriptionGeneration tests issue description formatting
 func TestGitLabClient_IssueDescriptionGeneration(t *testing.T) {
 	tests := []struct {
-		name           string
-		environment    string
-		driftIncrement int
-		threshold      int
-		planOutput     string
-		expectedParts  []string
+		name             string
+		environment      string
+		driftIncrement   int
+		threshold        int
+		planOutput       string
+		commitSHA        string
+		comparisonBranch string
+		pipelineSource   string
+		metadata         map[string]string
+		planSummary      *PlanSummary
+		expectedParts    []string
 	}{
 		{
 			name:           "description with plan output",
@@ -414,6 +822,60 @@ func TestGitLabClient_IssueDescriptionGeneration(t *testing.T) {
 				"automatically created by Drift Guardian",
 			},
 		},
+		{
+			name:           "description with commit SHA",
+			environment:    "production",
+			driftIncrement: 3,
+			threshold:      3,
+			commitSHA:      "4f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39",
+			expectedParts: []string{
+				"Detected at commit `4f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39`.",
+			},
+		},
+		{
+			name:             "description with comparison branch",
+			environment:      "canary",
+			driftIncrement:   1,
+			threshold:        1,
+			comparisonBranch: "release",
+			expectedParts: []string{
+				"Compared against the `release` branch.",
+			},
+		},
+		{
+			name:           "description with pipeline source",
+			environment:    "production",
+			driftIncrement: 1,
+			threshold:      1,
+			pipelineSource: "schedule",
+			expectedParts: []string{
+				"Triggered by a `schedule` pipeline.",
+			},
+		},
+		{
+			name:           "description with metadata",
+			environment:    "production",
+			driftIncrement: 1,
+			threshold:      1,
+			metadata:       map[string]string{"team": "payments", "region": "eu"},
+			expectedParts: []string{
+				"## Metadata\n\n| Key | Value |\n|-----|-------|\n| `region` | eu |\n| `team` | payments |\n",
+			},
+		},
+		{
+			name:           "description with plan summary",
+			environment:    "production",
+			driftIncrement: 1,
+			threshold:      1,
+			planOutput:     "Plan: 1 to add, 1 to change, 0 to destroy.",
+			planSummary: &PlanSummary{ToAdd: 1, ToChange: 1, Resources: []ResourceChange{
+				{Address: "aws_s3_bucket.logs", Action: "create"},
+				{Address: "aws_instance.web", Action: "update"},
+			}},
+			expectedParts: []string{
+				"## Plan Summary\n\n**1** to add, **1** to change, **0** to destroy.\n\n- **create** `aws_s3_bucket.logs`\n- **update** `aws_instance.web`\n\n",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -430,6 +892,29 @@ func TestGitLabClient_IssueDescriptionGeneration(t *testing.T) {
 						"Description should contain: %s", expectedPart)
 				}
 
+				if tt.commitSHA == "" {
+					assert.NotContains(t, description, "Detected at commit", "Description should omit the commit when it is unknown")
+				}
+
+				if len(tt.metadata) == 0 {
+					assert.NotContains(t, description, "## Metadata", "Description should omit metadata when none was forwarded")
+				}
+
+				if tt.comparisonBranch == "" {
+					assert.NotContains(t, description, "Compared against", "Description should omit the branch when it is unknown")
+				}
+
+				if tt.pipelineSource == "" {
+					assert.NotContains(t, description, "Triggered by", "Description should omit the trigger when it is unknown")
+				}
+
+				if tt.planSummary == nil {
+					assert.NotContains(t, description, "## Plan Summary", "Description should omit the summary when the plan was not parsed")
+				} else {
+					assert.Less(t, strings.Index(description, "## Plan Summary"), strings.Index(description, "## Terraform Plan Output"),
+						"Summary should come before the raw plan output")
+				}
+
 				// Verify plan output is included/excluded correctly
 				if tt.planOutput == "" {
 					assert.NotContains(t, description, "## Terraform Plan Output",
@@ -445,8 +930,393 @@ func TestGitLabClient_IssueDescriptionGeneration(t *testing.T) {
 			os.Setenv("GITLAB_API_URL", mockServer.URL)
 
 			client := NewGitLabClient(getTestConfig(mockServer.URL, "test-token"))
-			_, err := client.CreateDriftIssue(context.Background(), 123, "test-repo", tt.environment, tt.driftIncrement, tt.threshold, tt.planOutput)
+			_, err := client.CreateDriftIssue(context.Background(), 123, DriftDetails{
+				RepoName:         "test-repo",
+				Environment:      tt.environment,
+				DriftIncrement:   tt.driftIncrement,
+				Threshold:        tt.threshold,
+				PlanOutput:       tt.planOutput,
+				CommitSHA:        tt.commitSHA,
+				ComparisonBranch: tt.comparisonBranch,
+				PipelineSource:   tt.pipelineSource,
+				Metadata:         tt.metadata,
+				PlanSummary:      tt.planSummary,
+			})
 			assert.NoError(t, err)
 		})
 	}
 }
+
+// TestGitLabClient_CustomCACert tests that the transport trusts a configured CA bundle
+func TestGitLabClient_CustomCACert(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 1, "state": "opened"})
+	}))
+	defer mockServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mockServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	t.Run("custom CA is trusted", func(t *testing.T) {
+		cfg := getTestConfig(mockServer.URL, "test-token")
+		cfg.GitLabCACert = caFile
+
+		transport := newTransport(cfg)
+		require.NotNil(t, transport.TLSClientConfig)
+		assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+		assert.False(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+		isOpen, err := NewGitLabClient(cfg).GetIssueStatus(context.Background(), 123, 1)
+		assert.NoError(t, err)
+		assert.True(t, isOpen)
+	})
+
+	t.Run("unknown CA is rejected", func(t *testing.T) {
+		cfg := getTestConfig(mockServer.URL, "test-token")
+
+		_, err := NewGitLabClient(cfg).GetIssueStatus(context.Background(), 123, 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid CA file fails validation", func(t *testing.T) {
+		invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+		require.NoError(t, os.WriteFile(invalidFile, []byte("not a certificate"), 0o600))
+
+		cfg := &config.Config{StorageBackend: "redis", RedisURL: "redis://localhost:6379", DriftThreshold: 1, GitLabCACert: invalidFile}
+		err := cfg.Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "GITLAB_CA_CERT_FILE")
+	})
+}
+
+// TestGitLabClient_Retries tests retrying transient GitLab failures with backoff while failing fast on other errors
+func TestGitLabClient_Retries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		retryAfter       string
+		expectedAttempts int
+		expectError      bool
+	}{
+		{name: "transient errors retried", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusCreated}, expectedAttempts: 3},
+		{name: "rate limit honours retry-after", statuses: []int{http.StatusTooManyRequests, http.StatusCreated}, retryAfter: "0", expectedAttempts: 2},
+		{name: "retries exhausted", statuses: []int{http.StatusGatewayTimeout, http.StatusGatewayTimeout, http.StatusGatewayTimeout}, expectedAttempts: 3, expectError: true},
+		{name: "client errors fail fast", statuses: []int{http.StatusBadRequest, http.StatusCreated}, expectedAttempts: 1, expectError: true},
+		{name: "server errors fail fast", statuses: []int{http.StatusInternalServerError, http.StatusCreated}, expectedAttempts: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body issueRequest
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body), "Each attempt resends the request body")
+				assert.Equal(t, "Drift detected", body.Title)
+
+				status := tt.statuses[attempts]
+				attempts++
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"iid": 1, "project_id": 123}`))
+			}))
+			defer server.Close()
+
+			cfg := getTestConfig(server.URL, "test-token")
+			cfg.GitLabMaxRetries = 2
+			cfg.GitLabRetryBaseDelay = time.Millisecond
+			issue, err := NewGitLabClient(cfg).CreateIssue(context.Background(), 123, "Drift detected", "Plan output")
+
+			assert.Equal(t, tt.expectedAttempts, attempts)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, 1, issue.ID)
+			}
+		})
+	}
+
+	t.Run("connection errors retried", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		cfg := getTestConfig(server.URL, "test-token")
+		cfg.GitLabMaxRetries = 2
+		cfg.GitLabRetryBaseDelay = time.Millisecond
+		start := time.Now()
+		_, err := NewGitLabClient(cfg).GetIssueStatus(context.Background(), 123, 1)
+		assert.Error(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 3*time.Millisecond, "Backoff waits before each retry")
+	})
+}
+
+// TestRetryDelay tests exponential backoff and Retry-After handling
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, retryDelay(nil, 0, 100*time.Millisecond, time.Minute))
+	assert.Equal(t, 400*time.Millisecond, retryDelay(nil, 2, 100*time.Millisecond, time.Minute))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	assert.Equal(t, 7*time.Second, retryDelay(resp, 0, 100*time.Millisecond, time.Minute))
+
+	resp.Header.Set("Retry-After", "86400")
+	assert.Equal(t, time.Minute, retryDelay(resp, 0, 100*time.Millisecond, time.Minute), "Long waits are capped")
+
+	resp.Header.Set("Retry-After", time.Now().Add(24*time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Minute, retryDelay(resp, 0, 100*time.Millisecond, time.Minute), "Distant dates are capped")
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Zero(t, retryDelay(resp, 0, 100*time.Millisecond, time.Minute), "Dates in the past retry immediately")
+
+	resp.Header.Set("Retry-After", "soon")
+	assert.Equal(t, 200*time.Millisecond, retryDelay(resp, 1, 100*time.Millisecond, time.Minute), "Unparseable headers fall back to backoff")
+}
+
+// TestSlackNotifier_NotifyDrift tests posting threshold breaches to a Slack incoming webhook
+func TestSlackNotifier_NotifyDrift(t *testing.T) {
+	var received slackMessage
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/services/T000/B000/XXXX", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier(&config.Config{SlackWebhookURL: server.URL + "/services/T000/B000/XXXX"})
+	notification := DriftNotification{
+		RepoName:    "infra<prod>",
+		Environment: "production",
+		DriftCount:  4,
+		Threshold:   3,
+		IssueURL:    "https://gitlab.com/platform/infra/-/issues/7",
+	}
+
+	require.NoError(t, notifier.NotifyDrift(context.Background(), notification))
+	assert.Contains(t, received.Text, "Drift threshold exceeded")
+	assert.Contains(t, received.Text, "`infra&lt;prod&gt;` / `production`", "Names are escaped for Slack")
+	assert.Contains(t, received.Text, "Drift count: *4* (threshold 3)")
+	assert.Contains(t, received.Text, "<https://gitlab.com/platform/infra/-/issues/7>")
+
+	notification.Updated = true
+	notification.IssueURL = ""
+	require.NoError(t, notifier.NotifyDrift(context.Background(), notification))
+	assert.Contains(t, received.Text, "Drift continues")
+	assert.NotContains(t, received.Text, "Issue:")
+
+	status = http.StatusNotFound
+	assert.Error(t, notifier.NotifyDrift(context.Background(), notification), "Rejected webhooks are reported")
+}
+
+// TestTokenBucket tests pacing reservations to the rate after the burst is spent
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(1, 2)
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	assert.Zero(t, bucket.reserve(), "The burst is available immediately")
+	assert.Zero(t, bucket.reserve())
+	assert.Equal(t, time.Second, bucket.reserve(), "Excess calls queue for the next token")
+	assert.Equal(t, 2*time.Second, bucket.reserve(), "Queued calls are paced one token apart")
+
+	bucket.release()
+	assert.Equal(t, 2*time.Second, bucket.reserve(), "Abandoned reservations free their token")
+
+	now = now.Add(5 * time.Second)
+	assert.Zero(t, bucket.reserve(), "Tokens refill over time")
+	now = now.Add(time.Hour)
+	assert.Zero(t, bucket.reserve())
+	assert.Zero(t, bucket.reserve())
+	assert.Equal(t, time.Second, bucket.reserve(), "Refills are capped at the burst")
+
+	assert.Nil(t, newTokenBucket(0, 1), "A zero rate disables the limit")
+	waited, err := (*tokenBucket)(nil).Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, waited)
+}
+
+// TestGitLabClient_CreateRateLimit tests that issue creation is paced across calls while other calls are not
+func TestGitLabClient_CreateRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var created []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mu.Lock()
+			created = append(created, time.Now())
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		}
+		_, _ = w.Write([]byte(`{"iid": 1, "project_id": 123, "state": "opened"}`))
+	}))
+	defer server.Close()
+
+	cfg := getTestConfig(server.URL, "test-token")
+	cfg.GitLabCreateRPS = 20
+	cfg.GitLabCreateBurst = 1
+	gitlabClient := NewGitLabClient(cfg)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := gitlabClient.CreateIssue(context.Background(), 123, "Drift detected", "Plan output")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, created, 4)
+	sort.Slice(created, func(i, j int) bool { return created[i].Before(created[j]) })
+	assert.GreaterOrEqual(t, created[3].Sub(created[0]), 140*time.Millisecond, "Four creations at 20 per second span at least three intervals")
+
+	start := time.Now()
+	_, err := gitlabClient.GetIssueStatus(context.Background(), 123, 1)
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 40*time.Millisecond, "Status checks are not rate limited")
+
+	t.Run("abandoned when the context ends", func(t *testing.T) {
+		cfg := getTestConfig(server.URL, "test-token")
+		cfg.GitLabCreateRPS = 0.1
+		cfg.GitLabCreateBurst = 1
+		slowClient := NewGitLabClient(cfg)
+
+		_, err := slowClient.CreateIssue(context.Background(), 123, "Drift detected", "Plan output")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = slowClient.CreateIssue(ctx, 123, "Drift detected", "Plan output")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, created, 5, "The deferred creation is never sent")
+	})
+}
+
+// newGitHubTestServer serves a GitHub repository with ID 123 named acme/infra and records the
+// requests made against it
+func newGitHubTestServer(t *testing.T, issueState string) (*httptest.Server, *[]string, map[string]map[string]interface{}) {
+	var requests []string
+	bodies := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+
+		request := r.Method + " " + r.URL.Path
+		requests = append(requests, request)
+		if r.ContentLength > 0 {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			bodies[request] = body
+		}
+
+		switch request {
+		case "GET /repositories/123":
+			_, _ = w.Write([]byte(`{"id": 123, "full_name": "acme/infra"}`))
+		case "POST /repos/acme/infra/issues":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 7, "title": "Drift: production", "html_url": "https://github.com/acme/infra/issues/7", "state": "open"}`))
+		case "GET /repos/acme/infra/issues/7":
+			_, _ = fmt.Fprintf(w, `{"number": 7, "state": %q}`, issueState)
+		case "GET /repos/acme/infra/issues/8":
+			w.WriteHeader(http.StatusGone)
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, bodies
+}
+
+// TestGitHubClient_CreateDriftIssue tests creating drift issues in the repository resolved from its ID
+func TestGitHubClient_CreateDriftIssue(t *testing.T) {
+	server, requests, bodies := newGitHubTestServer(t, "open")
+	client := NewGitHubClient(&config.Config{GitHubBaseURL: server.URL, GitHubToken: "gh-token"})
+
+	details := DriftDetails{RepoName: "infra", Environment: "production", DriftIncrement: 3, Threshold: 2, PlanOutput: "Plan: 1 to add"}
+	issue, err := client.CreateDriftIssue(context.Background(), 123, details, "detection:scheduled")
+	require.NoError(t, err)
+	assert.Equal(t, 7, issue.ID)
+	assert.Equal(t, 123, issue.ProjectID)
+	assert.Equal(t, "https://github.com/acme/infra/issues/7", issue.WebURL)
+
+	body := bodies["POST /repos/acme/infra/issues"]
+	assert.Equal(t, "Drift: production", body["title"])
+	assert.Contains(t, body["body"], "# Drift report for `production` environment")
+	assert.Contains(t, body["body"], "Plan: 1 to add")
+	assert.Equal(t, []interface{}{"drift-alert", "automation", "detection:scheduled"}, body["labels"])
+
+	_, err = client.CreateDriftIssue(context.Background(), 123, details)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /repositories/123",
+		"POST /repos/acme/infra/issues",
+		"POST /repos/acme/infra/issues",
+	}, *requests, "The repository path is resolved once")
+}
+
+// TestGitHubClient_CloseIssue tests commenting on, closing and relabelling resolved drift issues
+func TestGitHubClient_CloseIssue(t *testing.T) {
+	server, requests, bodies := newGitHubTestServer(t, "open")
+	client := NewGitHubClient(&config.Config{
+		GitHubBaseURL: server.URL,
+		GitHubToken:   "gh-token",
+		IssueLabels:   []string{"drift::alert", "automation"},
+		ResolvedLabel: "drift::resolved",
+	})
+
+	require.NoError(t, client.CloseIssue(context.Background(), 123, 7, "apply", 2*time.Hour))
+
+	assert.Equal(t, []string{
+		"GET /repositories/123",
+		"POST /repos/acme/infra/issues/7/comments",
+		"PATCH /repos/acme/infra/issues/7",
+		"POST /repos/acme/infra/issues/7/labels",
+		"DELETE /repos/acme/infra/issues/7/labels/drift::alert",
+	}, *requests)
+	assert.Contains(t, bodies["POST /repos/acme/infra/issues/7/comments"]["body"], "**Drift Resolved**")
+	assert.Equal(t, "closed", bodies["PATCH /repos/acme/infra/issues/7"]["state"])
+	assert.Equal(t, []interface{}{"drift::resolved"}, bodies["POST /repos/acme/infra/issues/7/labels"]["labels"])
+}
+
+// TestGitHubClient_AnnotateIssue tests labelling and commenting on existing GitHub issues
+func TestGitHubClient_AnnotateIssue(t *testing.T) {
+	server, requests, bodies := newGitHubTestServer(t, "open")
+	var client IssueAnnotator = NewGitHubClient(&config.Config{GitHubBaseURL: server.URL, GitHubToken: "gh-token"})
+
+	require.NoError(t, client.AddIssueLabels(context.Background(), 123, 7, []string{"escalated"}))
+	require.NoError(t, client.AddIssueComment(context.Background(), 123, 7, "**Drift Escalated**"))
+
+	assert.Equal(t, []string{
+		"GET /repositories/123",
+		"POST /repos/acme/infra/issues/7/labels",
+		"POST /repos/acme/infra/issues/7/comments",
+	}, *requests)
+	assert.Equal(t, []interface{}{"escalated"}, bodies["POST /repos/acme/infra/issues/7/labels"]["labels"])
+	assert.Equal(t, "**Drift Escalated**", bodies["POST /repos/acme/infra/issues/7/comments"]["body"])
+}
+
+// TestGitHubClient_GetIssueStatus tests reading whether GitHub issues are open
+func TestGitHubClient_GetIssueStatus(t *testing.T) {
+	for state, expected := range map[string]bool{"open": true, "closed": false} {
+		server, _, _ := newGitHubTestServer(t, state)
+		client := NewGitHubClient(&config.Config{GitHubBaseURL: server.URL, GitHubToken: "gh-token"})
+
+		isOpen, err := client.GetIssueStatus(context.Background(), 123, 7)
+		require.NoError(t, err)
+		assert.Equal(t, expected, isOpen, state)
+
+		isOpen, err = client.GetIssueStatus(context.Background(), 123, 8)
+		require.NoError(t, err)
+		assert.False(t, isOpen, "Deleted issues are not open")
+	}
+
+	_, err := NewGitHubClient(&config.Config{}).GetIssueStatus(context.Background(), 123, 7)
+	assert.Error(t, err, "A token is required")
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// driftIssueTitle returns the title of an environment's drift issue
+func driftIssueTitle(environment string) string {
+	return fmt.Sprintf("Drift: %s", environment)
+}
+
+// issueFooter returns the closing line of an issue description written by Drift Guardian;
+// action is created or updated
+func issueFooter(action string) string {
+	return fmt.Sprintf("*This issue was automatically %s by Drift Guardian on %s*", action, time.Now().Format(time.RFC1123))
+}
+
+// resolvedComment returns the comment left on a drift issue when its drift is resolved; a non-zero
+// driftDuration says how long the environment was drifted
+func resolvedComment(operation string, driftDuration time.Duration) string {
+	comment := fmt.Sprintf("**Drift Resolved** - Infrastructure drift has been resolved through successful Terraform `%s` operation.", operation)
+	if driftDuration > 0 {
+		comment += fmt.Sprintf(" The environment was drifted for %s.", formatDriftDuration(driftDuration))
+	}
+	return comment + " Issue automatically closed by Drift Guardian."
+}
+
+// formatPlanSummary renders the plan's change counts and the resources it changes
+func formatPlanSummary(summary PlanSummary) string {
+	section := fmt.Sprintf("## Plan Summary\n\n**%d** to add, **%d** to change, **%d** to destroy.\n\n",
+		summary.ToAdd, summary.ToChange, summary.ToDestroy)
+	for _, resource := range summary.Resources {
+		section += fmt.Sprintf("- **%s** `%s`\n", resource.Action, resource.Address)
+	}
+	if len(summary.Resources) > 0 {
+		section += "\n"
+	}
+	return section
+}
+
+// formatDriftDescription renders the drift issue body shared by the issue trackers, without the
+// trailing timestamp line; remediation is the REMEDIATION_COMMAND template and maxPlanLines the
+// ISSUE_PLAN_MAX_LINES limit
+func formatDriftDescription(details DriftDetails, remediation string, maxPlanLines int) string {
+	// Base description
+	description := fmt.Sprintf(
+		"# Drift report for `%s` environment\n\n"+
+			"Environment **%s** has a drift increment of **%d**, "+
+			"which meets or exceeds the configured threshold of **%d**.\n\n"+
+			"Please investigate and address this drift as soon as possible.\n\n",
+		details.Environment, details.Environment, details.DriftIncrement, details.Threshold)
+
+	// Summarize what the plan changes ahead of the raw output
+	if details.PlanSummary != nil {
+		description += formatPlanSummary(*details.PlanSummary)
+	}
+
+	// Add the planned commit if known
+	if details.CommitSHA != "" {
+		description += fmt.Sprintf("Detected at commit `%s`.\n\n", details.CommitSHA)
+	}
+
+	// Say when the same drift persists, so responders know nothing new has drifted
+	if details.DriftUnchanged {
+		description += "Drift unchanged since last run.\n\n"
+	}
+
+	// Add what triggered the detecting run if known
+	if details.PipelineSource != "" {
+		description += fmt.Sprintf("Triggered by a `%s` pipeline.\n\n", details.PipelineSource)
+	}
+
+	// Add who last applied, so the drift reaches whoever last changed the infrastructure
+	if details.LastApplyAuthor != "" {
+		description += fmt.Sprintf("Last applied by %s.\n\n", details.LastApplyAuthor)
+	}
+
+	// Add the ref drift is measured against if known
+	if details.ComparisonBranch != "" {
+		if details.ComparisonRef == "tag" {
+			description += fmt.Sprintf("Compared against tags matching `%s`.\n\n", details.ComparisonBranch)
+		} else {
+			description += fmt.Sprintf("Compared against the `%s` branch.\n\n", details.ComparisonBranch)
+		}
+	}
+
+	// Add a command responders can run to investigate
+	if remediation != "" {
+		command := strings.NewReplacer("{repo}", details.RepoName, "{environment}", details.Environment).Replace(remediation)
+		description += fmt.Sprintf("## Investigate\n\n```shell\n%s\n```\n\n", command)
+	}
+
+	// Add metadata forwarded from CI in a stable order
+	if len(details.Metadata) > 0 {
+		description += "## Metadata\n\n| Key | Value |\n|-----|-------|\n"
+		for _, key := range slices.Sorted(maps.Keys(details.Metadata)) {
+			description += fmt.Sprintf("| `%s` | %s |\n", key, details.Metadata[key])
+		}
+		description += "\n"
+	}
+
+	// Add plan output if available
+	if details.PlanOutput != "" {
+		description += fmt.Sprintf("## Terraform Plan Output\n\n```\n%s\n```\n\n", limitLines(details.PlanOutput, maxPlanLines))
+	}
+
+	return description
+}
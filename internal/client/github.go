@@ -0,0 +1,336 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"drift-guardian/internal/config"
+)
+
+// githubAPIVersion is the GitHub REST API version the client is written against
+const githubAPIVersion = "2022-11-28"
+
+// errGitHubTokenMissing is returned when a GitHub call is made without a token
+var errGitHubTokenMissing = fmt.Errorf("GITHUB_TOKEN environment variable not set")
+
+// GitHubClient implements IssueTracker for GitHub Issues. Project IDs are numeric GitHub
+// repository IDs, such as GitHub Actions' GITHUB_REPOSITORY_ID, which are resolved to owner/repo;
+// issue IDs are issue numbers.
+type GitHubClient struct {
+	httpClient    *http.Client
+	baseURL       string
+	token         string
+	maxPlanLines  int
+	issueLabels   []string
+	resolvedLabel string
+	remediation   string
+
+	mu    sync.Mutex
+	repos map[int]string // Repository ID -> owner/repo, which does not change for a repository's ID
+}
+
+// NewGitHubClient creates a new GitHub client instance
+func NewGitHubClient(cfg *config.Config) *GitHubClient {
+	slog.Debug("Initializing GitHub client",
+		"base_url", cfg.GitHubBaseURL,
+		"token_configured", cfg.GitHubToken != "",
+	)
+
+	issueLabels := cfg.IssueLabels
+	if issueLabels == nil {
+		issueLabels = defaultIssueLabels
+	}
+
+	slog.Info("GitHub client initialized successfully", "base_url", cfg.GitHubBaseURL)
+
+	return &GitHubClient{
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:       cfg.GitHubBaseURL,
+		token:         cfg.GitHubToken,
+		maxPlanLines:  cfg.IssuePlanMaxLines,
+		issueLabels:   issueLabels,
+		resolvedLabel: cfg.ResolvedLabel,
+		remediation:   cfg.RemediationCommand,
+		repos:         make(map[int]string),
+	}
+}
+
+// githubIssue represents an issue in the GitHub API
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"`
+}
+
+// githubIssueRequest represents the request body for creating or updating a GitHub issue
+type githubIssueRequest struct {
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+// CreateIssue creates a new GitHub issue and returns issue details
+func (g *GitHubClient) CreateIssue(ctx context.Context, projectID int, title, description string) (*Issue, error) {
+	return g.createIssue(ctx, projectID, githubIssueRequest{Title: title, Body: description, Labels: g.issueLabels})
+}
+
+// CreateDriftIssue creates a drift-specific issue with formatted content; extraLabels are applied
+// alongside the configured issue labels, and GitHub creates labels the repository does not have yet
+func (g *GitHubClient) CreateDriftIssue(ctx context.Context, projectID int, details DriftDetails, extraLabels ...string) (*Issue, error) {
+	return g.createIssue(ctx, projectID, githubIssueRequest{
+		Title:  driftIssueTitle(details.Environment),
+		Body:   formatDriftDescription(details, g.remediation, g.maxPlanLines) + issueFooter("created"),
+		Labels: append(slices.Clone(g.issueLabels), extraLabels...),
+	})
+}
+
+// createIssue creates an issue in the repository with the given ID
+func (g *GitHubClient) createIssue(ctx context.Context, projectID int, issueRequest githubIssueRequest) (*Issue, error) {
+	slog.Info("Creating GitHub issue",
+		"project_id", projectID,
+		"title", issueRequest.Title,
+		"labels", issueRequest.Labels,
+	)
+
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var created githubIssue
+	status, err := g.send(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues", g.baseURL, repo), issueRequest, &created)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusCreated && status != http.StatusOK {
+		slog.Error("GitHub API issue creation failed", "status_code", status, "repo", repo)
+		return nil, fmt.Errorf("received non-success status code: %d", status)
+	}
+
+	slog.Info("GitHub issue created successfully", "repo", repo, "issue_id", created.Number, "issue_url", created.HTMLURL)
+
+	return &Issue{
+		ID:        created.Number,
+		ProjectID: projectID,
+		Title:     created.Title,
+		WebURL:    created.HTMLURL,
+		State:     created.State,
+	}, nil
+}
+
+// UpdateIssueDescription rewrites an existing drift issue's description from details
+func (g *GitHubClient) UpdateIssueDescription(ctx context.Context, projectID, issueID int, details DriftDetails) error {
+	slog.Info("Updating GitHub issue description",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"repo", details.RepoName,
+		"environment", details.Environment,
+		"drift_count", details.DriftIncrement,
+	)
+
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	body := formatDriftDescription(details, g.remediation, g.maxPlanLines) + issueFooter("updated")
+	status, err := g.send(ctx, http.MethodPatch, fmt.Sprintf("%s/repos/%s/issues/%d", g.baseURL, repo, issueID), githubIssueRequest{Body: body}, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		slog.Error("GitHub API update failed", "status_code", status, "repo", repo, "issue_id", issueID)
+		return fmt.Errorf("received non-success status code: %d", status)
+	}
+	return nil
+}
+
+// CloseIssue comments on and closes a GitHub issue, swapping the alert labels for the resolved label;
+// a non-zero driftDuration is reported in the closing comment
+func (g *GitHubClient) CloseIssue(ctx context.Context, projectID, issueID int, operation string, driftDuration time.Duration) error {
+	slog.Info("Closing GitHub issue", "project_id", projectID, "issue_id", issueID)
+
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	issueURL := fmt.Sprintf("%s/repos/%s/issues/%d", g.baseURL, repo, issueID)
+
+	// Continue with closing even if the comment fails
+	comment := map[string]string{"body": resolvedComment(operation, driftDuration)}
+	if status, err := g.send(ctx, http.MethodPost, issueURL+"/comments", comment, nil); err != nil || status < 200 || status >= 300 {
+		slog.Warn("Failed to add closing comment", "error", err, "status_code", status, "repo", repo, "issue_id", issueID)
+	}
+
+	status, err := g.send(ctx, http.MethodPatch, issueURL, githubIssueRequest{State: "closed", StateReason: "completed"}, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		slog.Error("GitHub API close failed", "status_code", status, "repo", repo, "issue_id", issueID)
+		return fmt.Errorf("received non-success status code for close: %d", status)
+	}
+
+	// Label changes are cosmetic, so the issue stays closed if they fail
+	if g.resolvedLabel != "" {
+		labels := map[string][]string{"labels": {g.resolvedLabel}}
+		if status, err := g.send(ctx, http.MethodPost, issueURL+"/labels", labels, nil); err != nil || status < 200 || status >= 300 {
+			slog.Warn("Failed to add resolved label", "error", err, "status_code", status, "repo", repo, "issue_id", issueID)
+		}
+		for _, label := range scopeConflicts([]string{g.resolvedLabel}, g.issueLabels) {
+			if status, err := g.send(ctx, http.MethodDelete, issueURL+"/labels/"+url.PathEscape(label), nil, nil); err != nil || status < 200 || status >= 300 {
+				slog.Warn("Failed to remove alert label", "error", err, "status_code", status, "label", label, "issue_id", issueID)
+			}
+		}
+	}
+
+	slog.Info("GitHub issue closed successfully", "repo", repo, "issue_id", issueID)
+	return nil
+}
+
+// GetIssueStatus checks if an issue exists and is open; deleted and transferred issues are not open
+func (g *GitHubClient) GetIssueStatus(ctx context.Context, projectID, issueID int) (bool, error) {
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return false, err
+	}
+
+	var issue githubIssue
+	status, err := g.send(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%d", g.baseURL, repo, issueID), nil, &issue)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusNotFound || status == http.StatusGone {
+		slog.Debug("Issue not found", "repo", repo, "issue_id", issueID)
+		return false, nil
+	}
+	if status < 200 || status >= 300 {
+		slog.Error("GitHub API status check failed", "status_code", status, "repo", repo, "issue_id", issueID)
+		return false, fmt.Errorf("received non-success status code: %d", status)
+	}
+
+	return issue.State == "open", nil
+}
+
+// AddIssueComment posts a comment on an existing GitHub issue
+func (g *GitHubClient) AddIssueComment(ctx context.Context, projectID, issueID int, body string) error {
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	status, err := g.send(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.baseURL, repo, issueID), map[string]string{"body": body}, nil)
+	if err != nil {
+		return fmt.Errorf("error adding comment: %w", err)
+	}
+	if status < 200 || status >= 300 {
+		slog.Error("GitHub API comment failed", "status_code", status, "repo", repo, "issue_id", issueID)
+		return fmt.Errorf("received non-success status code for comment: %d", status)
+	}
+	return nil
+}
+
+// AddIssueLabels adds labels to an existing GitHub issue without removing current ones
+func (g *GitHubClient) AddIssueLabels(ctx context.Context, projectID, issueID int, labels []string) error {
+	repo, err := g.repository(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	status, err := g.send(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/labels", g.baseURL, repo, issueID), map[string][]string{"labels": labels}, nil)
+	if err != nil {
+		return fmt.Errorf("error adding labels: %w", err)
+	}
+	if status < 200 || status >= 300 {
+		slog.Error("GitHub API label update failed", "status_code", status, "repo", repo, "issue_id", issueID)
+		return fmt.Errorf("received non-success status code for labels: %d", status)
+	}
+	return nil
+}
+
+// repository resolves a GitHub repository ID to its owner/repo path
+func (g *GitHubClient) repository(ctx context.Context, projectID int) (string, error) {
+	g.mu.Lock()
+	repo, cached := g.repos[projectID]
+	g.mu.Unlock()
+	if cached {
+		return repo, nil
+	}
+
+	var response struct {
+		FullName string `json:"full_name"`
+	}
+	status, err := g.send(ctx, http.MethodGet, fmt.Sprintf("%s/repositories/%d", g.baseURL, projectID), nil, &response)
+	if err != nil {
+		return "", err
+	}
+	if status < 200 || status >= 300 || response.FullName == "" {
+		slog.Error("Failed to resolve GitHub repository", "status_code", status, "project_id", projectID)
+		return "", fmt.Errorf("unable to resolve GitHub repository %d: status code %d", projectID, status)
+	}
+
+	g.mu.Lock()
+	g.repos[projectID] = response.FullName
+	g.mu.Unlock()
+	return response.FullName, nil
+}
+
+// send makes an authenticated GitHub API request, encoding payload as JSON when it is not nil and
+// decoding a successful response into out when it is not nil, and returns the response status
+func (g *GitHubClient) send(ctx context.Context, method, requestURL string, payload, out interface{}) (int, error) {
+	if g.token == "" {
+		slog.Error("GitHub API token not configured")
+		return 0, errGitHubTokenMissing
+	}
+
+	var body io.Reader
+	if payload != nil {
+		requestBody, err := json.Marshal(payload)
+		if err != nil {
+			slog.Error("Failed to marshal request", "error", err, "url", requestURL)
+			return 0, fmt.Errorf("error marshaling request: %w", err)
+		}
+		body = bytes.NewBuffer(requestBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		slog.Error("Failed to create HTTP request", "error", err, "url", requestURL, "method", method)
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	slog.Debug("Sending HTTP request to GitHub API", "url", requestURL, "method", method)
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		slog.Error("Failed to send HTTP request", "error", err, "url", requestURL, "method", method)
+		return 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			slog.Error("Failed to decode GitHub API response", "error", err, "url", requestURL)
+			return resp.StatusCode, fmt.Errorf("error decoding response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -8,16 +8,36 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"drift-guardian/internal/config"
 )
 
+// defaultIssueLabels are applied to drift issues when ISSUE_LABELS is not set; setting it empty
+// removes them, for organisations whose bot filters act on the automation label
+var defaultIssueLabels = []string{"drift-alert", "automation"}
+
 // GitLabClient implements IssueTracker interface for GitLab operations
 type GitLabClient struct {
-	httpClient *http.Client
-	baseURL    string
-	token      string
+	httpClient    *http.Client
+	baseURL       string
+	token         string
+	maxPlanLines  int
+	issueLabels   []string
+	resolvedLabel string
+	remediation   string
+	milestone     string
+	applyAuthor   string
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	createLimiter  *tokenBucket // Paces issue creation across all environments; nil leaves it unlimited
 }
 
 // NewGitLabClient creates a new GitLab client instance
@@ -25,38 +45,80 @@ func NewGitLabClient(cfg *config.Config) *GitLabClient {
 	slog.Debug("Initializing GitLab client",
 		"base_url", cfg.GitLabBaseURL,
 		"skip_tls", cfg.GitLabSkipTLS,
+		"ca_cert_file", cfg.GitLabCACert,
 		"token_configured", cfg.GitLabToken != "",
 	)
 
 	// Configure HTTP client with TLS settings
 	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newTransport(cfg),
+	}
+
+	issueLabels := cfg.IssueLabels
+	if issueLabels == nil {
+		issueLabels = defaultIssueLabels
 	}
 
+	slog.Info("GitLab client initialized successfully", "base_url", cfg.GitLabBaseURL)
+
+	return &GitLabClient{
+		httpClient:    httpClient,
+		baseURL:       cfg.GitLabBaseURL,
+		token:         cfg.GitLabToken,
+		maxPlanLines:  cfg.IssuePlanMaxLines,
+		issueLabels:   issueLabels,
+		resolvedLabel: cfg.ResolvedLabel,
+		remediation:   cfg.RemediationCommand,
+		milestone:     cfg.DriftMilestone,
+		applyAuthor:   cfg.ApplyAuthor,
+
+		maxRetries:     cfg.GitLabMaxRetries,
+		retryBaseDelay: cfg.GitLabRetryBaseDelay,
+		createLimiter:  newTokenBucket(cfg.GitLabCreateRPS, cfg.GitLabCreateBurst),
+	}
+}
+
+// newTransport builds the HTTP transport honouring the GitLab TLS settings
+func newTransport(cfg *config.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
 	// Check if TLS verification should be skipped
 	if cfg.GitLabSkipTLS {
 		slog.Warn("TLS verification disabled for GitLab client")
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
 		}
+		return transport
 	}
 
-	slog.Info("GitLab client initialized successfully", "base_url", cfg.GitLabBaseURL)
+	// Verify against the custom CA bundle if one is configured
+	if cfg.GitLabCACert != "" {
+		pool, err := cfg.LoadGitLabCACertPool()
+		if err != nil {
+			slog.Error("Failed to load GitLab CA certificate, using system roots", "error", err, "ca_cert_file", cfg.GitLabCACert)
+			return transport
+		}
 
-	return &GitLabClient{
-		httpClient: httpClient,
-		baseURL:    cfg.GitLabBaseURL,
-		token:      cfg.GitLabToken,
+		slog.Info("Using custom CA certificate for GitLab client", "ca_cert_file", cfg.GitLabCACert)
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
 	}
+
+	return transport
 }
 
 // issueRequest represents the request body for creating/updating a GitLab issue
 type issueRequest struct {
-	Title       string   `json:"title,omitempty"`
-	Description string   `json:"description"`
-	Labels      []string `json:"labels,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Description  string   `json:"description"`
+	Labels       []string `json:"labels,omitempty"`
+	AddLabels    string   `json:"add_labels,omitempty"`
+	RemoveLabels string   `json:"remove_labels,omitempty"`
+	MilestoneID  int      `json:"milestone_id,omitempty"`
+	AssigneeIDs  []int    `json:"assignee_ids,omitempty"`
 }
 
 // issueResponse represents the response from GitLab API
@@ -70,6 +132,12 @@ type issueResponse struct {
 
 // CreateIssue creates a new GitLab issue and returns issue details
 func (g *GitLabClient) CreateIssue(ctx context.Context, projectID int, title, description string) (*Issue, error) {
+	return g.createIssue(ctx, projectID, title, description, g.issueLabels, 0)
+}
+
+// createIssue creates a new GitLab issue with the given labels, assigned to milestoneID when non-zero
+// and to any assigneeIDs
+func (g *GitLabClient) createIssue(ctx context.Context, projectID int, title, description string, labels []string, milestoneID int, assigneeIDs ...int) (*Issue, error) {
 	slog.Debug("Creating GitLab issue",
 		"project_id", projectID,
 		"title", title,
@@ -81,11 +149,24 @@ func (g *GitLabClient) CreateIssue(ctx context.Context, projectID int, title, de
 		return nil, fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
 	}
 
+	// Queue behind earlier creations when drift is widespread, so bursts stay within the API quota;
+	// retries of this call are paced by their backoff rather than the limiter
+	waited, err := g.createLimiter.Wait(ctx)
+	if err != nil {
+		slog.Warn("Issue creation abandoned while rate limited", "error", err, "project_id", projectID, "waited", waited)
+		return nil, fmt.Errorf("issue creation rate limited: %w", err)
+	}
+	if waited > 0 {
+		slog.Info("Issue creation delayed by rate limit", "project_id", projectID, "waited", waited)
+	}
+
 	// Prepare request body
 	issueReq := issueRequest{
 		Title:       title,
 		Description: description,
-		Labels:      []string{"drift-alert", "automation"},
+		Labels:      labels,
+		MilestoneID: milestoneID,
+		AssigneeIDs: assigneeIDs,
 	}
 
 	slog.Debug("Marshaling issue request", "project_id", projectID, "labels", issueReq.Labels)
@@ -110,7 +191,7 @@ func (g *GitLabClient) CreateIssue(ctx context.Context, projectID int, title, de
 
 	// Send request
 	slog.Debug("Sending HTTP request to GitLab API", "url", url)
-	resp, err := g.httpClient.Do(req)
+	resp, err := g.do(req)
 	if err != nil {
 		slog.Error("Failed to send HTTP request", "error", err, "url", url)
 		return nil, fmt.Errorf("error sending request: %w", err)
@@ -151,7 +232,7 @@ func (g *GitLabClient) CreateIssue(ctx context.Context, projectID int, title, de
 }
 
 // CloseIssue closes a GitLab issue instead of deleting it
-func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, operation string) error {
+func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, operation string, driftDuration time.Duration) error {
 	slog.Info("Closing GitLab issue",
 		"project_id", projectID,
 		"issue_id", issueID,
@@ -165,7 +246,7 @@ func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, o
 	// First, add a comment to the issue
 	commentURL := fmt.Sprintf("%s/projects/%d/issues/%d/notes", g.baseURL, projectID, issueID)
 	commentRequest := map[string]string{
-		"body": fmt.Sprintf("**Drift Resolved** - Infrastructure drift has been resolved through successful Terraform `%s` operation. Issue automatically closed by Drift Guardian.", operation),
+		"body": resolvedComment(operation, driftDuration),
 	}
 
 	commentBody, err := json.Marshal(commentRequest)
@@ -183,7 +264,7 @@ func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, o
 	req.Header.Set("PRIVATE-TOKEN", g.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := g.httpClient.Do(req)
+	resp, err := g.do(req)
 	if err != nil {
 		slog.Error("Failed to add comment", "error", err, "url", commentURL)
 		// Continue with closing even if comment fails
@@ -202,6 +283,14 @@ func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, o
 		"state_event": "close",
 	}
 
+	// Swap the alert labels for the resolved label, replacing any in the same scope
+	if g.resolvedLabel != "" {
+		updateRequest["add_labels"] = g.resolvedLabel
+		if replaced := scopeConflicts([]string{g.resolvedLabel}, g.issueLabels); len(replaced) > 0 {
+			updateRequest["remove_labels"] = strings.Join(replaced, ",")
+		}
+	}
+
 	requestBody, err := json.Marshal(updateRequest)
 	if err != nil {
 		slog.Error("Failed to marshal close request", "error", err, "issue_id", issueID)
@@ -221,7 +310,7 @@ func (g *GitLabClient) CloseIssue(ctx context.Context, projectID, issueID int, o
 
 	// Send request
 	slog.Debug("Sending PUT request to close issue", "url", url)
-	resp, err = g.httpClient.Do(req)
+	resp, err = g.do(req)
 	if err != nil {
 		slog.Error("Failed to send PUT request", "error", err, "url", url)
 		return fmt.Errorf("error sending close request: %w", err)
@@ -275,7 +364,7 @@ func (g *GitLabClient) GetIssueStatus(ctx context.Context, projectID, issueID in
 
 	// Send request
 	slog.Debug("Sending GET request to GitLab API", "url", url)
-	resp, err := g.httpClient.Do(req)
+	resp, err := g.do(req)
 	if err != nil {
 		slog.Error("Failed to send GET request", "error", err, "url", url)
 		return false, fmt.Errorf("error sending request: %w", err)
@@ -327,45 +416,297 @@ func (g *GitLabClient) GetIssueStatus(ctx context.Context, projectID, issueID in
 	return isOpen, nil
 }
 
-// CreateDriftIssue creates a drift-specific issue with formatted content
-func (g *GitLabClient) CreateDriftIssue(ctx context.Context, projectID int, repoName, environment string, driftIncrement, threshold int, planOutput string) (*Issue, error) {
-	title := fmt.Sprintf("Drift: %s", environment)
+// maxIssueStatusBatch is the most issues GitLab returns in one page of an issue list
+const maxIssueStatusBatch = 100
 
-	// Base description
-	description := fmt.Sprintf(
-		"# Drift report for `%s` environment\n\n"+
-			"Environment **%s** has a drift increment of **%d**, "+
-			"which meets or exceeds the configured threshold of **%d**.\n\n"+
-			"Please investigate and address this drift as soon as possible.\n\n",
-		environment, environment, driftIncrement, threshold)
+// GetIssueStatuses checks many issues of a project with the issue list API, batchSize IIDs per
+// request (capped at one page of 100), and reports whether each is open. Issues the list does not
+// return were deleted and report as not open, just like closed ones.
+func (g *GitLabClient) GetIssueStatuses(ctx context.Context, projectID int, issueIDs []int, batchSize int) (map[int]bool, error) {
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return nil, fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+	if batchSize <= 0 || batchSize > maxIssueStatusBatch {
+		batchSize = maxIssueStatusBatch
+	}
 
-	// Add plan output if available
-	if planOutput != "" {
-		description += fmt.Sprintf("## Terraform Plan Output\n\n```\n%s\n```\n\n", planOutput)
+	statuses := make(map[int]bool, len(issueIDs))
+	for batch := range slices.Chunk(issueIDs, batchSize) {
+		query := url.Values{"per_page": {strconv.Itoa(maxIssueStatusBatch)}}
+		for _, issueID := range batch {
+			statuses[issueID] = false
+			query.Add("iids[]", strconv.Itoa(issueID))
+		}
+
+		listURL := fmt.Sprintf("%s/projects/%d/issues?%s", g.baseURL, projectID, query.Encode())
+		slog.Debug("Listing GitLab issue statuses", "project_id", projectID, "issue_count", len(batch))
+		req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("PRIVATE-TOKEN", g.token)
+
+		resp, err := g.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error sending request: %w", err)
+		}
+
+		var issues []issueResponse
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			_ = resp.Body.Close()
+			slog.Error("GitLab API issue list failed", "status_code", resp.StatusCode, "project_id", projectID)
+			return nil, fmt.Errorf("received non-success status code: %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&issues)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+
+		for _, issue := range issues {
+			if _, requested := statuses[issue.ID]; requested {
+				statuses[issue.ID] = issue.State == "opened"
+			}
+		}
 	}
 
-	// Add timestamp
-	description += fmt.Sprintf("*This issue was automatically created by Drift Guardian on %s*",
-		time.Now().Format(time.RFC1123))
+	return statuses, nil
+}
+
+// CreateDriftIssue creates a drift-specific issue with formatted content; extraLabels are applied
+// alongside the configured issue labels
+func (g *GitLabClient) CreateDriftIssue(ctx context.Context, projectID int, details DriftDetails, extraLabels ...string) (*Issue, error) {
+	title := driftIssueTitle(details.Environment)
+
+	var assigneeIDs []int
+	if userID := g.resolveApplyAuthor(ctx, &details); userID > 0 && g.applyAuthor == "assign" {
+		assigneeIDs = append(assigneeIDs, userID)
+	}
+
+	description := formatDriftDescription(details, g.remediation, g.maxPlanLines) + issueFooter("created")
 
 	slog.Debug("Calling CreateIssue with drift-specific content",
 		"title", title,
 		"description_length", len(description),
+		"extra_labels", extraLabels,
 	)
 
-	return g.CreateIssue(ctx, projectID, title, description)
+	labels := append(slices.Clone(g.issueLabels), extraLabels...)
+	return g.createIssue(ctx, projectID, title, description, labels, g.driftMilestone(ctx, projectID), assigneeIDs...)
+}
+
+// userResponse represents a user in the GitLab API
+type userResponse struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// resolveApplyAuthor replaces the last apply author in details with an @mention of their GitLab
+// user, per APPLY_AUTHOR, and returns the user's ID. An author without an email or without a
+// unique GitLab user is left as the commit author and 0 is returned.
+func (g *GitLabClient) resolveApplyAuthor(ctx context.Context, details *DriftDetails) int {
+	if g.applyAuthor == "" || details.LastApplyAuthor == "" {
+		return 0
+	}
+
+	address, err := mail.ParseAddress(details.LastApplyAuthor)
+	if err != nil {
+		slog.Debug("Apply author has no email address, not mentioning", "author", details.LastApplyAuthor)
+		return 0
+	}
+
+	user, err := g.findUserByEmail(ctx, address.Address)
+	if err != nil {
+		slog.Warn("Failed to look up apply author, not mentioning", "error", err, "author", details.LastApplyAuthor)
+		return 0
+	}
+	if user == nil {
+		slog.Debug("Apply author has no unique GitLab user, not mentioning", "author", details.LastApplyAuthor)
+		return 0
+	}
+
+	details.LastApplyAuthor = "@" + user.Username
+	return user.ID
+}
+
+// findUserByEmail returns the GitLab user with the email, or nil when there is no single match.
+// Only public emails match unless the token belongs to an administrator.
+func (g *GitLabClient) findUserByEmail(ctx context.Context, email string) (*userResponse, error) {
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return nil, fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	searchURL := fmt.Sprintf("%s/users?search=%s", g.baseURL, url.QueryEscape(email))
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("received non-success status code: %d", resp.StatusCode)
+	}
+
+	var users []userResponse
+	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if len(users) != 1 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
+// GetProject fetches a project by ID, returning nil when it does not exist or is not visible to the token
+func (g *GitLabClient) GetProject(ctx context.Context, projectID int) (*Project, error) {
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return nil, fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	projectURL := fmt.Sprintf("%s/projects/%d", g.baseURL, projectID)
+	req, err := http.NewRequestWithContext(ctx, "GET", projectURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("received non-success status code: %d", resp.StatusCode)
+	}
+
+	var project Project
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	return &project, nil
+}
+
+// milestoneResponse represents a milestone in the GitLab API
+type milestoneResponse struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	StartDate string `json:"start_date"`
+	DueDate   string `json:"due_date"`
+}
+
+// driftMilestone returns the milestone new drift issues are assigned to, per DRIFT_MILESTONE_ID, or 0
+// for none. A milestone that cannot be resolved is omitted rather than failing the issue.
+func (g *GitLabClient) driftMilestone(ctx context.Context, projectID int) int {
+	if g.milestone != "auto" {
+		milestoneID, _ := strconv.Atoi(g.milestone)
+		return milestoneID
+	}
+
+	milestoneID, err := g.ActiveMilestone(ctx, projectID, time.Now())
+	if err != nil {
+		slog.Warn("Failed to detect active milestone, creating issue without one", "error", err, "project_id", projectID)
+		return 0
+	}
+	return milestoneID
+}
+
+// ActiveMilestone returns the ID of the project's active milestone that spans now, including group
+// milestones. An undated milestone is used only when it is the sole active one; 0 means none.
+func (g *GitLabClient) ActiveMilestone(ctx context.Context, projectID int, now time.Time) (int, error) {
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return 0, fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	url := fmt.Sprintf("%s/projects/%d/milestones?state=active&include_ancestors=true", g.baseURL, projectID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	resp, err := g.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("received non-success status code: %d", resp.StatusCode)
+	}
+
+	var milestones []milestoneResponse
+	if err := json.NewDecoder(resp.Body).Decode(&milestones); err != nil {
+		return 0, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	today := now.Format(time.DateOnly)
+	for _, milestone := range milestones {
+		// ISO dates compare correctly as strings
+		if milestone.StartDate != "" && milestone.DueDate != "" && milestone.StartDate <= today && today <= milestone.DueDate {
+			slog.Debug("Active milestone detected", "project_id", projectID, "milestone", milestone.Title)
+			return milestone.ID, nil
+		}
+	}
+	if len(milestones) == 1 && milestones[0].StartDate == "" && milestones[0].DueDate == "" {
+		return milestones[0].ID, nil
+	}
+	return 0, nil
+}
+
+// PlanErrorLabel marks issues about plans that failed outright, as opposed to plans that found drift
+const PlanErrorLabel = "plan-error"
+
+// CreatePlanErrorIssue creates an issue for a comparison-branch plan that exited with an error.
+// It carries only the plan-error label so it is not mistaken for a drift issue.
+func (g *GitLabClient) CreatePlanErrorIssue(ctx context.Context, projectID int, details DriftDetails) (*Issue, error) {
+	title := fmt.Sprintf("Plan error: %s", details.Environment)
+
+	description := fmt.Sprintf(
+		"# Plan error for `%s` environment\n\n"+
+			"The terraform plan for environment **%s** in `%s` failed, so drift could not be checked. "+
+			"This usually means broken configuration or expired credentials rather than drift.\n\n",
+		details.Environment, details.Environment, details.RepoName)
+
+	if details.CommitSHA != "" {
+		description += fmt.Sprintf("Failed at commit `%s`.\n\n", details.CommitSHA)
+	}
+	if details.ComparisonBranch != "" {
+		description += fmt.Sprintf("Planned from the `%s` %s.\n\n", details.ComparisonBranch, refKind(details.ComparisonRef))
+	}
+	if details.PlanOutput != "" {
+		description += fmt.Sprintf("## Terraform Plan Output\n\n```\n%s\n```\n\n", limitLines(details.PlanOutput, g.maxPlanLines))
+	}
+
+	description += fmt.Sprintf("*This issue was automatically created by Drift Guardian on %s*",
+		time.Now().Format(time.RFC1123))
+
+	return g.createIssue(ctx, projectID, title, description, []string{PlanErrorLabel}, 0)
 }
 
 // UpdateIssueDescription updates the description of an existing GitLab issue
-func (g *GitLabClient) UpdateIssueDescription(ctx context.Context, projectID, issueID int, repoName, environment string, driftIncrement, threshold int, planOutput string) error {
+func (g *GitLabClient) UpdateIssueDescription(ctx context.Context, projectID, issueID int, details DriftDetails) error {
 	slog.Info("Updating GitLab issue description",
 		"project_id", projectID,
 		"issue_id", issueID,
-		"repo", repoName,
-		"environment", environment,
-		"drift_count", driftIncrement,
-		"threshold", threshold,
-		"has_plan_output", planOutput != "",
+		"repo", details.RepoName,
+		"environment", details.Environment,
+		"drift_count", details.DriftIncrement,
+		"threshold", details.Threshold,
+		"has_plan_output", details.PlanOutput != "",
 	)
 
 	if g.token == "" {
@@ -373,26 +714,309 @@ func (g *GitLabClient) UpdateIssueDescription(ctx context.Context, projectID, is
 		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
 	}
 
-	// Create updated description
+	g.resolveApplyAuthor(ctx, &details)
+
+	description := formatDriftDescription(details, g.remediation, g.maxPlanLines) + issueFooter("updated")
+
+	if err := g.putIssueDescription(ctx, projectID, issueID, description); err != nil {
+		return err
+	}
+
+	slog.Info("GitLab issue description updated successfully",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"environment", details.Environment,
+	)
+
+	return nil
+}
+
+// refKind names a ref type in prose; an empty type is a branch
+func refKind(refType string) string {
+	if refType == "" {
+		return "branch"
+	}
+	return refType
+}
+
+// CreateDigestIssue creates the daily digest issue listing drifted environments
+func (g *GitLabClient) CreateDigestIssue(ctx context.Context, projectID int, day string, drifted []DigestEntry) (*Issue, error) {
+	title := fmt.Sprintf("Drift digest: %s", day)
+
+	description := formatDigestDescription(day, drifted)
+	description += fmt.Sprintf("*This issue was automatically created by Drift Guardian on %s*",
+		time.Now().Format(time.RFC1123))
+
+	slog.Debug("Calling CreateIssue with digest content",
+		"title", title,
+		"environment_count", len(drifted),
+	)
+
+	return g.CreateIssue(ctx, projectID, title, description)
+}
+
+// UpdateDigestIssue refreshes the daily digest issue with the current drifted environments
+func (g *GitLabClient) UpdateDigestIssue(ctx context.Context, projectID, issueID int, day string, drifted []DigestEntry) error {
+	slog.Info("Updating GitLab digest issue",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"day", day,
+		"environment_count", len(drifted),
+	)
+
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	description := formatDigestDescription(day, drifted)
+	description += fmt.Sprintf("*This issue was automatically updated by Drift Guardian on %s*",
+		time.Now().Format(time.RFC1123))
+
+	if err := g.putIssueDescription(ctx, projectID, issueID, description); err != nil {
+		return err
+	}
+
+	slog.Info("GitLab digest issue updated successfully",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"day", day,
+	)
+
+	return nil
+}
+
+// formatDigestDescription renders the digest body with environments in a stable order
+func formatDigestDescription(day string, drifted []DigestEntry) string {
+	entries := make([]DigestEntry, len(drifted))
+	copy(entries, drifted)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RepoName != entries[j].RepoName {
+			return entries[i].RepoName < entries[j].RepoName
+		}
+		return entries[i].Environment < entries[j].Environment
+	})
+
 	description := fmt.Sprintf(
-		"# Drift report for `%s` environment\n\n"+
-			"Environment **%s** has a drift increment of **%d**, "+
-			"which meets or exceeds the configured threshold of **%d**.\n\n"+
-			"Please investigate and address this drift as soon as possible.\n\n",
-		environment, environment, driftIncrement, threshold)
+		"# Drift digest for %s\n\n"+
+			"The following environments met or exceeded their drift threshold today.\n\n"+
+			"| Repository | Environment | Drift increment |\n"+
+			"|------------|-------------|-----------------|\n",
+		day)
+
+	for _, entry := range entries {
+		description += fmt.Sprintf("| `%s` | `%s` | %d |\n", entry.RepoName, entry.Environment, entry.DriftCount)
+	}
 
-	// Add plan output if available
-	if planOutput != "" {
-		description += fmt.Sprintf("## Terraform Plan Output\n\n```\n%s\n```\n\n", planOutput)
+	return description + "\n"
+}
+
+// overviewTitle is the title of a project's Drift Overview issue
+const overviewTitle = "Drift Overview"
+
+// CreateOverviewIssue creates the project's Drift Overview issue listing every environment's drift
+func (g *GitLabClient) CreateOverviewIssue(ctx context.Context, projectID int, environments []DigestEntry) (*Issue, error) {
+	description := formatOverviewDescription(environments)
+	description += fmt.Sprintf("*This issue was automatically created by Drift Guardian on %s*",
+		time.Now().Format(time.RFC1123))
+
+	slog.Debug("Calling CreateIssue with overview content",
+		"title", overviewTitle,
+		"environment_count", len(environments),
+	)
+
+	return g.CreateIssue(ctx, projectID, overviewTitle, description)
+}
+
+// UpdateOverviewIssue refreshes the project's Drift Overview issue with the current drift counts
+func (g *GitLabClient) UpdateOverviewIssue(ctx context.Context, projectID, issueID int, environments []DigestEntry) error {
+	slog.Info("Updating GitLab overview issue",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"environment_count", len(environments),
+	)
+
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
 	}
 
-	// Add timestamp
+	description := formatOverviewDescription(environments)
 	description += fmt.Sprintf("*This issue was automatically updated by Drift Guardian on %s*",
 		time.Now().Format(time.RFC1123))
 
+	if err := g.putIssueDescription(ctx, projectID, issueID, description); err != nil {
+		return err
+	}
+
+	slog.Info("GitLab overview issue updated successfully", "project_id", projectID, "issue_id", issueID)
+	return nil
+}
+
+// formatOverviewDescription renders the overview body with environments in a stable order
+func formatOverviewDescription(environments []DigestEntry) string {
+	entries := slices.Clone(environments)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RepoName != entries[j].RepoName {
+			return entries[i].RepoName < entries[j].RepoName
+		}
+		return entries[i].Environment < entries[j].Environment
+	})
+
+	drifted := 0
+	for _, entry := range entries {
+		if entry.DriftCount > 0 {
+			drifted++
+		}
+	}
+
+	description := fmt.Sprintf(
+		"# Drift overview\n\n"+
+			"%d of %d environments reporting to this project currently have drift. "+
+			"This issue is informational; environments over their threshold also get their own drift issue.\n\n"+
+			"| Repository | Environment | Drift increment |\n"+
+			"|------------|-------------|-----------------|\n",
+		drifted, len(entries))
+
+	for _, entry := range entries {
+		description += fmt.Sprintf("| `%s` | `%s` | %d |\n", entry.RepoName, entry.Environment, entry.DriftCount)
+	}
+
+	return description + "\n"
+}
+
+// AddIssueComment posts a comment (note) on an existing GitLab issue
+func (g *GitLabClient) AddIssueComment(ctx context.Context, projectID, issueID int, body string) error {
+	slog.Debug("Adding comment to GitLab issue",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"body_length", len(body),
+	)
+
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	url := fmt.Sprintf("%s/projects/%d/issues/%d/notes", g.baseURL, projectID, issueID)
+	if err := g.sendJSON(ctx, "POST", url, map[string]string{"body": body}); err != nil {
+		return fmt.Errorf("error adding comment: %w", err)
+	}
+
+	slog.Debug("Comment added successfully", "project_id", projectID, "issue_id", issueID)
+	return nil
+}
+
+// AddIssueLabels adds labels to an existing GitLab issue without removing current ones
+func (g *GitLabClient) AddIssueLabels(ctx context.Context, projectID, issueID int, labels []string) error {
+	slog.Debug("Adding labels to GitLab issue",
+		"project_id", projectID,
+		"issue_id", issueID,
+		"labels", labels,
+	)
+
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	url := fmt.Sprintf("%s/projects/%d/issues/%d", g.baseURL, projectID, issueID)
+	if err := g.sendJSON(ctx, "PUT", url, map[string]string{"add_labels": strings.Join(labels, ",")}); err != nil {
+		return fmt.Errorf("error adding labels: %w", err)
+	}
+
+	slog.Debug("Labels added successfully", "project_id", projectID, "issue_id", issueID)
+	return nil
+}
+
+// AddMergeRequestNote posts a note on a GitLab merge request
+func (g *GitLabClient) AddMergeRequestNote(ctx context.Context, projectID, mergeRequestIID int, body string) error {
+	slog.Debug("Adding note to GitLab merge request",
+		"project_id", projectID,
+		"merge_request_iid", mergeRequestIID,
+		"body_length", len(body),
+	)
+
+	if g.token == "" {
+		slog.Error("GitLab API token not configured")
+		return fmt.Errorf("GITLAB_API_TOKEN environment variable not set")
+	}
+
+	url := fmt.Sprintf("%s/projects/%d/merge_requests/%d/notes", g.baseURL, projectID, mergeRequestIID)
+	if err := g.sendJSON(ctx, "POST", url, map[string]string{"body": body}); err != nil {
+		return fmt.Errorf("error adding merge request note: %w", err)
+	}
+
+	slog.Debug("Merge request note added successfully", "project_id", projectID, "merge_request_iid", mergeRequestIID)
+	return nil
+}
+
+// AddPlanPreviewNote posts a feature-branch plan summary on its merge request
+func (g *GitLabClient) AddPlanPreviewNote(ctx context.Context, projectID, mergeRequestIID int, environment, branch string, driftDetected bool, planOutput string) error {
+	summary := "shows **no changes** against"
+	if driftDetected {
+		summary = "shows **changes** against"
+	}
+
+	body := fmt.Sprintf(
+		"### Drift Guardian plan preview for `%s`\n\n"+
+			"The plan for branch `%s` %s the `%s` environment.\n\n",
+		environment, branch, summary, environment)
+
+	if planOutput != "" {
+		body += fmt.Sprintf("<details><summary>Terraform Plan Output</summary>\n\n```\n%s\n```\n\n</details>\n\n", limitLines(planOutput, g.maxPlanLines))
+	}
+
+	body += fmt.Sprintf("*Posted automatically by Drift Guardian on %s*", time.Now().Format(time.RFC1123))
+
+	return g.AddMergeRequestNote(ctx, projectID, mergeRequestIID, body)
+}
+
+// sendJSON sends a JSON request to the GitLab API and checks for a success status
+func (g *GitLabClient) sendJSON(ctx context.Context, method, url string, payload interface{}) error {
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to marshal request", "error", err, "url", url)
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		slog.Error("Failed to create HTTP request", "error", err, "url", url, "method", method)
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+
+	slog.Debug("Sending HTTP request to GitLab API", "url", url, "method", method)
+	resp, err := g.do(req)
+	if err != nil {
+		slog.Error("Failed to send HTTP request", "error", err, "url", url, "method", method)
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		slog.Error("GitLab API returned error status",
+			"status_code", resp.StatusCode,
+			"url", url,
+			"method", method,
+		)
+		return fmt.Errorf("received non-success status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// putIssueDescription replaces the description of an existing GitLab issue
+func (g *GitLabClient) putIssueDescription(ctx context.Context, projectID, issueID int, description string) error {
 	// Prepare request body
+	// Re-apply the alert labels so a reopened issue moves back out of the resolved scope
 	updateRequest := issueRequest{
-		Description: description,
+		Description:  description,
+		AddLabels:    strings.Join(g.issueLabels, ","),
+		RemoveLabels: strings.Join(scopeConflicts(g.issueLabels, []string{g.resolvedLabel}), ","),
 	}
 
 	slog.Debug("Marshaling update request", "issue_id", issueID, "description_length", len(description))
@@ -417,7 +1041,7 @@ func (g *GitLabClient) UpdateIssueDescription(ctx context.Context, projectID, is
 
 	// Send request
 	slog.Debug("Sending PUT request to GitLab API", "url", url)
-	resp, err := g.httpClient.Do(req)
+	resp, err := g.do(req)
 	if err != nil {
 		slog.Error("Failed to send PUT request", "url", url)
 		return fmt.Errorf("error sending request: %w", err)
@@ -437,11 +1061,72 @@ func (g *GitLabClient) UpdateIssueDescription(ctx context.Context, projectID, is
 		return fmt.Errorf("received non-success status code: %d", resp.StatusCode)
 	}
 
-	slog.Info("GitLab issue description updated successfully",
-		"project_id", projectID,
-		"issue_id", issueID,
-		"environment", environment,
-	)
-
 	return nil
 }
+
+// limitLines keeps the first and last lines of text so at most maxLines remain, marking the omitted
+// middle; the tail is kept because it holds the plan summary. Zero or negative maxLines disables the limit.
+func limitLines(text string, maxLines int) string {
+	lines := strings.Split(text, "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return text
+	}
+
+	head := maxLines / 2
+	tail := maxLines - head
+	omitted := len(lines) - maxLines
+
+	kept := make([]string, 0, maxLines+1)
+	kept = append(kept, lines[:head]...)
+	kept = append(kept, fmt.Sprintf("... (%d lines omitted) ...", omitted))
+	kept = append(kept, lines[len(lines)-tail:]...)
+	return strings.Join(kept, "\n")
+}
+
+// labelScope returns the scope of a GitLab scoped label, e.g. "drift" for "drift::alert"; nested
+// scopes use everything before the last separator, and unscoped labels return ""
+func labelScope(label string) string {
+	i := strings.LastIndex(label, "::")
+	if i < 0 {
+		return ""
+	}
+	return label[:i]
+}
+
+// scopeConflicts returns the candidates that share a scope with, but differ from, any of the added labels
+func scopeConflicts(added, candidates []string) []string {
+	var conflicts []string
+	for _, candidate := range candidates {
+		scope := labelScope(candidate)
+		if scope == "" {
+			continue
+		}
+		for _, label := range added {
+			if label != candidate && labelScope(label) == scope {
+				conflicts = append(conflicts, candidate)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// formatDriftDuration renders how long an environment was drifted to the minute, e.g. 2d 3h 15m
+func formatDriftDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "under a minute"
+	}
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -1,8 +1,11 @@
 package client
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
-// Issue represents a GitLab issue
+// Issue represents a GitLab or GitHub issue; ID is the project-scoped issue IID or number
 type Issue struct {
 	ID        int    `json:"iid"`
 	ProjectID int    `json:"project_id"`
@@ -11,14 +14,98 @@ type Issue struct {
 	State     string `json:"state"`
 }
 
-// IssueTracker defines the interface for GitLab issue management
+// Project identifies a GitLab project
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+// DriftDetails describes an environment's drift for its issue description
+type DriftDetails struct {
+	RepoName         string
+	Environment      string
+	DriftIncrement   int
+	Threshold        int
+	PlanOutput       string
+	CommitSHA        string
+	ComparisonBranch string
+	ComparisonRef    string            // Kind of ref ComparisonBranch names: branch (the default) or tag, where it is a pattern
+	Metadata         map[string]string // Forwarded from CI, e.g. team or region
+	PipelineSource   string            // What triggered the detecting run, e.g. schedule or push
+	LastApplyAuthor  string            // Commit author of the last apply, recorded with APPLY_AUTHOR
+	DriftUnchanged   bool              // The plan checksum matches the previous drifted run's
+	PlanSummary      *PlanSummary      // Changes parsed from the plan with PLAN_SUMMARY; nil omits the summary
+}
+
+// PlanSummary is the resource changes parsed from a terraform plan
+type PlanSummary struct {
+	ToAdd     int
+	ToChange  int
+	ToDestroy int
+	Resources []ResourceChange // Changed resources in plan order, up to a limit
+}
+
+// ResourceChange is a resource a plan changes and its action: create, update, replace, destroy or import
+type ResourceChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// DigestEntry is an environment and its drift count listed in a digest or overview issue
+type DigestEntry struct {
+	RepoName    string
+	Environment string
+	DriftCount  int
+}
+
+// IssueTracker defines the interface for issue management on GitLab or GitHub
 type IssueTracker interface {
 	// CreateIssue creates a new GitLab issue and returns issue details
 	CreateIssue(ctx context.Context, projectID int, title, description string) (*Issue, error)
 
-	// CloseIssue removes a GitLab issue
-	CloseIssue(ctx context.Context, projectID, issueID int, operation string) error
+	// CloseIssue removes a GitLab issue; a non-zero driftDuration is reported in the closing comment
+	CloseIssue(ctx context.Context, projectID, issueID int, operation string, driftDuration time.Duration) error
 
 	// GetIssueStatus checks if an issue exists and is open
 	GetIssueStatus(ctx context.Context, projectID, issueID int) (bool, error)
 }
+
+// DriftIssueTracker is an IssueTracker that renders drift issues from DriftDetails
+type DriftIssueTracker interface {
+	IssueTracker
+
+	// CreateDriftIssue creates a drift-specific issue with formatted content; extraLabels are applied
+	// alongside the configured issue labels
+	CreateDriftIssue(ctx context.Context, projectID int, details DriftDetails, extraLabels ...string) (*Issue, error)
+
+	// UpdateIssueDescription rewrites an existing drift issue's description from details
+	UpdateIssueDescription(ctx context.Context, projectID, issueID int, details DriftDetails) error
+}
+
+// IssueAnnotator is an IssueTracker that can label and comment on existing issues
+type IssueAnnotator interface {
+	IssueTracker
+
+	// AddIssueComment posts a comment on an existing issue
+	AddIssueComment(ctx context.Context, projectID, issueID int, body string) error
+
+	// AddIssueLabels adds labels to an existing issue without removing current ones
+	AddIssueLabels(ctx context.Context, projectID, issueID int, labels []string) error
+}
+
+// DriftNotification describes a threshold breach announced to a chat channel
+type DriftNotification struct {
+	RepoName    string
+	Environment string
+	DriftCount  int
+	Threshold   int
+	IssueURL    string
+	Updated     bool // The breach updated an open issue rather than creating one
+}
+
+// Notifier defines the interface for announcing drift outside the issue tracker, such as in Slack
+type Notifier interface {
+	// NotifyDrift announces a threshold breach and the issue tracking it
+	NotifyDrift(ctx context.Context, notification DriftNotification) error
+}
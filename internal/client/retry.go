@@ -0,0 +1,79 @@
+package client
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryableStatus reports whether a response status is a transient failure worth retrying
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// maxRetryAfterFactor bounds a Retry-After delay to this many times the longest backoff, so a server
+// asking for a long wait cannot hold a report for hours
+const maxRetryAfterFactor = 4
+
+// retryDelay returns the wait before retry number attempt (from zero), doubling baseDelay each
+// time unless the response asks for a specific delay with Retry-After, which is capped at maxDelay
+func retryDelay(resp *http.Response, attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	if resp != nil {
+		if header := resp.Header.Get("Retry-After"); header != "" {
+			if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+				return min(time.Duration(seconds)*time.Second, maxDelay)
+			}
+			if at, err := http.ParseTime(header); err == nil {
+				return min(max(time.Until(at), 0), maxDelay)
+			}
+		}
+	}
+	return baseDelay << attempt
+}
+
+// do sends a GitLab API request, retrying connection errors and transient statuses with exponential
+// backoff up to the configured number of retries. Other statuses, and the last attempt's transient
+// status, are returned for the caller to handle; waits end early when the request's context is done.
+func (g *GitLabClient) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := g.httpClient.Do(req)
+		if attempt >= g.maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := retryDelay(resp, attempt, g.retryBaseDelay, maxRetryAfterFactor*(g.retryBaseDelay<<g.maxRetries))
+		if err == nil {
+			slog.Warn("GitLab API returned a transient error, retrying", "status_code", resp.StatusCode, "url", req.URL.String(), "attempt", attempt+1, "delay", delay)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		} else {
+			slog.Warn("GitLab API request failed, retrying", "error", err, "url", req.URL.String(), "attempt", attempt+1, "delay", delay)
+		}
+
+		// The body was consumed by the failed attempt
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"drift-guardian/internal/config"
+)
+
+// SlackNotifier implements Notifier by posting to a Slack incoming webhook
+type SlackNotifier struct {
+	httpClient *http.Client
+	webhookURL string
+}
+
+// NewSlackNotifier creates a notifier posting to the SLACK_WEBHOOK_URL incoming webhook
+func NewSlackNotifier(cfg *config.Config) *SlackNotifier {
+	return &SlackNotifier{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		webhookURL: cfg.SlackWebhookURL,
+	}
+}
+
+// slackMessage represents the request body of an incoming webhook message
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// NotifyDrift posts the breach to the webhook's channel
+func (s *SlackNotifier) NotifyDrift(ctx context.Context, notification DriftNotification) error {
+	requestBody, err := json.Marshal(slackMessage{Text: formatSlackMessage(notification)})
+	if err != nil {
+		return fmt.Errorf("error marshaling Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("error creating Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The webhook URL is a credential, so it is never logged
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending Slack notification: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("received non-success status code from Slack: %d", resp.StatusCode)
+	}
+
+	slog.Debug("Slack notification sent", "repo", notification.RepoName, "environment", notification.Environment)
+	return nil
+}
+
+// formatSlackMessage renders a breach as Slack mrkdwn
+func formatSlackMessage(notification DriftNotification) string {
+	headline := "Drift threshold exceeded"
+	if notification.Updated {
+		headline = "Drift continues"
+	}
+
+	text := fmt.Sprintf(":warning: *%s* in `%s` / `%s`\nDrift count: *%d* (threshold %d)",
+		headline, slackEscape(notification.RepoName), slackEscape(notification.Environment), notification.DriftCount, notification.Threshold)
+	if notification.IssueURL != "" {
+		text += fmt.Sprintf("\nIssue: <%s>", notification.IssueURL)
+	}
+	return text
+}
+
+// slackEscaper escapes the characters Slack treats as control sequences in message text
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscape escapes text for inclusion in a Slack message
+func slackEscape(text string) string {
+	return slackEscaper.Replace(text)
+}
@@ -1,78 +1,626 @@
 package config
 
 import (
+	"crypto/x509"
+	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// defaultRemediationCommand is rendered into drift issues when REMEDIATION_COMMAND_TEMPLATE is not set;
+// {repo} and {environment} are replaced with the drifted environment's values
+const defaultRemediationCommand = "cd {repo} && terraform workspace select {environment} && terraform plan"
+
+// maxExitCodeSeriesLength bounds the exit code series kept per environment
+const maxExitCodeSeriesLength = 10000
+
+// maxTierDriftWeight bounds the drift increment multiplier of a tier
+const maxTierDriftWeight = 100
+
 // Config holds application configuration
 type Config struct {
 	// Logging configuration
-	LogLevel string
+	LogLevel             string
+	LogErrorResponseBody bool
 
 	// Authentication configuration
 	EnableAuthentication bool
-	BearerToken          string
+	BearerToken          string // Deprecated: use BearerTokens
+	BearerTokens         []string
+	TokenScopes          map[string][]string // Scoped token -> allowed repoName patterns
+	TokenIdentities      map[string]string   // Token -> team or service identity named in logs and audit entries
+	DefaultTokenIdentity string              // Identity of the deprecated BEARER_TOKEN
+
+	// Storage configuration
+	StorageBackend    string
+	MemoryStorageFile string
+	PostgresDSN       string
+	HashKeys          bool // Store environments under a fixed-length hash of repo:environment
 
 	// Redis configuration
-	RedisURL string
+	RedisURL        string
+	RedisReplicaURL string // Optional read-only replica for state lookups; empty reads from RedisURL
+	RedisOpTimeout  time.Duration
+	CompressPlans   bool // Store plan output gzip-compressed in Redis
 
 	// GitLab configuration
-	GitLabToken   string
-	GitLabBaseURL string
-	GitLabSkipTLS bool
+	GitLabToken          string
+	GitLabBaseURL        string
+	GitLabSkipTLS        bool
+	GitLabCACert         string
+	GitLabMaxRetries     int           // Retries of GitLab calls failing with a transient error; zero disables retries
+	GitLabRetryBaseDelay time.Duration // Delay before the first retry, doubling with each further retry
+	GitLabCreateRPS      float64       // Issue-creating calls allowed per second across all environments; zero disables the limit
+	GitLabCreateBurst    int           // Issue-creating calls allowed at once before the rate applies
+
+	// Issue provider configuration
+	IssueProvider string // gitlab or github
+	GitHubToken   string
+	GitHubBaseURL string
+
+	// Chat notification configuration
+	SlackWebhookURL string // Incoming webhook notified of threshold breaches; empty disables Slack notifications
 
 	// Application configuration
-	ComparisonBranch string
-	DriftThreshold   int
+	ComparisonBranch   string
+	ComparisonRefType  string // branch compares against the named branch; tag treats ComparisonBranch as a tag pattern
+	DriftThreshold     int
+	StripANSI          bool
+	ResourcesHeader    bool // Parse changed resource counts from plans for the X-Drift-Resources header
+	PlanSummary        bool // Parse change counts and changed resources from plans for the drift issue
+	PreviewMode        bool
+	FailedApplyAsDrift bool
+	ApplyResetBranches []string
+	ResolveOperations  map[string][]int // Operation -> exit codes that resolve drift; empty means apply with exit code 0
+	TierDriftWeights   map[string]int   // Lower-cased tier -> multiplier of each detection's drift increment; unlisted tiers count 1
+	DedupeReports      bool             // Collapse identical reports processed concurrently by this instance into one
+
+	// Issue tracking configuration
+	IssueTiers         []string
+	DigestMode         bool
+	ProjectOverview    bool
+	IssuePlanMaxLines  int
+	IssueLabels        []string
+	ResolvedLabel      string
+	SkipUnchangedPlan  bool
+	DetectionLabels    bool
+	MetadataLabels     bool
+	IssueAfterBreaches int
+	RemediationCommand string
+	TrackDriftDuration bool
+	PlanErrorIssues    bool
+	DriftMilestone     string // Milestone ID for new drift issues, or auto for the project's current milestone
+	ApplyAuthor        string // Route drift issues to the last apply's commit author: mention or assign
+	VerifyProject      string // Check the projectId belongs to the reported repo: warn, or true to reject mismatches
+
+	// Environment group configuration
+	EnvironmentGroups map[string][]string // Group -> repoName/environment patterns of its members
+	GroupAggregation  string              // Default way member drift is combined: any or sum
+
+	// Notification throttle configuration
+	NotificationThrottle time.Duration
+	CriticalDriftWeight  int
+	IssueUpdateInterval  time.Duration // Minimum time between updates of one issue across all replicas
+
+	// Change freeze configuration
+	FreezeWindows []FreezeWindow // Drift is counted but no issues are filed or notified while one is active
+
+	// Payload limits
+	MaxAcceptedPlanOutput int
+	MaxClockSkew          time.Duration // How far a payload timestamp may be from server time; zero disables the check
+	ClockSkewAction       string        // What happens to timestamps outside MaxClockSkew: reject or clamp
+	ReplayMaxAge          time.Duration // How far in the past a replayed report's timestamp may be; MaxClockSkew bounds it when smaller
+
+	// Escalation configuration
+	EscalationAfter         time.Duration
+	EscalationLabel         string
+	EscalationCheckInterval time.Duration
+
+	// Acknowledgement configuration
+	AckMaxDuration time.Duration
+
+	// Issue reconciliation configuration
+	IssueReconcileInterval time.Duration
+	IssueStatusBatchSize   int // Issues checked per GitLab list request while reconciling; zero checks them one at a time
+
+	// Exit code series configuration
+	ExitCodeSeriesLength int // Operations kept per environment for trend charts; zero records no series
+
+	// Staleness configuration
+	StaleAfter time.Duration // Environments without a successful report for this long are listed by /stats; zero disables tracking
+
+	// Metrics configuration
+	StatsdAddr   string
+	StatsdPrefix string
+
+	// Audit configuration
+	AuditLog         string // Audit sink: stdout, file or redis; empty disables the audit log
+	AuditLogFile     string // File the file sink appends to
+	AuditRedisStream string // Stream the redis sink appends to, on REDIS_URL
 
 	// Server configuration
-	Port string
+	Port     string
+	GRPCPort string // Port of the gRPC server for internal tooling; empty disables it
+
+	// Retention configuration; zero keeps environment data indefinitely. An environment that expires
+	// with an open drift issue leaves the issue open in the tracker, to be closed by hand.
+	RetentionProd    time.Duration
+	RetentionNonprod time.Duration
+	EnvironmentTTL   time.Duration // Retention of tiers without their own; refreshed by every report
+
+	// Maintenance configuration
+	MaintenanceMode       bool
+	MaintenanceRetryAfter time.Duration
+
+	// CLI version configuration
+	MinCLIVersion        string // Oldest CLI version whose reports are accepted without a warning, e.g. 1.4.0
+	EnforceMinCLIVersion bool   // Reject reports from older CLIs with 426 instead of only logging them
 }
 
+// durationEnvVars lists the duration settings checked by Validate
+var durationEnvVars = []string{"ACK_MAX_DURATION", "ENVIRONMENT_TTL", "ESCALATION_AFTER", "ESCALATION_CHECK_INTERVAL", "ISSUE_RECONCILE_INTERVAL", "ISSUE_UPDATE_MIN_INTERVAL", "MAINTENANCE_RETRY_AFTER", "MAX_CLOCK_SKEW", "NOTIFICATION_THROTTLE", "REDIS_OP_TIMEOUT", "REPLAY_MAX_AGE", "RETENTION_PROD", "RETENTION_NONPROD", "STALE_AFTER"}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
-	return &Config{
+	cfg := &Config{
 		// Logging
-		LogLevel: getEnvString("LOG_LEVEL", "info"),
+		LogLevel:             getEnvString("LOG_LEVEL", "info"),
+		LogErrorResponseBody: getEnvBool("LOG_ERROR_RESPONSE_BODY", false),
 
 		// Authentication
 		EnableAuthentication: getEnvBool("ENABLE_AUTHENTICATION", false),
 		BearerToken:          getEnvString("BEARER_TOKEN", ""),
+		BearerTokens:         getEnvStringSlice("BEARER_TOKENS", nil),
+		TokenScopes:          getEnvTokenScopes("SCOPED_TOKENS"),
+		TokenIdentities:      getEnvTokenIdentities("TOKEN_IDENTITIES"),
+		DefaultTokenIdentity: getEnvString("DEFAULT_TOKEN_IDENTITY", "default"),
+
+		// Storage
+		StorageBackend:    strings.ToLower(getEnvString("STORAGE_BACKEND", "redis")),
+		MemoryStorageFile: getEnvString("MEMORY_STORAGE_FILE", ""),
+		PostgresDSN:       getEnvString("POSTGRES_DSN", ""),
+		HashKeys:          getEnvBool("HASH_KEYS", false),
 
 		// Redis
-		RedisURL: getEnvString("REDIS_URL", ""),
+		RedisURL:        getEnvString("REDIS_URL", ""),
+		RedisReplicaURL: getEnvString("REDIS_REPLICA_URL", ""),
+		RedisOpTimeout:  getEnvDuration("REDIS_OP_TIMEOUT", 0), // Zero keeps the client's default timeouts
+		CompressPlans:   getEnvBool("COMPRESS_PLAN_OUTPUT", false),
 
 		// GitLab (maintaining backward compatibility)
-		GitLabToken:   getEnvString("GITLAB_API_TOKEN", ""),                        // Keep existing name
-		GitLabBaseURL: getEnvString("GITLAB_API_URL", "https://gitlab.com/api/v4"), // Use existing env var name with default
-		GitLabSkipTLS: getEnvBool("GITLAB_SKIP_TLS_VERIFY", false),
+		GitLabToken:          getEnvString("GITLAB_API_TOKEN", ""),                        // Keep existing name
+		GitLabBaseURL:        getEnvString("GITLAB_API_URL", "https://gitlab.com/api/v4"), // Use existing env var name with default
+		GitLabSkipTLS:        getEnvBool("GITLAB_SKIP_TLS_VERIFY", false),
+		GitLabCACert:         getEnvString("GITLAB_CA_CERT_FILE", ""),
+		GitLabMaxRetries:     getEnvInt("GITLAB_MAX_RETRIES", 2), // Three attempts in total
+		GitLabRetryBaseDelay: getEnvDuration("GITLAB_RETRY_BASE_DELAY", 500*time.Millisecond),
+		GitLabCreateRPS:      getEnvFloat("GITLAB_CREATE_RPS", 0), // Fractions pace slower than one call a second, e.g. 0.5 is 30 a minute
+		GitLabCreateBurst:    getEnvInt("GITLAB_CREATE_BURST", 1),
+
+		// Issue provider (GitHub Issues for repositories outside GitLab)
+		IssueProvider: strings.ToLower(getEnvString("ISSUE_PROVIDER", "gitlab")),
+		GitHubToken:   getEnvString("GITHUB_TOKEN", ""),
+		GitHubBaseURL: getEnvString("GITHUB_API_URL", "https://api.github.com"),
+
+		// Chat notifications (disabled when SLACK_WEBHOOK_URL is empty)
+		SlackWebhookURL: getEnvString("SLACK_WEBHOOK_URL", ""),
 
 		// Application (maintaining backward compatibility)
-		ComparisonBranch: getEnvString("COMPARISION_BRANCH", "main"), // Keep existing typo for compatibility
-		DriftThreshold:   getEnvInt("DEFAULT_DRIFT_THRESHOLD", 1),    // Keep existing name
+		ComparisonBranch:   getEnvString("COMPARISION_BRANCH", "main"),                     // Keep existing typo for compatibility
+		ComparisonRefType:  strings.ToLower(getEnvString("COMPARISON_REF_TYPE", "branch")), // tag matches COMPARISION_BRANCH as a pattern, e.g. v*
+		DriftThreshold:     getEnvInt("DEFAULT_DRIFT_THRESHOLD", 1),                        // Keep existing name
+		StripANSI:          getEnvBool("STRIP_ANSI", true),
+		ResourcesHeader:    getEnvBool("DRIFT_RESOURCES_HEADER", false),    // Report the changed resource count of the latest drifted plan
+		PlanSummary:        getEnvBool("PLAN_SUMMARY", false),              // Summarize the latest drifted plan's changes atop its issue
+		PreviewMode:        getEnvBool("PREVIEW_MODE", false),              // Record feature-branch plans as previews
+		FailedApplyAsDrift: getEnvBool("FAILED_APPLY_AS_DRIFT", false),     // Count a failed apply as a drift detection
+		ApplyResetBranches: getEnvStringSlice("APPLY_RESET_BRANCHES", nil), // Empty lets applies on any branch reset drift
+		ResolveOperations:  getEnvResolveOperations("RESOLVE_OPERATIONS"),  // e.g. apply,destroy,import=0
+		TierDriftWeights:   getEnvTierDriftWeights("TIER_DRIFT_WEIGHTS"),   // e.g. prod=2,nonprod=1
+		DedupeReports:      getEnvBool("DEDUPLICATE_REPORTS", false),       // Overlapping triggers of one job share a single processing
+
+		// Issue tracking (empty means all tiers)
+		IssueTiers:         getEnvStringSlice("ISSUE_TIERS", nil),
+		DigestMode:         getEnvBool("DIGEST_MODE", false),
+		ProjectOverview:    getEnvBool("PROJECT_OVERVIEW_ISSUE", false),      // Keep a Drift Overview issue listing every environment per project
+		IssuePlanMaxLines:  getEnvInt("ISSUE_PLAN_MAX_LINES", 0),             // Zero embeds the full plan
+		IssueLabels:        getEnvLabels("ISSUE_LABELS"),                     // Unset uses the client's default labels, empty means none
+		ResolvedLabel:      getEnvString("ISSUE_RESOLVED_LABEL", ""),         // e.g. drift::resolved to pair with drift::alert
+		SkipUnchangedPlan:  getEnvBool("SKIP_UNCHANGED_PLAN_UPDATES", false), // Leave the issue alone when the plan repeats
+		DetectionLabels:    getEnvBool("DETECTION_LABELS", false),            // Label new issues detection:scheduled or detection:manual
+		MetadataLabels:     getEnvBool("METADATA_LABELS", false),             // Label new issues key::value from the payload metadata
+		IssueAfterBreaches: getEnvInt("CREATE_ISSUE_AFTER_BREACHES", 1),      // Consecutive breaches before an issue is filed
+		RemediationCommand: getEnvString("REMEDIATION_COMMAND_TEMPLATE", defaultRemediationCommand),
+		TrackDriftDuration: getEnvBool("TRACK_DRIFT_DURATION", false), // Time drift from first breach to resolution
+		PlanErrorIssues:    getEnvBool("PLAN_ERROR_ISSUES", false),    // File or label plan-error issues when a comparison-branch plan fails
+		DriftMilestone:     getEnvString("DRIFT_MILESTONE_ID", ""),    // Empty leaves new drift issues without a milestone
+		ApplyAuthor:        getEnvString("APPLY_AUTHOR", ""),          // Empty neither records nor mentions apply authors
+		VerifyProject:      getEnvString("VERIFY_PROJECT", "false"),   // false trusts the payload's projectId
+
+		// Environment groups (root modules reporting separately for one logical environment)
+		EnvironmentGroups: getEnvEnvironmentGroups("ENVIRONMENT_GROUPS"),             // e.g. shop-prod=shop/prod-*|shop-data/prod
+		GroupAggregation:  strings.ToLower(getEnvString("GROUP_AGGREGATION", "any")), // any reports the most drifted member, sum the total
+
+		// Notification throttle (disabled when NOTIFICATION_THROTTLE is zero)
+		NotificationThrottle: getEnvDuration("NOTIFICATION_THROTTLE", 0),
+		CriticalDriftWeight:  getEnvInt("CRITICAL_DRIFT_WEIGHT", 0),          // Detections weighted at least this bypass the throttle; zero disables bypass
+		IssueUpdateInterval:  getEnvDuration("ISSUE_UPDATE_MIN_INTERVAL", 0), // Zero leaves issue updates unlimited
+
+		// Change freezes (e.g. 2024-12-20T00:00:00Z/2025-01-06T00:00:00Z)
+		FreezeWindows: getEnvFreezeWindows("FREEZE_WINDOWS"),
+
+		// Payload limits (zero accepts plan output of any size)
+		MaxAcceptedPlanOutput: getEnvInt("MAX_ACCEPTED_PLAN_OUTPUT", 1<<20),
+		MaxClockSkew:          getEnvDuration("MAX_CLOCK_SKEW", 0),
+		ClockSkewAction:       strings.ToLower(getEnvString("CLOCK_SKEW_ACTION", "reject")), // clamp records server time instead
+		ReplayMaxAge:          getEnvDuration("REPLAY_MAX_AGE", 7*24*time.Hour),             // Reports queued by the CLI longer than this are not accepted
+
+		// Escalation (disabled when ESCALATION_AFTER is zero)
+		EscalationAfter:         getEnvDuration("ESCALATION_AFTER", 0),
+		EscalationLabel:         getEnvString("ESCALATION_LABEL", "drift-escalated"),
+		EscalationCheckInterval: getEnvDuration("ESCALATION_CHECK_INTERVAL", 15*time.Minute),
+
+		// Acknowledgements (overdue resolve-by times are checked every ESCALATION_CHECK_INTERVAL)
+		AckMaxDuration: getEnvDuration("ACK_MAX_DURATION", 7*24*time.Hour), // Zero places no limit on ack windows
+
+		// Issue reconciliation (disabled when ISSUE_RECONCILE_INTERVAL is zero)
+		IssueReconcileInterval: getEnvDuration("ISSUE_RECONCILE_INTERVAL", 0),
+		IssueStatusBatchSize:   getEnvInt("ISSUE_STATUS_BATCH_SIZE", 0), // At most 100, one page of the issue list
+
+		// Exit code series (disabled when EXIT_CODE_SERIES_LENGTH is zero)
+		ExitCodeSeriesLength: getEnvInt("EXIT_CODE_SERIES_LENGTH", 0),
+
+		// Staleness (disabled when STALE_AFTER is zero)
+		StaleAfter: getEnvDuration("STALE_AFTER", 0), // e.g. 48h for daily scheduled jobs
+
+		// Metrics (disabled when STATSD_ADDR is empty)
+		StatsdAddr:   getEnvString("STATSD_ADDR", ""),
+		StatsdPrefix: getEnvString("STATSD_PREFIX", "drift_guardian."),
+
+		// Audit log of mutating operations (disabled when AUDIT_LOG is empty)
+		AuditLog:         strings.ToLower(getEnvString("AUDIT_LOG", "")),
+		AuditLogFile:     getEnvString("AUDIT_LOG_FILE", ""),
+		AuditRedisStream: getEnvString("AUDIT_REDIS_STREAM", "drift-guardian:audit"),
 
 		// Server
-		Port: getEnvString("PORT", "8080"),
+		Port:     getEnvString("PORT", "8080"),
+		GRPCPort: getEnvString("GRPC_PORT", ""),
+
+		// Retention by environment tier (refreshed on each report)
+		RetentionProd:    getEnvDuration("RETENTION_PROD", 0),
+		RetentionNonprod: getEnvDuration("RETENTION_NONPROD", 0),
+		EnvironmentTTL:   getEnvDuration("ENVIRONMENT_TTL", 0), // e.g. 720h
+
+		// Maintenance (reject drift reports with 503 so CI retries later)
+		MaintenanceMode:       getEnvBool("MAINTENANCE_MODE", false),
+		MaintenanceRetryAfter: getEnvDuration("MAINTENANCE_RETRY_AFTER", time.Minute),
+
+		// CLI version (unset accepts reports from any CLI)
+		MinCLIVersion:        getEnvString("MIN_CLI_VERSION", ""),
+		EnforceMinCLIVersion: getEnvBool("ENFORCE_MIN_CLI_VERSION", false),
+	}
+
+	// Accept the deprecated single token alongside the rotation list
+	if cfg.BearerToken != "" {
+		cfg.BearerTokens = append(cfg.BearerTokens, cfg.BearerToken)
+	}
+
+	// Accept named tokens as bearer tokens unless they are scoped
+	for token := range cfg.TokenIdentities {
+		if _, scoped := cfg.TokenScopes[token]; !scoped && !slices.Contains(cfg.BearerTokens, token) {
+			cfg.BearerTokens = append(cfg.BearerTokens, token)
+		}
 	}
+
+	return cfg
 }
 
 // Validate checks if required configuration is present
 func (c *Config) Validate() error {
 	// Check required fields
-	if c.RedisURL == "" {
-		return &ConfigError{Field: "REDIS_URL", Message: "Redis URL is required"}
+	switch c.StorageBackend {
+	case "redis":
+		if c.RedisURL == "" {
+			return &ConfigError{Field: "REDIS_URL", Message: "Redis URL is required"}
+		}
+		if c.RedisOpTimeout < 0 {
+			return &ConfigError{Field: "REDIS_OP_TIMEOUT", Message: "Redis operation timeout cannot be negative"}
+		}
+	case "postgres":
+		if c.PostgresDSN == "" {
+			return &ConfigError{Field: "POSTGRES_DSN", Message: "Postgres DSN is required when using the postgres storage backend"}
+		}
+	case "memory":
+	default:
+		return &ConfigError{Field: "STORAGE_BACKEND", Message: "Storage backend must be one of: redis, postgres, memory"}
+	}
+
+	if _, err := parseTokenScopes(os.Getenv("SCOPED_TOKENS")); err != nil {
+		return &ConfigError{Field: "SCOPED_TOKENS", Message: err.Error()}
+	}
+
+	if _, err := parseTokenIdentities(os.Getenv("TOKEN_IDENTITIES")); err != nil {
+		return &ConfigError{Field: "TOKEN_IDENTITIES", Message: err.Error()}
+	}
+
+	if c.EnableAuthentication && len(c.BearerTokens) == 0 && len(c.TokenScopes) == 0 {
+		return &ConfigError{Field: "BEARER_TOKENS", Message: "At least one bearer token is required when authentication is enabled"}
+	}
+
+	for _, key := range durationEnvVars {
+		if value := os.Getenv(key); value != "" {
+			if _, err := parseDuration(value); err != nil {
+				return &ConfigError{Field: key, Message: fmt.Sprintf("Invalid duration %q: use a Go duration such as 48h, a number of days such as 90d, or a number of hours", value)}
+			}
+		}
+	}
+
+	if c.DriftThreshold < 1 {
+		return &ConfigError{Field: "DEFAULT_DRIFT_THRESHOLD", Message: "Drift threshold must be at least 1"}
+	}
+
+	if c.EscalationAfter > 0 && c.EscalationCheckInterval <= 0 {
+		return &ConfigError{Field: "ESCALATION_CHECK_INTERVAL", Message: "Escalation check interval must be positive when escalation is enabled"}
+	}
+
+	if c.AckMaxDuration < 0 {
+		return &ConfigError{Field: "ACK_MAX_DURATION", Message: "Maximum acknowledgement duration cannot be negative"}
+	}
+
+	for _, label := range append([]string{c.ResolvedLabel}, c.IssueLabels...) {
+		if strings.HasPrefix(label, "::") || strings.HasSuffix(label, "::") {
+			return &ConfigError{Field: "ISSUE_LABELS", Message: fmt.Sprintf("Scoped label %q must have the form scope::value", label)}
+		}
+	}
+
+	if c.MaxAcceptedPlanOutput < 0 {
+		return &ConfigError{Field: "MAX_ACCEPTED_PLAN_OUTPUT", Message: "Maximum accepted plan output cannot be negative"}
+	}
+
+	if c.MaxClockSkew < 0 {
+		return &ConfigError{Field: "MAX_CLOCK_SKEW", Message: "Maximum clock skew cannot be negative"}
+	}
+
+	switch c.ClockSkewAction {
+	case "", "reject", "clamp":
+	default:
+		return &ConfigError{Field: "CLOCK_SKEW_ACTION", Message: "Clock skew action must be reject or clamp"}
+	}
+
+	if c.ReplayMaxAge < 0 {
+		return &ConfigError{Field: "REPLAY_MAX_AGE", Message: "Replay maximum age cannot be negative"}
+	}
+
+	if c.NotificationThrottle < 0 {
+		return &ConfigError{Field: "NOTIFICATION_THROTTLE", Message: "Notification throttle cannot be negative"}
+	}
+
+	if c.CriticalDriftWeight < 0 {
+		return &ConfigError{Field: "CRITICAL_DRIFT_WEIGHT", Message: "Critical drift weight cannot be negative"}
+	}
+
+	if c.IssueUpdateInterval < 0 {
+		return &ConfigError{Field: "ISSUE_UPDATE_MIN_INTERVAL", Message: "Issue update interval cannot be negative"}
+	}
+
+	if _, err := parseFreezeWindows(os.Getenv("FREEZE_WINDOWS")); err != nil {
+		return &ConfigError{Field: "FREEZE_WINDOWS", Message: err.Error()}
+	}
+
+	if c.IssueAfterBreaches < 0 {
+		return &ConfigError{Field: "CREATE_ISSUE_AFTER_BREACHES", Message: "Breach count cannot be negative"}
+	}
+
+	if c.DriftMilestone != "" && c.DriftMilestone != "auto" {
+		if id, err := strconv.Atoi(c.DriftMilestone); err != nil || id <= 0 {
+			return &ConfigError{Field: "DRIFT_MILESTONE_ID", Message: "Drift milestone must be a positive milestone ID or auto"}
+		}
+	}
+
+	switch c.ApplyAuthor {
+	case "", "mention", "assign":
+	default:
+		return &ConfigError{Field: "APPLY_AUTHOR", Message: "Apply author must be mention or assign"}
+	}
+
+	switch c.IssueProvider {
+	case "", "gitlab":
+	case "github":
+		if c.GitHubToken == "" {
+			return &ConfigError{Field: "GITHUB_TOKEN", Message: "GitHub token is required when using the github issue provider"}
+		}
+		// These features use GitLab APIs or client settings the GitHub client does not provide
+		gitlabOnly := []struct {
+			field   string
+			enabled bool
+		}{
+			{"DIGEST_MODE", c.DigestMode},
+			{"PROJECT_OVERVIEW_ISSUE", c.ProjectOverview},
+			{"PLAN_ERROR_ISSUES", c.PlanErrorIssues},
+			{"VERIFY_PROJECT", c.VerifyProject == "warn" || c.VerifyProject == "true"},
+			{"ISSUE_STATUS_BATCH_SIZE", c.IssueStatusBatchSize > 0},
+			{"PREVIEW_MODE", c.PreviewMode},
+			{"DRIFT_MILESTONE_ID", c.DriftMilestone != ""},
+			{"APPLY_AUTHOR", c.ApplyAuthor != ""},
+			{"GITLAB_CREATE_RPS", c.GitLabCreateRPS > 0},
+			{"GITLAB_CA_CERT_FILE", c.GitLabCACert != ""},
+			{"GITLAB_SKIP_TLS_VERIFY", c.GitLabSkipTLS},
+		}
+		for _, option := range gitlabOnly {
+			if option.enabled {
+				return &ConfigError{Field: option.field, Message: "Option is only supported with the gitlab issue provider"}
+			}
+		}
+	default:
+		return &ConfigError{Field: "ISSUE_PROVIDER", Message: "Issue provider must be gitlab or github"}
+	}
+
+	if c.SlackWebhookURL != "" {
+		if webhook, err := url.Parse(c.SlackWebhookURL); err != nil || (webhook.Scheme != "https" && webhook.Scheme != "http") || webhook.Host == "" {
+			return &ConfigError{Field: "SLACK_WEBHOOK_URL", Message: "Slack webhook URL must be an http or https URL"}
+		}
+	}
+
+	switch c.VerifyProject {
+	case "", "false", "warn", "true":
+	default:
+		return &ConfigError{Field: "VERIFY_PROJECT", Message: "Project verification must be false, warn or true"}
+	}
+
+	if c.IssuePlanMaxLines < 0 {
+		return &ConfigError{Field: "ISSUE_PLAN_MAX_LINES", Message: "Issue plan line limit cannot be negative"}
+	}
+
+	if c.IssueReconcileInterval < 0 {
+		return &ConfigError{Field: "ISSUE_RECONCILE_INTERVAL", Message: "Issue reconcile interval cannot be negative"}
+	}
+
+	if c.IssueStatusBatchSize < 0 || c.IssueStatusBatchSize > 100 {
+		return &ConfigError{Field: "ISSUE_STATUS_BATCH_SIZE", Message: "Issue status batch size must be between 0 and 100"}
+	}
+
+	if c.ExitCodeSeriesLength < 0 || c.ExitCodeSeriesLength > maxExitCodeSeriesLength {
+		return &ConfigError{Field: "EXIT_CODE_SERIES_LENGTH", Message: fmt.Sprintf("Exit code series length must be between 0 and %d", maxExitCodeSeriesLength)}
+	}
+
+	if c.StaleAfter < 0 {
+		return &ConfigError{Field: "STALE_AFTER", Message: "Staleness window cannot be negative"}
+	}
+
+	switch c.ComparisonRefType {
+	case "", "branch":
+	case "tag":
+		if _, err := path.Match(c.ComparisonBranch, ""); err != nil {
+			return &ConfigError{Field: "COMPARISION_BRANCH", Message: fmt.Sprintf("Invalid tag pattern %q", c.ComparisonBranch)}
+		}
+	default:
+		return &ConfigError{Field: "COMPARISON_REF_TYPE", Message: "Comparison ref type must be branch or tag"}
+	}
+
+	for _, pattern := range c.ApplyResetBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return &ConfigError{Field: "APPLY_RESET_BRANCHES", Message: fmt.Sprintf("Invalid branch pattern %q", pattern)}
+		}
+	}
+
+	if _, err := parseTierDriftWeights(os.Getenv("TIER_DRIFT_WEIGHTS")); err != nil {
+		return &ConfigError{Field: "TIER_DRIFT_WEIGHTS", Message: err.Error()}
+	}
+
+	if _, err := parseResolveOperations(os.Getenv("RESOLVE_OPERATIONS")); err != nil {
+		return &ConfigError{Field: "RESOLVE_OPERATIONS", Message: err.Error()}
+	}
+
+	if _, err := parseEnvironmentGroups(os.Getenv("ENVIRONMENT_GROUPS")); err != nil {
+		return &ConfigError{Field: "ENVIRONMENT_GROUPS", Message: err.Error()}
+	}
+
+	switch c.GroupAggregation {
+	case "", "any", "sum":
+	default:
+		return &ConfigError{Field: "GROUP_AGGREGATION", Message: "Group aggregation must be any or sum"}
+	}
+
+	if c.RetentionProd < 0 {
+		return &ConfigError{Field: "RETENTION_PROD", Message: "Prod retention cannot be negative"}
+	}
+
+	if c.RetentionNonprod < 0 {
+		return &ConfigError{Field: "RETENTION_NONPROD", Message: "Nonprod retention cannot be negative"}
+	}
+
+	if c.EnvironmentTTL < 0 {
+		return &ConfigError{Field: "ENVIRONMENT_TTL", Message: "Environment TTL cannot be negative"}
+	}
+
+	switch c.AuditLog {
+	case "", "stdout":
+	case "file":
+		if c.AuditLogFile == "" {
+			return &ConfigError{Field: "AUDIT_LOG_FILE", Message: "Audit log file is required when using the file audit sink"}
+		}
+	case "redis":
+		if c.RedisURL == "" {
+			return &ConfigError{Field: "REDIS_URL", Message: "Redis URL is required when using the redis audit sink"}
+		}
+	default:
+		return &ConfigError{Field: "AUDIT_LOG", Message: "Audit log must be stdout, file or redis"}
+	}
+
+	if c.GRPCPort != "" {
+		if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+			return &ConfigError{Field: "GRPC_PORT", Message: "gRPC port must be a port number between 1 and 65535"}
+		}
+		if c.GRPCPort == c.Port {
+			return &ConfigError{Field: "GRPC_PORT", Message: "gRPC port must differ from the HTTP port"}
+		}
+	}
+
+	if c.MaintenanceMode && c.MaintenanceRetryAfter < time.Second {
+		return &ConfigError{Field: "MAINTENANCE_RETRY_AFTER", Message: "Maintenance retry delay must be at least one second"}
+	}
+
+	if c.MinCLIVersion != "" {
+		if _, err := parseVersion(c.MinCLIVersion); err != nil {
+			return &ConfigError{Field: "MIN_CLI_VERSION", Message: err.Error()}
+		}
+	}
+
+	if c.EnforceMinCLIVersion && c.MinCLIVersion == "" {
+		return &ConfigError{Field: "ENFORCE_MIN_CLI_VERSION", Message: "Enforcing a minimum CLI version requires MIN_CLI_VERSION"}
 	}
 
-	if c.EnableAuthentication && c.BearerToken == "" {
-		return &ConfigError{Field: "BEARER_TOKEN", Message: "Bearer token is required when authentication is enabled"}
+	if c.GitLabMaxRetries < 0 {
+		return &ConfigError{Field: "GITLAB_MAX_RETRIES", Message: "GitLab retries cannot be negative"}
+	}
+
+	if c.GitLabMaxRetries > 0 && c.GitLabRetryBaseDelay <= 0 {
+		return &ConfigError{Field: "GITLAB_RETRY_BASE_DELAY", Message: "GitLab retry delay must be positive when retries are enabled"}
+	}
+
+	if c.GitLabCreateRPS < 0 {
+		return &ConfigError{Field: "GITLAB_CREATE_RPS", Message: "Issue creation rate cannot be negative"}
+	}
+
+	if c.GitLabCreateRPS > 0 && c.GitLabCreateBurst < 1 {
+		return &ConfigError{Field: "GITLAB_CREATE_BURST", Message: "Issue creation burst must be at least 1"}
+	}
+
+	if c.GitLabCACert != "" {
+		if _, err := c.LoadGitLabCACertPool(); err != nil {
+			return &ConfigError{Field: "GITLAB_CA_CERT_FILE", Message: err.Error()}
+		}
 	}
 
 	return nil
 }
 
+// LoadGitLabCACertPool returns the system cert pool extended with the configured GitLab CA bundle
+func (c *Config) LoadGitLabCACertPool() (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(c.GitLabCACert)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA certificate file %s: %w", c.GitLabCACert, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no valid PEM certificates found in %s", c.GitLabCACert)
+	}
+
+	return pool, nil
+}
+
 // GetLogLevel returns the slog.Level for the configured log level
 func (c *Config) GetLogLevel() slog.Level {
 	switch strings.ToLower(c.LogLevel) {
@@ -89,6 +637,83 @@ func (c *Config) GetLogLevel() slog.Level {
 	}
 }
 
+// TierDriftWeight returns the multiplier of drift increments in the given tier, 1 unless TIER_DRIFT_WEIGHTS lists it
+func (c *Config) TierDriftWeight(tier string) int {
+	if weight, ok := c.TierDriftWeights[strings.ToLower(tier)]; ok {
+		return weight
+	}
+	return 1
+}
+
+// IsIssueTier reports whether issues should be managed for the given environment tier
+func (c *Config) IsIssueTier(tier string) bool {
+	if len(c.IssueTiers) == 0 {
+		return true
+	}
+
+	for _, t := range c.IssueTiers {
+		if strings.EqualFold(t, tier) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FreezeWindow is a change freeze during which drift issues are neither filed nor notified
+type FreezeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ActiveFreeze returns the freeze window covering now, if any
+func (c *Config) ActiveFreeze(now time.Time) (FreezeWindow, bool) {
+	for _, window := range c.FreezeWindows {
+		if !now.Before(window.Start) && now.Before(window.End) {
+			return window, true
+		}
+	}
+	return FreezeWindow{}, false
+}
+
+// parseVersion parses a MAJOR[.MINOR[.PATCH]] version with an optional v prefix; a pre-release or
+// build suffix such as -rc1 is ignored
+func parseVersion(value string) ([3]int, error) {
+	var version [3]int
+	core, _, _ := strings.Cut(strings.TrimPrefix(value, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Split(core, ".")
+	if len(parts) > len(version) {
+		return version, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", value)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", value)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// CLIVersionOutdated reports whether a CLI version is below MinCLIVersion. CLIs that send no version
+// predate the version header and are outdated; versions that cannot be compared, such as dev builds,
+// are not.
+func (c *Config) CLIVersionOutdated(version string) bool {
+	minimum, err := parseVersion(c.MinCLIVersion)
+	if c.MinCLIVersion == "" || err != nil {
+		return false
+	}
+	if version == "" {
+		return true
+	}
+	current, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	return slices.Compare(current[:], minimum[:]) < 0
+}
+
 // ConfigError represents a configuration validation error
 type ConfigError struct {
 	Field   string
@@ -123,3 +748,302 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(floatValue) && !math.IsInf(floatValue, 0) {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvStringSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvLabels reads a comma-separated label list, telling an unset variable (nil) from one set to
+// no labels (an empty slice) so the default labels can be removed entirely
+func getEnvLabels(key string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return nil
+	}
+
+	labels := []string{}
+	for _, label := range strings.Split(os.Getenv(key), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func getEnvResolveOperations(key string) map[string][]int {
+	operations, _ := parseResolveOperations(os.Getenv(key)) // Validate reports malformed entries
+	return operations
+}
+
+// parseResolveOperations parses comma-separated operation or operation=code|code entries; an
+// operation without exit codes resolves drift when it exits 0
+func parseResolveOperations(value string) (map[string][]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	operations := make(map[string][]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		operation, codeList, hasCodes := strings.Cut(entry, "=")
+		operation = strings.TrimSpace(operation)
+		if operation == "" {
+			return operations, fmt.Errorf("entries must have the form operation or operation=code|code")
+		}
+
+		if !hasCodes {
+			operations[operation] = append(operations[operation], 0)
+			continue
+		}
+
+		var codes []int
+		for _, code := range strings.Split(codeList, "|") {
+			if code = strings.TrimSpace(code); code == "" {
+				continue
+			}
+			exitCode, err := strconv.Atoi(code)
+			if err != nil || exitCode < 0 {
+				return operations, fmt.Errorf("invalid exit code %q for operation %q", code, operation)
+			}
+			codes = append(codes, exitCode)
+		}
+		if len(codes) == 0 {
+			return operations, fmt.Errorf("operation %q must list at least one exit code", operation)
+		}
+
+		operations[operation] = append(operations[operation], codes...)
+	}
+	return operations, nil
+}
+
+func getEnvTierDriftWeights(key string) map[string]int {
+	weights, _ := parseTierDriftWeights(os.Getenv(key)) // Validate reports malformed entries
+	return weights
+}
+
+// parseTierDriftWeights parses comma-separated tier=weight entries; tiers are matched case-insensitively
+func parseTierDriftWeights(value string) (map[string]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tier, weightValue, ok := strings.Cut(entry, "=")
+		tier = strings.ToLower(strings.TrimSpace(tier))
+		if !ok || tier == "" {
+			return weights, fmt.Errorf("entries must have the form tier=weight")
+		}
+
+		weight, err := strconv.Atoi(strings.TrimSpace(weightValue))
+		if err != nil || weight < 1 || weight > maxTierDriftWeight {
+			return weights, fmt.Errorf("weight of tier %q must be between 1 and %d", tier, maxTierDriftWeight)
+		}
+		weights[tier] = weight
+	}
+	return weights, nil
+}
+
+func getEnvTokenScopes(key string) map[string][]string {
+	scopes, _ := parseTokenScopes(os.Getenv(key)) // Validate reports malformed entries
+	return scopes
+}
+
+// parseTokenScopes parses comma-separated token=pattern|pattern entries; patterns use path.Match syntax
+func parseTokenScopes(value string) (map[string][]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	scopes := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		token, patternList, ok := strings.Cut(entry, "=")
+		token = strings.TrimSpace(token)
+		if !ok || token == "" {
+			return scopes, fmt.Errorf("entries must have the form token=pattern|pattern")
+		}
+
+		var patterns []string
+		for _, pattern := range strings.Split(patternList, "|") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return scopes, fmt.Errorf("invalid repo pattern %q", pattern)
+			}
+			patterns = append(patterns, pattern)
+		}
+		if len(patterns) == 0 {
+			return scopes, fmt.Errorf("scoped token must allow at least one repo pattern")
+		}
+
+		scopes[token] = append(scopes[token], patterns...)
+	}
+	return scopes, nil
+}
+
+func getEnvTokenIdentities(key string) map[string]string {
+	identities, _ := parseTokenIdentities(os.Getenv(key)) // Validate reports malformed entries
+	return identities
+}
+
+// parseTokenIdentities parses comma-separated token=identity entries
+func parseTokenIdentities(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	identities := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		token, identity, ok := strings.Cut(entry, "=")
+		token, identity = strings.TrimSpace(token), strings.TrimSpace(identity)
+		if !ok || token == "" || identity == "" {
+			return identities, fmt.Errorf("entries must have the form token=identity")
+		}
+		if existing, duplicate := identities[token]; duplicate && existing != identity {
+			return identities, fmt.Errorf("token is mapped to both %q and %q", existing, identity)
+		}
+
+		identities[token] = identity
+	}
+	return identities, nil
+}
+
+func getEnvFreezeWindows(key string) []FreezeWindow {
+	windows, _ := parseFreezeWindows(os.Getenv(key)) // Validate reports malformed entries
+	return windows
+}
+
+// parseFreezeWindows parses comma-separated start/end pairs of RFC3339 times
+func parseFreezeWindows(value string) ([]FreezeWindow, error) {
+	var windows []FreezeWindow
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		startValue, endValue, ok := strings.Cut(entry, "/")
+		if !ok {
+			return windows, fmt.Errorf("entries must have the form start/end")
+		}
+		start, err := time.Parse(time.RFC3339, strings.TrimSpace(startValue))
+		if err != nil {
+			return windows, fmt.Errorf("invalid freeze start %q: use an RFC3339 time", startValue)
+		}
+		end, err := time.Parse(time.RFC3339, strings.TrimSpace(endValue))
+		if err != nil {
+			return windows, fmt.Errorf("invalid freeze end %q: use an RFC3339 time", endValue)
+		}
+		if !end.After(start) {
+			return windows, fmt.Errorf("freeze window %q must end after it starts", entry)
+		}
+
+		windows = append(windows, FreezeWindow{Start: start, End: end})
+	}
+	return windows, nil
+}
+
+func getEnvEnvironmentGroups(key string) map[string][]string {
+	groups, _ := parseEnvironmentGroups(os.Getenv(key)) // Validate reports malformed entries
+	return groups
+}
+
+// parseEnvironmentGroups parses comma-separated group=pattern|pattern entries; patterns use path.Match
+// syntax and are matched against repoName/environment
+func parseEnvironmentGroups(value string) (map[string][]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	groups := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, patternList, ok := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return groups, fmt.Errorf("entries must have the form group=pattern|pattern")
+		}
+
+		var patterns []string
+		for _, pattern := range strings.Split(patternList, "|") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return groups, fmt.Errorf("invalid member pattern %q", pattern)
+			}
+			patterns = append(patterns, pattern)
+		}
+		if len(patterns) == 0 {
+			return groups, fmt.Errorf("group %q must have at least one member pattern", group)
+		}
+
+		groups[group] = append(groups[group], patterns...)
+	}
+	return groups, nil
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := parseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// parseDuration parses a Go duration string, treating a bare number as hours and a "d" suffix as days
+func parseDuration(value string) (time.Duration, error) {
+	if hours, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(hours * float64(time.Hour)), nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.ParseFloat(days, 64); err == nil {
+			return time.Duration(n * float64(24*time.Hour)), nil
+		}
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,577 @@
+//go:build unit
+
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadConfig_Durations tests duration parsing and validation
+func TestLoadConfig_Durations(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      time.Duration
+		expectInvalid bool
+	}{
+		{name: "go duration", value: "48h", expected: 48 * time.Hour},
+		{name: "bare number is hours", value: "48", expected: 48 * time.Hour},
+		{name: "fractional hours", value: "1.5", expected: 90 * time.Minute},
+		{name: "days suffix", value: "90d", expected: 90 * 24 * time.Hour},
+		{name: "unset disables escalation", value: "", expected: 0},
+		{name: "malformed duration", value: "two days", expectInvalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORAGE_BACKEND", "memory")
+			t.Setenv("ESCALATION_AFTER", tt.value)
+
+			cfg := LoadConfig()
+			err := cfg.Validate()
+
+			if tt.expectInvalid {
+				var configErr *ConfigError
+				assert.ErrorAs(t, err, &configErr)
+				assert.Equal(t, "ESCALATION_AFTER", configErr.Field)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.EscalationAfter)
+		})
+	}
+}
+
+// TestLoadConfig_ScopedTokens tests parsing and validation of scoped tokens
+func TestLoadConfig_ScopedTokens(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+	t.Setenv("ENABLE_AUTHENTICATION", "true")
+
+	t.Run("valid scopes", func(t *testing.T) {
+		t.Setenv("SCOPED_TOKENS", "team-a-token=team-a-*|shared-infra, team-b-token=team-b-*")
+
+		cfg := LoadConfig()
+		assert.NoError(t, cfg.Validate(), "Scoped tokens alone satisfy the authentication requirement")
+		assert.Equal(t, map[string][]string{
+			"team-a-token": {"team-a-*", "shared-infra"},
+			"team-b-token": {"team-b-*"},
+		}, cfg.TokenScopes)
+	})
+
+	for _, value := range []string{"team-a-token", "team-a-token=", "=team-a-*", "team-a-token=team-[a"} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			t.Setenv("SCOPED_TOKENS", value)
+			t.Setenv("BEARER_TOKENS", "admin-token")
+
+			assert.Error(t, LoadConfig().Validate())
+		})
+	}
+}
+
+// TestValidate_ApplyResetBranches tests validation of apply reset branch patterns
+func TestValidate_ApplyResetBranches(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Setenv("APPLY_RESET_BRANCHES", "main,release/*")
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"main", "release/*"}, cfg.ApplyResetBranches)
+
+	t.Setenv("APPLY_RESET_BRANCHES", "release/[")
+	var configErr *ConfigError
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "APPLY_RESET_BRANCHES", configErr.Field)
+}
+
+// TestLoadConfig_ClockSkew tests parsing and validation of the payload clock skew settings
+func TestLoadConfig_ClockSkew(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Zero(t, cfg.MaxClockSkew)
+	assert.Equal(t, "reject", cfg.ClockSkewAction)
+	assert.Equal(t, 7*24*time.Hour, cfg.ReplayMaxAge)
+
+	t.Setenv("MAX_CLOCK_SKEW", "15m")
+	t.Setenv("CLOCK_SKEW_ACTION", "Clamp")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 15*time.Minute, cfg.MaxClockSkew)
+	assert.Equal(t, "clamp", cfg.ClockSkewAction)
+
+	t.Setenv("CLOCK_SKEW_ACTION", "drop")
+	var configErr *ConfigError
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "CLOCK_SKEW_ACTION", configErr.Field)
+
+	t.Setenv("CLOCK_SKEW_ACTION", "reject")
+	t.Setenv("REPLAY_MAX_AGE", "-1h")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "REPLAY_MAX_AGE", configErr.Field)
+}
+
+// TestLoadConfig_DriftMilestone tests validation of the milestone assigned to new drift issues
+func TestLoadConfig_DriftMilestone(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	for _, milestone := range []string{"", "auto", "42"} {
+		t.Setenv("DRIFT_MILESTONE_ID", milestone)
+		assert.NoError(t, LoadConfig().Validate(), milestone)
+	}
+
+	var configErr *ConfigError
+	for _, milestone := range []string{"sprint-1", "0"} {
+		t.Setenv("DRIFT_MILESTONE_ID", milestone)
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, milestone)
+		assert.Equal(t, "DRIFT_MILESTONE_ID", configErr.Field)
+	}
+}
+
+// TestLoadConfig_ApplyAuthor tests validation of how drift issues reach the last apply author
+func TestLoadConfig_ApplyAuthor(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	for _, mode := range []string{"", "mention", "assign"} {
+		t.Setenv("APPLY_AUTHOR", mode)
+		assert.NoError(t, LoadConfig().Validate(), mode)
+	}
+
+	t.Setenv("APPLY_AUTHOR", "notify")
+	var configErr *ConfigError
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "APPLY_AUTHOR", configErr.Field)
+}
+
+// TestLoadConfig_MinCLIVersion tests validation of the minimum CLI version
+func TestLoadConfig_MinCLIVersion(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Setenv("MIN_CLI_VERSION", "v1.4.0")
+	t.Setenv("ENFORCE_MIN_CLI_VERSION", "true")
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.True(t, cfg.CLIVersionOutdated("1.3.12"))
+	assert.False(t, cfg.CLIVersionOutdated("1.4.0-rc1"), "Pre-release suffixes are ignored")
+	assert.False(t, cfg.CLIVersionOutdated("1.10"))
+
+	var configErr *ConfigError
+	t.Setenv("MIN_CLI_VERSION", "1.4.0.1")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "MIN_CLI_VERSION", configErr.Field)
+
+	t.Setenv("MIN_CLI_VERSION", "")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "ENFORCE_MIN_CLI_VERSION", configErr.Field)
+}
+
+// TestLoadConfig_IssueLabels tests telling unset issue labels from labels removed entirely
+func TestLoadConfig_IssueLabels(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Setenv("ISSUE_LABELS", "drift-alert, ")
+	assert.Equal(t, []string{"drift-alert"}, LoadConfig().IssueLabels)
+
+	t.Setenv("ISSUE_LABELS", "")
+	assert.Equal(t, []string{}, LoadConfig().IssueLabels, "An empty value should mean no labels")
+
+	os.Unsetenv("ISSUE_LABELS")
+	assert.Nil(t, LoadConfig().IssueLabels, "An unset value should use the default labels")
+}
+
+// TestLoadConfig_VerifyProject tests validation of the project verification mode
+func TestLoadConfig_VerifyProject(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	for _, mode := range []string{"false", "warn", "true"} {
+		t.Setenv("VERIFY_PROJECT", mode)
+		assert.NoError(t, LoadConfig().Validate(), mode)
+	}
+
+	t.Setenv("VERIFY_PROJECT", "reject")
+	var configErr *ConfigError
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "VERIFY_PROJECT", configErr.Field)
+}
+
+// TestLoadConfig_GRPCPort tests enabling the gRPC server on a port of its own
+func TestLoadConfig_GRPCPort(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.GRPCPort)
+
+	t.Setenv("GRPC_PORT", "9090")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "9090", cfg.GRPCPort)
+
+	for _, port := range []string{"grpc", "0", "70000", "8080"} {
+		t.Setenv("GRPC_PORT", port)
+		var configErr *ConfigError
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, port)
+		assert.Equal(t, "GRPC_PORT", configErr.Field, port)
+	}
+}
+
+// TestLoadConfig_IssueProvider tests choosing between GitLab and GitHub issues
+func TestLoadConfig_IssueProvider(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+	t.Setenv("GITHUB_TOKEN", "") // Set in GitHub Actions runners
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "gitlab", cfg.IssueProvider)
+	assert.Equal(t, "https://api.github.com", cfg.GitHubBaseURL)
+
+	var configErr *ConfigError
+	t.Setenv("ISSUE_PROVIDER", "GitHub")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "GITHUB_TOKEN", configErr.Field)
+
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "github", cfg.IssueProvider)
+
+	// Features built on GitLab-only APIs are rejected rather than silently disabled
+	for _, option := range []struct{ name, value string }{
+		{"DIGEST_MODE", "true"},
+		{"PROJECT_OVERVIEW_ISSUE", "true"},
+		{"PLAN_ERROR_ISSUES", "true"},
+		{"VERIFY_PROJECT", "warn"},
+		{"ISSUE_STATUS_BATCH_SIZE", "50"},
+		{"PREVIEW_MODE", "true"},
+		{"DRIFT_MILESTONE_ID", "auto"},
+		{"APPLY_AUTHOR", "mention"},
+		{"GITLAB_CREATE_RPS", "2"},
+		{"GITLAB_CA_CERT_FILE", "/etc/ssl/gitlab-ca.pem"},
+		{"GITLAB_SKIP_TLS_VERIFY", "true"},
+	} {
+		t.Setenv(option.name, option.value)
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, option.name)
+		assert.Equal(t, option.name, configErr.Field)
+		os.Unsetenv(option.name)
+	}
+
+	t.Setenv("ISSUE_PROVIDER", "jira")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "ISSUE_PROVIDER", configErr.Field)
+}
+
+// TestLoadConfig_AuditLog tests choosing the audit log sink
+func TestLoadConfig_AuditLog(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.AuditLog)
+	assert.Equal(t, "drift-guardian:audit", cfg.AuditRedisStream)
+
+	t.Setenv("AUDIT_LOG", "stdout")
+	assert.NoError(t, LoadConfig().Validate())
+
+	var configErr *ConfigError
+	t.Setenv("AUDIT_LOG", "file")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "AUDIT_LOG_FILE", configErr.Field)
+
+	t.Setenv("AUDIT_LOG_FILE", "/var/log/drift-guardian/audit.log")
+	assert.NoError(t, LoadConfig().Validate())
+
+	t.Setenv("AUDIT_LOG", "redis")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "REDIS_URL", configErr.Field)
+
+	t.Setenv("AUDIT_LOG", "syslog")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "AUDIT_LOG", configErr.Field)
+}
+
+// TestLoadConfig_GitLabRetries tests configuring retries of transient GitLab failures
+func TestLoadConfig_GitLabRetries(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 2, cfg.GitLabMaxRetries)
+	assert.Equal(t, 500*time.Millisecond, cfg.GitLabRetryBaseDelay)
+
+	t.Setenv("GITLAB_MAX_RETRIES", "0")
+	t.Setenv("GITLAB_RETRY_BASE_DELAY", "0s")
+	assert.NoError(t, LoadConfig().Validate(), "Disabled retries need no delay")
+
+	var configErr *ConfigError
+	t.Setenv("GITLAB_MAX_RETRIES", "3")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "GITLAB_RETRY_BASE_DELAY", configErr.Field)
+
+	t.Setenv("GITLAB_MAX_RETRIES", "-1")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "GITLAB_MAX_RETRIES", configErr.Field)
+}
+
+// TestLoadConfig_TokenIdentities tests naming the identity each bearer token authenticates as
+func TestLoadConfig_TokenIdentities(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+	t.Setenv("ENABLE_AUTHENTICATION", "true")
+	t.Setenv("BEARER_TOKENS", "plain-token")
+	t.Setenv("SCOPED_TOKENS", "team-a-token=team-a-*")
+	t.Setenv("TOKEN_IDENTITIES", "platform-token=platform-team, team-a-token=team-a-ci")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, map[string]string{"platform-token": "platform-team", "team-a-token": "team-a-ci"}, cfg.TokenIdentities)
+	assert.Equal(t, "default", cfg.DefaultTokenIdentity)
+	assert.ElementsMatch(t, []string{"plain-token", "platform-token"}, cfg.BearerTokens, "Named tokens are accepted unless they are scoped")
+
+	var configErr *ConfigError
+	for _, value := range []string{"platform-token", "=platform-team", "platform-token=", "platform-token=a,platform-token=b"} {
+		t.Setenv("TOKEN_IDENTITIES", value)
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, value)
+		assert.Equal(t, "TOKEN_IDENTITIES", configErr.Field)
+	}
+}
+
+// TestLoadConfig_SlackWebhookURL tests enabling Slack notifications
+func TestLoadConfig_SlackWebhookURL(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Empty(t, cfg.SlackWebhookURL, "Slack notifications are disabled by default")
+
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T000/B000/XXXX")
+	assert.NoError(t, LoadConfig().Validate())
+
+	var configErr *ConfigError
+	t.Setenv("SLACK_WEBHOOK_URL", "hooks.slack.com/services/T000")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "SLACK_WEBHOOK_URL", configErr.Field)
+}
+
+// TestLoadConfig_CompressPlans tests enabling plan output compression
+func TestLoadConfig_CompressPlans(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+	assert.False(t, LoadConfig().CompressPlans, "Plan output is stored uncompressed by default")
+
+	t.Setenv("COMPRESS_PLAN_OUTPUT", "true")
+	assert.True(t, LoadConfig().CompressPlans)
+}
+
+// TestLoadConfig_EnvironmentTTL tests configuring the default retention of environments
+func TestLoadConfig_EnvironmentTTL(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Zero(t, cfg.EnvironmentTTL, "Environments never expire by default")
+
+	t.Setenv("ENVIRONMENT_TTL", "30d")
+	t.Setenv("RETENTION_PROD", "2160h")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate(), "Tier retention overrides the default")
+	assert.Equal(t, 720*time.Hour, cfg.EnvironmentTTL)
+
+	var configErr *ConfigError
+	t.Setenv("ENVIRONMENT_TTL", "soon")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "ENVIRONMENT_TTL", configErr.Field)
+}
+
+// TestLoadConfig_GitLabCreateRate tests configuring the global issue creation rate limit
+func TestLoadConfig_GitLabCreateRate(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Zero(t, cfg.GitLabCreateRPS, "Issue creation is unlimited by default")
+
+	t.Setenv("GITLAB_CREATE_RPS", "0.5")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 0.5, cfg.GitLabCreateRPS)
+	assert.Equal(t, 1, cfg.GitLabCreateBurst)
+
+	t.Setenv("GITLAB_CREATE_RPS", "NaN")
+	assert.Zero(t, LoadConfig().GitLabCreateRPS, "Non-finite rates are ignored")
+
+	var configErr *ConfigError
+	t.Setenv("GITLAB_CREATE_RPS", "2")
+	t.Setenv("GITLAB_CREATE_BURST", "0")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "GITLAB_CREATE_BURST", configErr.Field)
+
+	t.Setenv("GITLAB_CREATE_RPS", "-1")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "GITLAB_CREATE_RPS", configErr.Field)
+}
+
+// TestLoadConfig_TierDriftWeights tests parsing and validating per-tier drift increment weights
+func TestLoadConfig_TierDriftWeights(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 1, cfg.TierDriftWeight("prod"), "Drift counts one per detection by default")
+
+	t.Setenv("TIER_DRIFT_WEIGHTS", "Prod=2, nonprod=1")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, map[string]int{"prod": 2, "nonprod": 1}, cfg.TierDriftWeights)
+	assert.Equal(t, 2, cfg.TierDriftWeight("PROD"))
+	assert.Equal(t, 1, cfg.TierDriftWeight("nonprod"))
+	assert.Equal(t, 1, cfg.TierDriftWeight("sandbox"))
+
+	var configErr *ConfigError
+	for _, value := range []string{"prod", "prod=0", "prod=two", "prod=101"} {
+		t.Setenv("TIER_DRIFT_WEIGHTS", value)
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, value)
+		assert.Equal(t, "TIER_DRIFT_WEIGHTS", configErr.Field)
+	}
+}
+
+// TestLoadConfig_StaleAfter tests configuring the staleness window of /stats
+func TestLoadConfig_StaleAfter(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Zero(t, cfg.StaleAfter, "Staleness tracking is disabled by default")
+
+	t.Setenv("STALE_AFTER", "2d")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 48*time.Hour, cfg.StaleAfter)
+
+	var configErr *ConfigError
+	t.Setenv("STALE_AFTER", "-1h")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "STALE_AFTER", configErr.Field)
+}
+
+// TestLoadConfig_ComparisonRef tests choosing between branch and tag pattern comparison
+func TestLoadConfig_ComparisonRef(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "branch", cfg.ComparisonRefType)
+
+	t.Setenv("COMPARISON_REF_TYPE", "Tag")
+	t.Setenv("COMPARISION_BRANCH", "v*")
+	cfg = LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "tag", cfg.ComparisonRefType)
+
+	var configErr *ConfigError
+	t.Setenv("COMPARISION_BRANCH", "v[")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "COMPARISION_BRANCH", configErr.Field)
+
+	t.Setenv("COMPARISON_REF_TYPE", "commit")
+	assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+	assert.Equal(t, "COMPARISON_REF_TYPE", configErr.Field)
+}
+
+// TestLoadConfig_FreezeWindows tests parsing and validation of change freeze windows
+func TestLoadConfig_FreezeWindows(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Setenv("FREEZE_WINDOWS", "2024-12-20T00:00:00Z/2025-01-06T00:00:00Z, 2025-03-01T18:00:00+01:00/2025-03-02T06:00:00+01:00")
+	cfg := LoadConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Len(t, cfg.FreezeWindows, 2)
+
+	window, active := cfg.ActiveFreeze(time.Date(2024, 12, 24, 12, 0, 0, 0, time.UTC))
+	assert.True(t, active)
+	assert.Equal(t, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), window.End)
+
+	_, active = cfg.ActiveFreeze(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	assert.False(t, active, "The freeze ends at its end time")
+
+	_, active = cfg.ActiveFreeze(time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC))
+	assert.True(t, active, "Windows with offsets compare in absolute time")
+
+	for _, value := range []string{"2024-12-20T00:00:00Z", "2024-12-20/2025-01-06", "2025-01-06T00:00:00Z/2024-12-20T00:00:00Z"} {
+		t.Setenv("FREEZE_WINDOWS", value)
+		var configErr *ConfigError
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr, value)
+		assert.Equal(t, "FREEZE_WINDOWS", configErr.Field)
+	}
+}
+
+// TestLoadConfig_ResolveOperations tests parsing and validation of drift-resolving operations
+func TestLoadConfig_ResolveOperations(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Run("unset keeps the default", func(t *testing.T) {
+		cfg := LoadConfig()
+		assert.NoError(t, cfg.Validate())
+		assert.Empty(t, cfg.ResolveOperations)
+	})
+
+	t.Run("operations with and without exit codes", func(t *testing.T) {
+		t.Setenv("RESOLVE_OPERATIONS", "apply, destroy=0|2, import")
+
+		cfg := LoadConfig()
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, map[string][]int{
+			"apply":   {0},
+			"destroy": {0, 2},
+			"import":  {0},
+		}, cfg.ResolveOperations)
+	})
+
+	for _, value := range []string{"=0", "apply=", "apply=zero", "apply=-1"} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			t.Setenv("RESOLVE_OPERATIONS", value)
+
+			var configErr *ConfigError
+			assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+			assert.Equal(t, "RESOLVE_OPERATIONS", configErr.Field)
+		})
+	}
+}
+
+// TestLoadConfig_EnvironmentGroups tests parsing and validation of environment groups
+func TestLoadConfig_EnvironmentGroups(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "memory")
+
+	t.Run("groups and aggregation", func(t *testing.T) {
+		t.Setenv("ENVIRONMENT_GROUPS", "shop-prod=shop/prod-*|shop-data/prod, shop-staging=shop/staging-*")
+		t.Setenv("GROUP_AGGREGATION", "SUM")
+
+		cfg := LoadConfig()
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, map[string][]string{
+			"shop-prod":    {"shop/prod-*", "shop-data/prod"},
+			"shop-staging": {"shop/staging-*"},
+		}, cfg.EnvironmentGroups)
+		assert.Equal(t, "sum", cfg.GroupAggregation)
+	})
+
+	for _, value := range []string{"shop-prod", "shop-prod=", "=shop/prod-*", "shop-prod=shop/[prod"} {
+		t.Run("rejects "+value, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT_GROUPS", value)
+
+			var configErr *ConfigError
+			assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+			assert.Equal(t, "ENVIRONMENT_GROUPS", configErr.Field)
+		})
+	}
+
+	t.Run("rejects unknown aggregation", func(t *testing.T) {
+		t.Setenv("GROUP_AGGREGATION", "average")
+
+		var configErr *ConfigError
+		assert.ErrorAs(t, LoadConfig().Validate(), &configErr)
+		assert.Equal(t, "GROUP_AGGREGATION", configErr.Field)
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,24 +13,28 @@ import (
 
 // EnvironmentHandlerImpl implements EnvironmentHandler interface
 type EnvironmentHandlerImpl struct {
-	driftService service.DriftService
-	writer       ResponseWriter
+	driftService  service.DriftService
+	writer        ResponseWriter
+	maxPlanOutput int
 }
 
-// NewEnvironmentHandler creates a new environment handler instance
+// NewEnvironmentHandler creates a new environment handler instance; payloads with plan output
+// longer than maxPlanOutput bytes are rejected, and zero accepts any size
 func NewEnvironmentHandler(
 	driftService service.DriftService,
 	writer ResponseWriter,
+	maxPlanOutput int,
 ) *EnvironmentHandlerImpl {
 	return &EnvironmentHandlerImpl{
-		driftService: driftService,
-		writer:       writer,
+		driftService:  driftService,
+		writer:        writer,
+		maxPlanOutput: maxPlanOutput,
 	}
 }
 
 // HandleEnvironments processes HTTP requests to the /environments endpoint
 func (h *EnvironmentHandlerImpl) HandleEnvironments(w http.ResponseWriter, r *http.Request, ctx context.Context) {
-	// Only accept POST requests
+	// Only accept POST requests for processing
 	if r.Method != http.MethodPost {
 		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -50,20 +55,212 @@ func (h *EnvironmentHandlerImpl) HandleEnvironments(w http.ResponseWriter, r *ht
 		return
 	}
 
+	result, status, err := h.reportDrift(ctx, payload)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			// The failure is recorded as the environment's last error
+			w.Header().Set("X-Last-Error", err.Error())
+		}
+		_ = h.writer.WriteError(w, err.Error(), status)
+		return
+	}
+
+	headers := resultHeaders(result)
+
+	// Prepare response body (maintaining exact format for backward compatibility)
+	responseBody := fmt.Sprintf(
+		"Environment values retrieved for repository: %s, environment: %s\\nValues: {\"environmentTier\": \"%s\", \"projectID\": \"%s\", \"driftIncrement\": \"%s\", \"issueID\": \"%s\", \"issueURL\": \"%s\", \"log\": %s}",
+		payload.RepoName,
+		payload.Environment,
+		result.EnvironmentTier,
+		result.ProjectID,
+		result.DriftIncrement,
+		result.IssueID,
+		result.IssueURL,
+		result.Log["log"],
+	)
+
+	// Write successful response
+	err = h.writer.WriteSuccess(w, responseBody, headers)
+	if err != nil {
+		// Fallback error response
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// reportDrift validates and processes a drift report for both the HTTP and the gRPC endpoint,
+// returning the HTTP status that describes a failure
+func (h *EnvironmentHandlerImpl) reportDrift(ctx context.Context, payload service.Payload) (*service.DriftResult, int, error) {
+	// Bound the plan output independently of what the client chose to send
+	if h.maxPlanOutput > 0 && len(payload.PlanOutput) > h.maxPlanOutput {
+		return nil, http.StatusBadRequest, fmt.Errorf("planOutput exceeds maximum accepted size of %d bytes", h.maxPlanOutput)
+	}
+
 	// Validate the payload
 	if err := h.driftService.ValidatePayload(&payload); err != nil {
-		_ = h.writer.WriteError(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, http.StatusBadRequest, err
 	}
 
 	// Process drift detection
 	result, err := h.driftService.ProcessDriftDetection(ctx, payload)
+	if errors.Is(err, service.ErrProjectMismatch) {
+		return nil, http.StatusBadRequest, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return result, http.StatusOK, nil
+}
+
+// HandleAcknowledge processes HTTP requests to the /environments/ack endpoint
+func (h *EnvironmentHandlerImpl) HandleAcknowledge(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	if r.Method != http.MethodPost {
+		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ack service.Acknowledgement
+	if err := json.NewDecoder(r.Body).Decode(&ack); err != nil {
+		_ = h.writer.WriteError(w, "Error parsing JSON payload", http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	if err := h.driftService.AcknowledgeDrift(ctx, ack); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAcknowledgement):
+			_ = h.writer.WriteError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrEnvironmentNotFound):
+			_ = h.writer.WriteError(w, "Environment not found", http.StatusNotFound)
+		default:
+			_ = h.writer.WriteError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.writer.WriteJSON(w, ack, nil); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleGroupDrift processes HTTP requests to the /groups/drift endpoint
+func (h *EnvironmentHandlerImpl) HandleGroupDrift(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	if r.Method != http.MethodGet {
+		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		_ = h.writer.WriteError(w, "Missing group query parameter", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.driftService.GetGroupDrift(ctx, group, r.URL.Query().Get("aggregation"))
 	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrGroupNotFound):
+			_ = h.writer.WriteError(w, "Group not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidAggregation):
+			_ = h.writer.WriteError(w, err.Error(), http.StatusBadRequest)
+		default:
+			_ = h.writer.WriteError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.writer.WriteJSON(w, result, nil); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleExitCodes processes HTTP requests to the /environments/exit-codes endpoint
+func (h *EnvironmentHandlerImpl) HandleExitCodes(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	if r.Method != http.MethodGet {
+		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repoName := r.URL.Query().Get("repo")
+	environment := r.URL.Query().Get("environment")
+	if repoName == "" || environment == "" {
+		_ = h.writer.WriteError(w, "Missing repo or environment query parameter", http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.driftService.GetExitCodeSeries(ctx, repoName, environment)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExitCodeSeriesDisabled):
+			_ = h.writer.WriteError(w, "Exit code series is not enabled", http.StatusNotFound)
+		case errors.Is(err, service.ErrEnvironmentNotFound):
+			_ = h.writer.WriteError(w, "Environment not found", http.StatusNotFound)
+		default:
+			_ = h.writer.WriteError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.writer.WriteJSON(w, series, nil); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleStats processes HTTP requests to the /stats endpoint, listing environments that have not
+// reported successfully within STALE_AFTER
+func (h *EnvironmentHandlerImpl) HandleStats(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	if r.Method != http.MethodGet {
+		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := h.driftService.GetEnvironmentStats(ctx)
+	if err != nil {
+		if errors.Is(err, service.ErrStalenessDisabled) {
+			_ = h.writer.WriteError(w, "Staleness tracking is not enabled", http.StatusNotFound)
+			return
+		}
 		_ = h.writer.WriteError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Prepare response headers
+	if err := h.writer.WriteJSON(w, stats, nil); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleEnvironmentState processes HTTP requests to the /environments/{repo}/{environment} endpoint,
+// returning the stored state of an environment without modifying it
+func (h *EnvironmentHandlerImpl) HandleEnvironmentState(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	if r.Method != http.MethodGet {
+		_ = h.writer.WriteError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repoName := r.PathValue("repo")
+	environment := r.PathValue("environment")
+	if repoName == "" || environment == "" {
+		_ = h.writer.WriteError(w, "Missing repo or environment in path", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.driftService.GetEnvironmentState(ctx, repoName, environment)
+	if err != nil {
+		if errors.Is(err, service.ErrEnvironmentNotFound) {
+			_ = h.writer.WriteError(w, "Environment not found", http.StatusNotFound)
+			return
+		}
+		_ = h.writer.WriteError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.writer.WriteJSON(w, result, resultHeaders(result)); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// resultHeaders builds the response headers describing an environment's state
+func resultHeaders(result *service.DriftResult) map[string]string {
 	headers := make(map[string]string)
 	if result.EnvironmentTier != "" {
 		headers["X-Environment-Tier"] = result.EnvironmentTier
@@ -80,24 +277,15 @@ func (h *EnvironmentHandlerImpl) HandleEnvironments(w http.ResponseWriter, r *ht
 	if result.IssueURL != "" {
 		headers["X-Issue-URL"] = result.IssueURL
 	}
-
-	// Prepare response body (maintaining exact format for backward compatibility)
-	responseBody := fmt.Sprintf(
-		"Environment values retrieved for repository: %s, environment: %s\\nValues: {\"environmentTier\": \"%s\", \"projectID\": \"%s\", \"driftIncrement\": \"%s\", \"issueID\": \"%s\", \"issueURL\": \"%s\", \"log\": %s}",
-		payload.RepoName,
-		payload.Environment,
-		result.EnvironmentTier,
-		result.ProjectID,
-		result.DriftIncrement,
-		result.IssueID,
-		result.IssueURL,
-		result.Log["log"],
-	)
-
-	// Write successful response
-	err = h.writer.WriteSuccess(w, responseBody, headers)
-	if err != nil {
-		// Fallback error response
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if result.LastError != "" {
+		headers["X-Last-Error"] = result.LastError
 	}
+	if result.ChangedResources != "" {
+		headers["X-Drift-Resources"] = result.ChangedResources
+	}
+	if result.DriftUnchanged {
+		headers["X-Drift-Unchanged"] = "true"
+	}
+
+	return headers
 }
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+
+	"drift-guardian/internal/service"
+)
+
+// GRPCServiceName is the fully qualified name of the drift gRPC service
+const GRPCServiceName = "driftguardian.DriftGuardian"
+
+// GRPCCodecName is the content subtype gRPC clients must call with, e.g. grpc.CallContentSubtype(GRPCCodecName)
+const GRPCCodecName = "json"
+
+// jsonCodec encodes gRPC messages as JSON, so the gRPC service shares the HTTP API's payload types
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+func (jsonCodec) Name() string { return GRPCCodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// GetEnvironmentRequest identifies the environment a GetEnvironment call looks up
+type GetEnvironmentRequest struct {
+	RepoName    string `json:"repoName"`
+	Environment string `json:"environment"`
+}
+
+// ListEnvironmentsRequest names the environment group a ListEnvironments call lists
+type ListEnvironmentsRequest struct {
+	Group string `json:"group"`
+}
+
+// ListEnvironmentsResponse is the drift state of every environment in a group
+type ListEnvironmentsResponse struct {
+	Environments []service.GroupMember `json:"environments"`
+}
+
+// driftGuardianServer is the gRPC service implemented by GRPCHandler
+type driftGuardianServer interface {
+	ReportDrift(ctx context.Context, payload *service.Payload) (*service.DriftResult, error)
+	GetEnvironment(ctx context.Context, req *GetEnvironmentRequest) (*service.DriftResult, error)
+	ListEnvironments(ctx context.Context, req *ListEnvironmentsRequest) (*ListEnvironmentsResponse, error)
+}
+
+// GRPCHandler serves drift reports and environment lookups over gRPC for internal tooling, sharing
+// validation and processing with the HTTP environment handler
+type GRPCHandler struct {
+	environments *EnvironmentHandlerImpl
+}
+
+// NewGRPCServer creates a gRPC server exposing the drift service; opts add interceptors such as authentication
+func NewGRPCServer(environments *EnvironmentHandlerImpl, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&driftGuardianServiceDesc, &GRPCHandler{environments: environments})
+	return server
+}
+
+// ReportDrift validates and processes a drift report, like POST /environments
+func (g *GRPCHandler) ReportDrift(ctx context.Context, payload *service.Payload) (*service.DriftResult, error) {
+	// A client disconnect must not abort a half-applied update, as with the HTTP endpoint
+	result, httpStatus, err := g.environments.reportDrift(context.WithoutCancel(ctx), *payload)
+	if err != nil {
+		return nil, grpcError(httpStatus, err)
+	}
+	return result, nil
+}
+
+// GetEnvironment returns the stored state of an environment without modifying it, like GET /environments/{repo}/{environment}
+func (g *GRPCHandler) GetEnvironment(ctx context.Context, req *GetEnvironmentRequest) (*service.DriftResult, error) {
+	if req.RepoName == "" || req.Environment == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing repoName or environment")
+	}
+
+	result, err := g.environments.driftService.GetEnvironmentState(ctx, req.RepoName, req.Environment)
+	if err != nil {
+		if errors.Is(err, service.ErrEnvironmentNotFound) {
+			return nil, status.Error(codes.NotFound, "Environment not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return result, nil
+}
+
+// ListEnvironments returns the drift state of every environment recorded for an environment group
+func (g *GRPCHandler) ListEnvironments(ctx context.Context, req *ListEnvironmentsRequest) (*ListEnvironmentsResponse, error) {
+	if req.Group == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing group")
+	}
+
+	group, err := g.environments.driftService.GetGroupDrift(ctx, req.Group, "")
+	if err != nil {
+		if errors.Is(err, service.ErrGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "Group not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &ListEnvironmentsResponse{Environments: group.Members}, nil
+}
+
+// grpcError converts a failure described by an HTTP status into the matching gRPC status
+func grpcError(httpStatus int, err error) error {
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	}
+	return status.Error(code, err.Error())
+}
+
+// unaryMethod describes a unary gRPC method of driftGuardianServer, decoding its request and
+// running it through the server's interceptors
+func unaryMethod[Req, Resp any](name string, call func(driftGuardianServer, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(driftGuardianServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + GRPCServiceName + "/" + name}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(driftGuardianServer), ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+// driftGuardianServiceDesc describes the drift gRPC service
+var driftGuardianServiceDesc = grpc.ServiceDesc{
+	ServiceName: GRPCServiceName,
+	HandlerType: (*driftGuardianServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("ReportDrift", driftGuardianServer.ReportDrift),
+		unaryMethod("GetEnvironment", driftGuardianServer.GetEnvironment),
+		unaryMethod("ListEnvironments", driftGuardianServer.ListEnvironments),
+	},
+	Streams: []grpc.StreamDesc{},
+}
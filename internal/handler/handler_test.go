@@ -5,14 +5,26 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 
+	"drift-guardian/internal/repository"
 	"drift-guardian/internal/service"
 )
 
@@ -29,6 +41,43 @@ func (m *MockDriftService) ProcessDriftDetection(ctx context.Context, payload se
 	return args.Get(0).(*service.DriftResult), args.Error(1)
 }
 
+func (m *MockDriftService) GetEnvironmentState(ctx context.Context, repoName, environment string) (*service.DriftResult, error) {
+	args := m.Called(ctx, repoName, environment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.DriftResult), args.Error(1)
+}
+
+func (m *MockDriftService) AcknowledgeDrift(ctx context.Context, ack service.Acknowledgement) error {
+	args := m.Called(ctx, ack)
+	return args.Error(0)
+}
+
+func (m *MockDriftService) GetExitCodeSeries(ctx context.Context, repoName, environment string) (*service.ExitCodeSeries, error) {
+	args := m.Called(ctx, repoName, environment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ExitCodeSeries), args.Error(1)
+}
+
+func (m *MockDriftService) GetEnvironmentStats(ctx context.Context) (*service.EnvironmentStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.EnvironmentStats), args.Error(1)
+}
+
+func (m *MockDriftService) GetGroupDrift(ctx context.Context, group, aggregation string) (*service.GroupDrift, error) {
+	args := m.Called(ctx, group, aggregation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.GroupDrift), args.Error(1)
+}
+
 func (m *MockDriftService) HandleThresholdBreach(ctx context.Context, env service.EnvironmentInfo, driftCount int) error {
 	args := m.Called(ctx, env, driftCount)
 	return args.Error(0)
@@ -49,6 +98,16 @@ func (m *MockDriftService) GenerateKey(repoName, environment string) string {
 	return args.String(0)
 }
 
+// MockStorageChecker is a mock implementation of StorageChecker
+type MockStorageChecker struct {
+	mock.Mock
+}
+
+func (m *MockStorageChecker) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 // MockResponseWriter is a mock implementation of ResponseWriter
 type MockResponseWriter struct {
 	mock.Mock
@@ -59,6 +118,11 @@ func (m *MockResponseWriter) WriteSuccess(w http.ResponseWriter, payload interfa
 	return args.Error(0)
 }
 
+func (m *MockResponseWriter) WriteJSON(w http.ResponseWriter, payload interface{}, headers map[string]string) error {
+	args := m.Called(w, payload, headers)
+	return args.Error(0)
+}
+
 func (m *MockResponseWriter) WriteError(w http.ResponseWriter, message string, statusCode int) error {
 	args := m.Called(w, message, statusCode)
 	// Actually write the error for test assertions
@@ -71,7 +135,7 @@ func TestEnvironmentHandler_MethodValidation(t *testing.T) {
 	mockService := new(MockDriftService)
 	mockWriter := new(MockResponseWriter)
 
-	handler := NewEnvironmentHandler(mockService, mockWriter)
+	handler := NewEnvironmentHandler(mockService, mockWriter, 0)
 	ctx := context.Background()
 
 	methods := []string{"GET", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
@@ -103,7 +167,7 @@ func TestEnvironmentHandler_InvalidPayload(t *testing.T) {
 	mockService := new(MockDriftService)
 	mockWriter := new(MockResponseWriter)
 
-	handler := NewEnvironmentHandler(mockService, mockWriter)
+	handler := NewEnvironmentHandler(mockService, mockWriter, 0)
 	ctx := context.Background()
 
 	tests := []struct {
@@ -159,12 +223,52 @@ func TestEnvironmentHandler_InvalidPayload(t *testing.T) {
 	}
 }
 
+func TestEnvironmentHandler_PlanOutputLimit(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		planOutput string
+		oversized  bool
+	}{
+		{name: "plan output within limit", planOutput: strings.Repeat("x", 16), oversized: false},
+		{name: "plan output exceeds limit", planOutput: strings.Repeat("x", 17), oversized: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			mockWriter := new(MockResponseWriter)
+			handler := NewEnvironmentHandler(mockService, mockWriter, 16)
+
+			body := fmt.Sprintf(`{"repoName": "test", "branchName": "main", "environment": "prod", "environmentTier": "prod", "projectId": "123", "operation": "plan", "planOutput": %q}`, tt.planOutput)
+
+			if tt.oversized {
+				mockWriter.On("WriteError", mock.Anything, "planOutput exceeds maximum accepted size of 16 bytes", http.StatusBadRequest).Return(nil).Once()
+			} else {
+				mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+				mockService.On("ProcessDriftDetection", ctx, mock.AnythingOfType("service.Payload")).Return(&service.DriftResult{}, nil).Once()
+				mockWriter.On("WriteSuccess", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("map[string]string")).Return(nil).Once()
+			}
+
+			req := httptest.NewRequest("POST", "/environments", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.HandleEnvironments(rec, req, ctx)
+
+			mockService.AssertExpectations(t)
+			mockWriter.AssertExpectations(t)
+		})
+	}
+}
+
 func TestEnvironmentHandler_SuccessfulRequest(t *testing.T) {
 	// Setup mocks
 	mockService := new(MockDriftService)
 	mockWriter := new(MockResponseWriter)
 
-	handler := NewEnvironmentHandler(mockService, mockWriter)
+	handler := NewEnvironmentHandler(mockService, mockWriter, 0)
 	ctx := context.Background()
 
 	validPayload := `{
@@ -206,7 +310,7 @@ func TestEnvironmentHandler_ServiceError(t *testing.T) {
 	mockService := new(MockDriftService)
 	mockWriter := new(MockResponseWriter)
 
-	handler := NewEnvironmentHandler(mockService, mockWriter)
+	handler := NewEnvironmentHandler(mockService, mockWriter, 0)
 	ctx := context.Background()
 
 	validPayload := `{"repoName": "test", "branchName": "main", "environment": "prod", "environmentTier": "prod", "projectId": "123", "operation": "plan"}`
@@ -223,8 +327,672 @@ func TestEnvironmentHandler_ServiceError(t *testing.T) {
 	handler.HandleEnvironments(rec, req, ctx)
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "service error", rec.Header().Get("X-Last-Error"))
 
 	// Verify mocks were called
 	mockService.AssertExpectations(t)
 	mockWriter.AssertExpectations(t)
 }
+
+// TestEnvironmentHandler_ProjectMismatch tests that a report for another repository's project is a bad request
+func TestEnvironmentHandler_ProjectMismatch(t *testing.T) {
+	ctx := context.Background()
+	mockService := new(MockDriftService)
+	mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+	mockService.On("ProcessDriftDetection", ctx, mock.AnythingOfType("service.Payload")).Return(nil, fmt.Errorf("%w: project %q is apps/payments", service.ErrProjectMismatch, "456")).Once()
+
+	handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+	payload := `{"repoName": "test", "branchName": "main", "environment": "prod", "environmentTier": "prod", "projectId": "456", "operation": "plan"}`
+	req := httptest.NewRequest("POST", "/environments", bytes.NewBufferString(payload))
+	rec := httptest.NewRecorder()
+
+	handler.HandleEnvironments(rec, req, ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-Last-Error"), "A rejected report is not an environment error")
+	mockService.AssertExpectations(t)
+}
+
+func TestEnvironmentHandler_GetEnvironment(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		environment     string
+		setupMocks      func(mockService *MockDriftService)
+		expectedStatus  int
+		expectedHeaders map[string]string
+		expectedBody    string
+	}{
+		{
+			name:        "returns state with last error",
+			environment: "production",
+			setupMocks: func(mockService *MockDriftService) {
+				mockService.On("GetEnvironmentState", ctx, "test-repo", "production").Return(&service.DriftResult{
+					EnvironmentTier: "prod",
+					ProjectID:       "123",
+					DriftIncrement:  "2",
+					Log:             map[string]string{"log": ""},
+					LastError:       "failed to create drift issue: boom",
+					LastErrorAt:     "2025-01-31T10:30:00Z",
+				}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Content-Type":      "application/json",
+				"X-Drift-Increment": "2",
+				"X-Last-Error":      "failed to create drift issue: boom",
+			},
+			expectedBody: `{"environmentTier":"prod","projectID":"123","driftIncrement":"2","issueID":"","issueURL":"","log":{"log":""},"lastError":"failed to create drift issue: boom","lastErrorTimestamp":"2025-01-31T10:30:00Z"}`,
+		},
+		{
+			name:        "no last error header when healthy",
+			environment: "production",
+			setupMocks: func(mockService *MockDriftService) {
+				mockService.On("GetEnvironmentState", ctx, "test-repo", "production").Return(&service.DriftResult{
+					DriftIncrement: "0",
+					Log:            map[string]string{"log": ""},
+				}, nil).Once()
+			},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"X-Last-Error": "", "X-Drift-Resources": ""},
+		},
+		{
+			name:        "changed resources header when plan was parsed",
+			environment: "production",
+			setupMocks: func(mockService *MockDriftService) {
+				mockService.On("GetEnvironmentState", ctx, "test-repo", "production").Return(&service.DriftResult{
+					DriftIncrement:   "1",
+					Log:              map[string]string{"log": ""},
+					ChangedResources: "4",
+				}, nil).Once()
+			},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"X-Drift-Resources": "4"},
+		},
+		{
+			name:        "unknown environment",
+			environment: "missing",
+			setupMocks: func(mockService *MockDriftService) {
+				mockService.On("GetEnvironmentState", ctx, "test-repo", "missing").Return(nil, service.ErrEnvironmentNotFound).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Environment not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			tt.setupMocks(mockService)
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest("GET", "/environments/test-repo/"+tt.environment, nil)
+			req.SetPathValue("repo", "test-repo")
+			req.SetPathValue("environment", tt.environment)
+			rec := httptest.NewRecorder()
+
+			handler.HandleEnvironmentState(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			for header, value := range tt.expectedHeaders {
+				assert.Equal(t, value, rec.Header().Get(header), header)
+			}
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_EnvironmentState tests reading environment state by path without reporting
+func TestEnvironmentHandler_EnvironmentState(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		method         string
+		repo           string
+		environment    string
+		serviceErr     error
+		callsService   bool
+		expectedStatus int
+	}{
+		{name: "returns state", method: "GET", repo: "group/infra", environment: "production", callsService: true, expectedStatus: http.StatusOK},
+		{name: "unknown environment", method: "GET", repo: "group/infra", environment: "missing", callsService: true, serviceErr: service.ErrEnvironmentNotFound, expectedStatus: http.StatusNotFound},
+		{name: "storage failure", method: "GET", repo: "group/infra", environment: "production", callsService: true, serviceErr: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError},
+		{name: "reports rejected", method: "POST", repo: "group/infra", environment: "production", expectedStatus: http.StatusMethodNotAllowed},
+		{name: "missing environment", method: "GET", repo: "group/infra", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			if tt.callsService {
+				var result *service.DriftResult
+				if tt.serviceErr == nil {
+					result = &service.DriftResult{EnvironmentTier: "prod", DriftIncrement: "3", Log: map[string]string{"log": ""}}
+				}
+				mockService.On("GetEnvironmentState", ctx, tt.repo, tt.environment).Return(result, tt.serviceErr).Once()
+			}
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest(tt.method, "/environments/group%2Finfra/"+tt.environment, nil)
+			req.SetPathValue("repo", tt.repo)
+			req.SetPathValue("environment", tt.environment)
+			rec := httptest.NewRecorder()
+
+			handler.HandleEnvironmentState(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "3", rec.Header().Get("X-Drift-Increment"))
+				var result service.DriftResult
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				assert.Equal(t, "3", result.DriftIncrement)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_DriftResourcesHeader tests that a report's response carries the changed
+// resource count only when the plan was parsed
+func TestEnvironmentHandler_DriftResourcesHeader(t *testing.T) {
+	ctx := context.Background()
+	payload := `{"repoName": "test-repo", "branchName": "main", "environment": "production", "environmentTier": "prod", "projectId": "123", "operation": "plan", "exitCode": 2}`
+
+	for _, changedResources := range []string{"", "3"} {
+		t.Run("changedResources="+changedResources, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+			mockService.On("ProcessDriftDetection", ctx, mock.AnythingOfType("service.Payload")).Return(&service.DriftResult{
+				DriftIncrement:   "1",
+				Log:              map[string]string{"log": ""},
+				ChangedResources: changedResources,
+			}, nil).Once()
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+			req := httptest.NewRequest("POST", "/environments", bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.HandleEnvironments(rec, req, ctx)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, changedResources, rec.Header().Get("X-Drift-Resources"))
+			_, present := rec.Header()["X-Drift-Resources"]
+			assert.Equal(t, changedResources != "", present)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_DriftUnchangedHeader tests reporting unchanged drift in a response header
+func TestEnvironmentHandler_DriftUnchangedHeader(t *testing.T) {
+	ctx := context.Background()
+	payload := `{"repoName": "test-repo", "branchName": "main", "environment": "production", "environmentTier": "prod", "projectId": "123", "operation": "plan", "exitCode": 2}`
+
+	for _, unchanged := range []bool{false, true} {
+		t.Run(strconv.FormatBool(unchanged), func(t *testing.T) {
+			mockService := new(MockDriftService)
+			mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+			mockService.On("ProcessDriftDetection", ctx, mock.AnythingOfType("service.Payload")).Return(&service.DriftResult{
+				DriftIncrement: "2",
+				Log:            map[string]string{"log": ""},
+				DriftUnchanged: unchanged,
+			}, nil).Once()
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+			req := httptest.NewRequest("POST", "/environments", bytes.NewBufferString(payload))
+			rec := httptest.NewRecorder()
+
+			handler.HandleEnvironments(rec, req, ctx)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			_, present := rec.Header()["X-Drift-Unchanged"]
+			assert.Equal(t, unchanged, present)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_Acknowledge tests the acknowledgement endpoint's responses
+func TestEnvironmentHandler_Acknowledge(t *testing.T) {
+	ctx := context.Background()
+	body := `{"repoName":"test-repo","environment":"production","ackUntil":"2030-01-02T00:00:00Z","resolveBy":"2030-01-01T00:00:00Z"}`
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		callsService   bool
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "acknowledged", method: "POST", body: body, callsService: true, expectedStatus: http.StatusOK},
+		{name: "invalid acknowledgement", method: "POST", body: body, callsService: true, serviceErr: fmt.Errorf("%w: ackUntil must be in the future", service.ErrInvalidAcknowledgement), expectedStatus: http.StatusBadRequest},
+		{name: "unknown environment", method: "POST", body: body, callsService: true, serviceErr: service.ErrEnvironmentNotFound, expectedStatus: http.StatusNotFound},
+		{name: "storage failure", method: "POST", body: body, callsService: true, serviceErr: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError},
+		{name: "malformed JSON", method: "POST", body: `{"ackUntil": "tomorrow"}`, expectedStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: "GET", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			if tt.callsService {
+				mockService.On("AcknowledgeDrift", ctx, mock.AnythingOfType("service.Acknowledgement")).Return(tt.serviceErr).Once()
+			}
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest(tt.method, "/environments/ack", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.HandleAcknowledge(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_GroupDrift tests the environment group endpoint's responses
+func TestEnvironmentHandler_GroupDrift(t *testing.T) {
+	ctx := context.Background()
+	groupDrift := &service.GroupDrift{
+		Group:       "shop-prod",
+		Aggregation: "sum",
+		Drifted:     true,
+		DriftCount:  3,
+		Members: []service.GroupMember{
+			{RepoName: "shop", Environment: "prod-compute", DriftIncrement: 1},
+			{RepoName: "shop", Environment: "prod-network", DriftIncrement: 2},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		callsService   bool
+		result         *service.GroupDrift
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "aggregated", method: "GET", query: "?group=shop-prod&aggregation=sum", callsService: true, result: groupDrift, expectedStatus: http.StatusOK},
+		{name: "unknown group", method: "GET", query: "?group=shop-prod&aggregation=sum", callsService: true, serviceErr: service.ErrGroupNotFound, expectedStatus: http.StatusNotFound},
+		{name: "unknown aggregation", method: "GET", query: "?group=shop-prod&aggregation=sum", callsService: true, serviceErr: fmt.Errorf("%w: %q", service.ErrInvalidAggregation, "sum"), expectedStatus: http.StatusBadRequest},
+		{name: "storage failure", method: "GET", query: "?group=shop-prod&aggregation=sum", callsService: true, serviceErr: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError},
+		{name: "missing group", method: "GET", expectedStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: "POST", query: "?group=shop-prod", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			if tt.callsService {
+				if tt.serviceErr != nil {
+					mockService.On("GetGroupDrift", ctx, "shop-prod", "sum").Return(nil, tt.serviceErr).Once()
+				} else {
+					mockService.On("GetGroupDrift", ctx, "shop-prod", "sum").Return(tt.result, nil).Once()
+				}
+			}
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest(tt.method, "/groups/drift"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.HandleGroupDrift(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.result != nil {
+				var body service.GroupDrift
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, *tt.result, body)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_ExitCodes tests the exit code series endpoint's responses
+func TestEnvironmentHandler_ExitCodes(t *testing.T) {
+	ctx := context.Background()
+	series := &service.ExitCodeSeries{
+		RepoName:    "test-repo",
+		Environment: "production",
+		Points: []repository.ExitCodePoint{
+			{Timestamp: time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC), Operation: "plan", ExitCode: 2},
+			{Timestamp: time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC), Operation: "apply", ExitCode: 0},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		callsService   bool
+		result         *service.ExitCodeSeries
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "series", method: "GET", query: "?repo=test-repo&environment=production", callsService: true, result: series, expectedStatus: http.StatusOK},
+		{name: "disabled", method: "GET", query: "?repo=test-repo&environment=production", callsService: true, serviceErr: service.ErrExitCodeSeriesDisabled, expectedStatus: http.StatusNotFound},
+		{name: "unknown environment", method: "GET", query: "?repo=test-repo&environment=production", callsService: true, serviceErr: service.ErrEnvironmentNotFound, expectedStatus: http.StatusNotFound},
+		{name: "storage failure", method: "GET", query: "?repo=test-repo&environment=production", callsService: true, serviceErr: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError},
+		{name: "missing environment", method: "GET", query: "?repo=test-repo", expectedStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: "POST", query: "?repo=test-repo&environment=production", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			if tt.callsService {
+				if tt.serviceErr != nil {
+					mockService.On("GetExitCodeSeries", ctx, "test-repo", "production").Return(nil, tt.serviceErr).Once()
+				} else {
+					mockService.On("GetExitCodeSeries", ctx, "test-repo", "production").Return(tt.result, nil).Once()
+				}
+			}
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest(tt.method, "/environments/exit-codes"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.HandleExitCodes(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.result != nil {
+				var body service.ExitCodeSeries
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, *tt.result, body)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEnvironmentHandler_Stats tests listing stale environments
+func TestEnvironmentHandler_Stats(t *testing.T) {
+	ctx := context.Background()
+	stats := &service.EnvironmentStats{
+		Environments: 3,
+		StaleAfter:   "48h0m0s",
+		Stale: []service.StaleEnvironment{
+			{RepoName: "test-repo", Environment: "review-42", EnvironmentTier: "nonprod", LastSuccess: "2025-01-28T10:00:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		callsService   bool
+		result         *service.EnvironmentStats
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "stale environments", method: "GET", callsService: true, result: stats, expectedStatus: http.StatusOK},
+		{name: "disabled", method: "GET", callsService: true, serviceErr: service.ErrStalenessDisabled, expectedStatus: http.StatusNotFound},
+		{name: "storage failure", method: "GET", callsService: true, serviceErr: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError},
+		{name: "method not allowed", method: "POST", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockDriftService)
+			if tt.callsService {
+				if tt.serviceErr != nil {
+					mockService.On("GetEnvironmentStats", ctx).Return(nil, tt.serviceErr).Once()
+				} else {
+					mockService.On("GetEnvironmentStats", ctx).Return(tt.result, nil).Once()
+				}
+			}
+
+			handler := NewEnvironmentHandler(mockService, NewResponseWriter(), 0)
+
+			req := httptest.NewRequest(tt.method, "/stats", nil)
+			rec := httptest.NewRecorder()
+
+			handler.HandleStats(rec, req, ctx)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.result != nil {
+				var body service.EnvironmentStats
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, *tt.result, body)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// dialGRPC serves the gRPC handler over an in-memory listener and returns a connected client
+func dialGRPC(t *testing.T, mockService *MockDriftService, maxPlanOutput int) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(NewEnvironmentHandler(mockService, NewResponseWriter(), maxPlanOutput))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(GRPCCodecName)),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// TestGRPCHandler_ReportDrift tests that gRPC drift reports share the HTTP endpoint's validation and processing
+func TestGRPCHandler_ReportDrift(t *testing.T) {
+	payload := service.Payload{RepoName: "test-repo", Branch: "main", Environment: "prod", EnvironmentTier: "prod", ProjectID: "123", Operation: "plan", ExitCode: 2}
+
+	tests := []struct {
+		name         string
+		payload      service.Payload
+		setupMock    func(*MockDriftService)
+		expectedCode codes.Code
+	}{
+		{
+			name:    "processed report",
+			payload: payload,
+			setupMock: func(mockService *MockDriftService) {
+				mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+				mockService.On("ProcessDriftDetection", mock.Anything, payload).Return(&service.DriftResult{DriftIncrement: "1", ProjectID: "123"}, nil).Once()
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:    "invalid payload",
+			payload: service.Payload{RepoName: "test-repo"},
+			setupMock: func(mockService *MockDriftService) {
+				mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(errors.New("Missing branchName in payload")).Once()
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "plan output over limit",
+			payload:      service.Payload{RepoName: "test-repo", PlanOutput: strings.Repeat("x", 11)},
+			setupMock:    func(mockService *MockDriftService) {},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:    "project mismatch",
+			payload: payload,
+			setupMock: func(mockService *MockDriftService) {
+				mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+				mockService.On("ProcessDriftDetection", mock.Anything, payload).Return(nil, fmt.Errorf("%w: project 123", service.ErrProjectMismatch)).Once()
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:    "service error",
+			payload: payload,
+			setupMock: func(mockService *MockDriftService) {
+				mockService.On("ValidatePayload", mock.AnythingOfType("*service.Payload")).Return(nil).Once()
+				mockService.On("ProcessDriftDetection", mock.Anything, payload).Return(nil, errors.New("storage unavailable")).Once()
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDriftService{}
+			tt.setupMock(mockService)
+			conn := dialGRPC(t, mockService, 10)
+
+			var result service.DriftResult
+			err := conn.Invoke(context.Background(), "/"+GRPCServiceName+"/ReportDrift", &tt.payload, &result)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, "1", result.DriftIncrement)
+				assert.Equal(t, "123", result.ProjectID)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestGRPCHandler_GetEnvironment tests looking up an environment's stored state over gRPC
+func TestGRPCHandler_GetEnvironment(t *testing.T) {
+	mockService := &MockDriftService{}
+	mockService.On("GetEnvironmentState", mock.Anything, "test-repo", "prod").Return(&service.DriftResult{DriftIncrement: "3", IssueID: "42"}, nil).Once()
+	mockService.On("GetEnvironmentState", mock.Anything, "test-repo", "staging").Return(nil, service.ErrEnvironmentNotFound).Once()
+	conn := dialGRPC(t, mockService, 0)
+	method := "/" + GRPCServiceName + "/GetEnvironment"
+
+	var result service.DriftResult
+	assert.NoError(t, conn.Invoke(context.Background(), method, &GetEnvironmentRequest{RepoName: "test-repo", Environment: "prod"}, &result))
+	assert.Equal(t, "3", result.DriftIncrement)
+	assert.Equal(t, "42", result.IssueID)
+
+	err := conn.Invoke(context.Background(), method, &GetEnvironmentRequest{RepoName: "test-repo", Environment: "staging"}, &result)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = conn.Invoke(context.Background(), method, &GetEnvironmentRequest{RepoName: "test-repo"}, &result)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockService.AssertExpectations(t)
+}
+
+// TestGRPCHandler_ListEnvironments tests listing the environments of an environment group over gRPC
+func TestGRPCHandler_ListEnvironments(t *testing.T) {
+	members := []service.GroupMember{
+		{RepoName: "shop-eu", Environment: "prod", DriftIncrement: 2},
+		{RepoName: "shop-us", Environment: "prod"},
+	}
+	mockService := &MockDriftService{}
+	mockService.On("GetGroupDrift", mock.Anything, "shop-prod", "").Return(&service.GroupDrift{Group: "shop-prod", Members: members}, nil).Once()
+	mockService.On("GetGroupDrift", mock.Anything, "unknown", "").Return(nil, service.ErrGroupNotFound).Once()
+	conn := dialGRPC(t, mockService, 0)
+	method := "/" + GRPCServiceName + "/ListEnvironments"
+
+	var response ListEnvironmentsResponse
+	assert.NoError(t, conn.Invoke(context.Background(), method, &ListEnvironmentsRequest{Group: "shop-prod"}, &response))
+	assert.Equal(t, members, response.Environments)
+
+	err := conn.Invoke(context.Background(), method, &ListEnvironmentsRequest{Group: "unknown"}, &response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = conn.Invoke(context.Background(), method, &ListEnvironmentsRequest{}, &response)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockService.AssertExpectations(t)
+}
+
+// TestHealthHandler_Ready tests that readiness reflects storage health
+func TestHealthHandler_Ready(t *testing.T) {
+	tests := []struct {
+		name           string
+		backend        string
+		pingErr        error
+		expectedStatus int
+		expectedState  string
+	}{
+		{name: "redis reachable", backend: "redis", expectedStatus: http.StatusOK, expectedState: "ready"},
+		{name: "postgres unreachable", backend: "postgres", pingErr: errors.New("connection refused"), expectedStatus: http.StatusServiceUnavailable, expectedState: "not ready"},
+		{name: "memory directory missing", backend: "memory", pingErr: errors.New("no such file or directory"), expectedStatus: http.StatusServiceUnavailable, expectedState: "not ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(MockStorageChecker)
+			storage.On("Ping", mock.Anything).Return(tt.pingErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+			w := httptest.NewRecorder()
+
+			NewHealthHandler().HandleReady(w, req, storage, tt.backend, context.Background())
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response ReadinessResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, tt.expectedState, response.Status)
+			assert.Contains(t, response.Dependencies, tt.backend)
+			storage.AssertExpectations(t)
+		})
+	}
+}
+
+// TestHealthHandler_Methods tests that probes answer GET and HEAD and reject other methods with JSON
+func TestHealthHandler_Methods(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		expectedStatus int
+		expectBody     bool
+	}{
+		{name: "GET returns body", method: http.MethodGet, expectedStatus: http.StatusOK, expectBody: true},
+		{name: "HEAD returns headers only", method: http.MethodHead, expectedStatus: http.StatusOK, expectBody: false},
+		{name: "POST is rejected", method: http.MethodPost, expectedStatus: http.StatusMethodNotAllowed, expectBody: true},
+	}
+
+	probes := map[string]func(w http.ResponseWriter, r *http.Request){
+		"/health": NewHealthHandler().HandleHealth,
+		"/ready": func(w http.ResponseWriter, r *http.Request) {
+			storage := new(MockStorageChecker)
+			storage.On("Ping", mock.Anything).Return(nil)
+			NewHealthHandler().HandleReady(w, r, storage, "redis", context.Background())
+		},
+	}
+
+	for path, probe := range probes {
+		for _, tt := range tests {
+			t.Run(path+" "+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, path, nil)
+				w := httptest.NewRecorder()
+
+				probe(w, req)
+
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				assert.NotEmpty(t, w.Header().Get("Content-Length"))
+
+				if !tt.expectBody {
+					assert.Empty(t, w.Body.String())
+					return
+				}
+
+				if tt.expectedStatus == http.StatusMethodNotAllowed {
+					assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+					var response ProbeErrorResponse
+					assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+					assert.Equal(t, "Method not allowed", response.Error)
+					return
+				}
+
+				assert.Equal(t, w.Header().Get("Content-Length"), strconv.Itoa(w.Body.Len()))
+			})
+		}
+	}
+}
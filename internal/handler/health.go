@@ -4,9 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
-
-	"github.com/redis/go-redis/v9"
 )
 
 // HealthResponse represents the JSON response for health endpoints
@@ -25,6 +24,16 @@ type ReadinessResponse struct {
 	Dependencies map[string]interface{} `json:"dependencies"`
 }
 
+// ProbeErrorResponse represents the JSON error body returned by health endpoints
+type ProbeErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// StorageChecker reports whether the storage backend is reachable
+type StorageChecker interface {
+	Ping(ctx context.Context) error
+}
+
 // HealthHandler handles health check endpoints
 type HealthHandler struct{}
 
@@ -35,10 +44,8 @@ func NewHealthHandler() *HealthHandler {
 
 // HandleHealth handles the /health endpoint for Kubernetes liveness probes
 func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
-	// Only allow GET requests
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		_, _ = w.Write([]byte("Method not allowed\n"))
+	// Only allow GET requests, plus HEAD for probes that only read the status
+	if !probeMethodAllowed(w, r) {
 		return
 	}
 
@@ -50,70 +57,80 @@ func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 		Version:   "0.1.2",
 	}
 
-	// Set response headers
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	// Encode and send response
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte("Internal server error\n"))
-		return
-	}
+	writeProbeResponse(w, r, http.StatusOK, response)
 }
 
 // HandleReady handles the /ready endpoint for Kubernetes readiness probes
-func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request, rdb *redis.Client, ctx context.Context) {
-	// Only allow GET requests
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		_, _ = w.Write([]byte("Method not allowed\n"))
+func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request, storage StorageChecker, backend string, ctx context.Context) {
+	// Only allow GET requests, plus HEAD for probes that only read the status
+	if !probeMethodAllowed(w, r) {
 		return
 	}
 
-	// Check Redis connectivity with timeout
-	redisStatus := h.checkRedisConnectivity(rdb, ctx)
-
 	// Determine overall readiness status
 	overallStatus := "ready"
 	statusCode := http.StatusOK
+	dependencies := map[string]interface{}{}
 
-	if !redisStatus["healthy"].(bool) {
+	// Check storage connectivity with timeout
+	storageStatus := h.checkStorageConnectivity(storage, ctx)
+	if !storageStatus["healthy"].(bool) {
 		overallStatus = "not ready"
 		statusCode = http.StatusServiceUnavailable
 	}
+	dependencies[backend] = storageStatus
 
 	// Create readiness response
 	response := ReadinessResponse{
-		Status:    overallStatus,
-		Timestamp: time.Now(),
-		Service:   "drift-guardian",
-		Dependencies: map[string]interface{}{
-			"redis": redisStatus,
-		},
+		Status:       overallStatus,
+		Timestamp:    time.Now(),
+		Service:      "drift-guardian",
+		Dependencies: dependencies,
+	}
+
+	writeProbeResponse(w, r, statusCode, response)
+}
+
+// probeMethodAllowed accepts GET and HEAD, answering any other method with a JSON 405
+func probeMethodAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+
+	w.Header().Set("Allow", "GET, HEAD")
+	writeProbeResponse(w, r, http.StatusMethodNotAllowed, ProbeErrorResponse{Error: "Method not allowed"})
+	return false
+}
+
+// writeProbeResponse writes a JSON probe response; HEAD requests get the headers without a body
+func writeProbeResponse(w http.ResponseWriter, r *http.Request, statusCode int, response interface{}) {
+	// Encode before writing headers so an encoding failure can still change the status
+	body, err := json.Marshal(response)
+	if err != nil {
+		body, _ = json.Marshal(ProbeErrorResponse{Error: "Internal server error"})
+		statusCode = http.StatusInternalServerError
 	}
+	body = append(body, '\n')
 
-	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
 	w.WriteHeader(statusCode)
 
-	// Encode and send response
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte("Internal server error\n"))
+	if r.Method == http.MethodHead {
 		return
 	}
+	_, _ = w.Write(body)
 }
 
-// checkRedisConnectivity checks Redis connectivity with 5-second timeout
-func (h *HealthHandler) checkRedisConnectivity(rdb *redis.Client, ctx context.Context) map[string]interface{} {
+// checkStorageConnectivity checks storage connectivity with 5-second timeout
+func (h *HealthHandler) checkStorageConnectivity(storage StorageChecker, ctx context.Context) map[string]interface{} {
 	// Create context with 5-second timeout
 	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// Attempt Redis PING
+	// Attempt storage ping
 	start := time.Now()
-	err := rdb.Ping(timeoutCtx).Err()
+	err := storage.Ping(timeoutCtx)
 	duration := time.Since(start)
 
 	if err != nil {
@@ -9,6 +9,21 @@ import (
 type EnvironmentHandler interface {
 	// HandleEnvironments processes HTTP requests to the /environments endpoint
 	HandleEnvironments(w http.ResponseWriter, r *http.Request, ctx context.Context)
+
+	// HandleEnvironmentState processes HTTP requests to the /environments/{repo}/{environment} endpoint
+	HandleEnvironmentState(w http.ResponseWriter, r *http.Request, ctx context.Context)
+
+	// HandleAcknowledge processes HTTP requests to the /environments/ack endpoint
+	HandleAcknowledge(w http.ResponseWriter, r *http.Request, ctx context.Context)
+
+	// HandleGroupDrift processes HTTP requests to the /groups/drift endpoint
+	HandleGroupDrift(w http.ResponseWriter, r *http.Request, ctx context.Context)
+
+	// HandleExitCodes processes HTTP requests to the /environments/exit-codes endpoint
+	HandleExitCodes(w http.ResponseWriter, r *http.Request, ctx context.Context)
+
+	// HandleStats processes HTTP requests to the /stats endpoint
+	HandleStats(w http.ResponseWriter, r *http.Request, ctx context.Context)
 }
 
 // ResponseWriter wraps HTTP response writing functionality
@@ -16,6 +31,9 @@ type ResponseWriter interface {
 	// WriteSuccess writes a successful response with headers and body
 	WriteSuccess(w http.ResponseWriter, payload interface{}, headers map[string]string) error
 
+	// WriteJSON writes a successful JSON response with headers
+	WriteJSON(w http.ResponseWriter, payload interface{}, headers map[string]string) error
+
 	// WriteError writes an error response with appropriate status code
 	WriteError(w http.ResponseWriter, message string, statusCode int) error
 }
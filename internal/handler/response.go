@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -38,6 +39,25 @@ func (r *ResponseWriterImpl) WriteSuccess(w http.ResponseWriter, payload interfa
 	}
 }
 
+// WriteJSON writes a successful JSON response with headers
+func (r *ResponseWriterImpl) WriteJSON(w http.ResponseWriter, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding JSON response: %w", err)
+	}
+
+	// Set custom headers
+	for key, value := range headers {
+		w.Header().Set(key, value)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	return err
+}
+
 // WriteError writes an error response with appropriate status code
 func (r *ResponseWriterImpl) WriteError(w http.ResponseWriter, message string, statusCode int) error {
 	http.Error(w, message, statusCode)
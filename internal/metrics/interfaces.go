@@ -0,0 +1,13 @@
+package metrics
+
+// Recorder defines the interface for emitting drift metrics
+type Recorder interface {
+	// Count adds value to a counter metric
+	Count(name string, value int64, tags []string)
+
+	// Gauge records the current value of a gauge metric
+	Gauge(name string, value float64, tags []string)
+
+	// Histogram records a sample for a histogram metric
+	Histogram(name string, value float64, tags []string)
+}
@@ -0,0 +1,106 @@
+//go:build unit
+
+package metrics
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsdClient_Emit tests that metrics reach a statsd listener in DogStatsD format
+func TestStatsdClient_Emit(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	client, err := NewStatsdClient(listener.LocalAddr().String(), "drift_guardian.")
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	tests := []struct {
+		name     string
+		emit     func()
+		expected string
+	}{
+		{
+			name: "counter with tags",
+			emit: func() {
+				client.Count("drift.increment", 1, []string{"repo:test-repo", "environment:production", "tier:prod"})
+			},
+			expected: "drift_guardian.drift.increment:1|c|#repo:test-repo,environment:production,tier:prod",
+		},
+		{
+			name: "gauge without tags",
+			emit: func() {
+				client.Gauge("drift.current", 3, nil)
+			},
+			expected: "drift_guardian.drift.current:3|g",
+		},
+		{
+			name: "histogram with tags",
+			emit: func() {
+				client.Histogram("drift.resolution_seconds", 5400, []string{"repo:test-repo"})
+			},
+			expected: "drift_guardian.drift.resolution_seconds:5400|h|#repo:test-repo",
+		},
+	}
+
+	buffer := make([]byte, 1024)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.emit()
+
+			require.NoError(t, listener.SetReadDeadline(time.Now().Add(2*time.Second)))
+			n, _, err := listener.ReadFrom(buffer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(buffer[:n]))
+		})
+	}
+}
+
+// TestStatsdClient_NoOp tests that an unconfigured client silently discards metrics
+func TestStatsdClient_NoOp(t *testing.T) {
+	client, err := NewStatsdClient("", "drift_guardian.")
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		client.Count("drift.increment", 1, nil)
+		client.Gauge("drift.current", 1, nil)
+		client.Histogram("drift.resolution_seconds", 1, nil)
+	})
+	assert.NoError(t, client.Close())
+}
+
+// TestStatsdClient_CloseWhileEmitting tests that closing the client never panics concurrent emitters
+func TestStatsdClient_CloseWhileEmitting(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	client, err := NewStatsdClient(listener.LocalAddr().String(), "drift_guardian.")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				client.Count("drift.increment", 1, nil)
+			}
+		}()
+	}
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close(), "Closing twice should be safe")
+	wg.Wait()
+
+	assert.NotPanics(t, func() {
+		client.Gauge("drift.current", 1, nil)
+	}, "Emitting after close should be a no-op")
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// statsdQueueSize bounds the number of metrics waiting to be sent before new ones are dropped
+const statsdQueueSize = 1000
+
+// StatsdClient implements Recorder by pushing DogStatsD packets over UDP
+type StatsdClient struct {
+	conn      net.Conn
+	prefix    string
+	queue     chan string
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStatsdClient creates a new statsd client; an empty address yields a no-op client
+func NewStatsdClient(addr, prefix string) (*StatsdClient, error) {
+	if addr == "" {
+		slog.Debug("Statsd address not configured, metrics disabled")
+		return &StatsdClient{}, nil
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to statsd at %s: %w", addr, err)
+	}
+
+	client := &StatsdClient{
+		conn:    conn,
+		prefix:  prefix,
+		queue:   make(chan string, statsdQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go client.send()
+
+	slog.Info("Statsd client initialized successfully", "address", addr, "prefix", prefix)
+	return client, nil
+}
+
+// Count adds value to a counter metric
+func (s *StatsdClient) Count(name string, value int64, tags []string) {
+	s.enqueue(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Gauge records the current value of a gauge metric
+func (s *StatsdClient) Gauge(name string, value float64, tags []string) {
+	s.enqueue(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Histogram records a sample for a histogram metric
+func (s *StatsdClient) Histogram(name string, value float64, tags []string) {
+	s.enqueue(name, strconv.FormatFloat(value, 'f', -1, 64), "h", tags)
+}
+
+// Close stops sending metrics and releases the connection; metrics emitted afterwards are dropped
+func (s *StatsdClient) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	var err error
+	s.closeOnce.Do(func() {
+		// The queue is never closed, so concurrent emit calls cannot panic
+		close(s.done)
+		<-s.stopped
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// enqueue formats a DogStatsD packet and queues it without blocking the caller
+func (s *StatsdClient) enqueue(name, value, metricType string, tags []string) {
+	if s.conn == nil {
+		return
+	}
+
+	packet := s.prefix + name + ":" + value + "|" + metricType
+	if len(tags) > 0 {
+		packet += "|#" + strings.Join(tags, ",")
+	}
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	select {
+	case s.queue <- packet:
+	default:
+		slog.Debug("Statsd queue full, dropping metric", "metric", name)
+	}
+}
+
+// send writes queued packets to the statsd server until the client is closed
+func (s *StatsdClient) send() {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case packet := <-s.queue:
+			if _, err := s.conn.Write([]byte(packet)); err != nil {
+				slog.Debug("Failed to send statsd metric", "error", err)
+			}
+		}
+	}
+}
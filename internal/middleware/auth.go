@@ -1,13 +1,23 @@
 package middleware
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"path"
 	"strings"
 
+	"drift-guardian/internal/audit"
 	"drift-guardian/internal/config"
 )
 
+// maxScopedBodyBytes limits how much of a request body is buffered to check a scoped token's repo
+const maxScopedBodyBytes = 10 << 20
+
 // AuthenticationMiddleware creates middleware for bearer token authentication
 func AuthenticationMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -15,7 +25,7 @@ func AuthenticationMiddleware(cfg *config.Config) func(http.Handler) http.Handle
 			// Check if authentication is enabled
 			if !cfg.EnableAuthentication {
 				slog.Debug("Authentication disabled, allowing request")
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, r.WithContext(audit.WithPrincipal(r.Context(), audit.AnonymousPrincipal)))
 				return
 			}
 
@@ -31,8 +41,29 @@ func AuthenticationMiddleware(cfg *config.Config) func(http.Handler) http.Handle
 				return
 			}
 
+			// Scoped tokens may only report for repositories matching their patterns
+			if patterns, scoped := tokenScope(token, cfg.TokenScopes); scoped {
+				repoName, err := requestRepoName(r)
+				if err != nil || !repoInScope(repoName, patterns) {
+					slog.Warn("Scoped token used outside its repository scope",
+						"method", r.Method,
+						"path", r.URL.Path,
+						"remote_addr", r.RemoteAddr,
+						"repo", repoName,
+						"identity", tokenIdentity(token, "scoped", cfg),
+					)
+					http.Error(w, "Forbidden: token is not allowed to report for this repository", http.StatusForbidden)
+					return
+				}
+
+				identity := tokenIdentity(token, "scoped", cfg)
+				slog.Debug("Scoped authentication successful", "repo", repoName, "path", r.URL.Path, "identity", identity)
+				next.ServeHTTP(w, r.WithContext(audit.WithPrincipal(r.Context(), identity)))
+				return
+			}
+
 			// Validate token
-			if !validateToken(token, cfg.BearerToken) {
+			if !validateToken(token, cfg.BearerTokens) {
 				slog.Warn("Invalid bearer token provided",
 					"method", r.Method,
 					"path", r.URL.Path,
@@ -43,14 +74,16 @@ func AuthenticationMiddleware(cfg *config.Config) func(http.Handler) http.Handle
 				return
 			}
 
+			identity := tokenIdentity(token, "token", cfg)
 			slog.Debug("Authentication successful",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
+				"identity", identity,
 			)
 
-			// Authentication successful, proceed to next handler
-			next.ServeHTTP(w, r)
+			// Authentication successful, proceed to next handler with the token's identity for logs and the audit log
+			next.ServeHTTP(w, r.WithContext(audit.WithPrincipal(r.Context(), identity)))
 		})
 	}
 }
@@ -73,13 +106,124 @@ func extractBearerToken(r *http.Request) string {
 	return token
 }
 
-// validateToken validates the bearer token against the configured token
-func validateToken(token, expectedToken string) bool {
-	if expectedToken == "" {
-		// If no token is configured, reject all authentication attempts
-		return false
+// validateToken validates the bearer token against the configured tokens in constant time
+func validateToken(token string, validTokens []string) bool {
+	// Compare against every token so timing does not reveal which one matched
+	matched := 0
+	for _, expectedToken := range validTokens {
+		if expectedToken == "" {
+			continue
+		}
+		matched |= subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken))
+	}
+
+	// If no token is configured, reject all authentication attempts
+	return matched == 1
+}
+
+// tokenScope returns the repo patterns of a scoped token, comparing every configured token in constant time
+func tokenScope(token string, scopes map[string][]string) ([]string, bool) {
+	var matchedPatterns []string
+	matched := 0
+	for scopedToken, patterns := range scopes {
+		if scopedToken == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(scopedToken)) == 1 {
+			matchedPatterns = patterns
+			matched = 1
+		}
 	}
+	return matchedPatterns, matched == 1
+}
+
+// tokenIdentity returns the identity an authenticated token acts as: its TOKEN_IDENTITIES name, the
+// default identity for the deprecated BEARER_TOKEN, or otherwise a fingerprint of the token; kind
+// distinguishes unnamed bearer and scoped tokens. Every named token is compared in constant time.
+func tokenIdentity(token, kind string, cfg *config.Config) string {
+	identity := ""
+	for namedToken, name := range cfg.TokenIdentities {
+		if namedToken == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(namedToken)) == 1 {
+			identity = name
+		}
+	}
+	if identity != "" {
+		return identity
+	}
+
+	if cfg.BearerToken != "" && cfg.DefaultTokenIdentity != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.BearerToken)) == 1 {
+		return cfg.DefaultTokenIdentity
+	}
+	return audit.TokenPrincipal(kind, token)
+}
+
+// errRepoMismatch is returned when a report's URL names a different repository than its body
+var errRepoMismatch = errors.New("repo in the URL does not match the body's repoName")
 
-	// Simple string comparison for bearer token validation
-	return token == expectedToken
+// requestRepoName reads the repository a request acts on, restoring the body for the next handler.
+// Reports are processed for the JSON body's repoName, so POST requests are checked against the body
+// and rejected if the repo path segment or query parameter names another repository. Other requests
+// are checked against the path segment, the query parameter, or the body, in that order.
+func requestRepoName(r *http.Request) (string, error) {
+	urlRepoNames := []string{r.PathValue("repo"), r.URL.Query().Get("repo")}
+
+	if r.Method != http.MethodPost {
+		for _, repoName := range urlRepoNames {
+			if repoName != "" {
+				return repoName, nil
+			}
+		}
+	}
+
+	repoName, err := bodyRepoName(r)
+	if err != nil {
+		return "", err
+	}
+
+	if r.Method == http.MethodPost {
+		for _, urlRepoName := range urlRepoNames {
+			if urlRepoName != "" && urlRepoName != repoName {
+				return repoName, errRepoMismatch
+			}
+		}
+	}
+	return repoName, nil
+}
+
+// bodyRepoName reads the JSON body's repoName, restoring the body for the next handler
+func bodyRepoName(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxScopedBodyBytes))
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var payload struct {
+		RepoName string `json:"repoName"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", err
+	}
+	return payload.RepoName, nil
+}
+
+// repoInScope reports whether repoName matches any of the scoped token's patterns
+func repoInScope(repoName string, patterns []string) bool {
+	if repoName == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, repoName); matched {
+			return true
+		}
+	}
+	return false
 }
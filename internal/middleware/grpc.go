@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/config"
+)
+
+// GRPCAuthenticationInterceptor applies AuthenticationMiddleware's bearer token checks to gRPC
+// calls, reading the token from the authorization metadata
+func GRPCAuthenticationInterceptor(cfg *config.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !cfg.EnableAuthentication {
+			return handler(audit.WithPrincipal(ctx, audit.AnonymousPrincipal), req)
+		}
+
+		token := grpcBearerToken(ctx)
+		if token == "" {
+			slog.Warn("gRPC call missing bearer token", "method", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "Bearer token required")
+		}
+
+		// Scoped tokens may only report for repositories matching their patterns
+		if patterns, scoped := tokenScope(token, cfg.TokenScopes); scoped {
+			repoName := grpcRepoName(req)
+			if !repoInScope(repoName, patterns) {
+				slog.Warn("Scoped token used outside its repository scope", "method", info.FullMethod, "repo", repoName)
+				return nil, status.Error(codes.PermissionDenied, "Token is not allowed to report for this repository")
+			}
+			return handler(audit.WithPrincipal(ctx, tokenIdentity(token, "scoped", cfg)), req)
+		}
+
+		if !validateToken(token, cfg.BearerTokens) {
+			slog.Warn("Invalid bearer token provided", "method", info.FullMethod, "token_prefix", "***")
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+
+		return handler(audit.WithPrincipal(ctx, tokenIdentity(token, "token", cfg)), req)
+	}
+}
+
+// GRPCMaintenanceInterceptor rejects gRPC calls as unavailable when cfg.MaintenanceMode is set,
+// sending the retry delay in the retry-after header like MaintenanceMiddleware
+func GRPCMaintenanceInterceptor(cfg *config.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !cfg.MaintenanceMode {
+			return handler(ctx, req)
+		}
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(cfg.MaintenanceRetryAfter.Seconds()))))
+		return nil, status.Error(codes.Unavailable, "Drift tracking is paused for maintenance")
+	}
+}
+
+// grpcBearerToken extracts the bearer token from the authorization metadata
+func grpcBearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	const bearerPrefix = "Bearer "
+	if !strings.HasPrefix(values[0], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(values[0][len(bearerPrefix):])
+}
+
+// grpcRepoName reads the repository a gRPC request reports for from its repoName field
+func grpcRepoName(req any) string {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		RepoName string `json:"repoName"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.RepoName
+}
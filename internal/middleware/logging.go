@@ -5,8 +5,14 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/config"
 )
 
+// maxLoggedBodyBytes limits how much of an error response body is logged
+const maxLoggedBodyBytes = 1024
+
 // ResponseWriter wraps http.ResponseWriter to capture response data
 type ResponseWriter struct {
 	http.ResponseWriter
@@ -35,8 +41,9 @@ func (rw *ResponseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
-// LoggingMiddleware creates middleware for request/response logging
-func LoggingMiddleware() func(http.Handler) http.Handler {
+// LoggingMiddleware creates middleware for request/response logging; error response bodies are
+// logged when cfg.LogErrorResponseBody is set
+func LoggingMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -56,7 +63,22 @@ func LoggingMiddleware() func(http.Handler) http.Handler {
 				"path", r.URL.Path,
 				"status", rw.statusCode,
 				"duration_ms", duration.Milliseconds(),
+				"identity", audit.PrincipalFrom(r.Context()),
 			)
+
+			// Log the body of error responses for debugging; success bodies may hold sensitive data
+			if cfg.LogErrorResponseBody && (rw.statusCode < 200 || rw.statusCode >= 300) {
+				body := rw.body.String()
+				if len(body) > maxLoggedBodyBytes {
+					body = body[:maxLoggedBodyBytes] + "... [truncated]"
+				}
+				slog.Warn("HTTP error response",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", rw.statusCode,
+					"body", body,
+				)
+			}
 		})
 	}
 }
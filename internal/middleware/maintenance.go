@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"drift-guardian/internal/config"
+)
+
+// MaintenanceMiddleware rejects requests with 503 and a Retry-After header when cfg.MaintenanceMode
+// is set, so CI retries later instead of the report being counted against unavailable storage
+func MaintenanceMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.MaintenanceMode {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(cfg.MaintenanceRetryAfter.Seconds())))
+			http.Error(w, "Service Unavailable: drift tracking is paused for maintenance", http.StatusServiceUnavailable)
+		})
+	}
+}
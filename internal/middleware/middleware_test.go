@@ -0,0 +1,443 @@
+//go:build unit
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/config"
+)
+
+// TestAuthenticationMiddleware_MultipleTokens tests token rotation with several valid tokens
+func TestAuthenticationMiddleware_MultipleTokens(t *testing.T) {
+	cfg := &config.Config{
+		EnableAuthentication: true,
+		BearerTokens:         []string{"old-token", "new-token"},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := AuthenticationMiddleware(cfg)(next)
+
+	tests := []struct {
+		name           string
+		authHeader     string
+		expectedStatus int
+	}{
+		{
+			name:           "old token accepted",
+			authHeader:     "Bearer old-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "new token accepted",
+			authHeader:     "Bearer new-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid token rejected",
+			authHeader:     "Bearer other-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token prefix rejected",
+			authHeader:     "Bearer new",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing token rejected",
+			authHeader:     "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/environments", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// TestAuthenticationMiddleware_ScopedTokens tests that scoped tokens only report for their repositories
+func TestAuthenticationMiddleware_ScopedTokens(t *testing.T) {
+	cfg := &config.Config{
+		EnableAuthentication: true,
+		BearerTokens:         []string{"admin-token"},
+		TokenScopes:          map[string][]string{"team-a-token": {"team-a-*", "shared-infra"}},
+	}
+
+	var receivedBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+	// Route like main so path values are set before the middleware runs
+	handler := http.NewServeMux()
+	handler.Handle("/environments", AuthenticationMiddleware(cfg)(next))
+	handler.Handle("/environments/{repo}/{environment}", AuthenticationMiddleware(cfg)(next))
+	handler.Handle("/environments/exit-codes", AuthenticationMiddleware(cfg)(next))
+
+	tests := []struct {
+		name           string
+		token          string
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "in-scope report accepted", token: "team-a-token", method: http.MethodPost, target: "/environments", body: `{"repoName":"team-a-network"}`, expectedStatus: http.StatusOK},
+		{name: "exact pattern accepted", token: "team-a-token", method: http.MethodPost, target: "/environments", body: `{"repoName":"shared-infra"}`, expectedStatus: http.StatusOK},
+		{name: "out-of-scope report rejected", token: "team-a-token", method: http.MethodPost, target: "/environments", body: `{"repoName":"team-b-network"}`, expectedStatus: http.StatusForbidden},
+		{name: "in-scope query with out-of-scope body rejected", token: "team-a-token", method: http.MethodPost, target: "/environments?repo=team-a-network", body: `{"repoName":"team-b-network"}`, expectedStatus: http.StatusForbidden},
+		{name: "query disagreeing with the body rejected", token: "team-a-token", method: http.MethodPost, target: "/environments?repo=team-a-network", body: `{"repoName":"team-a-storage"}`, expectedStatus: http.StatusForbidden},
+		{name: "query matching the body accepted", token: "team-a-token", method: http.MethodPost, target: "/environments?repo=team-a-network", body: `{"repoName":"team-a-network"}`, expectedStatus: http.StatusOK},
+		{name: "missing repoName rejected", token: "team-a-token", method: http.MethodPost, target: "/environments", body: `{}`, expectedStatus: http.StatusForbidden},
+		{name: "malformed body rejected", token: "team-a-token", method: http.MethodPost, target: "/environments", body: `not json`, expectedStatus: http.StatusForbidden},
+		{name: "in-scope read accepted", token: "team-a-token", method: http.MethodGet, target: "/environments/exit-codes?repo=team-a-network&environment=prod", expectedStatus: http.StatusOK},
+		{name: "out-of-scope read rejected", token: "team-a-token", method: http.MethodGet, target: "/environments/exit-codes?repo=team-b-network&environment=prod", expectedStatus: http.StatusForbidden},
+		{name: "in-scope path read accepted", token: "team-a-token", method: http.MethodGet, target: "/environments/team-a-network/prod", expectedStatus: http.StatusOK},
+		{name: "out-of-scope path read rejected", token: "team-a-token", method: http.MethodGet, target: "/environments/team-b-network/prod", expectedStatus: http.StatusForbidden},
+		{name: "unscoped token reports for any repo", token: "admin-token", method: http.MethodPost, target: "/environments", body: `{"repoName":"team-b-network"}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receivedBody = ""
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.body, receivedBody, "Handler should receive the full request body")
+			}
+		})
+	}
+}
+
+// TestAuthenticationMiddleware_AuditPrincipal tests that authenticated requests carry their principal for the audit log
+func TestAuthenticationMiddleware_AuditPrincipal(t *testing.T) {
+	cfg := &config.Config{
+		EnableAuthentication: true,
+		BearerTokens:         []string{"admin-token"},
+		TokenScopes:          map[string][]string{"team-a-token": {"team-a-*"}},
+	}
+
+	var principal string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = audit.PrincipalFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := AuthenticationMiddleware(cfg)(next)
+
+	tests := []struct {
+		name      string
+		token     string
+		principal string
+	}{
+		{name: "bearer token", token: "admin-token", principal: audit.TokenPrincipal("token", "admin-token")},
+		{name: "scoped token", token: "team-a-token", principal: audit.TokenPrincipal("scoped", "team-a-token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/environments", strings.NewReader(`{"repoName":"team-a-network"}`))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.principal, principal)
+		})
+	}
+
+	cfg.EnableAuthentication = false
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/environments", nil))
+	assert.Equal(t, audit.AnonymousPrincipal, principal, "Requests accepted without authentication are anonymous")
+}
+
+// TestAuthenticationMiddleware_TokenIdentities tests that tokens authenticate as their configured identity
+// and that the identity is logged with the request
+func TestAuthenticationMiddleware_TokenIdentities(t *testing.T) {
+	cfg := &config.Config{
+		EnableAuthentication: true,
+		BearerToken:          "legacy-token",
+		BearerTokens:         []string{"platform-token", "unnamed-token", "legacy-token"},
+		TokenScopes:          map[string][]string{"team-a-token": {"team-a-*"}},
+		TokenIdentities:      map[string]string{"platform-token": "platform-team", "team-a-token": "team-a-ci"},
+		DefaultTokenIdentity: "default",
+	}
+
+	var identity string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = audit.PrincipalFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := AuthenticationMiddleware(cfg)(LoggingMiddleware(cfg)(next))
+
+	tests := []struct {
+		name     string
+		token    string
+		identity string
+	}{
+		{name: "named token", token: "platform-token", identity: "platform-team"},
+		{name: "named scoped token", token: "team-a-token", identity: "team-a-ci"},
+		{name: "deprecated single token", token: "legacy-token", identity: "default"},
+		{name: "unnamed token", token: "unnamed-token", identity: audit.TokenPrincipal("token", "unnamed-token")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+			defer slog.SetDefault(previous)
+
+			req := httptest.NewRequest(http.MethodPost, "/environments", strings.NewReader(`{"repoName":"team-a-network"}`))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.identity, identity, "The identity is attached to the request context")
+			assert.Contains(t, logs.String(), "identity="+tt.identity, "The identity is logged with the request")
+			assert.NotContains(t, logs.String(), tt.token, "Tokens are never logged")
+		})
+	}
+}
+
+// TestValidateToken tests constant-time token validation
+func TestValidateToken(t *testing.T) {
+	assert.True(t, validateToken("b", []string{"a", "b"}))
+	assert.False(t, validateToken("c", []string{"a", "b"}))
+	assert.False(t, validateToken("", []string{""}), "Empty configured tokens must never match")
+	assert.False(t, validateToken("a", nil), "No configured tokens rejects all requests")
+}
+
+// TestLoggingMiddleware_ErrorResponseBody tests that error response bodies are logged only when enabled
+func TestLoggingMiddleware_ErrorResponseBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		status    int
+		expectLog bool
+	}{
+		{name: "error body logged when enabled", enabled: true, status: http.StatusBadRequest, expectLog: true},
+		{name: "success body never logged", enabled: true, status: http.StatusOK, expectLog: false},
+		{name: "error body not logged when disabled", enabled: false, status: http.StatusBadRequest, expectLog: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+			defer slog.SetDefault(previous)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("missing repoName in payload"))
+			})
+			handler := LoggingMiddleware(&config.Config{LogErrorResponseBody: tt.enabled})(next)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/environments", nil))
+
+			if tt.expectLog {
+				assert.Contains(t, logs.String(), "HTTP error response")
+				assert.Contains(t, logs.String(), "missing repoName in payload")
+			} else {
+				assert.NotContains(t, logs.String(), "missing repoName in payload")
+			}
+		})
+	}
+}
+
+// TestLoggingMiddleware_TruncatesBody tests that long error bodies are truncated
+func TestLoggingMiddleware_TruncatesBody(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 2*maxLoggedBodyBytes))
+	})
+	LoggingMiddleware(&config.Config{LogErrorResponseBody: true})(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/environments", nil))
+
+	assert.Contains(t, logs.String(), "[truncated]")
+	assert.NotContains(t, logs.String(), string(bytes.Repeat([]byte("x"), maxLoggedBodyBytes+1)))
+}
+
+// TestMaintenanceMiddleware tests that requests are rejected with Retry-After during maintenance
+func TestMaintenanceMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		expectedStatus int
+		retryAfter     string
+	}{
+		{
+			name:           "Maintenance disabled",
+			cfg:            &config.Config{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Maintenance enabled",
+			cfg:            &config.Config{MaintenanceMode: true, MaintenanceRetryAfter: 2 * time.Minute},
+			expectedStatus: http.StatusServiceUnavailable,
+			retryAfter:     "120",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			MaintenanceMiddleware(tt.cfg)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/environments", strings.NewReader(`{}`)))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+			assert.Equal(t, tt.retryAfter == "", called, "Handler should only run outside maintenance")
+		})
+	}
+}
+
+// TestCLIVersionMiddleware tests that outdated CLIs are flagged, and rejected only when enforced
+func TestCLIVersionMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		method         string
+		version        string
+		expectedStatus int
+		expectedMin    string
+	}{
+		{name: "no minimum", cfg: &config.Config{}, method: http.MethodPost, expectedStatus: http.StatusOK},
+		{name: "current CLI", cfg: &config.Config{MinCLIVersion: "1.4.0", EnforceMinCLIVersion: true}, method: http.MethodPost, version: "v1.4.2", expectedStatus: http.StatusOK},
+		{name: "dev build", cfg: &config.Config{MinCLIVersion: "1.4.0", EnforceMinCLIVersion: true}, method: http.MethodPost, version: "dev", expectedStatus: http.StatusOK},
+		{name: "outdated CLI is flagged", cfg: &config.Config{MinCLIVersion: "1.4.0"}, method: http.MethodPost, version: "1.3.9", expectedStatus: http.StatusOK, expectedMin: "1.4.0"},
+		{name: "outdated CLI is rejected", cfg: &config.Config{MinCLIVersion: "1.4.0", EnforceMinCLIVersion: true}, method: http.MethodPost, version: "1.3.9", expectedStatus: http.StatusUpgradeRequired, expectedMin: "1.4.0"},
+		{name: "CLI without version is rejected", cfg: &config.Config{MinCLIVersion: "1.4", EnforceMinCLIVersion: true}, method: http.MethodPost, expectedStatus: http.StatusUpgradeRequired, expectedMin: "1.4"},
+		{name: "state queries are not checked", cfg: &config.Config{MinCLIVersion: "1.4.0", EnforceMinCLIVersion: true}, method: http.MethodGet, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/environments", strings.NewReader(`{}`))
+			if tt.version != "" {
+				req.Header.Set(CLIVersionHeader, tt.version)
+			}
+			rr := httptest.NewRecorder()
+			CLIVersionMiddleware(tt.cfg)(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedMin, rr.Header().Get(MinCLIVersionHeader))
+		})
+	}
+}
+
+// TestGRPCAuthenticationInterceptor tests bearer and scoped token checks on gRPC calls
+func TestGRPCAuthenticationInterceptor(t *testing.T) {
+	cfg := &config.Config{
+		EnableAuthentication: true,
+		BearerTokens:         []string{"valid-token"},
+		TokenScopes:          map[string][]string{"team-a-token": {"team-a-*"}},
+	}
+	interceptor := GRPCAuthenticationInterceptor(cfg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/driftguardian.DriftGuardian/ReportDrift"}
+	var principal string
+	next := func(ctx context.Context, req any) (any, error) {
+		principal = audit.PrincipalFrom(ctx)
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name         string
+		authHeader   string
+		req          any
+		expectedCode codes.Code
+	}{
+		{name: "valid token", authHeader: "Bearer valid-token", req: map[string]string{"repoName": "any-repo"}, expectedCode: codes.OK},
+		{name: "invalid token", authHeader: "Bearer other-token", req: map[string]string{"repoName": "any-repo"}, expectedCode: codes.Unauthenticated},
+		{name: "missing token", authHeader: "", req: map[string]string{"repoName": "any-repo"}, expectedCode: codes.Unauthenticated},
+		{name: "scoped token in scope", authHeader: "Bearer team-a-token", req: map[string]string{"repoName": "team-a-network"}, expectedCode: codes.OK},
+		{name: "scoped token out of scope", authHeader: "Bearer team-a-token", req: map[string]string{"repoName": "team-b-network"}, expectedCode: codes.PermissionDenied},
+		{name: "scoped token without repo", authHeader: "Bearer team-a-token", req: map[string]string{"group": "shop-prod"}, expectedCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authHeader != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authHeader))
+			}
+
+			_, err := interceptor(ctx, tt.req, info, next)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+
+	_, err := interceptor(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer valid-token")), nil, info, next)
+	assert.NoError(t, err)
+	assert.Equal(t, audit.TokenPrincipal("token", "valid-token"), principal, "Calls carry their principal for the audit log")
+
+	cfg.EnableAuthentication = false
+	_, err = interceptor(context.Background(), nil, info, next)
+	assert.NoError(t, err)
+	assert.Equal(t, audit.AnonymousPrincipal, principal)
+}
+
+// TestGRPCMaintenanceInterceptor tests that gRPC calls are rejected as unavailable during maintenance
+func TestGRPCMaintenanceInterceptor(t *testing.T) {
+	cfg := &config.Config{MaintenanceRetryAfter: time.Minute}
+	interceptor := GRPCMaintenanceInterceptor(cfg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/driftguardian.DriftGuardian/ReportDrift"}
+	next := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	response, err := interceptor(context.Background(), nil, info, next)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", response)
+
+	cfg.MaintenanceMode = true
+	_, err = interceptor(context.Background(), nil, info, next)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
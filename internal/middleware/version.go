@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"drift-guardian/internal/config"
+)
+
+// CLIVersionHeader carries the version of the drift-guardian CLI that sent a report
+const CLIVersionHeader = "X-Drift-Guardian-CLI-Version"
+
+// MinCLIVersionHeader tells an outdated CLI the oldest version the server accepts without a warning
+const MinCLIVersionHeader = "X-Drift-Guardian-Min-CLI-Version"
+
+// CLIVersionMiddleware checks the CLI version of drift reports against cfg.MinCLIVersion. Reports
+// from outdated CLIs are logged and answered with the minimum in MinCLIVersionHeader, or rejected
+// with 426 Upgrade Required when cfg.EnforceMinCLIVersion is set. Only POST requests are checked,
+// since state queries do not come from the CLI.
+func CLIVersionMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := r.Header.Get(CLIVersionHeader)
+			if r.Method != http.MethodPost || !cfg.CLIVersionOutdated(version) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			slog.Warn("Drift report from outdated CLI",
+				"cli_version", version,
+				"min_cli_version", cfg.MinCLIVersion,
+				"enforced", cfg.EnforceMinCLIVersion,
+				"remote_addr", r.RemoteAddr,
+			)
+			w.Header().Set(MinCLIVersionHeader, cfg.MinCLIVersion)
+
+			if cfg.EnforceMinCLIVersion {
+				http.Error(w, "Upgrade Required: drift-guardian CLI "+cfg.MinCLIVersion+" or newer is required", http.StatusUpgradeRequired)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compressedPlanPrefix marks a gzip-compressed plan output. Plan output is text, which never starts
+// with a NUL byte, so values stored before compression was enabled are read back unchanged.
+const compressedPlanPrefix = "\x00gzip\x00"
+
+// compressPlanOutput gzips plan output behind compressedPlanPrefix
+func compressPlanOutput(planOutput string) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString(compressedPlanPrefix)
+
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(planOutput)); err != nil {
+		return "", fmt.Errorf("error compressing plan output: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("error compressing plan output: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// decompressPlanOutput returns stored plan output as text, decompressing it when it carries
+// compressedPlanPrefix and returning it unchanged otherwise
+func decompressPlanOutput(stored string) (string, error) {
+	compressed, ok := strings.CutPrefix(stored, compressedPlanPrefix)
+	if !ok {
+		return stored, nil
+	}
+
+	reader, err := gzip.NewReader(strings.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("error decompressing plan output: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	planOutput, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("error decompressing plan output: %w", err)
+	}
+	return string(planOutput), nil
+}
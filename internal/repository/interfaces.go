@@ -1,17 +1,58 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEnvironmentNotFound is returned when no data is stored for an environment key
+var ErrEnvironmentNotFound = errors.New("no data found for key")
+
+// replicaReadsKey marks a context whose reads may be served by a read replica
+type replicaReadsKey struct{}
+
+// WithReplicaReads returns a context whose reads may be served by a read replica, for read-only
+// queries that tolerate replication lag. Backends without a replica read from the primary.
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// replicaReads reports whether reads made with ctx may be served by a read replica
+func replicaReads(ctx context.Context) bool {
+	allowed, _ := ctx.Value(replicaReadsKey{}).(bool)
+	return allowed
+}
+
+// OperationLogEntry describes the most recent operation recorded for an environment
+type OperationLogEntry struct {
+	Timestamp      string `json:"timestamp"`
+	Operation      string `json:"operation"`
+	ExitCode       int    `json:"exitCode"`
+	Branch         string `json:"branch,omitempty"`
+	PipelineSource string `json:"pipelineSource,omitempty"` // What triggered the CI run, e.g. schedule, push or web
+}
+
+// ExitCodePoint is one operation's exit code in an environment's exit code series
+type ExitCodePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Operation string    `json:"operation"`
+	ExitCode  int       `json:"exitCode"`
+}
 
 // StorageRepository defines the interface for environment data persistence
 type StorageRepository interface {
-	// InitializeEnvironment creates a new environment hash with default values
-	InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold string) (bool, error)
+	// InitializeEnvironment creates a new environment hash with default values and the branch drift is compared against
+	InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error)
+
+	// UpdateOperationLog records the operation timestamp, type, exit code, branch and pipeline source
+	UpdateOperationLog(ctx context.Context, key string, entry OperationLogEntry) error
 
-	// UpdateOperationLog records operation timestamp and type
-	UpdateOperationLog(ctx context.Context, key, timestamp, operation string) error
+	// IncrementDrift increases drift counter by amount and returns new value
+	IncrementDrift(ctx context.Context, key string, amount int) (int, error)
 
-	// IncrementDrift increases drift counter and returns new value
-	IncrementDrift(ctx context.Context, key string) (int, error)
+	// IncrementAndCheck atomically increases drift counter by amount and reports whether the threshold is reached
+	IncrementAndCheck(ctx context.Context, key string, amount int) (int, bool, error)
 
 	// ResetDrift sets drift counter to zero
 	ResetDrift(ctx context.Context, key string) error
@@ -22,9 +63,46 @@ type StorageRepository interface {
 	// SetField updates a specific field in the environment hash
 	SetField(ctx context.Context, key, field, value string) error
 
+	// SetFieldIfEmpty sets a field only when it is missing or empty and reports whether it did
+	SetFieldIfEmpty(ctx context.Context, key, field, value string) (bool, error)
+
 	// GetField retrieves a specific field from the environment hash
 	GetField(ctx context.Context, key, field string) (string, error)
 
+	// Expire removes the key and its data once ttl has elapsed; a non-positive ttl removes it immediately
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	// AddOpenIssue records an environment key in the open-issue index
+	AddOpenIssue(ctx context.Context, key string) error
+
+	// RemoveOpenIssue removes an environment key from the open-issue index
+	RemoveOpenIssue(ctx context.Context, key string) error
+
+	// ListOpenIssues returns all environment keys in the open-issue index
+	ListOpenIssues(ctx context.Context) ([]string, error)
+
+	// ListEnvironments scans storage for the keys of all initialized environments, sorted
+	ListEnvironments(ctx context.Context) ([]string, error)
+
+	// AcquireLock claims the named lock for ttl and reports whether it was free; the lock is never
+	// released early, so it also limits how often the guarded work runs
+	AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+
+	// AddGroupMember records an environment key as a member of an environment group
+	AddGroupMember(ctx context.Context, group, key string) error
+
+	// ListGroupMembers returns all environment keys recorded for an environment group
+	ListGroupMembers(ctx context.Context, group string) ([]string, error)
+
+	// RecordExitCode appends an operation's exit code to the environment's series, keeping the newest limit points
+	RecordExitCode(ctx context.Context, key string, point ExitCodePoint, limit int) error
+
+	// ListExitCodes returns the environment's exit code series, oldest first
+	ListExitCodes(ctx context.Context, key string) ([]ExitCodePoint, error)
+
 	// StorePlanOutput saves Terraform plan output for the environment
 	StorePlanOutput(ctx context.Context, key, planOutput string) error
+
+	// Ping checks that the storage backend is reachable
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,520 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySnapshot is the on-disk representation of the in-memory store
+type memorySnapshot struct {
+	Environments map[string]map[string]string `json:"environments"`
+	OpenIssues   []string                     `json:"openIssues"`
+	Groups       map[string][]string          `json:"groups,omitempty"`
+	ExitCodes    map[string][]ExitCodePoint   `json:"exitCodes,omitempty"`
+	Expiry       map[string]time.Time         `json:"expiry,omitempty"`
+}
+
+// MemoryRepository implements StorageRepository interface with in-process maps
+type MemoryRepository struct {
+	mu           sync.Mutex
+	environments map[string]map[string]string
+	openIssues   map[string]struct{}
+	groups       map[string]map[string]struct{}
+	exitCodes    map[string][]ExitCodePoint
+	locks        map[string]time.Time // Lock name -> expiry; held only in process memory
+	expiry       map[string]time.Time
+	filePath     string
+	threshold    int
+}
+
+// NewMemoryRepository creates a new in-memory repository, loading and persisting to filePath when set;
+// defaultThreshold applies when none is stored
+func NewMemoryRepository(filePath string, defaultThreshold int) (*MemoryRepository, error) {
+	repo := &MemoryRepository{
+		environments: make(map[string]map[string]string),
+		openIssues:   make(map[string]struct{}),
+		groups:       make(map[string]map[string]struct{}),
+		exitCodes:    make(map[string][]ExitCodePoint),
+		locks:        make(map[string]time.Time),
+		expiry:       make(map[string]time.Time),
+		filePath:     filePath,
+		threshold:    defaultThreshold,
+	}
+
+	if filePath == "" {
+		return repo, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		slog.Info("Memory storage file not found, starting empty", "file", filePath)
+		return repo, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading memory storage file: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error parsing memory storage file: %w", err)
+	}
+
+	for key, fields := range snapshot.Environments {
+		repo.environments[key] = fields
+	}
+	for _, key := range snapshot.OpenIssues {
+		repo.openIssues[key] = struct{}{}
+	}
+	for group, keys := range snapshot.Groups {
+		members := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			members[key] = struct{}{}
+		}
+		repo.groups[group] = members
+	}
+	for key, points := range snapshot.ExitCodes {
+		repo.exitCodes[key] = points
+	}
+	for key, deadline := range snapshot.Expiry {
+		repo.expiry[key] = deadline
+	}
+
+	slog.Info("Memory storage loaded from file", "file", filePath, "environments", len(repo.environments))
+	return repo, nil
+}
+
+// InitializeEnvironment creates a new environment hash with default values and the branch drift is compared against
+func (m *MemoryRepository) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	if _, exists := m.environments[key]; exists {
+		slog.Debug("Environment already exists, skipping initialization", "key", key)
+		return false, nil
+	}
+
+	if threshold == "" {
+		threshold = strconv.Itoa(m.threshold)
+	}
+
+	m.environments[key] = map[string]string{
+		"driftThreshold":   threshold,
+		"environmentTier":  tier,
+		"projectID":        projectID,
+		"driftIncrement":   "0",
+		"comparisonBranch": comparisonBranch,
+	}
+
+	if err := m.persist(); err != nil {
+		return false, fmt.Errorf("error initializing environment hash: %w", err)
+	}
+
+	slog.Info("Environment initialized successfully",
+		"key", key,
+		"tier", tier,
+		"project_id", projectID,
+		"threshold", threshold,
+		"comparison_branch", comparisonBranch,
+	)
+
+	return true, nil
+}
+
+// UpdateOperationLog records the operation timestamp, type, exit code, branch and pipeline source
+func (m *MemoryRepository) UpdateOperationLog(ctx context.Context, key string, entry OperationLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fields(key)["log"] = formatLogEntry(entry)
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error updating operation log: %w", err)
+	}
+	return nil
+}
+
+// IncrementDrift increases drift counter by amount and returns new value
+func (m *MemoryRepository) IncrementDrift(ctx context.Context, key string, amount int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, err := m.increment(key, amount)
+	if err != nil {
+		return 0, fmt.Errorf("error incrementing drift: %w", err)
+	}
+	return value, nil
+}
+
+// IncrementAndCheck atomically increases drift counter by amount and reports whether the threshold is reached
+func (m *MemoryRepository) IncrementAndCheck(ctx context.Context, key string, amount int) (int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, err := m.increment(key, amount)
+	if err != nil {
+		return 0, false, fmt.Errorf("error incrementing drift and checking threshold: %w", err)
+	}
+
+	threshold, err := strconv.Atoi(m.environments[key]["driftThreshold"])
+	if err != nil || threshold < 1 {
+		threshold = m.threshold
+	}
+
+	return value, value >= threshold, nil
+}
+
+// ResetDrift sets drift counter to zero
+func (m *MemoryRepository) ResetDrift(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fields(key)["driftIncrement"] = "0"
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error resetting drift: %w", err)
+	}
+	return nil
+}
+
+// GetEnvironmentData retrieves all environment data as map
+func (m *MemoryRepository) GetEnvironmentData(ctx context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	fields, exists := m.environments[key]
+	if !exists || len(fields) == 0 {
+		slog.Warn("No environment data found", "key", key)
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, key)
+	}
+
+	data := make(map[string]string, len(fields))
+	for field, value := range fields {
+		data[field] = value
+	}
+	return data, nil
+}
+
+// SetField updates a specific field in the environment hash
+func (m *MemoryRepository) SetField(ctx context.Context, key, field, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fields(key)[field] = value
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error setting field %s: %w", field, err)
+	}
+	return nil
+}
+
+// SetFieldIfEmpty sets a field only when it is missing or empty and reports whether it did
+func (m *MemoryRepository) SetFieldIfEmpty(ctx context.Context, key, field, value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fields := m.fields(key)
+	if fields[field] != "" {
+		return false, nil
+	}
+	fields[field] = value
+
+	if err := m.persist(); err != nil {
+		return false, fmt.Errorf("error setting field %s: %w", field, err)
+	}
+	return true, nil
+}
+
+// GetField retrieves a specific field from the environment hash
+func (m *MemoryRepository) GetField(ctx context.Context, key, field string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	return m.environments[key][field], nil // Missing fields return empty string
+}
+
+// Expire removes the key and its data once ttl has elapsed
+func (m *MemoryRepository) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Sweep keys that expired without being accessed again
+	for expiredKey := range m.expiry {
+		m.evict(expiredKey)
+	}
+
+	if _, exists := m.environments[key]; !exists {
+		return nil // Like Redis, expiring a missing key is a no-op
+	}
+	m.expiry[key] = time.Now().Add(ttl)
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+	return nil
+}
+
+// AddOpenIssue records an environment key in the open-issue index
+func (m *MemoryRepository) AddOpenIssue(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.openIssues[key] = struct{}{}
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error adding to open issue index: %w", err)
+	}
+	return nil
+}
+
+// RemoveOpenIssue removes an environment key from the open-issue index
+func (m *MemoryRepository) RemoveOpenIssue(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.openIssues, key)
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error removing from open issue index: %w", err)
+	}
+	return nil
+}
+
+// ListOpenIssues returns all environment keys in the open-issue index
+func (m *MemoryRepository) ListOpenIssues(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.openIssueKeys(), nil
+}
+
+// ListEnvironments returns the keys of stored environment hashes, recognized by their tier
+func (m *MemoryRepository) ListEnvironments(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.environments))
+	for key := range m.environments {
+		m.evict(key)
+		if _, ok := m.environments[key]["environmentTier"]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// AcquireLock claims the named lock for ttl and reports whether it was free
+func (m *MemoryRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if expiry, held := m.locks[name]; held && now.Before(expiry) {
+		return false, nil
+	}
+	m.locks[name] = now.Add(ttl)
+	return true, nil
+}
+
+// AddGroupMember records an environment key as a member of an environment group
+func (m *MemoryRepository) AddGroupMember(ctx context.Context, group, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, exists := m.groups[group]
+	if !exists {
+		members = make(map[string]struct{})
+		m.groups[group] = members
+	}
+	members[key] = struct{}{}
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error adding to group index: %w", err)
+	}
+	return nil
+}
+
+// ListGroupMembers returns all environment keys recorded for an environment group
+func (m *MemoryRepository) ListGroupMembers(ctx context.Context, group string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return sortedKeys(m.groups[group]), nil
+}
+
+// RecordExitCode appends an operation's exit code to the environment's series, keeping the newest limit points
+func (m *MemoryRepository) RecordExitCode(ctx context.Context, key string, point ExitCodePoint, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	points := append(m.exitCodes[key], point)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	if len(points) > limit {
+		points = slices.Clone(points[len(points)-limit:])
+	}
+	m.exitCodes[key] = points
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+	return nil
+}
+
+// ListExitCodes returns the environment's exit code series, oldest first
+func (m *MemoryRepository) ListExitCodes(ctx context.Context, key string) ([]ExitCodePoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(key)
+	return slices.Clone(m.exitCodes[key]), nil
+}
+
+// StorePlanOutput saves Terraform plan output for the environment
+func (m *MemoryRepository) StorePlanOutput(ctx context.Context, key, planOutput string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fields(key)["planOutput"] = planOutput
+
+	if err := m.persist(); err != nil {
+		return fmt.Errorf("error storing plan output: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the persistence directory is still usable when file persistence is enabled
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	if m.filePath == "" {
+		return nil
+	}
+
+	info, err := os.Stat(filepath.Dir(m.filePath))
+	if err != nil {
+		return fmt.Errorf("error checking memory storage directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("memory storage path %s is not a directory", filepath.Dir(m.filePath))
+	}
+	return nil
+}
+
+// evict deletes the key if its expiry has passed; callers must hold the lock
+func (m *MemoryRepository) evict(key string) {
+	deadline, ok := m.expiry[key]
+	if !ok || time.Now().Before(deadline) {
+		return
+	}
+	delete(m.environments, key)
+	delete(m.exitCodes, key)
+	delete(m.expiry, key)
+}
+
+// fields returns the environment hash, creating it like Redis does on first write
+func (m *MemoryRepository) fields(key string) map[string]string {
+	m.evict(key)
+	fields, exists := m.environments[key]
+	if !exists {
+		fields = make(map[string]string)
+		m.environments[key] = fields
+	}
+	return fields
+}
+
+// increment adds one to the drift counter; callers must hold the lock
+func (m *MemoryRepository) increment(key string, amount int) (int, error) {
+	fields := m.fields(key)
+
+	current := 0
+	if value := fields["driftIncrement"]; value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("hash value is not an integer")
+		}
+		current = parsed
+	}
+
+	current += amount
+	fields["driftIncrement"] = strconv.Itoa(current)
+
+	if err := m.persist(); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+// openIssueKeys returns the indexed keys in a stable order; callers must hold the lock
+func (m *MemoryRepository) openIssueKeys() []string {
+	return sortedKeys(m.openIssues)
+}
+
+// groupKeys returns each group's member keys in a stable order; callers must hold the lock
+func (m *MemoryRepository) groupKeys() map[string][]string {
+	if len(m.groups) == 0 {
+		return nil
+	}
+	groups := make(map[string][]string, len(m.groups))
+	for group, members := range m.groups {
+		groups[group] = sortedKeys(members)
+	}
+	return groups
+}
+
+// sortedKeys returns the members of a key set in a stable order
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// persist writes the store to disk when file persistence is enabled; callers must hold the lock
+func (m *MemoryRepository) persist() error {
+	if m.filePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(memorySnapshot{
+		Environments: m.environments,
+		OpenIssues:   m.openIssueKeys(),
+		Groups:       m.groupKeys(),
+		ExitCodes:    m.exitCodes,
+		Expiry:       m.expiry,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding memory storage: %w", err)
+	}
+
+	// Write to a temporary file and rename so a crash never leaves a partial file
+	tmpFile, err := os.CreateTemp(filepath.Dir(m.filePath), filepath.Base(m.filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating memory storage file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("error writing memory storage file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error writing memory storage file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), m.filePath); err != nil {
+		return fmt.Errorf("error saving memory storage file: %w", err)
+	}
+	return nil
+}
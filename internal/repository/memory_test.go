@@ -0,0 +1,456 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMemoryRepository returns an in-memory repository without file persistence
+func newTestMemoryRepository(t *testing.T) *MemoryRepository {
+	repo, err := NewMemoryRepository("", 1)
+	require.NoError(t, err)
+	return repo
+}
+
+// TestMemoryRepository_InitializeEnvironment tests environment initialization
+func TestMemoryRepository_InitializeEnvironment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new environment initialization", func(t *testing.T) {
+		repo := newTestMemoryRepository(t)
+
+		isNew, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+
+		data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"driftThreshold":   "3",
+			"environmentTier":  "prod",
+			"projectID":        "12345",
+			"driftIncrement":   "0",
+			"comparisonBranch": "main",
+		}, data)
+	})
+
+	t.Run("existing environment", func(t *testing.T) {
+		repo := newTestMemoryRepository(t)
+
+		_, err := repo.InitializeEnvironment(ctx, "test-repo:staging", "nonprod", "67890", "5", "main")
+		require.NoError(t, err)
+
+		isNew, err := repo.InitializeEnvironment(ctx, "test-repo:staging", "prod", "11111", "1", "release")
+		assert.NoError(t, err)
+		assert.False(t, isNew)
+
+		tier, _ := repo.GetField(ctx, "test-repo:staging", "environmentTier")
+		assert.Equal(t, "nonprod", tier, "Existing environment should not be overwritten")
+
+		branch, _ := repo.GetField(ctx, "test-repo:staging", "comparisonBranch")
+		assert.Equal(t, "main", branch, "Comparison branch should be kept from initialization")
+	})
+
+	t.Run("empty threshold uses configured default", func(t *testing.T) {
+		t.Setenv("DEFAULT_DRIFT_THRESHOLD", "not-a-number")
+		repo, err := NewMemoryRepository("", 4)
+		require.NoError(t, err)
+
+		isNew, err := repo.InitializeEnvironment(ctx, "test-repo:dev", "dev", "99999", "", "main")
+		assert.NoError(t, err)
+		assert.True(t, isNew)
+
+		threshold, _ := repo.GetField(ctx, "test-repo:dev", "driftThreshold")
+		assert.Equal(t, "4", threshold, "Fallback should come from configuration, not the environment")
+	})
+}
+
+// TestMemoryRepository_IncrementDrift tests drift increment operations
+func TestMemoryRepository_IncrementDrift(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+
+	for expected := 1; expected <= 3; expected++ {
+		driftCount, err := repo.IncrementDrift(ctx, "test-repo:production", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, driftCount)
+	}
+
+	driftCount, err := repo.IncrementDrift(ctx, "test-repo:production", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, driftCount, "The counter grows by the given amount")
+
+	// Incrementing a missing key creates it, matching HINCRBY
+	driftCount, err = repo.IncrementDrift(ctx, "test-repo:missing", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, driftCount)
+}
+
+// TestMemoryRepository_IncrementAndCheck tests increment with threshold check
+func TestMemoryRepository_IncrementAndCheck(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "2", "main")
+	require.NoError(t, err)
+
+	driftCount, reached, err := repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, driftCount)
+	assert.False(t, reached)
+
+	driftCount, reached, err = repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, driftCount)
+	assert.True(t, reached)
+
+	t.Run("invalid stored threshold uses configured default", func(t *testing.T) {
+		repo, err := NewMemoryRepository("", 3)
+		require.NoError(t, err)
+		require.NoError(t, repo.SetField(ctx, "test-repo:staging", "driftThreshold", "abc"))
+
+		driftCount, reached, err := repo.IncrementAndCheck(ctx, "test-repo:staging", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, driftCount)
+		assert.False(t, reached, "A single drift should not reach the default threshold of 3")
+	})
+	t.Run("weighted increment", func(t *testing.T) {
+		repo := newTestMemoryRepository(t)
+		_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "5", "main")
+		require.NoError(t, err)
+
+		driftCount, reached, err := repo.IncrementAndCheck(ctx, "test-repo:production", 5)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, driftCount)
+		assert.True(t, reached, "A drift weighted at the threshold should reach it at once")
+	})
+}
+
+// TestMemoryRepository_IncrementAndCheck_Concurrent tests that concurrent increments are not lost
+func TestMemoryRepository_IncrementAndCheck_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "50", "main")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reachedCount := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			driftCount, reached, err := repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+			assert.NoError(t, err)
+			if reached && driftCount == 50 {
+				mu.Lock()
+				reachedCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, _ := repo.GetField(ctx, "test-repo:production", "driftIncrement")
+	assert.Equal(t, "100", value)
+	assert.Equal(t, 1, reachedCount, "Exactly one increment should land on the threshold")
+}
+
+// TestMemoryRepository_InitializeEnvironment_Concurrent tests that only one concurrent first run initializes
+func TestMemoryRepository_InitializeEnvironment_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(threshold string) {
+			defer wg.Done()
+			isNew, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", threshold, "main")
+			assert.NoError(t, err)
+			if isNew {
+				mu.Lock()
+				winners = append(winners, threshold)
+				mu.Unlock()
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	require.Len(t, winners, 1, "Exactly one initialization should win")
+	value, _ := repo.GetField(ctx, "test-repo:production", "driftThreshold")
+	assert.Equal(t, winners[0], value, "Stored threshold should come from the winning initialization")
+}
+
+// TestMemoryRepository_ResetDrift tests drift reset operations
+func TestMemoryRepository_ResetDrift(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+	_, err = repo.IncrementDrift(ctx, "test-repo:production", 1)
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.ResetDrift(ctx, "test-repo:production"))
+
+	value, _ := repo.GetField(ctx, "test-repo:production", "driftIncrement")
+	assert.Equal(t, "0", value)
+}
+
+// TestMemoryRepository_GetEnvironmentData tests environment data retrieval
+func TestMemoryRepository_GetEnvironmentData(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	t.Run("nonexistent environment", func(t *testing.T) {
+		_, err := repo.GetEnvironmentData(ctx, "nonexistent-key")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "no data found for key")
+		assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+	})
+
+	t.Run("returned data is a copy", func(t *testing.T) {
+		_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+		require.NoError(t, err)
+
+		data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+		require.NoError(t, err)
+		data["driftIncrement"] = "99"
+
+		value, _ := repo.GetField(ctx, "test-repo:production", "driftIncrement")
+		assert.Equal(t, "0", value)
+	})
+}
+
+// TestMemoryRepository_Fields tests field, log, and plan output operations
+func TestMemoryRepository_Fields(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	assert.NoError(t, repo.SetField(ctx, "test-repo:production", "issueID", "456"))
+	value, err := repo.GetField(ctx, "test-repo:production", "issueID")
+	assert.NoError(t, err)
+	assert.Equal(t, "456", value)
+
+	value, err = repo.GetField(ctx, "test-repo:production", "nonexistent")
+	assert.NoError(t, err)
+	assert.Equal(t, "", value, "Missing field should return empty string")
+
+	assert.NoError(t, repo.UpdateOperationLog(ctx, "test-repo:production", OperationLogEntry{Timestamp: "2025-01-31T10:30:00Z", Operation: "plan", ExitCode: 2, Branch: "main"}))
+	value, _ = repo.GetField(ctx, "test-repo:production", "log")
+	assert.Equal(t, `{"timestamp":"2025-01-31T10:30:00Z","operation":"plan","exitCode":2,"branch":"main"}`, value)
+
+	assert.NoError(t, repo.StorePlanOutput(ctx, "test-repo:production", "Plan: 1 to add"))
+	value, _ = repo.GetField(ctx, "test-repo:production", "planOutput")
+	assert.Equal(t, "Plan: 1 to add", value)
+}
+
+// TestMemoryRepository_OpenIssues tests the open-issue index
+func TestMemoryRepository_OpenIssues(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	assert.NoError(t, repo.AddOpenIssue(ctx, "b:prod"))
+	assert.NoError(t, repo.AddOpenIssue(ctx, "a:prod"))
+	assert.NoError(t, repo.AddOpenIssue(ctx, "a:prod"))
+
+	keys, err := repo.ListOpenIssues(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:prod", "b:prod"}, keys)
+
+	assert.NoError(t, repo.RemoveOpenIssue(ctx, "a:prod"))
+	keys, _ = repo.ListOpenIssues(ctx)
+	assert.Equal(t, []string{"b:prod"}, keys)
+}
+
+// TestMemoryRepository_ListEnvironments tests that only initialized environments are listed
+func TestMemoryRepository_ListEnvironments(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "b:prod", "prod", "123", "1", "main")
+	assert.NoError(t, err)
+	_, err = repo.InitializeEnvironment(ctx, "a:prod", "prod", "123", "1", "main")
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SetField(ctx, "index:names", "a:prod", "sha256:abc"))
+
+	keys, err := repo.ListEnvironments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:prod", "b:prod"}, keys)
+
+	assert.NoError(t, repo.Expire(ctx, "a:prod", 0))
+	keys, _ = repo.ListEnvironments(ctx)
+	assert.Equal(t, []string{"b:prod"}, keys)
+}
+
+// TestMemoryRepository_AcquireLock tests that a lock is held until its ttl passes
+func TestMemoryRepository_AcquireLock(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	acquired, err := repo.AcquireLock(ctx, "issue-update:a:prod", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = repo.AcquireLock(ctx, "issue-update:a:prod", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "A held lock cannot be acquired again")
+
+	acquired, _ = repo.AcquireLock(ctx, "issue-update:b:prod", time.Hour)
+	assert.True(t, acquired, "Locks are independent by name")
+
+	acquired, _ = repo.AcquireLock(ctx, "issue-update:c:prod", time.Millisecond)
+	assert.True(t, acquired)
+	time.Sleep(5 * time.Millisecond)
+	acquired, _ = repo.AcquireLock(ctx, "issue-update:c:prod", time.Hour)
+	assert.True(t, acquired, "An expired lock can be acquired")
+}
+
+// TestMemoryRepository_GroupMembers tests the environment group index
+func TestMemoryRepository_GroupMembers(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	assert.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-network"))
+	assert.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-compute"))
+	assert.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-compute"))
+	assert.NoError(t, repo.AddGroupMember(ctx, "shop-staging", "shop:staging-network"))
+
+	keys, err := repo.ListGroupMembers(ctx, "shop-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shop:prod-compute", "shop:prod-network"}, keys)
+
+	keys, err = repo.ListGroupMembers(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// TestMemoryRepository_ExitCodes tests that the exit code series is ordered, capped and expired with its environment
+func TestMemoryRepository_ExitCodes(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	start := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC)
+	for i, exitCode := range []int{0, 2, 2, 1} {
+		point := ExitCodePoint{Timestamp: start.Add(time.Duration(i) * time.Hour), Operation: "plan", ExitCode: exitCode}
+		require.NoError(t, repo.RecordExitCode(ctx, "test-repo:production", point, 3))
+	}
+	// A late report lands in time order
+	require.NoError(t, repo.RecordExitCode(ctx, "test-repo:production", ExitCodePoint{Timestamp: start.Add(90 * time.Minute), Operation: "apply"}, 3))
+
+	points, err := repo.ListExitCodes(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, []ExitCodePoint{
+		{Timestamp: start.Add(90 * time.Minute), Operation: "apply", ExitCode: 0},
+		{Timestamp: start.Add(2 * time.Hour), Operation: "plan", ExitCode: 2},
+		{Timestamp: start.Add(3 * time.Hour), Operation: "plan", ExitCode: 1},
+	}, points, "Only the newest points are kept")
+
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "driftIncrement", "0"))
+	require.NoError(t, repo.Expire(ctx, "test-repo:production", -time.Second))
+
+	points, err = repo.ListExitCodes(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Empty(t, points, "The series expires with its environment")
+}
+
+// TestMemoryRepository_Persistence tests that data survives a reload from file
+func TestMemoryRepository_Persistence(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "drift-guardian.json")
+
+	repo, err := NewMemoryRepository(filePath, 1)
+	require.NoError(t, err)
+
+	_, err = repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+	_, err = repo.IncrementDrift(ctx, "test-repo:production", 1)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddOpenIssue(ctx, "test-repo:production"))
+	require.NoError(t, repo.AddGroupMember(ctx, "production", "test-repo:production"))
+
+	reloaded, err := NewMemoryRepository(filePath, 1)
+	require.NoError(t, err)
+
+	value, _ := reloaded.GetField(ctx, "test-repo:production", "driftIncrement")
+	assert.Equal(t, "1", value)
+
+	keys, _ := reloaded.ListOpenIssues(ctx)
+	assert.Equal(t, []string{"test-repo:production"}, keys)
+
+	members, _ := reloaded.ListGroupMembers(ctx, "production")
+	assert.Equal(t, []string{"test-repo:production"}, members)
+}
+
+// TestMemoryRepository_Expire tests that expired keys are removed
+func TestMemoryRepository_Expire(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	require.NoError(t, repo.SetField(ctx, "digest:123:2025-01-31", "drift:test-repo:production", "3"))
+	require.NoError(t, repo.SetField(ctx, "digest:123:2025-02-01", "drift:test-repo:production", "1"))
+
+	require.NoError(t, repo.Expire(ctx, "digest:123:2025-01-31", -time.Second))
+	require.NoError(t, repo.Expire(ctx, "digest:123:2025-02-01", time.Hour))
+	require.NoError(t, repo.Expire(ctx, "missing-key", time.Hour), "Expiring a missing key should be a no-op")
+
+	_, err := repo.GetEnvironmentData(ctx, "digest:123:2025-01-31")
+	assert.ErrorIs(t, err, ErrEnvironmentNotFound, "Expired key should be removed")
+
+	value, err := repo.GetField(ctx, "digest:123:2025-02-01", "drift:test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value, "Unexpired key should be kept")
+}
+
+// TestMemoryRepository_SetFieldIfEmpty tests that a field is only claimed once
+func TestMemoryRepository_SetFieldIfEmpty(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestMemoryRepository(t)
+
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "escalated", ""))
+
+	set, err := repo.SetFieldIfEmpty(ctx, "test-repo:production", "escalated", "true")
+	require.NoError(t, err)
+	assert.True(t, set, "Empty field should be set")
+
+	set, err = repo.SetFieldIfEmpty(ctx, "test-repo:production", "escalated", "true")
+	require.NoError(t, err)
+	assert.False(t, set, "Field should only be set once")
+
+	set, err = repo.SetFieldIfEmpty(ctx, "test-repo:staging", "escalated", "true")
+	require.NoError(t, err)
+	assert.True(t, set, "Missing field should be set")
+}
+
+// TestMemoryRepository_Ping tests that readiness fails when the persistence directory is gone
+func TestMemoryRepository_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, newTestMemoryRepository(t).Ping(ctx), "Without file persistence the store is always ready")
+
+	dir := filepath.Join(t.TempDir(), "state")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	repo, err := NewMemoryRepository(filepath.Join(dir, "drift-guardian.json"), 1)
+	require.NoError(t, err)
+	assert.NoError(t, repo.Ping(ctx))
+
+	require.NoError(t, os.Remove(dir))
+	assert.Error(t, repo.Ping(ctx))
+}
@@ -0,0 +1,20 @@
+-- Environment state, one row per repository/environment key
+CREATE TABLE IF NOT EXISTS environments (
+    key              TEXT PRIMARY KEY,
+    environment_tier TEXT        NOT NULL DEFAULT '',
+    project_id       TEXT        NOT NULL DEFAULT '',
+    drift_threshold  INTEGER,
+    drift_increment  INTEGER     NOT NULL DEFAULT 0,
+    issue_id         TEXT        NOT NULL DEFAULT '',
+    issue_url        TEXT        NOT NULL DEFAULT '',
+    plan_output      TEXT        NOT NULL DEFAULT '',
+    log              TEXT        NOT NULL DEFAULT '',
+    fields           JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Environments with an open drift issue
+CREATE TABLE IF NOT EXISTS open_issues (
+    key TEXT PRIMARY KEY
+);
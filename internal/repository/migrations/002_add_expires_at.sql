@@ -0,0 +1,4 @@
+-- Optional expiry, after which an environment row is treated as deleted
+ALTER TABLE environments ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS environments_expires_at_idx ON environments (expires_at) WHERE expires_at IS NOT NULL;
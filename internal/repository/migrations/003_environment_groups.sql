@@ -0,0 +1,6 @@
+-- Environments recorded as members of an environment group
+CREATE TABLE IF NOT EXISTS environment_groups (
+    group_name TEXT NOT NULL,
+    key        TEXT NOT NULL,
+    PRIMARY KEY (group_name, key)
+);
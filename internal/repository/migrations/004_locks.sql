@@ -0,0 +1,5 @@
+-- Named locks held until expires_at, taken with AcquireLock
+CREATE TABLE IF NOT EXISTS locks (
+    name       TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
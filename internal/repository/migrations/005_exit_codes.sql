@@ -0,0 +1,10 @@
+-- Exit codes of each environment's operations over time, trimmed by RecordExitCode
+CREATE TABLE IF NOT EXISTS exit_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    key         TEXT NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    operation   TEXT NOT NULL,
+    exit_code   INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS exit_codes_key_recorded_at ON exit_codes (key, recorded_at);
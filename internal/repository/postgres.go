@@ -0,0 +1,709 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq" // Postgres driver for database/sql
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key that serializes migrations across replicas
+const migrationLockID = 4729361058
+
+// postgresColumns maps environment hash fields to their dedicated table columns;
+// any other field is stored in the fields JSONB column
+var postgresColumns = map[string]string{
+	"environmentTier": "environment_tier",
+	"projectID":       "project_id",
+	"driftThreshold":  "drift_threshold",
+	"driftIncrement":  "drift_increment",
+	"issueID":         "issue_id",
+	"issueURL":        "issue_url",
+	"planOutput":      "plan_output",
+	"log":             "log",
+}
+
+// expirySweepInterval is how often rows that expired without being accessed again are deleted
+const expirySweepInterval = 5 * time.Minute
+
+// PostgresRepository implements StorageRepository interface for Postgres operations
+type PostgresRepository struct {
+	db               *sql.DB
+	defaultThreshold int
+	stopSweep        chan struct{}
+	sweepDone        chan struct{}
+}
+
+// NewPostgresRepository connects to Postgres, applies pending migrations, and returns a repository;
+// defaultThreshold applies when none is stored
+func NewPostgresRepository(ctx context.Context, dsn string, defaultThreshold int) (*PostgresRepository, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening postgres connection: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error connecting to postgres: %w", err)
+	}
+
+	repo := &PostgresRepository{db: db, defaultThreshold: defaultThreshold, stopSweep: make(chan struct{}), sweepDone: make(chan struct{})}
+	if err := repo.Migrate(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	go repo.runExpirySweep()
+	return repo, nil
+}
+
+// runExpirySweep deletes expired rows every expirySweepInterval until Close; reads already treat
+// expired rows as missing, so the sweep only reclaims their space
+func (p *PostgresRepository) runExpirySweep() {
+	defer close(p.sweepDone)
+
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopSweep:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), expirySweepInterval)
+			if err := p.sweepExpired(ctx); err != nil {
+				slog.Warn("Failed to sweep expired environments", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// sweepExpired deletes expired environment rows with their exit code series
+func (p *PostgresRepository) sweepExpired(ctx context.Context) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting expiry sweep: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM exit_codes WHERE key IN (SELECT key FROM environments WHERE expires_at <= now())`)
+	if err != nil {
+		return fmt.Errorf("error removing expired exit code series: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM environments WHERE expires_at <= now()`)
+	if err != nil {
+		return fmt.Errorf("error removing expired environments: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Migrate applies any embedded schema migrations that have not yet run, holding an advisory lock
+// so replicas starting together do not apply the same migration twice
+func (p *PostgresRepository) Migrate(ctx context.Context) error {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring migration connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Session-level lock, so it must be taken and released on the same connection
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			slog.Warn("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, entry.Name())
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		var applied bool
+		err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("error checking migration %s: %w", version, err)
+		}
+		if applied {
+			continue
+		}
+
+		script, err := migrationFiles.ReadFile("migrations/" + version)
+		if err != nil {
+			return fmt.Errorf("error reading migration %s: %w", version, err)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("error starting migration %s: %w", version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error applying migration %s: %w", version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error recording migration %s: %w", version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing migration %s: %w", version, err)
+		}
+
+		slog.Info("Applied postgres migration", "version", version)
+	}
+
+	return nil
+}
+
+// Close releases the database connection pool
+func (p *PostgresRepository) Close() error {
+	close(p.stopSweep)
+	<-p.sweepDone
+	return p.db.Close()
+}
+
+// InitializeEnvironment creates a new environment row with default values and the branch drift is compared against
+func (p *PostgresRepository) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error) {
+	slog.Debug("Initializing environment in Postgres",
+		"key", key,
+		"tier", tier,
+		"project_id", projectID,
+		"threshold", threshold,
+		"comparison_branch", comparisonBranch,
+	)
+
+	if threshold == "" {
+		threshold = strconv.Itoa(p.defaultThreshold)
+	}
+
+	thresholdValue, err := strconv.Atoi(threshold)
+	if err != nil {
+		return false, fmt.Errorf("invalid threshold %q: %w", threshold, err)
+	}
+
+	// An expired row is replaced with its exit code series, as Redis would have deleted the key
+	_, err = p.db.ExecContext(ctx, `DELETE FROM exit_codes WHERE key IN (SELECT key FROM environments WHERE key = $1 AND expires_at <= now())`, key)
+	if err != nil {
+		slog.Error("Failed to remove expired exit code series", "key", key)
+		return false, fmt.Errorf("error initializing environment: %w", err)
+	}
+
+	_, err = p.db.ExecContext(ctx, `DELETE FROM environments WHERE key = $1 AND expires_at <= now()`, key)
+	if err != nil {
+		slog.Error("Failed to remove expired environment", "key", key)
+		return false, fmt.Errorf("error initializing environment: %w", err)
+	}
+
+	result, err := p.db.ExecContext(ctx, `
+		INSERT INTO environments (key, environment_tier, project_id, drift_threshold, fields)
+		VALUES ($1, $2, $3, $4, jsonb_build_object('comparisonBranch', $5::text))
+		ON CONFLICT (key) DO NOTHING`,
+		key, tier, projectID, thresholdValue, comparisonBranch)
+	if err != nil {
+		slog.Error("Failed to initialize environment", "key", key)
+		return false, fmt.Errorf("error initializing environment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error initializing environment: %w", err)
+	}
+
+	if rows == 0 {
+		slog.Debug("Environment already exists, skipping initialization", "key", key)
+		return false, nil
+	}
+
+	slog.Info("Environment initialized successfully",
+		"key", key,
+		"tier", tier,
+		"project_id", projectID,
+		"threshold", threshold,
+		"comparison_branch", comparisonBranch,
+	)
+
+	return true, nil
+}
+
+// UpdateOperationLog records the operation timestamp, type, exit code, branch and pipeline source
+func (p *PostgresRepository) UpdateOperationLog(ctx context.Context, key string, entry OperationLogEntry) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO environments (key, log) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET log = EXCLUDED.log, updated_at = now()`,
+		key, formatLogEntry(entry))
+	if err != nil {
+		slog.Error("Failed to update operation log", "key", key, "operation", entry.Operation)
+		return fmt.Errorf("error updating operation log: %w", err)
+	}
+	return nil
+}
+
+// IncrementDrift increases drift counter by amount and returns new value
+func (p *PostgresRepository) IncrementDrift(ctx context.Context, key string, amount int) (int, error) {
+	var value int
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO environments (key, drift_increment) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET drift_increment = environments.drift_increment + $2, updated_at = now()
+		RETURNING drift_increment`,
+		key, amount).Scan(&value)
+	if err != nil {
+		slog.Error("Failed to increment drift counter", "key", key)
+		return 0, fmt.Errorf("error incrementing drift: %w", err)
+	}
+
+	return value, nil
+}
+
+// IncrementAndCheck increases drift counter by amount and compares it with the stored threshold in one transaction
+func (p *PostgresRepository) IncrementAndCheck(ctx context.Context, key string, amount int) (int, bool, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("error incrementing drift and checking threshold: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// The upsert takes a row lock, so the read threshold matches the incremented row
+	var value int
+	var threshold sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO environments (key, drift_increment) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET drift_increment = environments.drift_increment + $2, updated_at = now()
+		RETURNING drift_increment, drift_threshold`,
+		key, amount).Scan(&value, &threshold)
+	if err != nil {
+		slog.Error("Failed to increment drift counter and check threshold", "key", key)
+		return 0, false, fmt.Errorf("error incrementing drift and checking threshold: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("error incrementing drift and checking threshold: %w", err)
+	}
+
+	limit := int(threshold.Int64)
+	if !threshold.Valid || limit < 1 {
+		limit = p.defaultThreshold
+	}
+
+	return value, value >= limit, nil
+}
+
+// ResetDrift sets drift counter to zero
+func (p *PostgresRepository) ResetDrift(ctx context.Context, key string) error {
+	return p.SetField(ctx, key, "driftIncrement", "0")
+}
+
+// GetEnvironmentData retrieves all environment data as map
+func (p *PostgresRepository) GetEnvironmentData(ctx context.Context, key string) (map[string]string, error) {
+	data, found, err := p.loadEnvironment(ctx, key)
+	if err != nil {
+		slog.Error("Failed to retrieve environment data", "key", key)
+		return nil, fmt.Errorf("error retrieving environment data: %w", err)
+	}
+
+	if !found {
+		slog.Warn("No environment data found", "key", key)
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, key)
+	}
+
+	return data, nil
+}
+
+// loadEnvironment reads an environment row into hash-style fields and reports whether it exists
+func (p *PostgresRepository) loadEnvironment(ctx context.Context, key string) (map[string]string, bool, error) {
+	var tier, projectID, issueID, issueURL, planOutput, logEntry string
+	var threshold sql.NullInt64
+	var driftIncrement int
+	var extra []byte
+
+	err := p.db.QueryRowContext(ctx, `
+		SELECT environment_tier, project_id, drift_threshold, drift_increment,
+		       issue_id, issue_url, plan_output, log, fields
+		FROM environments WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		key).Scan(&tier, &projectID, &threshold, &driftIncrement, &issueID, &issueURL, &planOutput, &logEntry, &extra)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	data := map[string]string{}
+	if err := json.Unmarshal(extra, &data); err != nil {
+		return nil, false, fmt.Errorf("error decoding environment fields: %w", err)
+	}
+
+	data["environmentTier"] = tier
+	data["projectID"] = projectID
+	data["driftIncrement"] = strconv.Itoa(driftIncrement)
+	if threshold.Valid {
+		data["driftThreshold"] = strconv.FormatInt(threshold.Int64, 10)
+	}
+
+	// Optional columns behave like unset hash fields when empty
+	for field, value := range map[string]string{
+		"issueID":    issueID,
+		"issueURL":   issueURL,
+		"planOutput": planOutput,
+		"log":        logEntry,
+	} {
+		if value != "" {
+			data[field] = value
+		}
+	}
+
+	return data, true, nil
+}
+
+// SetField updates a specific field in the environment row, creating the row if needed
+func (p *PostgresRepository) SetField(ctx context.Context, key, field, value string) error {
+	var err error
+
+	switch column := postgresColumns[field]; column {
+	case "":
+		_, err = p.db.ExecContext(ctx, `
+			INSERT INTO environments (key, fields) VALUES ($1, jsonb_build_object($2::text, $3::text))
+			ON CONFLICT (key) DO UPDATE SET fields = environments.fields || EXCLUDED.fields, updated_at = now()`,
+			key, field, value)
+	case "drift_threshold", "drift_increment":
+		var number int
+		number, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("error setting field %s: value %q is not an integer", field, value)
+		}
+		// Column names come from the fixed postgresColumns map, never from input
+		_, err = p.db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO environments (key, %[1]s) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET %[1]s = EXCLUDED.%[1]s, updated_at = now()`, column),
+			key, number)
+	default:
+		_, err = p.db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO environments (key, %[1]s) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET %[1]s = EXCLUDED.%[1]s, updated_at = now()`, column),
+			key, value)
+	}
+
+	if err != nil {
+		slog.Error("Failed to set field", "key", key, "field", field)
+		return fmt.Errorf("error setting field %s: %w", field, err)
+	}
+
+	return nil
+}
+
+// SetFieldIfEmpty sets a field only when it is missing or empty and reports whether it did
+func (p *PostgresRepository) SetFieldIfEmpty(ctx context.Context, key, field, value string) (bool, error) {
+	var result sql.Result
+	var err error
+
+	switch column := postgresColumns[field]; column {
+	case "":
+		result, err = p.db.ExecContext(ctx, `
+			INSERT INTO environments (key, fields) VALUES ($1, jsonb_build_object($2::text, $3::text))
+			ON CONFLICT (key) DO UPDATE SET fields = environments.fields || EXCLUDED.fields, updated_at = now()
+			WHERE COALESCE(environments.fields->>$2, '') = ''`,
+			key, field, value)
+	case "issue_id", "issue_url", "plan_output", "log", "environment_tier", "project_id":
+		// Column names come from the fixed postgresColumns map, never from input
+		result, err = p.db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO environments (key, %[1]s) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET %[1]s = EXCLUDED.%[1]s, updated_at = now()
+			WHERE environments.%[1]s = ''`, column),
+			key, value)
+	default:
+		return false, fmt.Errorf("error setting field %s: numeric fields are never empty", field)
+	}
+
+	if err != nil {
+		slog.Error("Failed to set field if empty", "key", key, "field", field)
+		return false, fmt.Errorf("error setting field %s: %w", field, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error setting field %s: %w", field, err)
+	}
+
+	return rows > 0, nil
+}
+
+// GetField retrieves a specific field from the environment row
+func (p *PostgresRepository) GetField(ctx context.Context, key, field string) (string, error) {
+	data, _, err := p.loadEnvironment(ctx, key)
+	if err != nil {
+		slog.Error("Failed to get field", "key", key, "field", field)
+		return "", fmt.Errorf("error getting field %s: %w", field, err)
+	}
+
+	return data[field], nil // Missing rows and fields return empty string
+}
+
+// Expire removes the row and its data once ttl has elapsed, straight away when ttl is not positive
+func (p *PostgresRepository) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return p.deleteEnvironment(ctx, key)
+	}
+
+	_, err := p.db.ExecContext(ctx, `
+		UPDATE environments SET expires_at = now() + make_interval(secs => $2), updated_at = now()
+		WHERE key = $1`,
+		key, ttl.Seconds())
+	if err != nil {
+		slog.Error("Failed to set key expiry", "key", key)
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+
+	return nil
+}
+
+// deleteEnvironment removes the row and its exit code series, like Redis deleting a key
+func (p *PostgresRepository) deleteEnvironment(ctx context.Context, key string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exit_codes WHERE key = $1`, key); err != nil {
+		slog.Error("Failed to remove exit code series", "key", key)
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM environments WHERE key = $1`, key); err != nil {
+		slog.Error("Failed to remove environment", "key", key)
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+	return nil
+}
+
+// AddOpenIssue records an environment key in the open-issue index
+func (p *PostgresRepository) AddOpenIssue(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO open_issues (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key)
+	if err != nil {
+		slog.Error("Failed to add environment to open issue index", "key", key)
+		return fmt.Errorf("error adding to open issue index: %w", err)
+	}
+	return nil
+}
+
+// RemoveOpenIssue removes an environment key from the open-issue index
+func (p *PostgresRepository) RemoveOpenIssue(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM open_issues WHERE key = $1`, key)
+	if err != nil {
+		slog.Error("Failed to remove environment from open issue index", "key", key)
+		return fmt.Errorf("error removing from open issue index: %w", err)
+	}
+	return nil
+}
+
+// ListOpenIssues returns all environment keys in the open-issue index
+func (p *PostgresRepository) ListOpenIssues(ctx context.Context) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT key FROM open_issues ORDER BY key`)
+	if err != nil {
+		slog.Error("Failed to list open issue index")
+		return nil, fmt.Errorf("error listing open issue index: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error listing open issue index: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing open issue index: %w", err)
+	}
+	return keys, nil
+}
+
+// ListEnvironments returns the keys of unexpired environment rows; rows written only through
+// SetField, such as indexes, have no tier
+func (p *PostgresRepository) ListEnvironments(ctx context.Context) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT key FROM environments
+		WHERE environment_tier <> '' AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY key`)
+	if err != nil {
+		slog.Error("Failed to list environments")
+		return nil, fmt.Errorf("error listing environments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error listing environments: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing environments: %w", err)
+	}
+	return keys, nil
+}
+
+// AcquireLock claims the named lock for ttl, taking over an expired holder's row in the same statement
+func (p *PostgresRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	result, err := p.db.ExecContext(ctx, `
+		INSERT INTO locks (name, expires_at) VALUES ($1, now() + $2 * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE locks.expires_at <= now()`,
+		name, ttl.Milliseconds())
+	if err != nil {
+		slog.Error("Failed to acquire lock", "name", name)
+		return false, fmt.Errorf("error acquiring lock: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error acquiring lock: %w", err)
+	}
+	return rows == 1, nil
+}
+
+// AddGroupMember records an environment key as a member of an environment group
+func (p *PostgresRepository) AddGroupMember(ctx context.Context, group, key string) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO environment_groups (group_name, key) VALUES ($1, $2) ON CONFLICT DO NOTHING`, group, key)
+	if err != nil {
+		slog.Error("Failed to add environment to group index", "group", group, "key", key)
+		return fmt.Errorf("error adding to group index: %w", err)
+	}
+	return nil
+}
+
+// ListGroupMembers returns all environment keys recorded for an environment group
+func (p *PostgresRepository) ListGroupMembers(ctx context.Context, group string) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT key FROM environment_groups WHERE group_name = $1 ORDER BY key`, group)
+	if err != nil {
+		slog.Error("Failed to list group index", "group", group)
+		return nil, fmt.Errorf("error listing group index: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error listing group index: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing group index: %w", err)
+	}
+	return keys, nil
+}
+
+// RecordExitCode appends an operation's exit code to the environment's series, keeping the newest limit points
+func (p *PostgresRepository) RecordExitCode(ctx context.Context, key string, point ExitCodePoint, limit int) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO exit_codes (key, recorded_at, operation, exit_code) VALUES ($1, $2, $3, $4)`,
+		key, point.Timestamp, point.Operation, point.ExitCode)
+	if err != nil {
+		slog.Error("Failed to record exit code", "key", key)
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM exit_codes WHERE key = $1 AND id NOT IN (
+			SELECT id FROM exit_codes WHERE key = $1 ORDER BY recorded_at DESC, id DESC LIMIT $2
+		)`,
+		key, limit)
+	if err != nil {
+		slog.Error("Failed to trim exit code series", "key", key)
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+	return nil
+}
+
+// ListExitCodes returns the environment's exit code series, oldest first
+func (p *PostgresRepository) ListExitCodes(ctx context.Context, key string) ([]ExitCodePoint, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT recorded_at, operation, exit_code FROM exit_codes WHERE key = $1 ORDER BY recorded_at, id`, key)
+	if err != nil {
+		slog.Error("Failed to list exit codes", "key", key)
+		return nil, fmt.Errorf("error listing exit codes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	points := []ExitCodePoint{}
+	for rows.Next() {
+		var point ExitCodePoint
+		if err := rows.Scan(&point.Timestamp, &point.Operation, &point.ExitCode); err != nil {
+			return nil, fmt.Errorf("error listing exit codes: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing exit codes: %w", err)
+	}
+	return points, nil
+}
+
+// StorePlanOutput saves Terraform plan output for the environment
+func (p *PostgresRepository) StorePlanOutput(ctx context.Context, key, planOutput string) error {
+	if err := p.SetField(ctx, key, "planOutput", planOutput); err != nil {
+		return fmt.Errorf("error storing plan output: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that Postgres is reachable
+func (p *PostgresRepository) Ping(ctx context.Context) error {
+	if err := p.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("error pinging postgres: %w", err)
+	}
+	return nil
+}
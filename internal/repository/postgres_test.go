@@ -0,0 +1,378 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgresRepository connects to the database in POSTGRES_TEST_DSN and clears existing data
+func newTestPostgresRepository(t *testing.T) *PostgresRepository {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set, skipping Postgres integration tests")
+	}
+
+	ctx := context.Background()
+	repo, err := NewPostgresRepository(ctx, dsn, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	_, err = repo.db.ExecContext(ctx, `TRUNCATE environments, open_issues, environment_groups, locks, exit_codes`)
+	require.NoError(t, err)
+
+	return repo
+}
+
+// TestPostgresRepository_Migrate tests that migrations can be applied repeatedly
+func TestPostgresRepository_Migrate(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+
+	assert.NoError(t, repo.Migrate(context.Background()), "Re-running migrations should be a no-op")
+
+	// Concurrent migrations wait on the advisory lock instead of racing
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.Migrate(context.Background()))
+		}()
+	}
+	wg.Wait()
+}
+
+// TestPostgresRepository_InitializeEnvironment tests environment initialization
+func TestPostgresRepository_InitializeEnvironment(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	isNew, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+	assert.True(t, isNew)
+
+	isNew, err = repo.InitializeEnvironment(ctx, "test-repo:production", "nonprod", "1", "1", "release")
+	require.NoError(t, err)
+	assert.False(t, isNew, "Existing environment should not be reinitialized")
+
+	data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"driftThreshold":   "3",
+		"environmentTier":  "prod",
+		"projectID":        "12345",
+		"driftIncrement":   "0",
+		"comparisonBranch": "main",
+	}, data)
+}
+
+// TestPostgresRepository_InitializeEnvironment_Concurrent tests that only one concurrent first run initializes
+func TestPostgresRepository_InitializeEnvironment_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(threshold string) {
+			defer wg.Done()
+			isNew, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", threshold, "main")
+			assert.NoError(t, err)
+			if isNew {
+				mu.Lock()
+				winners = append(winners, threshold)
+				mu.Unlock()
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	require.Len(t, winners, 1, "Exactly one initialization should win")
+	value, err := repo.GetField(ctx, "test-repo:production", "driftThreshold")
+	require.NoError(t, err)
+	assert.Equal(t, winners[0], value, "Stored threshold should come from the winning initialization")
+}
+
+// TestPostgresRepository_IncrementAndReset tests drift increment, threshold check, and reset
+func TestPostgresRepository_IncrementAndReset(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "2", "main")
+	require.NoError(t, err)
+
+	driftCount, err := repo.IncrementDrift(ctx, "test-repo:production", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, driftCount)
+
+	driftCount, reached, err := repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, driftCount)
+	assert.True(t, reached)
+
+	require.NoError(t, repo.ResetDrift(ctx, "test-repo:production"))
+	value, err := repo.GetField(ctx, "test-repo:production", "driftIncrement")
+	require.NoError(t, err)
+	assert.Equal(t, "0", value)
+}
+
+// TestPostgresRepository_IncrementAndCheck_Concurrent tests that concurrent increments are not lost
+func TestPostgresRepository_IncrementAndCheck_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "25", "main")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	value, err := repo.GetField(ctx, "test-repo:production", "driftIncrement")
+	require.NoError(t, err)
+	assert.Equal(t, "50", value)
+}
+
+// TestPostgresRepository_Fields tests column-backed and free-form fields
+func TestPostgresRepository_Fields(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	value, err := repo.GetField(ctx, "test-repo:missing", "issueID")
+	require.NoError(t, err)
+	assert.Equal(t, "", value, "Missing environment should return empty string")
+
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "issueID", "456"))
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "lastError", "boom"))
+	require.NoError(t, repo.StorePlanOutput(ctx, "test-repo:production", "Plan: 1 to add"))
+	require.NoError(t, repo.UpdateOperationLog(ctx, "test-repo:production", OperationLogEntry{Timestamp: "2025-01-31T10:30:00Z", Operation: "plan", ExitCode: 2, Branch: "main"}))
+	require.NoError(t, repo.UpdateOperationLog(ctx, "test-repo:production", OperationLogEntry{Timestamp: "2025-01-31T11:30:00Z", Operation: "apply"}))
+
+	data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, "456", data["issueID"])
+	assert.Equal(t, "boom", data["lastError"])
+	assert.Equal(t, "Plan: 1 to add", data["planOutput"])
+	assert.Equal(t, `{"timestamp":"2025-01-31T11:30:00Z","operation":"apply","exitCode":0}`, data["log"])
+
+	assert.Error(t, repo.SetField(ctx, "test-repo:production", "driftThreshold", "abc"))
+}
+
+// TestPostgresRepository_OpenIssues tests the open-issue index
+func TestPostgresRepository_OpenIssues(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	require.NoError(t, repo.AddOpenIssue(ctx, "b:prod"))
+	require.NoError(t, repo.AddOpenIssue(ctx, "a:prod"))
+	require.NoError(t, repo.AddOpenIssue(ctx, "a:prod"))
+
+	keys, err := repo.ListOpenIssues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:prod", "b:prod"}, keys)
+
+	require.NoError(t, repo.RemoveOpenIssue(ctx, "a:prod"))
+	keys, err = repo.ListOpenIssues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b:prod"}, keys)
+}
+
+// TestPostgresRepository_ListEnvironments tests that only initialized, unexpired environments are listed
+func TestPostgresRepository_ListEnvironments(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "b:prod", "prod", "123", "1", "main")
+	require.NoError(t, err)
+	_, err = repo.InitializeEnvironment(ctx, "a:prod", "prod", "123", "1", "main")
+	require.NoError(t, err)
+	require.NoError(t, repo.SetField(ctx, "drift-guardian:index:names", "a:prod", "sha256:abc"))
+
+	keys, err := repo.ListEnvironments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:prod", "b:prod"}, keys)
+
+	require.NoError(t, repo.Expire(ctx, "a:prod", 0))
+	keys, err = repo.ListEnvironments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b:prod"}, keys)
+}
+
+// TestPostgresRepository_AcquireLock tests that a lock is held until its ttl passes
+func TestPostgresRepository_AcquireLock(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	acquired, err := repo.AcquireLock(ctx, "issue-update:a:prod", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = repo.AcquireLock(ctx, "issue-update:a:prod", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, acquired, "A held lock cannot be acquired again")
+
+	acquired, err = repo.AcquireLock(ctx, "issue-update:b:prod", -time.Second)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = repo.AcquireLock(ctx, "issue-update:b:prod", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired, "An expired lock can be acquired")
+}
+
+// TestPostgresRepository_GroupMembers tests the environment group index
+func TestPostgresRepository_GroupMembers(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	require.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-network"))
+	require.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-compute"))
+	require.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-compute"))
+	require.NoError(t, repo.AddGroupMember(ctx, "shop-staging", "shop:staging-network"))
+
+	keys, err := repo.ListGroupMembers(ctx, "shop-prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop:prod-compute", "shop:prod-network"}, keys)
+}
+
+// TestPostgresRepository_Expire tests that expired rows are treated as deleted
+func TestPostgresRepository_Expire(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	require.NoError(t, repo.SetField(ctx, "digest:123:2025-01-31", "drift:test-repo:production", "3"))
+	require.NoError(t, repo.SetField(ctx, "digest:123:2025-02-01", "drift:test-repo:production", "1"))
+
+	require.NoError(t, repo.Expire(ctx, "digest:123:2025-01-31", -time.Second))
+	require.NoError(t, repo.Expire(ctx, "digest:123:2025-02-01", time.Hour))
+
+	_, err := repo.GetEnvironmentData(ctx, "digest:123:2025-01-31")
+	assert.ErrorIs(t, err, ErrEnvironmentNotFound, "Expired row should be treated as missing")
+
+	value, err := repo.GetField(ctx, "digest:123:2025-02-01", "drift:test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value, "Unexpired row should be kept")
+
+	// A zero ttl deletes the row and its exit code series straight away
+	point := ExitCodePoint{Timestamp: time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC), Operation: "plan", ExitCode: 2}
+	require.NoError(t, repo.RecordExitCode(ctx, "digest:123:2025-02-01", point, 3))
+	require.NoError(t, repo.Expire(ctx, "digest:123:2025-02-01", 0))
+	var rows int
+	require.NoError(t, repo.db.QueryRowContext(ctx, `SELECT count(*) FROM environments WHERE key = $1`, "digest:123:2025-02-01").Scan(&rows))
+	assert.Zero(t, rows, "Expiring with a zero ttl should delete the row")
+	points, err := repo.ListExitCodes(ctx, "digest:123:2025-02-01")
+	require.NoError(t, err)
+	assert.Empty(t, points)
+}
+
+// TestPostgresRepository_SweepExpired tests that the sweep deletes rows that expired without being accessed again
+func TestPostgresRepository_SweepExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "driftIncrement", "1"))
+	require.NoError(t, repo.SetField(ctx, "test-repo:staging", "driftIncrement", "1"))
+	require.NoError(t, repo.Expire(ctx, "test-repo:production", time.Millisecond))
+	require.NoError(t, repo.Expire(ctx, "test-repo:staging", time.Hour))
+	time.Sleep(10 * time.Millisecond)
+
+	require.NoError(t, repo.sweepExpired(ctx))
+
+	var keys []string
+	rows, err := repo.db.QueryContext(ctx, `SELECT key FROM environments`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var key string
+		require.NoError(t, rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"test-repo:staging"}, keys)
+}
+
+// TestPostgresRepository_ExitCodes tests that the exit code series is ordered and capped
+func TestPostgresRepository_ExitCodes(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	start := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC)
+	for i, exitCode := range []int{0, 2, 2, 1} {
+		point := ExitCodePoint{Timestamp: start.Add(time.Duration(i) * time.Hour), Operation: "plan", ExitCode: exitCode}
+		require.NoError(t, repo.RecordExitCode(ctx, "test-repo:production", point, 3))
+	}
+
+	points, err := repo.ListExitCodes(ctx, "test-repo:production")
+	require.NoError(t, err)
+	require.Len(t, points, 3, "Only the newest points are kept")
+	for i, exitCode := range []int{2, 2, 1} {
+		assert.True(t, start.Add(time.Duration(i+1)*time.Hour).Equal(points[i].Timestamp))
+		assert.Equal(t, exitCode, points[i].ExitCode)
+	}
+
+	points, err = repo.ListExitCodes(ctx, "test-repo:staging")
+	require.NoError(t, err)
+	assert.Empty(t, points)
+}
+
+// TestPostgresRepository_InitializeEnvironment_Expired tests that an expired row is replaced without its exit code series
+func TestPostgresRepository_InitializeEnvironment_Expired(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	_, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+	point := ExitCodePoint{Timestamp: time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC), Operation: "plan", ExitCode: 2}
+	require.NoError(t, repo.RecordExitCode(ctx, "test-repo:production", point, 3))
+	_, err = repo.db.ExecContext(ctx, `UPDATE environments SET expires_at = now() - interval '1 second' WHERE key = $1`, "test-repo:production")
+	require.NoError(t, err)
+
+	isNew, err := repo.InitializeEnvironment(ctx, "test-repo:production", "prod", "12345", "3", "main")
+	require.NoError(t, err)
+	assert.True(t, isNew, "Expired environment should be reinitialized")
+
+	points, err := repo.ListExitCodes(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Empty(t, points, "Expired exit code series should be removed")
+}
+
+// TestPostgresRepository_SetFieldIfEmpty tests that a field is only claimed once
+func TestPostgresRepository_SetFieldIfEmpty(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestPostgresRepository(t)
+
+	require.NoError(t, repo.SetField(ctx, "test-repo:production", "escalated", ""))
+
+	set, err := repo.SetFieldIfEmpty(ctx, "test-repo:production", "escalated", "true")
+	require.NoError(t, err)
+	assert.True(t, set, "Empty field should be set")
+
+	set, err = repo.SetFieldIfEmpty(ctx, "test-repo:production", "escalated", "true")
+	require.NoError(t, err)
+	assert.False(t, set, "Field should only be set once")
+
+	set, err = repo.SetFieldIfEmpty(ctx, "test-repo:production", "issueID", "7")
+	require.NoError(t, err)
+	assert.True(t, set, "Empty column should be set")
+}
+
+// TestPostgresRepository_Ping tests the readiness check
+func TestPostgresRepository_Ping(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	assert.NoError(t, repo.Ping(context.Background()))
+}
@@ -2,67 +2,189 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// incrementAndCheckSource increments the drift counter and compares it with the stored threshold
+// in a single atomic step. ARGV[1] is the fallback threshold when none, or one below 1, is stored, and
+// ARGV[2] is the amount to add.
+const incrementAndCheckSource = `
+local drift = redis.call("HINCRBY", KEYS[1], "driftIncrement", ARGV[2])
+local threshold = tonumber(redis.call("HGET", KEYS[1], "driftThreshold"))
+if threshold == nil or threshold < 1 then
+	threshold = tonumber(ARGV[1])
+end
+if drift >= threshold then
+	return {drift, 1}
+end
+return {drift, 0}
+`
+
+var incrementAndCheckScript = redis.NewScript(incrementAndCheckSource)
+
+// initializeEnvironmentSource creates the environment hash only when the key does not exist, so
+// concurrent first runs cannot overwrite each other. ARGV holds the threshold, tier, project ID, and
+// comparison branch.
+const initializeEnvironmentSource = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "driftThreshold", ARGV[1], "environmentTier", ARGV[2], "projectID", ARGV[3], "driftIncrement", "0", "comparisonBranch", ARGV[4])
+return 1
+`
+
+var initializeEnvironmentScript = redis.NewScript(initializeEnvironmentSource)
+
+// setFieldIfEmptySource sets ARGV[1] to ARGV[2] only when the field is missing or empty
+const setFieldIfEmptySource = `
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if current and current ~= "" then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`
+
+var setFieldIfEmptyScript = redis.NewScript(setFieldIfEmptySource)
+
+// recordExitCodeSource adds ARGV[2] scored ARGV[1] to the series in KEYS[2] and trims it to the
+// newest ARGV[3] points. The series takes the TTL of the environment in KEYS[1], so it expires along
+// with the environment's retention.
+const recordExitCodeSource = `
+redis.call("ZADD", KEYS[2], ARGV[1], ARGV[2])
+redis.call("ZREMRANGEBYRANK", KEYS[2], 0, -tonumber(ARGV[3]) - 1)
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+else
+	redis.call("PERSIST", KEYS[2])
+end
+return 1
+`
+
+var recordExitCodeScript = redis.NewScript(recordExitCodeSource)
+
+// openIssuesIndexKey holds the set of environment keys that have an open issue. Environment keys
+// contain exactly one unescaped ':', so this two-separator key can never collide with one.
+const openIssuesIndexKey = "drift-guardian:index:open-issues"
+
+// groupIndexKeyPrefix prefixes the set of environment keys belonging to each environment group
+const groupIndexKeyPrefix = "drift-guardian:index:group:"
+
+// exitCodeSeriesKeyPrefix prefixes the sorted set of exit codes recorded for each environment key
+const exitCodeSeriesKeyPrefix = "drift-guardian:series:exit-codes:"
+
+// lockKeyPrefix prefixes the keys of locks taken with AcquireLock
+const lockKeyPrefix = "drift-guardian:lock:"
+
+// environmentScanCount is how many keys each SCAN step of ListEnvironments asks Redis to examine
+const environmentScanCount = 1000
+
+// formatLogEntry builds the JSON operation log entry stored with the environment
+func formatLogEntry(entry OperationLogEntry) string {
+	data, _ := json.Marshal(entry) // Marshalling a struct of strings and ints cannot fail
+	return string(data)
+}
+
 // RedisRepository implements StorageRepository interface for Redis operations
 type RedisRepository struct {
-	client *redis.Client
+	client           *redis.Client
+	replica          *redis.Client // Serves reads made WithReplicaReads; nil reads from client
+	defaultThreshold int
+	compressPlans    bool // Plan output is stored gzip-compressed
+}
+
+// NewRedisClient creates a Redis client from a connection URL; a positive opTimeout bounds the
+// network reads and writes of each command so a degraded server fails requests fast
+func NewRedisClient(url string, opTimeout time.Duration) (*redis.Client, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Redis URL: %w", err)
+	}
+
+	if opTimeout > 0 {
+		opt.ReadTimeout = opTimeout
+		opt.WriteTimeout = opTimeout
+	}
+	// Also honor deadlines on the caller's context
+	opt.ContextTimeoutEnabled = true
+
+	return redis.NewClient(opt), nil
 }
 
-// NewRedisRepository creates a new Redis repository instance
-func NewRedisRepository(client *redis.Client) *RedisRepository {
+// NewRedisRepository creates a new Redis repository instance; defaultThreshold applies when none is stored
+func NewRedisRepository(client *redis.Client, defaultThreshold int) *RedisRepository {
 	return &RedisRepository{
-		client: client,
+		client:           client,
+		defaultThreshold: defaultThreshold,
+	}
+}
+
+// WithReplica routes reads made with a WithReplicaReads context to a read-only replica; writes and
+// all other reads stay on the primary
+func (r *RedisRepository) WithReplica(replica *redis.Client) *RedisRepository {
+	r.replica = replica
+	return r
+}
+
+// WithPlanCompression stores plan output gzip-compressed to save memory; plan output stored
+// uncompressed, before compression was enabled, is still read back as is
+func (r *RedisRepository) WithPlanCompression() *RedisRepository {
+	r.compressPlans = true
+	return r
+}
+
+// encodeField returns the stored form of a field's value, compressing plan output when enabled
+func (r *RedisRepository) encodeField(field, value string) (string, error) {
+	if field != "planOutput" || !r.compressPlans || value == "" {
+		return value, nil
+	}
+	return compressPlanOutput(value)
+}
+
+// decodeField returns a field's value from its stored form, decompressing plan output whether or
+// not compression is currently enabled
+func decodeField(field, stored string) (string, error) {
+	if field != "planOutput" {
+		return stored, nil
 	}
+	return decompressPlanOutput(stored)
 }
 
-// InitializeEnvironment creates a new environment hash with default values
-func (r *RedisRepository) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold string) (bool, error) {
+// reader returns the client that serves a read made with ctx
+func (r *RedisRepository) reader(ctx context.Context) *redis.Client {
+	if r.replica != nil && replicaReads(ctx) {
+		return r.replica
+	}
+	return r.client
+}
+
+// InitializeEnvironment creates a new environment hash with default values and the branch drift is compared against
+func (r *RedisRepository) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error) {
 	slog.Debug("Initializing environment in Redis",
 		"key", key,
 		"tier", tier,
 		"project_id", projectID,
 		"threshold", threshold,
+		"comparison_branch", comparisonBranch,
 	)
 
-	// Check if hash exists
-	slog.Debug("Checking if environment already exists", "key", key)
-	exists, err := r.client.Exists(ctx, key).Result()
-	if err != nil {
-		slog.Error("Failed to check if environment exists", "key", key)
-		return false, fmt.Errorf("error checking hash existence: %w", err)
-	}
-
-	// If hash already exists, return false
-	if exists > 0 {
-		slog.Debug("Environment already exists, skipping initialization", "key", key)
-		return false, nil
-	}
-
 	// Use provided threshold (service layer should provide default)
 	if threshold == "" {
-		threshold = os.Getenv("DEFAULT_DRIFT_THRESHOLD")
-		if threshold == "" {
-			threshold = "1"
-		}
+		threshold = strconv.Itoa(r.defaultThreshold)
 		slog.Debug("Using fallback threshold", "threshold", threshold)
 	}
 
-	// Initialize hash with default values
-	fields := map[string]interface{}{
-		"driftThreshold":  threshold,
-		"environmentTier": tier,
-		"projectID":       projectID,
-		"driftIncrement":  "0",
-	}
-
-	slog.Debug("Creating environment hash in Redis", "key", key, "fields", fields)
-	err = r.client.HMSet(ctx, key, fields).Err()
+	// Check and create in one step so only the first concurrent writer initializes
+	slog.Debug("Creating environment hash in Redis if missing", "key", key)
+	created, err := initializeEnvironmentScript.Run(ctx, r.client, []string{key}, threshold, tier, projectID, comparisonBranch).Int()
 	if err != nil {
 		slog.Error("Failed to initialize environment hash",
 			"key", key,
@@ -72,25 +194,32 @@ func (r *RedisRepository) InitializeEnvironment(ctx context.Context, key, tier,
 		return false, fmt.Errorf("error initializing environment hash: %w", err)
 	}
 
+	if created == 0 {
+		slog.Debug("Environment already exists, skipping initialization", "key", key)
+		return false, nil
+	}
+
 	slog.Info("Environment initialized successfully",
 		"key", key,
 		"tier", tier,
 		"project_id", projectID,
 		"threshold", threshold,
+		"comparison_branch", comparisonBranch,
 	)
 
 	return true, nil
 }
 
-// UpdateOperationLog records operation timestamp and type
-func (r *RedisRepository) UpdateOperationLog(ctx context.Context, key, timestamp, operation string) error {
+// UpdateOperationLog records the operation timestamp, type, exit code, branch and pipeline source
+func (r *RedisRepository) UpdateOperationLog(ctx context.Context, key string, entry OperationLogEntry) error {
 	slog.Debug("Updating operation log",
 		"key", key,
-		"timestamp", timestamp,
-		"operation", operation,
+		"timestamp", entry.Timestamp,
+		"operation", entry.Operation,
+		"exit_code", entry.ExitCode,
 	)
 
-	logEntry := fmt.Sprintf(`{"timestamp": "%s", "operation": "%s"}`, timestamp, operation)
+	logEntry := formatLogEntry(entry)
 	err := r.client.HMSet(ctx, key, map[string]interface{}{
 		"log": logEntry,
 	}).Err()
@@ -98,20 +227,20 @@ func (r *RedisRepository) UpdateOperationLog(ctx context.Context, key, timestamp
 	if err != nil {
 		slog.Error("Failed to update operation log",
 			"key", key,
-			"operation", operation,
+			"operation", entry.Operation,
 		)
 		return fmt.Errorf("error updating operation log: %w", err)
 	}
 
-	slog.Debug("Operation log updated successfully", "key", key, "operation", operation)
+	slog.Debug("Operation log updated successfully", "key", key, "operation", entry.Operation)
 	return nil
 }
 
-// IncrementDrift increases drift counter and returns new value
-func (r *RedisRepository) IncrementDrift(ctx context.Context, key string) (int, error) {
-	slog.Debug("Incrementing drift counter", "key", key)
+// IncrementDrift increases drift counter by amount and returns new value
+func (r *RedisRepository) IncrementDrift(ctx context.Context, key string, amount int) (int, error) {
+	slog.Debug("Incrementing drift counter", "key", key, "amount", amount)
 
-	newValue, err := r.client.HIncrBy(ctx, key, "driftIncrement", 1).Result()
+	newValue, err := r.client.HIncrBy(ctx, key, "driftIncrement", int64(amount)).Result()
 	if err != nil {
 		slog.Error("Failed to increment drift counter", "key", key)
 		return 0, fmt.Errorf("error incrementing drift: %w", err)
@@ -120,6 +249,29 @@ func (r *RedisRepository) IncrementDrift(ctx context.Context, key string) (int,
 	return int(newValue), nil
 }
 
+// IncrementAndCheck atomically increases drift counter by amount and reports whether the threshold is reached
+func (r *RedisRepository) IncrementAndCheck(ctx context.Context, key string, amount int) (int, bool, error) {
+	slog.Debug("Incrementing drift counter and checking threshold", "key", key, "amount", amount)
+
+	values, err := incrementAndCheckScript.Run(ctx, r.client, []string{key}, r.defaultThreshold, amount).Int64Slice()
+	if err != nil {
+		slog.Error("Failed to increment drift counter and check threshold", "key", key)
+		return 0, false, fmt.Errorf("error incrementing drift and checking threshold: %w", err)
+	}
+
+	if len(values) != 2 {
+		return 0, false, fmt.Errorf("unexpected increment script result length: %d", len(values))
+	}
+
+	slog.Debug("Drift counter incremented atomically",
+		"key", key,
+		"drift", values[0],
+		"threshold_reached", values[1] == 1,
+	)
+
+	return int(values[0]), values[1] == 1, nil
+}
+
 // ResetDrift sets drift counter to zero
 func (r *RedisRepository) ResetDrift(ctx context.Context, key string) error {
 	slog.Debug("Resetting drift counter", "key", key)
@@ -137,7 +289,7 @@ func (r *RedisRepository) ResetDrift(ctx context.Context, key string) error {
 func (r *RedisRepository) GetEnvironmentData(ctx context.Context, key string) (map[string]string, error) {
 	slog.Debug("Retrieving environment data", "key", key)
 
-	data, err := r.client.HGetAll(ctx, key).Result()
+	data, err := r.reader(ctx).HGetAll(ctx, key).Result()
 	if err != nil {
 		slog.Error("Failed to retrieve environment data", "key", key)
 		return nil, fmt.Errorf("error retrieving environment data: %w", err)
@@ -145,7 +297,16 @@ func (r *RedisRepository) GetEnvironmentData(ctx context.Context, key string) (m
 
 	if len(data) == 0 {
 		slog.Warn("No environment data found", "key", key)
-		return nil, fmt.Errorf("no data found for key: %s", key)
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, key)
+	}
+
+	if stored, ok := data["planOutput"]; ok {
+		planOutput, err := decodeField("planOutput", stored)
+		if err != nil {
+			slog.Error("Failed to decode plan output", "error", err, "key", key)
+			return nil, err
+		}
+		data["planOutput"] = planOutput
 	}
 
 	slog.Debug("Environment data retrieved successfully",
@@ -164,7 +325,12 @@ func (r *RedisRepository) SetField(ctx context.Context, key, field, value string
 		"value", value,
 	)
 
-	err := r.client.HSet(ctx, key, field, value).Err()
+	stored, err := r.encodeField(field, value)
+	if err != nil {
+		return fmt.Errorf("error setting field %s: %w", field, err)
+	}
+
+	err = r.client.HSet(ctx, key, field, stored).Err()
 	if err != nil {
 		slog.Error("Failed to set field",
 			"key", key,
@@ -177,11 +343,24 @@ func (r *RedisRepository) SetField(ctx context.Context, key, field, value string
 	return nil
 }
 
+// SetFieldIfEmpty sets a field only when it is missing or empty and reports whether it did
+func (r *RedisRepository) SetFieldIfEmpty(ctx context.Context, key, field, value string) (bool, error) {
+	slog.Debug("Setting field if empty", "key", key, "field", field, "value", value)
+
+	set, err := setFieldIfEmptyScript.Run(ctx, r.client, []string{key}, field, value).Int()
+	if err != nil {
+		slog.Error("Failed to set field if empty", "key", key, "field", field)
+		return false, fmt.Errorf("error setting field %s: %w", field, err)
+	}
+
+	return set == 1, nil
+}
+
 // GetField retrieves a specific field from the environment hash
 func (r *RedisRepository) GetField(ctx context.Context, key, field string) (string, error) {
 	slog.Debug("Getting field from environment hash", "key", key, "field", field)
 
-	value, err := r.client.HGet(ctx, key, field).Result()
+	value, err := r.reader(ctx).HGet(ctx, key, field).Result()
 	if err != nil {
 		if err == redis.Nil {
 			slog.Debug("Field not found", "key", key, "field", field)
@@ -191,6 +370,12 @@ func (r *RedisRepository) GetField(ctx context.Context, key, field string) (stri
 		return "", fmt.Errorf("error getting field %s: %w", field, err)
 	}
 
+	value, err = decodeField(field, value)
+	if err != nil {
+		slog.Error("Failed to decode field", "error", err, "key", key, "field", field)
+		return "", fmt.Errorf("error getting field %s: %w", field, err)
+	}
+
 	slog.Debug("Field retrieved successfully",
 		"key", key,
 		"field", field,
@@ -200,6 +385,173 @@ func (r *RedisRepository) GetField(ctx context.Context, key, field string) (stri
 	return value, nil
 }
 
+// Expire removes the key and its data once ttl has elapsed
+func (r *RedisRepository) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	slog.Debug("Setting key expiry", "key", key, "ttl", ttl)
+
+	err := r.client.Expire(ctx, key, ttl).Err()
+	if err != nil {
+		slog.Error("Failed to set key expiry", "key", key)
+		return fmt.Errorf("error setting expiry: %w", err)
+	}
+
+	return nil
+}
+
+// AddOpenIssue records an environment key in the open-issue index
+func (r *RedisRepository) AddOpenIssue(ctx context.Context, key string) error {
+	slog.Debug("Adding environment to open issue index", "key", key)
+
+	err := r.client.SAdd(ctx, openIssuesIndexKey, key).Err()
+	if err != nil {
+		slog.Error("Failed to add environment to open issue index", "key", key)
+		return fmt.Errorf("error adding to open issue index: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveOpenIssue removes an environment key from the open-issue index
+func (r *RedisRepository) RemoveOpenIssue(ctx context.Context, key string) error {
+	slog.Debug("Removing environment from open issue index", "key", key)
+
+	err := r.client.SRem(ctx, openIssuesIndexKey, key).Err()
+	if err != nil {
+		slog.Error("Failed to remove environment from open issue index", "key", key)
+		return fmt.Errorf("error removing from open issue index: %w", err)
+	}
+
+	return nil
+}
+
+// ListOpenIssues returns all environment keys in the open-issue index
+func (r *RedisRepository) ListOpenIssues(ctx context.Context) ([]string, error) {
+	slog.Debug("Listing open issue index")
+
+	keys, err := r.reader(ctx).SMembers(ctx, openIssuesIndexKey).Result()
+	if err != nil {
+		slog.Error("Failed to list open issue index")
+		return nil, fmt.Errorf("error listing open issue index: %w", err)
+	}
+
+	return keys, nil
+}
+
+// ListEnvironments scans for environment hashes, recognized by the tier InitializeEnvironment stores
+func (r *RedisRepository) ListEnvironments(ctx context.Context) ([]string, error) {
+	slog.Debug("Scanning for environments")
+
+	client := r.reader(ctx)
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := client.ScanType(ctx, cursor, "*", environmentScanCount, "hash").Result()
+		if err != nil {
+			slog.Error("Failed to scan for environments")
+			return nil, fmt.Errorf("error scanning environments: %w", err)
+		}
+
+		if len(batch) > 0 {
+			pipe := client.Pipeline()
+			tiers := make([]*redis.BoolCmd, len(batch))
+			for i, key := range batch {
+				tiers[i] = pipe.HExists(ctx, key, "environmentTier")
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				slog.Error("Failed to check scanned keys for environments")
+				return nil, fmt.Errorf("error scanning environments: %w", err)
+			}
+			for i, key := range batch {
+				if tiers[i].Val() {
+					keys = append(keys, key)
+				}
+			}
+		}
+
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	// SCAN may return a key more than once
+	slices.Sort(keys)
+	return slices.Compact(keys), nil
+}
+
+// AcquireLock claims the named lock for ttl with SET NX, so only one replica holds it at a time
+func (r *RedisRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	acquired, err := r.client.SetNX(ctx, lockKeyPrefix+name, "1", ttl).Result()
+	if err != nil {
+		slog.Error("Failed to acquire lock", "name", name)
+		return false, fmt.Errorf("error acquiring lock: %w", err)
+	}
+
+	slog.Debug("Lock acquisition attempted", "name", name, "ttl", ttl, "acquired", acquired)
+	return acquired, nil
+}
+
+// AddGroupMember records an environment key as a member of an environment group
+func (r *RedisRepository) AddGroupMember(ctx context.Context, group, key string) error {
+	slog.Debug("Adding environment to group index", "group", group, "key", key)
+
+	err := r.client.SAdd(ctx, groupIndexKeyPrefix+group, key).Err()
+	if err != nil {
+		slog.Error("Failed to add environment to group index", "group", group, "key", key)
+		return fmt.Errorf("error adding to group index: %w", err)
+	}
+
+	return nil
+}
+
+// ListGroupMembers returns all environment keys recorded for an environment group
+func (r *RedisRepository) ListGroupMembers(ctx context.Context, group string) ([]string, error) {
+	slog.Debug("Listing group index", "group", group)
+
+	keys, err := r.reader(ctx).SMembers(ctx, groupIndexKeyPrefix+group).Result()
+	if err != nil {
+		slog.Error("Failed to list group index", "group", group)
+		return nil, fmt.Errorf("error listing group index: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RecordExitCode appends an operation's exit code to the environment's series, keeping the newest limit points
+func (r *RedisRepository) RecordExitCode(ctx context.Context, key string, point ExitCodePoint, limit int) error {
+	slog.Debug("Recording exit code", "key", key, "operation", point.Operation, "exit_code", point.ExitCode)
+
+	member, _ := json.Marshal(point) // Marshalling a struct of strings, ints and a time cannot fail
+	err := recordExitCodeScript.Run(ctx, r.client, []string{key, exitCodeSeriesKeyPrefix + key}, point.Timestamp.UnixMilli(), string(member), limit).Err()
+	if err != nil {
+		slog.Error("Failed to record exit code", "key", key)
+		return fmt.Errorf("error recording exit code: %w", err)
+	}
+
+	return nil
+}
+
+// ListExitCodes returns the environment's exit code series, oldest first
+func (r *RedisRepository) ListExitCodes(ctx context.Context, key string) ([]ExitCodePoint, error) {
+	slog.Debug("Listing exit codes", "key", key)
+
+	members, err := r.reader(ctx).ZRange(ctx, exitCodeSeriesKeyPrefix+key, 0, -1).Result()
+	if err != nil {
+		slog.Error("Failed to list exit codes", "key", key)
+		return nil, fmt.Errorf("error listing exit codes: %w", err)
+	}
+
+	points := make([]ExitCodePoint, 0, len(members))
+	for _, member := range members {
+		var point ExitCodePoint
+		if err := json.Unmarshal([]byte(member), &point); err != nil {
+			return nil, fmt.Errorf("error decoding exit code: %w", err)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
 // StorePlanOutput saves Terraform plan output for the environment
 func (r *RedisRepository) StorePlanOutput(ctx context.Context, key, planOutput string) error {
 	slog.Debug("Storing plan output",
@@ -207,7 +559,13 @@ func (r *RedisRepository) StorePlanOutput(ctx context.Context, key, planOutput s
 		"plan_output_length", len(planOutput),
 	)
 
-	err := r.client.HSet(ctx, key, "planOutput", planOutput).Err()
+	stored, err := r.encodeField("planOutput", planOutput)
+	if err != nil {
+		slog.Error("Failed to compress plan output", "error", err, "key", key)
+		return fmt.Errorf("error storing plan output: %w", err)
+	}
+
+	err = r.client.HSet(ctx, key, "planOutput", stored).Err()
 	if err != nil {
 		slog.Error("Failed to store plan output",
 			"key", key,
@@ -216,6 +574,14 @@ func (r *RedisRepository) StorePlanOutput(ctx context.Context, key, planOutput s
 		return fmt.Errorf("error storing plan output: %w", err)
 	}
 
-	slog.Debug("Plan output stored successfully", "key", key)
+	slog.Debug("Plan output stored successfully", "key", key, "stored_length", len(stored))
+	return nil
+}
+
+// Ping checks that Redis is reachable
+func (r *RedisRepository) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("error pinging redis: %w", err)
+	}
 	return nil
 }
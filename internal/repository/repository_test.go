@@ -4,12 +4,24 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redismock/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// redisError mimics an error reply from the Redis server
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
+
 // TestRedisRepository_InitializeEnvironment tests environment initialization
 func TestRedisRepository_InitializeEnvironment(t *testing.T) {
 	ctx := context.Background()
@@ -31,13 +43,7 @@ func TestRedisRepository_InitializeEnvironment(t *testing.T) {
 			projectID: "123",
 			threshold: "3",
 			setupMock: func(mock redismock.ClientMock) {
-				mock.ExpectExists("test-repo:production").SetVal(0) // Key doesn't exist
-				mock.ExpectHMSet("test-repo:production", map[string]interface{}{
-					"driftThreshold":  "3",
-					"environmentTier": "prod",
-					"projectID":       "123",
-					"driftIncrement":  "0",
-				}).SetVal(true)
+				mock.ExpectEvalSha(initializeEnvironmentScript.Hash(), []string{"test-repo:production"}, "3", "prod", "123", "main").SetVal(int64(1))
 			},
 			expectError: false,
 			expectNew:   true,
@@ -49,21 +55,57 @@ func TestRedisRepository_InitializeEnvironment(t *testing.T) {
 			projectID: "456",
 			threshold: "5",
 			setupMock: func(mock redismock.ClientMock) {
-				mock.ExpectExists("test-repo:staging").SetVal(1) // Key exists
+				mock.ExpectEvalSha(initializeEnvironmentScript.Hash(), []string{"test-repo:staging"}, "5", "nonprod", "456", "main").SetVal(int64(0)) // Key exists
 			},
 			expectError: false,
 			expectNew:   false,
 		},
+		{
+			name:      "fallback threshold",
+			key:       "test-repo:dev",
+			tier:      "nonprod",
+			projectID: "789",
+			threshold: "",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(initializeEnvironmentScript.Hash(), []string{"test-repo:dev"}, "1", "nonprod", "789", "main").SetVal(int64(1))
+			},
+			expectError: false,
+			expectNew:   true,
+		},
+		{
+			name:      "script not cached",
+			key:       "test-repo:production",
+			tier:      "prod",
+			projectID: "123",
+			threshold: "3",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(initializeEnvironmentScript.Hash(), []string{"test-repo:production"}, "3", "prod", "123", "main").SetErr(redisError("NOSCRIPT No matching script"))
+				mock.ExpectEval(initializeEnvironmentSource, []string{"test-repo:production"}, "3", "prod", "123", "main").SetVal(int64(1))
+			},
+			expectError: false,
+			expectNew:   true,
+		},
+		{
+			name:      "redis error",
+			key:       "test-repo:production",
+			tier:      "prod",
+			projectID: "123",
+			threshold: "3",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(initializeEnvironmentScript.Hash(), []string{"test-repo:production"}, "3", "prod", "123", "main").SetErr(errors.New("connection refused"))
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
-			isNew, err := repo.InitializeEnvironment(ctx, tt.key, tt.tier, tt.projectID, tt.threshold)
+			isNew, err := repo.InitializeEnvironment(ctx, tt.key, tt.tier, tt.projectID, tt.threshold, "main")
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -84,29 +126,41 @@ func TestRedisRepository_IncrementDrift(t *testing.T) {
 	tests := []struct {
 		name          string
 		key           string
+		amount        int
 		setupMock     func(mock redismock.ClientMock)
 		expectError   bool
 		expectedDrift int
 	}{
 		{
-			name: "successful drift increment",
-			key:  "test-repo:production",
+			name:   "successful drift increment",
+			key:    "test-repo:production",
+			amount: 1,
 			setupMock: func(mock redismock.ClientMock) {
 				mock.ExpectHIncrBy("test-repo:production", "driftIncrement", 1).SetVal(3)
 			},
 			expectError:   false,
 			expectedDrift: 3,
 		},
+		{
+			name:   "weighted drift increment",
+			key:    "test-repo:production",
+			amount: 2,
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectHIncrBy("test-repo:production", "driftIncrement", 2).SetVal(4)
+			},
+			expectError:   false,
+			expectedDrift: 4,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
-			driftCount, err := repo.IncrementDrift(ctx, tt.key)
+			driftCount, err := repo.IncrementDrift(ctx, tt.key, tt.amount)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -120,6 +174,91 @@ func TestRedisRepository_IncrementDrift(t *testing.T) {
 	}
 }
 
+// TestRedisRepository_IncrementAndCheck tests atomic drift increment and threshold check
+func TestRedisRepository_IncrementAndCheck(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		key             string
+		setupMock       func(mock redismock.ClientMock)
+		expectError     bool
+		expectedDrift   int
+		expectedReached bool
+	}{
+		{
+			name: "threshold not reached",
+			key:  "test-repo:production",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(incrementAndCheckScript.Hash(), []string{"test-repo:production"}, 1, 1).SetVal([]interface{}{int64(1), int64(0)})
+			},
+			expectedDrift:   1,
+			expectedReached: false,
+		},
+		{
+			name: "threshold reached",
+			key:  "test-repo:production",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(incrementAndCheckScript.Hash(), []string{"test-repo:production"}, 1, 1).SetVal([]interface{}{int64(3), int64(1)})
+			},
+			expectedDrift:   3,
+			expectedReached: true,
+		},
+		{
+			name: "script not cached falls back to eval",
+			key:  "test-repo:production",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(incrementAndCheckScript.Hash(), []string{"test-repo:production"}, 1, 1).SetErr(redisError("NOSCRIPT No matching script"))
+				mock.ExpectEval(incrementAndCheckSource, []string{"test-repo:production"}, 1, 1).SetVal([]interface{}{int64(2), int64(1)})
+			},
+			expectedDrift:   2,
+			expectedReached: true,
+		},
+		{
+			name: "script error",
+			key:  "test-repo:production",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(incrementAndCheckScript.Hash(), []string{"test-repo:production"}, 1, 1).SetErr(errors.New("connection refused"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := redismock.NewClientMock()
+			repo := NewRedisRepository(client, 1)
+
+			tt.setupMock(mock)
+
+			driftCount, reached, err := repo.IncrementAndCheck(ctx, tt.key, 1)
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedDrift, driftCount)
+				assert.Equal(t, tt.expectedReached, reached)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("configured default threshold is passed to the script", func(t *testing.T) {
+		t.Setenv("DEFAULT_DRIFT_THRESHOLD", "not-a-number")
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 5)
+
+		mock.ExpectEvalSha(incrementAndCheckScript.Hash(), []string{"test-repo:production"}, 5, 1).SetVal([]interface{}{int64(1), int64(0)})
+
+		_, reached, err := repo.IncrementAndCheck(ctx, "test-repo:production", 1)
+		assert.NoError(t, err)
+		assert.False(t, reached)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 // TestRedisRepository_ResetDrift tests drift reset operations
 func TestRedisRepository_ResetDrift(t *testing.T) {
 	ctx := context.Background()
@@ -143,7 +282,7 @@ func TestRedisRepository_ResetDrift(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
@@ -160,6 +299,40 @@ func TestRedisRepository_ResetDrift(t *testing.T) {
 	}
 }
 
+// TestRedisRepository_UpdateOperationLog tests that the log entry records the exit code and branch
+func TestRedisRepository_UpdateOperationLog(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		entry       OperationLogEntry
+		expectedLog string
+	}{
+		{
+			name:        "drift plan",
+			entry:       OperationLogEntry{Timestamp: "2025-01-31T10:30:00Z", Operation: "plan", ExitCode: 2, Branch: "main"},
+			expectedLog: `{"timestamp":"2025-01-31T10:30:00Z","operation":"plan","exitCode":2,"branch":"main"}`,
+		},
+		{
+			name:        "clean plan without branch",
+			entry:       OperationLogEntry{Timestamp: "2025-01-31T10:30:00Z", Operation: "plan"},
+			expectedLog: `{"timestamp":"2025-01-31T10:30:00Z","operation":"plan","exitCode":0}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := redismock.NewClientMock()
+			repo := NewRedisRepository(client, 1)
+
+			mock.ExpectHMSet("test-repo:production", map[string]interface{}{"log": tt.expectedLog}).SetVal(true)
+
+			assert.NoError(t, repo.UpdateOperationLog(ctx, "test-repo:production", tt.entry))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 // TestRedisRepository_GetEnvironmentData tests environment data retrieval
 func TestRedisRepository_GetEnvironmentData(t *testing.T) {
 	ctx := context.Background()
@@ -204,7 +377,7 @@ func TestRedisRepository_GetEnvironmentData(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
@@ -222,6 +395,273 @@ func TestRedisRepository_GetEnvironmentData(t *testing.T) {
 	}
 }
 
+// TestRedisRepository_Expire tests setting key expiry
+func TestRedisRepository_Expire(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful expiry", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectExpire("digest:123:2025-01-31", 7*24*time.Hour).SetVal(true)
+
+		assert.NoError(t, repo.Expire(ctx, "digest:123:2025-01-31", 7*24*time.Hour))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redis error", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectExpire("digest:123:2025-01-31", time.Hour).SetErr(errors.New("connection refused"))
+
+		assert.Error(t, repo.Expire(ctx, "digest:123:2025-01-31", time.Hour))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestRedisRepository_ListOpenIssues tests reading the open issue index
+func TestRedisRepository_ListOpenIssues(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	repo := NewRedisRepository(client, 1)
+
+	mock.ExpectSMembers("drift-guardian:index:open-issues").SetVal([]string{"a:prod", "b:prod"})
+
+	keys, err := repo.ListOpenIssues(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:prod", "b:prod"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRedisRepository_ListEnvironments tests scanning for environment hashes across SCAN pages
+func TestRedisRepository_ListEnvironments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("environments across pages", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectScanType(0, "*", environmentScanCount, "hash").SetVal([]string{"b:prod", "index:names"}, 7)
+		mock.ExpectHExists("b:prod", "environmentTier").SetVal(true)
+		mock.ExpectHExists("index:names", "environmentTier").SetVal(false)
+		mock.ExpectScanType(7, "*", environmentScanCount, "hash").SetVal([]string{"a:prod", "b:prod"}, 0)
+		mock.ExpectHExists("a:prod", "environmentTier").SetVal(true)
+		mock.ExpectHExists("b:prod", "environmentTier").SetVal(true)
+
+		keys, err := repo.ListEnvironments(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a:prod", "b:prod"}, keys, "Keys returned by more than one page are listed once")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectScanType(0, "*", environmentScanCount, "hash").SetErr(errors.New("connection refused"))
+
+		_, err := repo.ListEnvironments(ctx)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestRedisRepository_AcquireLock tests lock acquisition with SET NX
+func TestRedisRepository_AcquireLock(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	repo := NewRedisRepository(client, 1)
+
+	mock.ExpectSetNX("drift-guardian:lock:issue-update:a:prod", "1", time.Minute).SetVal(true)
+	mock.ExpectSetNX("drift-guardian:lock:issue-update:a:prod", "1", time.Minute).SetVal(false)
+	mock.ExpectSetNX("drift-guardian:lock:issue-update:a:prod", "1", time.Minute).SetErr(errors.New("connection refused"))
+
+	acquired, err := repo.AcquireLock(ctx, "issue-update:a:prod", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = repo.AcquireLock(ctx, "issue-update:a:prod", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "A held lock cannot be acquired again")
+
+	_, err = repo.AcquireLock(ctx, "issue-update:a:prod", time.Minute)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRedisRepository_GroupMembers tests the environment group index
+func TestRedisRepository_GroupMembers(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	repo := NewRedisRepository(client, 1)
+
+	mock.ExpectSAdd("drift-guardian:index:group:shop-prod", "shop:prod-network").SetVal(1)
+	mock.ExpectSMembers("drift-guardian:index:group:shop-prod").SetVal([]string{"shop:prod-network"})
+	mock.ExpectSMembers("drift-guardian:index:group:shop-staging").SetErr(errors.New("connection refused"))
+
+	assert.NoError(t, repo.AddGroupMember(ctx, "shop-prod", "shop:prod-network"))
+
+	keys, err := repo.ListGroupMembers(ctx, "shop-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shop:prod-network"}, keys)
+
+	_, err = repo.ListGroupMembers(ctx, "shop-staging")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRedisRepository_ExitCodes tests recording and listing the exit code series
+func TestRedisRepository_ExitCodes(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	repo := NewRedisRepository(client, 1)
+
+	point := ExitCodePoint{Timestamp: time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC), Operation: "plan", ExitCode: 2}
+	member := `{"timestamp":"2025-01-31T10:30:00Z","operation":"plan","exitCode":2}`
+	keys := []string{"test-repo:production", "drift-guardian:series:exit-codes:test-repo:production"}
+
+	mock.ExpectEvalSha(recordExitCodeScript.Hash(), keys, point.Timestamp.UnixMilli(), member, 100).SetVal(int64(1))
+	mock.ExpectZRange(keys[1], 0, -1).SetVal([]string{member, `{"timestamp":"2025-01-31T11:00:00Z","operation":"apply","exitCode":0}`})
+	mock.ExpectZRange("drift-guardian:series:exit-codes:test-repo:staging", 0, -1).SetErr(errors.New("connection refused"))
+
+	require.NoError(t, repo.RecordExitCode(ctx, "test-repo:production", point, 100))
+
+	points, err := repo.ListExitCodes(ctx, "test-repo:production")
+	require.NoError(t, err)
+	assert.Equal(t, []ExitCodePoint{
+		point,
+		{Timestamp: time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC), Operation: "apply", ExitCode: 0},
+	}, points)
+
+	_, err = repo.ListExitCodes(ctx, "test-repo:staging")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRedisRepository_ReadReplica tests that reads made WithReplicaReads go to the replica while
+// writes and other reads stay on the primary
+func TestRedisRepository_ReadReplica(t *testing.T) {
+	ctx := context.Background()
+	replicaCtx := WithReplicaReads(ctx)
+	key := "test-repo:production"
+
+	t.Run("replica reads", func(t *testing.T) {
+		primary, primaryMock := redismock.NewClientMock()
+		replica, replicaMock := redismock.NewClientMock()
+		repo := NewRedisRepository(primary, 1).WithReplica(replica)
+
+		replicaMock.ExpectHGetAll(key).SetVal(map[string]string{"driftIncrement": "2"})
+		replicaMock.ExpectHGet(key, "issueID").SetVal("7")
+		replicaMock.ExpectSMembers(groupIndexKeyPrefix + "shop-prod").SetVal([]string{key})
+		replicaMock.ExpectSMembers(openIssuesIndexKey).SetVal([]string{key})
+
+		data, err := repo.GetEnvironmentData(replicaCtx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, "2", data["driftIncrement"])
+
+		issueID, err := repo.GetField(replicaCtx, key, "issueID")
+		assert.NoError(t, err)
+		assert.Equal(t, "7", issueID)
+
+		members, err := repo.ListGroupMembers(replicaCtx, "shop-prod")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{key}, members)
+
+		openIssues, err := repo.ListOpenIssues(replicaCtx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{key}, openIssues)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+	})
+
+	t.Run("writes and unmarked reads use the primary", func(t *testing.T) {
+		primary, primaryMock := redismock.NewClientMock()
+		replica, replicaMock := redismock.NewClientMock()
+		repo := NewRedisRepository(primary, 1).WithReplica(replica)
+
+		primaryMock.ExpectHSet(key, "issueID", "7").SetVal(1)
+		primaryMock.ExpectHGet(key, "issueID").SetVal("7")
+
+		assert.NoError(t, repo.SetField(replicaCtx, key, "issueID", "7"))
+		issueID, err := repo.GetField(ctx, key, "issueID")
+		assert.NoError(t, err)
+		assert.Equal(t, "7", issueID)
+
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+	})
+
+	t.Run("no replica falls back to the primary", func(t *testing.T) {
+		primary, primaryMock := redismock.NewClientMock()
+		repo := NewRedisRepository(primary, 1)
+
+		primaryMock.ExpectHGetAll(key).SetVal(map[string]string{"driftIncrement": "2"})
+
+		_, err := repo.GetEnvironmentData(replicaCtx, key)
+		assert.NoError(t, err)
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+	})
+}
+
+// TestRedisRepository_SetFieldIfEmpty tests conditional field setting
+func TestRedisRepository_SetFieldIfEmpty(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		setupMock   func(mock redismock.ClientMock)
+		expectError bool
+		expectSet   bool
+	}{
+		{
+			name: "field claimed",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(setFieldIfEmptyScript.Hash(), []string{"test-repo:production"}, "escalated", "true").SetVal(int64(1))
+			},
+			expectSet: true,
+		},
+		{
+			name: "field already set",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(setFieldIfEmptyScript.Hash(), []string{"test-repo:production"}, "escalated", "true").SetVal(int64(0))
+			},
+			expectSet: false,
+		},
+		{
+			name: "script error",
+			setupMock: func(mock redismock.ClientMock) {
+				mock.ExpectEvalSha(setFieldIfEmptyScript.Hash(), []string{"test-repo:production"}, "escalated", "true").SetErr(errors.New("connection refused"))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := redismock.NewClientMock()
+			repo := NewRedisRepository(client, 1)
+
+			tt.setupMock(mock)
+
+			set, err := repo.SetFieldIfEmpty(ctx, "test-repo:production", "escalated", "true")
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectSet, set)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 // TestRedisRepository_SetField tests field setting
 func TestRedisRepository_SetField(t *testing.T) {
 	ctx := context.Background()
@@ -249,7 +689,7 @@ func TestRedisRepository_SetField(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
@@ -293,7 +733,7 @@ func TestRedisRepository_GetField(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client, mock := redismock.NewClientMock()
-			repo := NewRedisRepository(client)
+			repo := NewRedisRepository(client, 1)
 
 			tt.setupMock(mock)
 
@@ -310,3 +750,128 @@ func TestRedisRepository_GetField(t *testing.T) {
 		})
 	}
 }
+
+// TestRedisRepository_PlanCompression tests storing plan output compressed and reading back both
+// compressed and uncompressed plans
+func TestRedisRepository_PlanCompression(t *testing.T) {
+	ctx := context.Background()
+	planOutput := strings.Repeat("  # aws_instance.web will be updated in-place\n", 200)
+
+	compressed, err := compressPlanOutput(planOutput)
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(planOutput)/10, "Repetitive plans compress well")
+
+	t.Run("round trip", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1).WithPlanCompression()
+
+		mock.ExpectHSet("test-repo:production", "planOutput", compressed).SetVal(1)
+		require.NoError(t, repo.StorePlanOutput(ctx, "test-repo:production", planOutput))
+
+		mock.ExpectHGet("test-repo:production", "planOutput").SetVal(compressed)
+		value, err := repo.GetField(ctx, "test-repo:production", "planOutput")
+		require.NoError(t, err)
+		assert.Equal(t, planOutput, value)
+
+		mock.ExpectHGetAll("test-repo:production").SetVal(map[string]string{"driftIncrement": "1", "planOutput": compressed})
+		data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+		require.NoError(t, err)
+		assert.Equal(t, planOutput, data["planOutput"])
+		assert.Equal(t, "1", data["driftIncrement"])
+
+		// Fields moved between keys keep their compression
+		mock.ExpectHSet("sha256:abc", "planOutput", compressed).SetVal(1)
+		require.NoError(t, repo.SetField(ctx, "sha256:abc", "planOutput", planOutput))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("uncompressed plans read unchanged", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1).WithPlanCompression()
+
+		mock.ExpectHGet("test-repo:production", "planOutput").SetVal(planOutput)
+		value, err := repo.GetField(ctx, "test-repo:production", "planOutput")
+		require.NoError(t, err)
+		assert.Equal(t, planOutput, value)
+
+		mock.ExpectHGetAll("test-repo:production").SetVal(map[string]string{"planOutput": "No changes."})
+		data, err := repo.GetEnvironmentData(ctx, "test-repo:production")
+		require.NoError(t, err)
+		assert.Equal(t, "No changes.", data["planOutput"])
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("compressed plans read after disabling compression", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectHSet("test-repo:production", "planOutput", planOutput).SetVal(1)
+		require.NoError(t, repo.StorePlanOutput(ctx, "test-repo:production", planOutput))
+
+		mock.ExpectHGet("test-repo:production", "planOutput").SetVal(compressed)
+		value, err := repo.GetField(ctx, "test-repo:production", "planOutput")
+		require.NoError(t, err)
+		assert.Equal(t, planOutput, value)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("corrupt compressed plan", func(t *testing.T) {
+		client, mock := redismock.NewClientMock()
+		repo := NewRedisRepository(client, 1)
+
+		mock.ExpectHGet("test-repo:production", "planOutput").SetVal(compressedPlanPrefix + "not gzip")
+		_, err := repo.GetField(ctx, "test-repo:production", "planOutput")
+		assert.Error(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestRedisRepository_Ping tests the readiness check
+func TestRedisRepository_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	client, mock := redismock.NewClientMock()
+	repo := NewRedisRepository(client, 1)
+
+	mock.ExpectPing().SetVal("PONG")
+	assert.NoError(t, repo.Ping(ctx))
+
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+	assert.Error(t, repo.Ping(ctx))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestNewRedisClient_OpTimeout tests that commands against an unresponsive server fail within the operation timeout
+func TestNewRedisClient_OpTimeout(t *testing.T) {
+	// A server that accepts connections but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer func() { _ = conn.Close() }()
+		}
+	}()
+
+	client, err := NewRedisClient("redis://"+listener.Addr().String(), 50*time.Millisecond)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	repo := NewRedisRepository(client, 1)
+	start := time.Now()
+	_, err = repo.GetField(context.Background(), "test-repo:production", "issueID")
+	assert.Error(t, err, "Slow Redis should time out")
+	assert.Less(t, time.Since(start), 2*time.Second, "Command should fail fast")
+
+	_, err = NewRedisClient("://invalid", time.Second)
+	assert.Error(t, err)
+}
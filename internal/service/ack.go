@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+	"drift-guardian/internal/config"
+	"drift-guardian/internal/repository"
+)
+
+// ackState describes an environment's acknowledgement at a point in time
+type ackState int
+
+const (
+	ackNone    ackState = iota // No acknowledgement stored
+	ackActive                  // Drift is silenced
+	ackExpired                 // The acknowledgement lapsed without a resolve-by time being missed
+	ackOverdue                 // Drift outlived the resolve-by time and must be escalated
+)
+
+// ackStatus evaluates the stored ackUntil and ackResolveBy fields at now; unparsable times count as lapsed
+func ackStatus(ackUntil, resolveBy string, now time.Time) ackState {
+	if ackUntil == "" {
+		return ackNone
+	}
+
+	if resolveBy != "" {
+		deadline, err := time.Parse(time.RFC3339, resolveBy)
+		if err != nil || !now.Before(deadline) {
+			return ackOverdue
+		}
+	}
+
+	until, err := time.Parse(time.RFC3339, ackUntil)
+	if err != nil || !now.Before(until) {
+		return ackExpired
+	}
+	return ackActive
+}
+
+// AcknowledgeDrift records an acknowledgement of an environment's drift
+func (d *DriftServiceImpl) AcknowledgeDrift(ctx context.Context, ack Acknowledgement) error {
+	if ack.RepoName == "" || ack.Environment == "" {
+		return fmt.Errorf("%w: missing repoName or environment", ErrInvalidAcknowledgement)
+	}
+
+	now := time.Now()
+	if !ack.AckUntil.After(now) {
+		return fmt.Errorf("%w: ackUntil must be in the future", ErrInvalidAcknowledgement)
+	}
+	if d.config.AckMaxDuration > 0 && ack.AckUntil.Sub(now) > d.config.AckMaxDuration {
+		return fmt.Errorf("%w: ackUntil must be within %s", ErrInvalidAcknowledgement, d.config.AckMaxDuration)
+	}
+	if ack.ResolveBy != nil && (!ack.ResolveBy.After(now) || ack.ResolveBy.After(ack.AckUntil)) {
+		return fmt.Errorf("%w: resolveBy must be in the future and no later than ackUntil", ErrInvalidAcknowledgement)
+	}
+
+	key := d.environmentKey(ctx, ack.RepoName, ack.Environment)
+
+	if _, err := d.storage.GetEnvironmentData(ctx, key); err != nil {
+		if errors.Is(err, repository.ErrEnvironmentNotFound) {
+			return ErrEnvironmentNotFound
+		}
+		return fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	resolveBy := ""
+	if ack.ResolveBy != nil {
+		resolveBy = ack.ResolveBy.UTC().Format(time.RFC3339)
+	}
+
+	if err := d.storage.SetField(ctx, key, "ackUntil", ack.AckUntil.UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to store acknowledgement: %w", err)
+	}
+	if err := d.storage.SetField(ctx, key, "ackResolveBy", resolveBy); err != nil {
+		return fmt.Errorf("failed to store acknowledgement resolve-by time: %w", err)
+	}
+
+	slog.Info("Drift acknowledged",
+		"key", key,
+		"ack_until", ack.AckUntil,
+		"resolve_by", resolveBy,
+	)
+	d.auditor.Record(ctx, audit.Entry{
+		Action:      audit.ActionAcknowledge,
+		RepoName:    ack.RepoName,
+		Environment: ack.Environment,
+		Details:     map[string]string{"ackUntil": ack.AckUntil.UTC().Format(time.RFC3339), "resolveBy": resolveBy},
+	})
+	return nil
+}
+
+// checkAcknowledgement reports whether an acknowledgement silences this breach and whether the
+// drift outlived its resolve-by time. Lapsed and overdue acknowledgements are cleared, and the open
+// issue of an overdue one is escalated.
+func (d *DriftServiceImpl) checkAcknowledgement(ctx context.Context, env EnvironmentInfo) (silenced, overdue bool) {
+	ackUntil, _ := d.storage.GetField(ctx, env.Key, "ackUntil")
+	if ackUntil == "" {
+		return false, false
+	}
+	resolveBy, _ := d.storage.GetField(ctx, env.Key, "ackResolveBy")
+
+	switch ackStatus(ackUntil, resolveBy, time.Now()) {
+	case ackActive:
+		slog.Info("Drift acknowledged, skipping issue management",
+			"key", env.Key,
+			"ack_until", ackUntil,
+			"resolve_by", resolveBy,
+		)
+		return true, false
+	case ackOverdue:
+		slog.Warn("Acknowledged drift was not resolved in time, escalating",
+			"key", env.Key,
+			"resolve_by", resolveBy,
+		)
+		data, err := d.storage.GetEnvironmentData(ctx, env.Key)
+		if err != nil {
+			slog.Warn("Failed to read environment for acknowledgement escalation", "error", err, "key", env.Key)
+		} else if err := escalateAcknowledgement(ctx, d.issueTracker, d.auditor, d.config, env.Key, data, resolveBy); err != nil {
+			slog.Warn("Failed to escalate acknowledged drift", "error", err, "key", env.Key)
+		}
+		clearAcknowledgement(ctx, d.storage, env.Key)
+		return false, true
+	default:
+		clearAcknowledgement(ctx, d.storage, env.Key)
+		return false, false
+	}
+}
+
+// endAcknowledgement clears the acknowledgement of drift that resolved, honouring it silently
+func (d *DriftServiceImpl) endAcknowledgement(ctx context.Context, key string) {
+	ackUntil, _ := d.storage.GetField(ctx, key, "ackUntil")
+	if ackUntil == "" {
+		return
+	}
+	slog.Info("Acknowledged drift resolved, ending acknowledgement", "key", key)
+	clearAcknowledgement(ctx, d.storage, key)
+}
+
+// clearAcknowledgement removes the acknowledgement fields from an environment
+func clearAcknowledgement(ctx context.Context, storage repository.StorageRepository, key string) {
+	for _, field := range []string{"ackUntil", "ackResolveBy"} {
+		if err := storage.SetField(ctx, key, field, ""); err != nil {
+			slog.Warn("Failed to clear acknowledgement", "error", err, "key", key, "field", field)
+		}
+	}
+}
+
+// escalateAcknowledgement labels and comments on the environment's issue, if it has one
+func escalateAcknowledgement(ctx context.Context, issueTracker client.IssueTracker, auditor audit.Logger, cfg *config.Config, key string, data map[string]string, resolveBy string) error {
+	issueID, err := strconv.Atoi(data["issueID"])
+	if err != nil || issueID <= 0 {
+		return nil
+	}
+
+	projectID, err := strconv.Atoi(issueProjectFromData(data))
+	if err != nil {
+		return fmt.Errorf("invalid project ID: %w", err)
+	}
+
+	annotator, ok := issueTracker.(client.IssueAnnotator)
+	if !ok {
+		return nil
+	}
+
+	if err := annotator.AddIssueLabels(ctx, projectID, issueID, []string{cfg.EscalationLabel}); err != nil {
+		return fmt.Errorf("failed to add escalation label: %w", err)
+	}
+
+	err = annotator.AddIssueComment(ctx, projectID, issueID, fmt.Sprintf(
+		"**Drift Escalated** - This drift was acknowledged to be resolved by %s but is still present. Escalated automatically by Drift Guardian.",
+		resolveBy))
+	if err != nil {
+		return fmt.Errorf("failed to add escalation comment: %w", err)
+	}
+
+	repoName, environment := environmentNames(key, data)
+	auditor.Record(ctx, audit.Entry{
+		Action:      audit.ActionIssueEscalate,
+		RepoName:    repoName,
+		Environment: environment,
+		Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issueID), "resolveBy": resolveBy},
+	})
+
+	return nil
+}
+
+// AckChecker escalates acknowledged drift whose resolve-by time passes before the drift resolves
+type AckChecker struct {
+	storage      repository.StorageRepository
+	issueTracker client.IssueTracker
+	config       *config.Config
+	auditor      audit.Logger
+}
+
+// NewAckChecker creates a new acknowledgement checker instance
+func NewAckChecker(
+	storage repository.StorageRepository,
+	issueTracker client.IssueTracker,
+	cfg *config.Config,
+) *AckChecker {
+	return &AckChecker{
+		storage:      storage,
+		issueTracker: issueTracker,
+		config:       cfg,
+		auditor:      audit.NopLogger{},
+	}
+}
+
+// WithAuditLogger records escalations of overdue acknowledgements to auditor
+func (a *AckChecker) WithAuditLogger(auditor audit.Logger) *AckChecker {
+	a.auditor = auditor
+	return a
+}
+
+// Start runs acknowledgement checks on the escalation check interval until the context is cancelled
+func (a *AckChecker) Start(ctx context.Context) {
+	slog.Info("Acknowledgement checker started", "check_interval", a.config.EscalationCheckInterval)
+
+	ticker := time.NewTicker(a.config.EscalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Acknowledgement checker stopped")
+			return
+		case <-ticker.C:
+			escalated, err := a.CheckAcknowledgements(ctx)
+			if err != nil {
+				slog.Error("Acknowledgement check failed", "error", err)
+				continue
+			}
+			slog.Debug("Acknowledgement check completed", "escalated", escalated)
+		}
+	}
+}
+
+// CheckAcknowledgements scans environments with open issues once, escalating overdue
+// acknowledgements and clearing lapsed ones
+func (a *AckChecker) CheckAcknowledgements(ctx context.Context) (int, error) {
+	// Overdue acknowledgements wait out a change freeze and escalate on the next check after it
+	if window, active := a.config.ActiveFreeze(time.Now()); active {
+		slog.Info("Change freeze active, deferring acknowledgement escalations", "freeze_end", window.End)
+		return 0, nil
+	}
+
+	keys, err := a.storage.ListOpenIssues(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list open issues: %w", err)
+	}
+
+	escalated := 0
+	now := time.Now()
+	for _, key := range keys {
+		data, err := a.storage.GetEnvironmentData(ctx, key)
+		if err != nil {
+			slog.Warn("Failed to check environment acknowledgement", "error", err, "key", key)
+			continue
+		}
+
+		switch ackStatus(data["ackUntil"], data["ackResolveBy"], now) {
+		case ackExpired:
+			clearAcknowledgement(ctx, a.storage, key)
+		case ackOverdue:
+			// Resolved drift ends its acknowledgement, so a remaining one means the drift persists.
+			// A failed escalation keeps the acknowledgement so the next check retries it.
+			if err := escalateAcknowledgement(audit.WithPrincipal(ctx, audit.SystemPrincipal), a.issueTracker, a.auditor, a.config, key, data, data["ackResolveBy"]); err != nil {
+				slog.Warn("Failed to escalate acknowledged drift", "error", err, "key", key)
+				continue
+			}
+			clearAcknowledgement(ctx, a.storage, key)
+			slog.Warn("Acknowledged drift was not resolved in time, escalated", "key", key, "resolve_by", data["ackResolveBy"])
+			escalated++
+		}
+	}
+
+	return escalated, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+)
+
+// recordApplyAuthor stores the commit author of an apply per APPLY_AUTHOR, so a later drift issue
+// reaches whoever last changed the infrastructure. Applies without author data keep the previous one.
+func (d *DriftServiceImpl) recordApplyAuthor(ctx context.Context, payload Payload, key string) {
+	if d.config.ApplyAuthor == "" || payload.Operation != "apply" || payload.CommitAuthor == "" {
+		return
+	}
+	if err := d.storage.SetField(ctx, key, "lastApplyAuthor", payload.CommitAuthor); err != nil {
+		slog.Warn("Failed to store apply author", "error", err, "key", key)
+	}
+}
+
+// lastApplyAuthor returns the stored commit author of the latest apply, or "" when none is known
+func (d *DriftServiceImpl) lastApplyAuthor(ctx context.Context, key string) string {
+	if d.config.ApplyAuthor == "" {
+		return ""
+	}
+	author, err := d.storage.GetField(ctx, key, "lastApplyAuthor")
+	if err != nil {
+		slog.Warn("Failed to get apply author", "error", err, "key", key)
+		return ""
+	}
+	return author
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+)
+
+// breachFilesIssue records a threshold breach for an environment without an open issue and reports
+// whether it is the breach that should file one, per CREATE_ISSUE_AFTER_BREACHES
+func (d *DriftServiceImpl) breachFilesIssue(ctx context.Context, env EnvironmentInfo, driftCount int) bool {
+	if d.config.IssueAfterBreaches <= 1 {
+		return true
+	}
+
+	stored, _ := d.storage.GetField(ctx, env.Key, "breachCount")
+	breaches, _ := strconv.Atoi(stored)
+	breaches++
+
+	if err := d.storage.SetField(ctx, env.Key, "breachCount", strconv.Itoa(breaches)); err != nil {
+		// Without a stored count the breach can never accumulate, so file the issue now
+		slog.Warn("Failed to record threshold breach, creating issue", "error", err, "key", env.Key)
+		return true
+	}
+
+	if breaches < d.config.IssueAfterBreaches {
+		slog.Warn("Drift threshold breached, deferring issue creation until the breach repeats",
+			"key", env.Key,
+			"breach_count", breaches,
+			"create_issue_after", d.config.IssueAfterBreaches,
+			"drift_count", driftCount,
+			"repo", env.RepoName,
+			"environment", env.Environment,
+		)
+		d.metrics.Count("issue.deferred", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+		return false
+	}
+
+	return true
+}
+
+// resetBreachCount clears the breaches counted towards filing an issue
+func (d *DriftServiceImpl) resetBreachCount(ctx context.Context, key string) {
+	if d.config.IssueAfterBreaches <= 1 {
+		return
+	}
+	if err := d.storage.SetField(ctx, key, "breachCount", ""); err != nil {
+		slog.Warn("Failed to reset breach count", "error", err, "key", key)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+)
+
+// recordPlanChecksum stores the checksum of a drifted plan and reports whether the same drift persists
+// unchanged: ongoing drift whose plan matches the previous drifted run's. Only CLIs run with
+// PLAN_CHECKSUM send checksums; reports without one leave the stored checksum alone.
+func (d *DriftServiceImpl) recordPlanChecksum(ctx context.Context, payload Payload, key string, ongoing bool) bool {
+	if payload.PlanChecksum == "" {
+		return false
+	}
+
+	previous, err := d.storage.GetField(ctx, key, "planChecksum")
+	if err != nil {
+		slog.Warn("Failed to get previous plan checksum", "error", err, "key", key)
+	}
+	unchanged := ongoing && previous == payload.PlanChecksum
+
+	if err := d.storage.SetField(ctx, key, "planChecksum", payload.PlanChecksum); err != nil {
+		slog.Warn("Failed to store plan checksum", "error", err, "key", key)
+	}
+	if err := d.storage.SetField(ctx, key, "driftUnchanged", strconv.FormatBool(unchanged)); err != nil {
+		slog.Warn("Failed to store unchanged drift state", "error", err, "key", key)
+	}
+
+	if unchanged {
+		slog.Info("Drift unchanged since last run", "key", key, "plan_checksum", payload.PlanChecksum)
+	}
+	return unchanged
+}
+
+// driftUnchanged reports whether the stored drift is unchanged since the previous run; resolved drift
+// never is, whatever the last checksum comparison found
+func driftUnchanged(environmentData map[string]string) bool {
+	if environmentData["driftUnchanged"] != "true" {
+		return false
+	}
+	driftIncrement, _ := strconv.Atoi(environmentData["driftIncrement"])
+	return driftIncrement > 0
+}
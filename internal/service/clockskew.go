@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// checkClockSkew holds the payload timestamp to MAX_CLOCK_SKEW of server time. Outliers are rejected,
+// or with CLOCK_SKEW_ACTION=clamp replaced by server time, so a skewed CI clock cannot distort the
+// operation log. Replayed reports are old by design, so they may instead be up to REPLAY_MAX_AGE old.
+func (d *DriftServiceImpl) checkClockSkew(payload *Payload) error {
+	if d.config == nil || d.config.MaxClockSkew <= 0 || payload.Timestamp == "" {
+		return nil
+	}
+
+	maxAge := d.config.MaxClockSkew
+	if payload.Replayed {
+		maxAge = max(maxAge, d.config.ReplayMaxAge)
+	}
+
+	now := time.Now()
+	reportedAt, err := time.Parse(time.RFC3339, payload.Timestamp)
+	skew := reportedAt.Sub(now)
+	if err == nil && skew <= d.config.MaxClockSkew && -skew <= maxAge {
+		return nil
+	}
+
+	if d.config.ClockSkewAction == "clamp" {
+		slog.Warn("Payload timestamp outside the allowed clock skew, using server time",
+			"repo", payload.RepoName,
+			"environment", payload.Environment,
+			"timestamp", payload.Timestamp,
+			"max_clock_skew", d.config.MaxClockSkew,
+			"max_age", maxAge,
+		)
+		payload.Timestamp = now.UTC().Format(time.RFC3339)
+		return nil
+	}
+
+	slog.Warn("Rejecting payload timestamp outside the allowed clock skew",
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+		"timestamp", payload.Timestamp,
+		"max_clock_skew", d.config.MaxClockSkew,
+		"max_age", maxAge,
+	)
+	if payload.Replayed {
+		return fmt.Errorf("invalid timestamp in payload: replayed reports must be an RFC3339 time at most %s old and %s ahead of server time", maxAge, d.config.MaxClockSkew)
+	}
+	return fmt.Errorf("invalid timestamp in payload: must be an RFC3339 time within %s of server time", d.config.MaxClockSkew)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+)
+
+// processDeduplicated processes the report once for all identical reports in flight, returning
+// the shared result to each of them
+func (d *DriftServiceImpl) processDeduplicated(ctx context.Context, payload Payload) (*DriftResult, error) {
+	result, err, shared := d.inflight.Do(d.reportFingerprint(payload), func() (interface{}, error) {
+		return d.processDriftDetection(ctx, payload)
+	})
+	if shared {
+		slog.Info("Shared drift processing with an identical concurrent report",
+			"repo", payload.RepoName,
+			"environment", payload.Environment,
+			"operation", payload.Operation,
+		)
+		d.metrics.Count("report.deduplicated", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.(*DriftResult), nil
+}
+
+// reportFingerprint identifies a report by its environment key and a hash of its contents. The
+// send timestamp is left out, so overlapping triggers of the same job match while reports that
+// differ in anything else, such as a plan and an apply, are each processed.
+func (d *DriftServiceImpl) reportFingerprint(payload Payload) string {
+	payload.Timestamp = ""
+	body, _ := json.Marshal(payload) // Marshalling a struct of strings, ints, bools and a string map cannot fail
+	sum := sha256.Sum256(body)
+	return d.GenerateKey(payload.RepoName, payload.Environment) + "#" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+)
+
+// digestFieldPrefix marks digest hash fields that hold an environment's drift count, keyed by environment name
+const digestFieldPrefix = "drift:"
+
+// digestClaimFieldPrefix marks the digest hash field claiming creation of the issue that replaces the
+// one whose ID follows it, or today's first issue when nothing follows
+const digestClaimFieldPrefix = "issueClaim:"
+
+// digestRetention is how long a day's digest record is kept before it expires
+const digestRetention = 7 * 24 * time.Hour
+
+// DigestKey creates the Redis key for a project's digest on the given day
+func DigestKey(projectID int, day string) string {
+	return "digest:" + strconv.Itoa(projectID) + ":" + day
+}
+
+// handleDigestBreach records the drifted environment in the daily digest and creates or updates the digest issue
+func (d *DriftServiceImpl) handleDigestBreach(ctx context.Context, env EnvironmentInfo, projectID, driftCount int) error {
+	day := time.Now().UTC().Format("2006-01-02")
+	key := DigestKey(projectID, day)
+
+	slog.Info("Recording drift in daily digest",
+		"digest_key", key,
+		"repo", env.RepoName,
+		"environment", env.Environment,
+		"drift_count", driftCount,
+	)
+
+	// Record the environment's current drift count in the digest
+	err := d.storage.SetField(ctx, key, digestFieldPrefix+environmentName(env.RepoName, env.Environment), strconv.Itoa(driftCount))
+	if err != nil {
+		slog.Error("Failed to record environment in digest", "error", err, "repo", env.RepoName, "environment", env.Environment)
+		return fmt.Errorf("failed to record environment in digest: %w", err)
+	}
+
+	// Past digests are only needed while their issue may still be updated
+	if err := d.storage.Expire(ctx, key, digestRetention); err != nil {
+		slog.Warn("Failed to set digest expiry", "error", err, "digest_key", key)
+	}
+
+	digestData, err := d.storage.GetEnvironmentData(ctx, key)
+	if err != nil {
+		slog.Error("Failed to get digest data", "error", err, "digest_key", key)
+		return fmt.Errorf("failed to get digest data: %w", err)
+	}
+
+	var drifted []client.DigestEntry
+	for field, value := range digestData {
+		envName, ok := strings.CutPrefix(field, digestFieldPrefix)
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Warn("Invalid digest drift count, skipping", "field", field, "value", value, "digest_key", key)
+			continue
+		}
+		repoName, environment := splitKey(envName)
+		drifted = append(drifted, client.DigestEntry{RepoName: repoName, Environment: environment, DriftCount: count})
+	}
+
+	gitlabClient, ok := d.issueTracker.(*client.GitLabClient)
+	if !ok {
+		return nil
+	}
+
+	// Update today's digest issue if it is still open
+	if issueID, err := strconv.Atoi(digestData["issueID"]); err == nil && issueID > 0 {
+		isOpen, err := d.issueTracker.GetIssueStatus(ctx, projectID, issueID)
+		if err != nil {
+			slog.Error("Failed to check digest issue status", "error", err, "digest_key", key)
+			return fmt.Errorf("failed to check digest issue status: %w", err)
+		}
+
+		if isOpen {
+			err = gitlabClient.UpdateDigestIssue(ctx, projectID, issueID, day, drifted)
+			if err != nil {
+				slog.Error("Failed to update digest issue", "error", err, "digest_key", key)
+				return fmt.Errorf("failed to update digest issue: %w", err)
+			}
+			slog.Info("Digest issue updated successfully", "issue_id", issueID, "digest_key", key)
+			return nil
+		}
+
+		slog.Info("Digest issue is closed, will create new digest issue", "issue_id", issueID)
+	}
+
+	// Claim the creation so concurrent breaches in the project do not each create a digest issue; the
+	// breach holding the claim lists this environment when it next updates the issue
+	claimField := digestClaimFieldPrefix + digestData["issueID"]
+	claimed, err := d.storage.SetFieldIfEmpty(ctx, key, claimField, "true")
+	if err != nil {
+		slog.Error("Failed to claim digest issue creation", "error", err, "digest_key", key)
+		return fmt.Errorf("failed to claim digest issue creation: %w", err)
+	}
+	if !claimed {
+		slog.Info("Digest issue creation already claimed, skipping", "digest_key", key)
+		return nil
+	}
+
+	issue, err := gitlabClient.CreateDigestIssue(ctx, projectID, day, drifted)
+	if err != nil {
+		// Release the claim so the next breach can retry the creation
+		if releaseErr := d.storage.SetField(ctx, key, claimField, ""); releaseErr != nil {
+			slog.Error("Failed to release digest issue claim", "error", releaseErr, "digest_key", key)
+		}
+		slog.Error("Failed to create digest issue", "error", err, "digest_key", key)
+		return fmt.Errorf("failed to create digest issue: %w", err)
+	}
+
+	d.metrics.Count("issue.created", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+	d.auditor.Record(ctx, audit.Entry{
+		Action:      audit.ActionIssueCreate,
+		RepoName:    env.RepoName,
+		Environment: env.Environment,
+		Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issue.ID), "issueType": "digest"},
+	})
+
+	slog.Info("Digest issue created successfully",
+		"issue_id", issue.ID,
+		"issue_url", issue.WebURL,
+		"digest_key", key,
+	)
+
+	err = d.storage.SetField(ctx, key, "issueID", strconv.Itoa(issue.ID))
+	if err != nil {
+		slog.Error("Failed to store digest issue ID", "error", err, "digest_key", key)
+		return fmt.Errorf("failed to store digest issue ID: %w", err)
+	}
+
+	err = d.storage.SetField(ctx, key, "issueURL", issue.WebURL)
+	if err != nil {
+		slog.Error("Failed to store digest issue URL", "error", err, "digest_key", key)
+		return fmt.Errorf("failed to store digest issue URL: %w", err)
+	}
+
+	return nil
+}
@@ -2,21 +2,62 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
+	"drift-guardian/internal/audit"
 	"drift-guardian/internal/client"
 	"drift-guardian/internal/config"
+	"drift-guardian/internal/metrics"
 	"drift-guardian/internal/repository"
 )
 
+// prodTiers are the environment tiers kept for RETENTION_PROD; all others use RETENTION_NONPROD
+var prodTiers = []string{"prod", "production"}
+
+// maxNameLength limits repository and environment names used to build storage keys
+const maxNameLength = 255
+
+// maxDriftWeight caps how much a single detection can add to the drift counter
+const maxDriftWeight = 100
+
+// pipelineSourcePattern matches CI trigger names such as schedule, push, web or merge_request_event
+var pipelineSourcePattern = regexp.MustCompile(`^[a-z_]{1,64}$`)
+
+// resourceLabelPattern matches resource type labels, which become GitLab label names
+var resourceLabelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/-]{0,63}$`)
+
+// planChecksumPattern matches a hex-encoded SHA256 plan checksum
+var planChecksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ansiEscapePattern matches ANSI CSI and OSC escape sequences such as terminal colours
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripANSI removes ANSI escape sequences from terminal output
+func StripANSI(output string) string {
+	return ansiEscapePattern.ReplaceAllString(output, "")
+}
+
 // DriftServiceImpl implements the DriftService interface
 type DriftServiceImpl struct {
 	storage      repository.StorageRepository
 	issueTracker client.IssueTracker
 	threshold    ThresholdManager
+	metrics      metrics.Recorder
+	auditor      audit.Logger
+	notifier     client.Notifier    // Optional chat notifications of threshold breaches
+	inflight     singleflight.Group // Reports being processed, shared by identical ones with DEDUPLICATE_REPORTS
 	config       *config.Config
 }
 
@@ -25,16 +66,37 @@ func NewDriftService(
 	storage repository.StorageRepository,
 	issueTracker client.IssueTracker,
 	threshold ThresholdManager,
+	recorder metrics.Recorder,
 	cfg *config.Config,
 ) *DriftServiceImpl {
 	return &DriftServiceImpl{
 		storage:      storage,
 		issueTracker: issueTracker,
 		threshold:    threshold,
+		metrics:      recorder,
+		auditor:      audit.NopLogger{},
 		config:       cfg,
 	}
 }
 
+// WithAuditLogger records drift increments and resets, issue creation, closing and escalation, and
+// acknowledgements to auditor
+func (d *DriftServiceImpl) WithAuditLogger(auditor audit.Logger) *DriftServiceImpl {
+	d.auditor = auditor
+	return d
+}
+
+// driftAmount returns how much a detection of the given weight adds to the drift counter in tier,
+// capped at maxDriftWeight
+func (d *DriftServiceImpl) driftAmount(tier string, weight int) int {
+	return min(weight*d.config.TierDriftWeight(tier), maxDriftWeight)
+}
+
+// metricTags builds the statsd tags identifying an environment
+func metricTags(repoName, environment, tier string) []string {
+	return []string{"repo:" + repoName, "environment:" + environment, "tier:" + tier}
+}
+
 // ValidatePayload ensures payload contains all required fields
 func (d *DriftServiceImpl) ValidatePayload(payload *Payload) error {
 	if payload.RepoName == "" {
@@ -49,6 +111,14 @@ func (d *DriftServiceImpl) ValidatePayload(payload *Payload) error {
 		return fmt.Errorf("missing environment in payload")
 	}
 
+	if len(payload.RepoName) > maxNameLength {
+		return fmt.Errorf("repoName exceeds maximum length of %d characters", maxNameLength)
+	}
+
+	if len(payload.Environment) > maxNameLength {
+		return fmt.Errorf("environment exceeds maximum length of %d characters", maxNameLength)
+	}
+
 	if payload.EnvironmentTier == "" {
 		return fmt.Errorf("missing environmentTier in payload")
 	}
@@ -61,16 +131,179 @@ func (d *DriftServiceImpl) ValidatePayload(payload *Payload) error {
 		return fmt.Errorf("invalid terraform operation in payload")
 	}
 
-	return nil
+	if payload.RefType != "" && payload.RefType != "branch" && payload.RefType != "tag" {
+		return fmt.Errorf("invalid refType in payload: must be branch or tag")
+	}
+
+	if payload.MergeRequestIID != "" {
+		if _, err := strconv.Atoi(payload.MergeRequestIID); err != nil {
+			return fmt.Errorf("invalid mergeRequestIid in payload: must be a numeric merge request IID")
+		}
+	}
+
+	if payload.IssueProjectID != "" {
+		if _, err := strconv.Atoi(payload.IssueProjectID); err != nil {
+			return fmt.Errorf("invalid issueProjectId in payload: must be a numeric project ID")
+		}
+	}
+
+	if err := validateMetadata(payload.Metadata); err != nil {
+		return fmt.Errorf("invalid metadata in payload: %w", err)
+	}
+
+	if payload.DriftWeight < 0 || payload.DriftWeight > maxDriftWeight {
+		return fmt.Errorf("invalid driftWeight in payload: must be between 0 and %d", maxDriftWeight)
+	}
+
+	if payload.PipelineSource != "" && !pipelineSourcePattern.MatchString(payload.PipelineSource) {
+		return fmt.Errorf("invalid pipelineSource in payload: must be lowercase letters and underscores")
+	}
+
+	if len(payload.CommitAuthor) > maxNameLength || strings.ContainsAny(payload.CommitAuthor, "\r\n") {
+		return fmt.Errorf("invalid commitAuthor in payload: must be a single line of at most %d characters", maxNameLength)
+	}
+
+	if payload.ResourceLabel != "" && !resourceLabelPattern.MatchString(payload.ResourceLabel) {
+		return fmt.Errorf("invalid resourceLabel in payload: must start with a letter or digit and contain only letters, digits, '_', '.', ':', '/' or '-'")
+	}
+
+	if payload.PlanChecksum != "" && !planChecksumPattern.MatchString(payload.PlanChecksum) {
+		return fmt.Errorf("invalid planChecksum in payload: must be a lowercase hex SHA256")
+	}
+
+	if payload.Replayed {
+		if _, err := time.Parse(time.RFC3339, payload.Timestamp); err != nil {
+			return fmt.Errorf("invalid timestamp in payload: replayed reports must carry the RFC3339 time they were made")
+		}
+	}
+
+	if payload.DriftThreshold != "" {
+		threshold, err := strconv.Atoi(payload.DriftThreshold)
+		if err != nil || threshold < 1 {
+			return fmt.Errorf("invalid driftThreshold in payload: must be a whole number of at least 1")
+		}
+	}
+
+	return d.checkClockSkew(payload)
 }
 
-// GenerateKey creates Redis key from repo name and environment
+// keyComponentEscaper percent-encodes the key separator so components cannot collide
+var keyComponentEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// GenerateKey creates Redis key from repo name and environment; with HASH_KEYS the key is a
+// fixed-length hash of the environment name
 func (d *DriftServiceImpl) GenerateKey(repoName, environment string) string {
+	name := environmentName(repoName, environment)
+	if d.config.HashKeys {
+		return hashedKey(name)
+	}
+	return name
+}
+
+// environmentName identifies an environment as repoName:environment with separators escaped;
+// it is the environment's key unless HASH_KEYS is set
+func environmentName(repoName, environment string) string {
+	return keyComponentEscaper.Replace(repoName) + ":" + keyComponentEscaper.Replace(environment)
+}
+
+// keyComponentUnescaper reverses keyComponentEscaper
+var keyComponentUnescaper = strings.NewReplacer("%3A", ":", "%25", "%")
+
+// splitKey recovers the repo name and environment from a name built by environmentName
+func splitKey(key string) (string, string) {
+	repoName, environment, _ := strings.Cut(key, ":")
+	return keyComponentUnescaper.Replace(repoName), keyComponentUnescaper.Replace(environment)
+}
+
+// legacyKey returns the key format used before GenerateKey escaped separators
+func legacyKey(repoName, environment string) string {
 	return repoName + ":" + environment
 }
 
-// ProcessDriftDetection handles the complete drift detection workflow
+// previousKeys returns the keys an environment's state may have been stored under before key:
+// its unhashed name when HASH_KEYS is set, and the legacy unescaped key
+func (d *DriftServiceImpl) previousKeys(repoName, environment string) []string {
+	var keys []string
+	name := environmentName(repoName, environment)
+	if d.config.HashKeys {
+		keys = append(keys, name)
+	}
+
+	// Only names containing ':' or '%' have a different legacy key, and a legacy key that is
+	// also a valid escaped name belongs to another environment
+	oldKey := legacyKey(repoName, environment)
+	if oldKey != name && environmentName(splitKey(oldKey)) != oldKey {
+		keys = append(keys, oldKey)
+	}
+	return keys
+}
+
+// migrateLegacyKey moves an environment's state from a key it was stored under before key.
+// Failures are logged and the environment continues under the new key.
+func (d *DriftServiceImpl) migrateLegacyKey(ctx context.Context, repoName, environment, key string) {
+	for _, oldKey := range d.previousKeys(repoName, environment) {
+		if !d.moveEnvironment(ctx, oldKey, key) {
+			continue
+		}
+		if d.config.HashKeys {
+			d.recordEnvironmentName(ctx, repoName, environment, key)
+		}
+		return
+	}
+}
+
+// moveEnvironment copies the state stored under oldKey to key and removes oldKey, reporting
+// whether state was moved; existing state under key is never overwritten
+func (d *DriftServiceImpl) moveEnvironment(ctx context.Context, oldKey, key string) bool {
+	if _, err := d.storage.GetEnvironmentData(ctx, key); !errors.Is(err, repository.ErrEnvironmentNotFound) {
+		return false // Already migrated, or storage is unavailable
+	}
+
+	legacyData, err := d.storage.GetEnvironmentData(ctx, oldKey)
+	if err != nil {
+		return false // Nothing stored under the previous key
+	}
+
+	slog.Info("Migrating environment state from previous key", "previous_key", oldKey, "key", key)
+
+	for field, value := range legacyData {
+		if err := d.storage.SetField(ctx, key, field, value); err != nil {
+			slog.Warn("Failed to migrate environment field", "error", err, "previous_key", oldKey, "field", field)
+			return false
+		}
+	}
+
+	if legacyData["issueID"] != "" {
+		if err := d.storage.AddOpenIssue(ctx, key); err != nil {
+			slog.Warn("Failed to index migrated open issue", "error", err, "key", key)
+		}
+		if err := d.storage.RemoveOpenIssue(ctx, oldKey); err != nil {
+			slog.Warn("Failed to remove previous key from open issue index", "error", err, "previous_key", oldKey)
+		}
+		d.moveIssueOwner(ctx, legacyData, oldKey, key)
+	}
+
+	if err := d.storage.Expire(ctx, oldKey, 0); err != nil {
+		slog.Warn("Failed to remove previous environment key", "error", err, "previous_key", oldKey)
+	}
+	return true
+}
+
+// ProcessDriftDetection handles the complete drift detection workflow; with DEDUPLICATE_REPORTS,
+// identical reports arriving while one is processed share its result. Heartbeats only mark the
+// environment as seen.
 func (d *DriftServiceImpl) ProcessDriftDetection(ctx context.Context, payload Payload) (*DriftResult, error) {
+	if payload.Heartbeat {
+		return d.processHeartbeat(ctx, payload)
+	}
+	if d.config.DedupeReports {
+		return d.processDeduplicated(ctx, payload)
+	}
+	return d.processDriftDetection(ctx, payload)
+}
+
+// processDriftDetection runs the drift detection workflow for one report
+func (d *DriftServiceImpl) processDriftDetection(ctx context.Context, payload Payload) (*DriftResult, error) {
 	// Log the start of drift processing (NORMAL OPERATION)
 	slog.Info("Starting drift detection processing",
 		"repo", payload.RepoName,
@@ -80,8 +313,14 @@ func (d *DriftServiceImpl) ProcessDriftDetection(ctx context.Context, payload Pa
 		"scheduled", payload.Scheduled,
 	)
 
-	// Generate Redis key
-	key := d.GenerateKey(payload.RepoName, payload.Environment)
+	// Resolve the Redis key, carrying over state stored under an earlier key format
+	key := d.environmentKey(ctx, payload.RepoName, payload.Environment)
+
+	// Reports filed against another repository's project are caught before they create any state
+	projectVerified, err := d.verifyProject(ctx, payload, key)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use configured default threshold if payload threshold is empty
 	threshold := payload.DriftThreshold
@@ -90,74 +329,217 @@ func (d *DriftServiceImpl) ProcessDriftDetection(ctx context.Context, payload Pa
 	}
 
 	// Initialize environment if needed
-	_, err := d.storage.InitializeEnvironment(ctx, key, payload.EnvironmentTier, payload.ProjectID, threshold)
+	created, err := d.storage.InitializeEnvironment(ctx, key, payload.EnvironmentTier, payload.ProjectID, threshold, d.config.ComparisonBranch)
 	if err != nil {
 		slog.Error("Failed to initialize environment", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
 		return nil, fmt.Errorf("failed to initialize environment: %w", err)
 	}
 
+	// A hashed key no longer carries the environment's name, so it is stored alongside the state
+	if created && d.config.HashKeys {
+		d.recordEnvironmentName(ctx, payload.RepoName, payload.Environment, key)
+	}
+
+	if projectVerified {
+		d.recordVerifiedProject(ctx, payload, key)
+	}
+
+	// Track which configured environment groups this environment reports for
+	d.recordGroupMembership(ctx, payload.RepoName, payload.Environment, key)
+
+	// Refresh the tier's retention on every report so only inactive environments expire
+	d.refreshRetention(ctx, key, payload.EnvironmentTier)
+
+	// Process the operation, recording any failure against the environment
+	if err := d.processOperation(ctx, payload, key); err != nil {
+		d.recordLastError(ctx, key, err)
+		return nil, err
+	}
+
+	// Clear the last error now that an operation has succeeded
+	d.clearLastError(ctx, key)
+	d.recordSuccessfulProcessing(ctx, key)
+
+	// Get final environment data
+	result, err := d.environmentResult(ctx, key)
+	if err != nil {
+		slog.Error("Failed to get environment data", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+		return nil, fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	d.updateProjectOverview(ctx, payload, key, result.DriftIncrement)
+
+	if currentDrift, err := strconv.Atoi(result.DriftIncrement); err == nil {
+		d.metrics.Gauge("drift.current", float64(currentDrift), metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+	}
+
+	slog.Info("Drift detection processing completed successfully",
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+		"operation", payload.Operation,
+		"final_drift_count", result.DriftIncrement,
+		"issue_id", result.IssueID,
+	)
+
+	return result, nil
+}
+
+// processOperation applies the operation to an initialized environment
+func (d *DriftServiceImpl) processOperation(ctx context.Context, payload Payload, key string) error {
+	// A replayed report older than the latest recorded operation no longer describes the environment
+	if d.staleReplay(ctx, payload, key) {
+		return nil
+	}
+
 	// Update operation log
 	timestamp := payload.Timestamp
 	if timestamp == "" {
 		timestamp = time.Now().Format(time.RFC3339)
 	}
 
-	err = d.storage.UpdateOperationLog(ctx, key, timestamp, payload.Operation)
+	err := d.storage.UpdateOperationLog(ctx, key, repository.OperationLogEntry{
+		Timestamp:      timestamp,
+		Operation:      payload.Operation,
+		ExitCode:       payload.ExitCode,
+		Branch:         payload.Branch,
+		PipelineSource: payload.PipelineSource,
+	})
 	if err != nil {
 		slog.Error("Failed to update operation log", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
-		return nil, fmt.Errorf("failed to update operation log: %w", err)
+		return fmt.Errorf("failed to update operation log: %w", err)
 	}
 	slog.Info("Operation log updated successfully", "key", key, "operation", payload.Operation)
 
+	d.recordExitCode(ctx, payload, key, timestamp)
+
+	// A run that could not lock the state did not observe the infrastructure
+	if payload.StateLockError {
+		return d.recordStateLock(ctx, payload, key)
+	}
+
+	// Nor did a run whose terraform init failed, since the operation itself never ran
+	if payload.InitFailed {
+		slog.Warn("Terraform init failed, skipping drift counting",
+			"key", key,
+			"operation", payload.Operation,
+			"exit_code", payload.ExitCode,
+			"repo", payload.RepoName,
+			"environment", payload.Environment,
+		)
+		d.metrics.Count("init.failed", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+		return nil
+	}
+
+	d.recordApplyAuthor(ctx, payload, key)
+
+	// Feature-branch plans are recorded separately and never affect the comparison-branch counter
+	if d.isPreview(payload) {
+		return d.recordPreview(ctx, payload, key)
+	}
+
+	// A failed comparison-branch plan is terraform breakage, surfaced apart from drift
+	if d.isPlanError(payload) {
+		return d.recordPlanError(ctx, payload, key)
+	}
+
 	// Handle drift increment for scheduled operations
-	var incrementVal int
-	if payload.Scheduled && payload.Operation == "plan" && payload.ExitCode == 2 && payload.Branch == d.config.ComparisonBranch {
+	if d.detectsDrift(payload) {
 		slog.Info("Drift detected: incrementing drift counter",
+			"operation", payload.Operation,
+			"exit_code", payload.ExitCode,
 			"repo", payload.RepoName,
 			"environment", payload.Environment,
 			"branch", payload.Branch,
 			"comparison_branch", d.config.ComparisonBranch,
 		)
 
-		incrementVal, err = d.storage.IncrementDrift(ctx, key)
+		// Critical resources and tiers weighted with TIER_DRIFT_WEIGHTS count for more, so the
+		// threshold is crossed sooner
+		weight := max(payload.DriftWeight, 1)
+		amount := d.driftAmount(payload.EnvironmentTier, weight)
+
+		// Increment and compare against the stored threshold atomically
+		incrementVal, exceeded, err := d.storage.IncrementAndCheck(ctx, key, amount)
 		if err != nil {
 			slog.Error("Failed to increment drift counter", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
-			return nil, fmt.Errorf("failed to increment drift: %w", err)
+			return fmt.Errorf("failed to increment drift: %w", err)
 		}
 
+		d.metrics.Count("drift.increment", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+		d.auditor.Record(ctx, audit.Entry{
+			Action:      audit.ActionDriftIncrement,
+			RepoName:    payload.RepoName,
+			Environment: payload.Environment,
+			Details:     map[string]string{"driftIncrement": strconv.Itoa(incrementVal), "weight": strconv.Itoa(weight), "amount": strconv.Itoa(amount)},
+		})
+
 		slog.Info("Drift counter incremented",
 			"key", key,
 			"new_drift_count", incrementVal,
+			"weight", weight,
+			"amount", amount,
+			"threshold_reached", exceeded,
 			"repo", payload.RepoName,
 			"environment", payload.Environment,
 		)
 
 		// Store plan output if provided
 		if payload.PlanOutput != "" {
-			err = d.storage.StorePlanOutput(ctx, key, payload.PlanOutput)
+			planOutput := payload.PlanOutput
+			if d.config.StripANSI {
+				planOutput = StripANSI(planOutput)
+			}
+
+			err = d.storage.StorePlanOutput(ctx, key, planOutput)
 			if err != nil {
 				slog.Error("Failed to store plan output", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
-				return nil, fmt.Errorf("failed to store plan output: %w", err)
+				return fmt.Errorf("failed to store plan output: %w", err)
 			}
 		}
+		d.recordChangedResources(ctx, key, payload.PlanOutput)
+		d.recordPlanSummary(ctx, key, payload.PlanOutput)
+
+		// A counter above this run's increment means drift was already detected before it
+		unchanged := d.recordPlanChecksum(ctx, payload, key, incrementVal > amount)
+
+		// Record the planned commit; an absent SHA clears the previous one so it is never stale
+		err = d.storage.SetField(ctx, key, "commitSHA", payload.CommitSHA)
+		if err != nil {
+			slog.Error("Failed to store commit SHA", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+			return fmt.Errorf("failed to store commit SHA: %w", err)
+		}
+
+		// Metadata follows the latest detection in the same way as the commit
+		err = d.storeMetadata(ctx, key, payload.Metadata)
+		if err != nil {
+			slog.Error("Failed to store metadata", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+			return fmt.Errorf("failed to store metadata: %w", err)
+		}
 
 		// Check threshold and create GitLab issue if needed
 		env := EnvironmentInfo{
-			RepoName:    payload.RepoName,
-			Environment: payload.Environment,
-			ProjectID:   payload.ProjectID,
-			Key:         key,
+			RepoName:        payload.RepoName,
+			Environment:     payload.Environment,
+			EnvironmentTier: payload.EnvironmentTier,
+			ProjectID:       payload.ProjectID,
+			IssueProjectID:  payload.IssueProjectID,
+			Key:             key,
+			Scheduled:       payload.Scheduled,
+			PipelineSource:  payload.PipelineSource,
+			DriftWeight:     weight,
+			DriftUnchanged:  unchanged,
+			ResourceLabel:   payload.ResourceLabel,
 		}
 
-		err = d.HandleThresholdBreach(ctx, env, incrementVal)
+		err = d.manageThresholdBreach(ctx, env, incrementVal, exceeded)
 		if err != nil {
 			slog.Error("Failed to handle threshold breach", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
-			return nil, fmt.Errorf("failed to handle threshold breach: %w", err)
+			return fmt.Errorf("failed to handle threshold breach: %w", err)
 		}
 	}
 
-	// Reset drift increment for successful operations
-	if payload.Operation == "apply" || (payload.Operation == "plan" && payload.ExitCode == 0 && payload.Branch == d.config.ComparisonBranch) {
+	// Reset drift increment for resolving operations; a failed apply leaves the drift unresolved
+	if d.resolvesDrift(payload) || (payload.Operation == "plan" && payload.ExitCode == 0 && d.onComparisonRef(payload)) {
 		slog.Info("Resetting drift counter - successful operation detected",
 			"operation", payload.Operation,
 			"exit_code", payload.ExitCode,
@@ -167,48 +549,174 @@ func (d *DriftServiceImpl) ProcessDriftDetection(ctx context.Context, payload Pa
 		)
 
 		env := EnvironmentInfo{
-			RepoName:    payload.RepoName,
-			Environment: payload.Environment,
-			ProjectID:   payload.ProjectID,
-			Key:         key,
+			RepoName:        payload.RepoName,
+			Environment:     payload.Environment,
+			EnvironmentTier: payload.EnvironmentTier,
+			ProjectID:       payload.ProjectID,
+			IssueProjectID:  payload.IssueProjectID,
+			Key:             key,
 		}
 
 		err = d.ResetDriftIncrement(ctx, env, payload.Operation)
 		if err != nil {
 			slog.Error("Failed to reset drift increment", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
-			return nil, fmt.Errorf("failed to reset drift increment: %w", err)
+			return fmt.Errorf("failed to reset drift increment: %w", err)
 		}
 	}
 
-	// Get final environment data
+	return nil
+}
+
+// detectsDrift reports whether the payload counts as a drift detection: a scheduled comparison-branch
+// plan with changes, or a failed apply when FailedApplyAsDrift is set
+func (d *DriftServiceImpl) detectsDrift(payload Payload) bool {
+	if payload.Operation == "apply" && payload.ExitCode != 0 {
+		return d.config.FailedApplyAsDrift
+	}
+	return payload.Scheduled && payload.Operation == "plan" && payload.ExitCode == 2 && d.onComparisonRef(payload)
+}
+
+// onComparisonRef reports whether the payload ran on the ref drift is measured against: the
+// comparison branch itself, or with COMPARISON_REF_TYPE=tag a tag matching the comparison pattern
+func (d *DriftServiceImpl) onComparisonRef(payload Payload) bool {
+	refType := payload.RefType
+	if refType == "" {
+		refType = "branch"
+	}
+
+	if d.config.ComparisonRefType != "tag" {
+		return refType == "branch" && payload.Branch == d.config.ComparisonBranch
+	}
+	if refType != "tag" {
+		return false
+	}
+	matched, err := path.Match(d.config.ComparisonBranch, payload.Branch)
+	return err == nil && matched
+}
+
+// defaultResolveOperations resolves drift on a successful apply when RESOLVE_OPERATIONS is unset
+var defaultResolveOperations = map[string][]int{"apply": {0}}
+
+// resolvesDrift reports whether the payload's operation and exit code are configured to resolve drift.
+// Applies are additionally limited to the ApplyResetBranches patterns.
+func (d *DriftServiceImpl) resolvesDrift(payload Payload) bool {
+	operations := d.config.ResolveOperations
+	if len(operations) == 0 {
+		operations = defaultResolveOperations
+	}
+
+	if !slices.Contains(operations[payload.Operation], payload.ExitCode) {
+		return false
+	}
+	return payload.Operation != "apply" || d.applyResetsDrift(payload.Branch)
+}
+
+// applyResetsDrift reports whether an apply from the branch resets drift; with no ApplyResetBranches
+// patterns configured, applies from any branch do
+func (d *DriftServiceImpl) applyResetsDrift(branch string) bool {
+	if len(d.config.ApplyResetBranches) == 0 {
+		return true
+	}
+	for _, pattern := range d.config.ApplyResetBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	slog.Info("Apply branch not in APPLY_RESET_BRANCHES, keeping drift", "branch", branch)
+	return false
+}
+
+// environmentResult builds the drift result from the stored environment data
+func (d *DriftServiceImpl) environmentResult(ctx context.Context, key string) (*DriftResult, error) {
 	environmentData, err := d.storage.GetEnvironmentData(ctx, key)
 	if err != nil {
-		slog.Error("Failed to get environment data", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+		return nil, err
+	}
+
+	return &DriftResult{
+		EnvironmentTier:  environmentData["environmentTier"],
+		ProjectID:        environmentData["projectID"],
+		DriftIncrement:   environmentData["driftIncrement"],
+		IssueID:          environmentData["issueID"],
+		IssueURL:         environmentData["issueURL"],
+		Log:              map[string]string{"log": environmentData["log"]},
+		LastError:        environmentData["lastError"],
+		LastErrorAt:      environmentData["lastErrorTimestamp"],
+		CommitSHA:        environmentData["commitSHA"],
+		ComparisonBranch: environmentData["comparisonBranch"],
+		AckUntil:         environmentData["ackUntil"],
+		AckResolveBy:     environmentData["ackResolveBy"],
+		FirstBreachAt:    environmentData["firstBreachAt"],
+		DriftDuration:    environmentData["lastDriftDurationSeconds"],
+		LastPlanErrorAt:  environmentData["lastPlanErrorAt"],
+		PlanErrorIssueID: environmentData["planErrorIssueID"],
+		ChangedResources: environmentData["changedResources"],
+		PlanChecksum:     environmentData["planChecksum"],
+		DriftUnchanged:   driftUnchanged(environmentData),
+		LastSuccess:      environmentData["lastSuccessfulProcessing"],
+		LastSeen:         environmentData["lastSeen"],
+	}, nil
+}
+
+// GetEnvironmentState returns the stored state of an environment without modifying it
+func (d *DriftServiceImpl) GetEnvironmentState(ctx context.Context, repoName, environment string) (*DriftResult, error) {
+	// The lookup is read-only, so it can be served by a read replica and leaves state stored under
+	// an earlier key format to be migrated by the next report
+	ctx = repository.WithReplicaReads(ctx)
+	key := d.lookupEnvironmentKey(ctx, repoName, environment)
+
+	result, err := d.environmentResult(ctx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrEnvironmentNotFound) {
+			return nil, ErrEnvironmentNotFound
+		}
+		slog.Error("Failed to get environment data", "error", err, "repo", repoName, "environment", environment)
 		return nil, fmt.Errorf("failed to get environment data: %w", err)
 	}
 
-	result := &DriftResult{
-		EnvironmentTier: environmentData["environmentTier"],
-		ProjectID:       environmentData["projectID"],
-		DriftIncrement:  environmentData["driftIncrement"],
-		IssueID:         environmentData["issueID"],
-		IssueURL:        environmentData["issueURL"],
-		Log:             map[string]string{"log": environmentData["log"]},
+	return result, nil
+}
+
+// recordLastError stores the failure message and time against the environment
+func (d *DriftServiceImpl) recordLastError(ctx context.Context, key string, processErr error) {
+	if err := d.storage.SetField(ctx, key, "lastError", processErr.Error()); err != nil {
+		slog.Warn("Failed to record last error", "error", err, "key", key)
+		return
 	}
 
-	slog.Info("Drift detection processing completed successfully",
-		"repo", payload.RepoName,
-		"environment", payload.Environment,
-		"operation", payload.Operation,
-		"final_drift_count", result.DriftIncrement,
-		"issue_id", result.IssueID,
-	)
+	if err := d.storage.SetField(ctx, key, "lastErrorTimestamp", time.Now().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record last error timestamp", "error", err, "key", key)
+	}
+}
 
-	return result, nil
+// clearLastError removes any previously recorded failure from the environment
+func (d *DriftServiceImpl) clearLastError(ctx context.Context, key string) {
+	lastError, err := d.storage.GetField(ctx, key, "lastError")
+	if err != nil {
+		slog.Warn("Failed to read last error", "error", err, "key", key)
+		return
+	}
+
+	if lastError == "" {
+		return
+	}
+
+	if err := d.storage.SetField(ctx, key, "lastError", ""); err != nil {
+		slog.Warn("Failed to clear last error", "error", err, "key", key)
+		return
+	}
+
+	if err := d.storage.SetField(ctx, key, "lastErrorTimestamp", ""); err != nil {
+		slog.Warn("Failed to clear last error timestamp", "error", err, "key", key)
+	}
 }
 
 // HandleThresholdBreach manages GitLab issue creation when drift threshold is exceeded
 func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env EnvironmentInfo, driftCount int) error {
+	// Skip issue management for tiers not configured for issue tracking
+	if !d.issueTrackingEnabled(env, driftCount) {
+		return nil
+	}
 
 	// Check if threshold is exceeded
 	exceeded, err := d.threshold.CheckThreshold(ctx, env.Key, driftCount)
@@ -217,6 +725,37 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 		return fmt.Errorf("failed to check threshold: %w", err)
 	}
 
+	return d.applyThresholdResult(ctx, env, driftCount, exceeded)
+}
+
+// issueTrackingEnabled reports whether issues are managed for the environment's tier
+func (d *DriftServiceImpl) issueTrackingEnabled(env EnvironmentInfo, driftCount int) bool {
+	if d.config.IsIssueTier(env.EnvironmentTier) {
+		return true
+	}
+
+	slog.Info("Issue tracking disabled for environment tier, skipping issue management",
+		"key", env.Key,
+		"tier", env.EnvironmentTier,
+		"drift_count", driftCount,
+		"repo", env.RepoName,
+		"environment", env.Environment,
+	)
+	return false
+}
+
+// manageThresholdBreach creates or updates the drift issue once the threshold check has been made
+func (d *DriftServiceImpl) manageThresholdBreach(ctx context.Context, env EnvironmentInfo, driftCount int, exceeded bool) error {
+	// Skip issue management for tiers not configured for issue tracking
+	if !d.issueTrackingEnabled(env, driftCount) {
+		return nil
+	}
+
+	return d.applyThresholdResult(ctx, env, driftCount, exceeded)
+}
+
+// applyThresholdResult creates or updates the drift issue for a tier with issue tracking enabled
+func (d *DriftServiceImpl) applyThresholdResult(ctx context.Context, env EnvironmentInfo, driftCount int, exceeded bool) error {
 	if !exceeded {
 		slog.Info("Threshold not exceeded, no action required",
 			"key", env.Key,
@@ -234,13 +773,31 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 		"environment", env.Environment,
 	)
 
-	// Convert project ID to integer
-	projectID, err := strconv.Atoi(env.ProjectID)
+	d.recordFirstBreach(ctx, env)
+
+	// Breaches during a change freeze are counted but neither filed nor notified
+	if d.changeFreezeActive(env, driftCount) {
+		return nil
+	}
+
+	// Convert the project receiving issues to an integer
+	projectID, err := strconv.Atoi(env.issueProject())
 	if err != nil {
 		slog.Error("Invalid project ID format", "error", err, "repo", env.RepoName, "environment", env.Environment)
 		return fmt.Errorf("invalid project ID: %w", err)
 	}
 
+	// Aggregate into the daily digest issue instead of a per-environment issue
+	if d.config.DigestMode {
+		return d.handleDigestBreach(ctx, env, projectID, driftCount)
+	}
+
+	// Acknowledged drift stays quiet in its own issue unless it outlives its resolve-by time
+	silenced, ackOverdue := d.checkAcknowledgement(ctx, env)
+	if silenced {
+		return nil
+	}
+
 	// Check for existing issue
 	existingIssueIDStr, err := d.storage.GetField(ctx, env.Key, "issueID")
 	if err != nil {
@@ -262,6 +819,9 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 
 	// Get plan output if available
 	planOutput, _ := d.storage.GetField(ctx, env.Key, "planOutput")
+	commitSHA, _ := d.storage.GetField(ctx, env.Key, "commitSHA")
+	comparisonBranch, _ := d.storage.GetField(ctx, env.Key, "comparisonBranch")
+	metadata := d.storedMetadata(ctx, env.Key)
 
 	// Get threshold value
 	thresholdValue, err := d.threshold.GetThreshold(ctx, env.Key)
@@ -270,22 +830,64 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 		return fmt.Errorf("failed to get threshold value: %w", err)
 	}
 
+	details := client.DriftDetails{
+		RepoName:         env.RepoName,
+		Environment:      env.Environment,
+		DriftIncrement:   driftCount,
+		Threshold:        thresholdValue,
+		PlanOutput:       planOutput,
+		CommitSHA:        commitSHA,
+		ComparisonBranch: comparisonBranch,
+		ComparisonRef:    d.config.ComparisonRefType,
+		Metadata:         metadata,
+		PipelineSource:   env.PipelineSource,
+		LastApplyAuthor:  d.lastApplyAuthor(ctx, env.Key),
+		DriftUnchanged:   env.DriftUnchanged,
+		PlanSummary:      d.storedPlanSummary(ctx, env.Key),
+	}
+
 	// Check if existing issue is still open
 	if existingIssueID > 0 {
+		// The issue lives in the project it was created in, even if the override has since changed
+		existingProjectID, err := d.storedIssueProject(ctx, env)
+		if err != nil {
+			slog.Error("Invalid issue project ID format", "error", err, "repo", env.RepoName, "environment", env.Environment)
+			return fmt.Errorf("invalid issue project ID: %w", err)
+		}
+
 		slog.Info("Checking status of existing issue",
 			"issue_id", existingIssueID,
-			"project_id", projectID,
+			"project_id", existingProjectID,
 			"repo", env.RepoName,
 			"environment", env.Environment,
 		)
 
-		isOpen, err := d.issueTracker.GetIssueStatus(ctx, projectID, existingIssueID)
+		isOpen, err := d.issueTracker.GetIssueStatus(ctx, existingProjectID, existingIssueID)
 		if err != nil {
 			slog.Error("Failed to check existing issue status", "error", err, "repo", env.RepoName, "environment", env.Environment)
 			return fmt.Errorf("failed to check existing issue status: %w", err)
 		}
 
 		if isOpen {
+			// Identical plans add nothing to the issue but notify every subscriber
+			hash := planHash(planOutput)
+			if d.config.SkipUnchangedPlan {
+				storedHash, _ := d.storage.GetField(ctx, env.Key, "planHash")
+				if storedHash == hash {
+					slog.Info("Plan unchanged since last issue update, skipping update",
+						"issue_id", existingIssueID,
+						"repo", env.RepoName,
+						"environment", env.Environment,
+					)
+					return nil
+				}
+			}
+
+			// Replicas sharing storage update one issue at most once per interval
+			if !d.claimIssueUpdate(ctx, env, existingIssueID) {
+				return nil
+			}
+
 			slog.Info("Updating existing open issue",
 				"issue_id", existingIssueID,
 				"drift_count", driftCount,
@@ -293,13 +895,15 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 			)
 
 			// Update existing issue instead of creating new one
-			if gitlabClient, ok := d.issueTracker.(*client.GitLabClient); ok {
-				err = gitlabClient.UpdateIssueDescription(ctx, projectID, existingIssueID, env.RepoName, env.Environment, driftCount, thresholdValue, planOutput)
+			if driftTracker, ok := d.issueTracker.(client.DriftIssueTracker); ok {
+				err = driftTracker.UpdateIssueDescription(ctx, existingProjectID, existingIssueID, details)
 				if err != nil {
 					slog.Error("Failed to update existing issue", "error", err, "repo", env.RepoName, "environment", env.Environment)
 					return fmt.Errorf("failed to update existing issue: %w", err)
 				}
 				slog.Info("Existing issue updated successfully", "issue_id", existingIssueID)
+				d.storePlanHash(ctx, env, hash)
+				d.notifyDriftThrottled(ctx, env, driftCount, thresholdValue, "", true)
 			}
 			return nil
 		} else {
@@ -307,6 +911,11 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 		}
 	}
 
+	// Transient drift only warns until the breach repeats
+	if !d.breachFilesIssue(ctx, env, driftCount) {
+		return nil
+	}
+
 	// Create new issue
 	slog.Info("Creating new drift issue",
 		"project_id", projectID,
@@ -316,13 +925,28 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 		"threshold", thresholdValue,
 	)
 
-	if gitlabClient, ok := d.issueTracker.(*client.GitLabClient); ok {
-		issue, err := gitlabClient.CreateDriftIssue(ctx, projectID, env.RepoName, env.Environment, driftCount, thresholdValue, planOutput)
+	if driftTracker, ok := d.issueTracker.(client.DriftIssueTracker); ok {
+		labels := append(d.detectionLabels(env), d.metadataLabels(metadata)...)
+		if env.ResourceLabel != "" {
+			labels = append(labels, env.ResourceLabel)
+		}
+		if ackOverdue {
+			labels = append(labels, d.config.EscalationLabel)
+		}
+		issue, err := driftTracker.CreateDriftIssue(ctx, projectID, details, labels...)
 		if err != nil {
 			slog.Error("Failed to create drift issue", "error", err, "repo", env.RepoName, "environment", env.Environment)
 			return fmt.Errorf("failed to create drift issue: %w", err)
 		}
 
+		d.metrics.Count("issue.created", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+		d.auditor.Record(ctx, audit.Entry{
+			Action:      audit.ActionIssueCreate,
+			RepoName:    env.RepoName,
+			Environment: env.Environment,
+			Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issue.ID), "driftCount": strconv.Itoa(driftCount)},
+		})
+
 		slog.Info("Drift issue created successfully",
 			"issue_id", issue.ID,
 			"issue_url", issue.WebURL,
@@ -342,11 +966,49 @@ func (d *DriftServiceImpl) HandleThresholdBreach(ctx context.Context, env Enviro
 			return fmt.Errorf("failed to store issue URL: %w", err)
 		}
 
+		err = d.storage.SetField(ctx, env.Key, "issueProjectID", strconv.Itoa(projectID))
+		if err != nil {
+			slog.Error("Failed to store issue project ID", "error", err, "repo", env.RepoName, "environment", env.Environment)
+			return fmt.Errorf("failed to store issue project ID: %w", err)
+		}
+
+		// Track issue age for escalation
+		err = d.storage.SetField(ctx, env.Key, "issueCreatedAt", time.Now().Format(time.RFC3339))
+		if err != nil {
+			slog.Error("Failed to store issue creation time", "error", err, "repo", env.RepoName, "environment", env.Environment)
+			return fmt.Errorf("failed to store issue creation time: %w", err)
+		}
+
+		err = d.storage.SetField(ctx, env.Key, "escalated", "")
+		if err != nil {
+			slog.Error("Failed to reset escalation state", "error", err, "repo", env.RepoName, "environment", env.Environment)
+			return fmt.Errorf("failed to reset escalation state: %w", err)
+		}
+
+		d.resetBreachCount(ctx, env.Key)
+
+		if err := d.storage.AddOpenIssue(ctx, env.Key); err != nil {
+			slog.Warn("Failed to index open issue", "error", err, "key", env.Key)
+		}
+		d.recordIssueOwner(ctx, env, projectID, issue.ID)
+		d.storePlanHash(ctx, env, planHash(planOutput))
+		d.notifyDriftThrottled(ctx, env, driftCount, thresholdValue, issue.WebURL, false)
 	}
 
 	return nil
 }
 
+// detectionLabels returns the label recording whether a scheduled or manual run created the issue
+func (d *DriftServiceImpl) detectionLabels(env EnvironmentInfo) []string {
+	if !d.config.DetectionLabels {
+		return nil
+	}
+	if env.Scheduled {
+		return []string{"detection:scheduled"}
+	}
+	return []string{"detection:manual"}
+}
+
 // ResetDriftIncrement resets drift counter and handles issue cleanup
 func (d *DriftServiceImpl) ResetDriftIncrement(ctx context.Context, env EnvironmentInfo, operation string) error {
 	// Reset drift counter
@@ -356,6 +1018,24 @@ func (d *DriftServiceImpl) ResetDriftIncrement(ctx context.Context, env Environm
 		return fmt.Errorf("failed to reset drift: %w", err)
 	}
 	slog.Info("Drift counter reset successfully", "key", env.Key)
+	d.auditor.Record(ctx, audit.Entry{
+		Action:      audit.ActionDriftReset,
+		RepoName:    env.RepoName,
+		Environment: env.Environment,
+		Details:     map[string]string{"operation": operation},
+	})
+
+	// Breaches only count towards an issue while the drift persists
+	d.resetBreachCount(ctx, env.Key)
+	d.resetNotificationThrottle(ctx, env.Key)
+	d.clearChangedResources(ctx, env.Key)
+	d.clearPlanSummary(ctx, env.Key)
+
+	// Time the drift from its first breach for the closing comment and MTTR
+	driftDuration := d.recordDriftDuration(ctx, env)
+
+	// Drift that resolves on its own honours its acknowledgement
+	d.endAcknowledgement(ctx, env.Key)
 
 	// Check for existing open issue that needs to be closed
 	slog.Debug("Checking for existing issue to close", "key", env.Key)
@@ -380,14 +1060,29 @@ func (d *DriftServiceImpl) ResetDriftIncrement(ctx context.Context, env Environm
 		return nil // Invalid issue ID
 	}
 
-	projectID, err := strconv.Atoi(env.ProjectID)
+	projectID, err := d.storedIssueProject(ctx, env)
 	if err != nil {
 		slog.Error("Invalid project ID format during issue cleanup", "error", err, "repo", env.RepoName, "environment", env.Environment)
 		return fmt.Errorf("invalid project ID: %w", err)
 	}
 
-	// Check if issue is still open
+	// A stale or shared reference must not close another environment's issue
+	owned, err := d.ownsIssue(ctx, env, projectID, issueID)
+	if err != nil {
+		slog.Error("Failed to check issue owner", "error", err, "repo", env.RepoName, "environment", env.Environment)
+		return err
+	}
+	if !owned {
+		slog.Warn("Issue belongs to another environment, skipping close and clearing the stale reference",
+			"issue_id", issueID,
+			"project_id", projectID,
+			"repo", env.RepoName,
+			"environment", env.Environment,
+		)
+		return d.clearIssueReference(ctx, env)
+	}
 
+	// Check if issue is still open
 	isOpen, err := d.issueTracker.GetIssueStatus(ctx, projectID, issueID)
 	if err != nil {
 		slog.Error("Failed to check issue status", "error", err, "repo", env.RepoName, "environment", env.Environment)
@@ -403,28 +1098,95 @@ func (d *DriftServiceImpl) ResetDriftIncrement(ctx context.Context, env Environm
 		)
 
 		// Close the issue
-		err = d.issueTracker.CloseIssue(ctx, projectID, issueID, operation)
+		err = d.issueTracker.CloseIssue(ctx, projectID, issueID, operation, driftDuration)
 		if err != nil {
 			slog.Error("Failed to delete issue", "error", err, "repo", env.RepoName, "environment", env.Environment)
 			return fmt.Errorf("failed to delete issue: %w", err)
 		}
 
 		slog.Info("Issue deleted successfully", "issue_id", issueID)
-
-		// Clear issue details from Redis
-		err = d.storage.SetField(ctx, env.Key, "issueID", "")
-		if err != nil {
-			slog.Error("Failed to clear issue ID from Redis", "error", err, "repo", env.RepoName, "environment", env.Environment)
-			return fmt.Errorf("failed to clear issue ID: %w", err)
+		d.auditor.Record(ctx, audit.Entry{
+			Action:      audit.ActionIssueClose,
+			RepoName:    env.RepoName,
+			Environment: env.Environment,
+			Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issueID), "operation": operation},
+		})
+
+		if err := d.clearIssueReference(ctx, env); err != nil {
+			return err
 		}
+		d.clearIssueOwner(ctx, env, projectID, issueID)
+	}
 
-		err = d.storage.SetField(ctx, env.Key, "issueURL", "")
-		if err != nil {
-			slog.Error("Failed to clear issue URL from Redis", "error", err, "repo", env.RepoName, "environment", env.Environment)
-			return fmt.Errorf("failed to clear issue URL: %w", err)
-		}
+	return nil
+}
+
+// refreshRetention slides the environment's expiry forward by the tier's retention, along with
+// the reverse index of its open issue
+func (d *DriftServiceImpl) refreshRetention(ctx context.Context, key, tier string) {
+	ttl := d.retentionFor(tier)
+	if ttl <= 0 {
+		return
+	}
+	if err := d.storage.Expire(ctx, key, ttl); err != nil {
+		slog.Warn("Failed to refresh environment retention", "error", err, "key", key, "ttl", ttl)
+	}
+	d.refreshIssueOwner(ctx, key, ttl)
+}
 
+// retentionFor returns how long an environment of the tier is kept after its last report, falling
+// back to ENVIRONMENT_TTL when the tier has no retention of its own; zero keeps it
+func (d *DriftServiceImpl) retentionFor(tier string) time.Duration {
+	retention := d.config.RetentionNonprod
+	if slices.Contains(prodTiers, strings.ToLower(tier)) {
+		retention = d.config.RetentionProd
 	}
+	if retention > 0 {
+		return retention
+	}
+	return d.config.EnvironmentTTL
+}
 
+// planHash returns a fingerprint of the plan output for detecting unchanged plans
+func planHash(planOutput string) string {
+	sum := sha256.Sum256([]byte(planOutput))
+	return hex.EncodeToString(sum[:])
+}
+
+// storePlanHash records the hash of the plan shown in the issue when unchanged plans are skipped
+func (d *DriftServiceImpl) storePlanHash(ctx context.Context, env EnvironmentInfo, hash string) {
+	if !d.config.SkipUnchangedPlan {
+		return
+	}
+	if err := d.storage.SetField(ctx, env.Key, "planHash", hash); err != nil {
+		slog.Warn("Failed to store plan hash", "error", err, "key", env.Key)
+	}
+}
+
+// clearIssueReference removes the environment's issue details and its open-issue index entry
+func (d *DriftServiceImpl) clearIssueReference(ctx context.Context, env EnvironmentInfo) error {
+	if err := d.storage.SetField(ctx, env.Key, "issueID", ""); err != nil {
+		slog.Error("Failed to clear issue ID from Redis", "error", err, "repo", env.RepoName, "environment", env.Environment)
+		return fmt.Errorf("failed to clear issue ID: %w", err)
+	}
+
+	if err := d.storage.SetField(ctx, env.Key, "issueURL", ""); err != nil {
+		slog.Error("Failed to clear issue URL from Redis", "error", err, "repo", env.RepoName, "environment", env.Environment)
+		return fmt.Errorf("failed to clear issue URL: %w", err)
+	}
+
+	if err := d.storage.RemoveOpenIssue(ctx, env.Key); err != nil {
+		slog.Warn("Failed to remove issue from open issue index", "error", err, "key", env.Key)
+	}
 	return nil
 }
+
+// storedIssueProject returns the project the environment's current issue was filed in,
+// falling back to ProjectID for issues created before the override existed
+func (d *DriftServiceImpl) storedIssueProject(ctx context.Context, env EnvironmentInfo) (int, error) {
+	issueProjectID, err := d.storage.GetField(ctx, env.Key, "issueProjectID")
+	if err != nil || issueProjectID == "" {
+		issueProjectID = env.ProjectID
+	}
+	return strconv.Atoi(issueProjectID)
+}
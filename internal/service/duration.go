@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// recordFirstBreach stores when the environment's drift first crossed the threshold, per
+// TRACK_DRIFT_DURATION; later breaches of the same drift keep the original time
+func (d *DriftServiceImpl) recordFirstBreach(ctx context.Context, env EnvironmentInfo) {
+	if !d.config.TrackDriftDuration {
+		return
+	}
+
+	if firstBreachAt, _ := d.storage.GetField(ctx, env.Key, "firstBreachAt"); firstBreachAt != "" {
+		return
+	}
+	if err := d.storage.SetField(ctx, env.Key, "firstBreachAt", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record first threshold breach", "error", err, "key", env.Key)
+	}
+}
+
+// recordDriftDuration computes how long resolved drift lasted since its first breach, storing it as
+// lastDriftDurationSeconds and emitting it for MTTR. It returns zero when the drift never breached
+// the threshold or the first breach was not recorded.
+func (d *DriftServiceImpl) recordDriftDuration(ctx context.Context, env EnvironmentInfo) time.Duration {
+	if !d.config.TrackDriftDuration {
+		return 0
+	}
+
+	firstBreachAt, _ := d.storage.GetField(ctx, env.Key, "firstBreachAt")
+	if firstBreachAt == "" {
+		return 0
+	}
+
+	// Clear the breach first so the next drift is timed from its own breach
+	if err := d.storage.SetField(ctx, env.Key, "firstBreachAt", ""); err != nil {
+		slog.Warn("Failed to clear first threshold breach", "error", err, "key", env.Key)
+	}
+
+	breachedAt, err := time.Parse(time.RFC3339, firstBreachAt)
+	if err != nil {
+		slog.Warn("Invalid first threshold breach time, skipping drift duration", "error", err, "key", env.Key, "first_breach_at", firstBreachAt)
+		return 0
+	}
+	duration := max(time.Since(breachedAt), 0)
+
+	seconds := int64(duration / time.Second)
+	if err := d.storage.SetField(ctx, env.Key, "lastDriftDurationSeconds", strconv.FormatInt(seconds, 10)); err != nil {
+		slog.Warn("Failed to store drift duration", "error", err, "key", env.Key)
+	}
+	d.metrics.Histogram("drift.resolution_seconds", float64(seconds), metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+
+	slog.Info("Drift resolved",
+		"key", env.Key,
+		"first_breach_at", firstBreachAt,
+		"drift_duration", duration.Round(time.Second),
+	)
+	return duration
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+	"drift-guardian/internal/config"
+	"drift-guardian/internal/repository"
+)
+
+// EscalationChecker escalates drift issues that stay open beyond the configured window
+type EscalationChecker struct {
+	storage      repository.StorageRepository
+	issueTracker client.IssueTracker
+	config       *config.Config
+	auditor      audit.Logger
+}
+
+// NewEscalationChecker creates a new escalation checker instance
+func NewEscalationChecker(
+	storage repository.StorageRepository,
+	issueTracker client.IssueTracker,
+	cfg *config.Config,
+) *EscalationChecker {
+	return &EscalationChecker{
+		storage:      storage,
+		issueTracker: issueTracker,
+		config:       cfg,
+		auditor:      audit.NopLogger{},
+	}
+}
+
+// WithAuditLogger records escalations to auditor
+func (e *EscalationChecker) WithAuditLogger(auditor audit.Logger) *EscalationChecker {
+	e.auditor = auditor
+	return e
+}
+
+// Start runs escalation checks on the configured interval until the context is cancelled
+func (e *EscalationChecker) Start(ctx context.Context) {
+	slog.Info("Escalation checker started",
+		"escalation_after", e.config.EscalationAfter,
+		"check_interval", e.config.EscalationCheckInterval,
+		"label", e.config.EscalationLabel,
+	)
+
+	ticker := time.NewTicker(e.config.EscalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Escalation checker stopped")
+			return
+		case <-ticker.C:
+			escalated, err := e.CheckEscalations(ctx)
+			if err != nil {
+				slog.Error("Escalation check failed", "error", err)
+				continue
+			}
+			slog.Debug("Escalation check completed", "escalated", escalated)
+		}
+	}
+}
+
+// CheckEscalations scans open drift issues once and escalates those past the window
+func (e *EscalationChecker) CheckEscalations(ctx context.Context) (int, error) {
+	// Escalations wait out a change freeze; issues still overdue afterwards escalate on the next check
+	if window, active := e.config.ActiveFreeze(time.Now()); active {
+		slog.Info("Change freeze active, deferring escalations", "freeze_end", window.End)
+		return 0, nil
+	}
+
+	keys, err := e.storage.ListOpenIssues(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list open issues: %w", err)
+	}
+
+	escalated := 0
+	for _, key := range keys {
+		done, err := e.checkEnvironment(ctx, key)
+		if err != nil {
+			slog.Warn("Failed to check environment for escalation", "error", err, "key", key)
+			continue
+		}
+		if done {
+			escalated++
+		}
+	}
+
+	return escalated, nil
+}
+
+// checkEnvironment escalates a single environment's issue if it is due and reports whether it did
+func (e *EscalationChecker) checkEnvironment(ctx context.Context, key string) (bool, error) {
+	data, err := e.storage.GetEnvironmentData(ctx, key)
+	if errors.Is(err, repository.ErrEnvironmentNotFound) {
+		// Retention expired the environment, and its issue reference with it
+		slog.Warn("Environment expired with an open issue, removing from open issue index", "key", key)
+		return false, e.storage.RemoveOpenIssue(ctx, key)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	issueID, err := strconv.Atoi(data["issueID"])
+	if err != nil || issueID <= 0 {
+		slog.Debug("Environment has no issue, removing from open issue index", "key", key)
+		return false, e.storage.RemoveOpenIssue(ctx, key)
+	}
+
+	// Skip issues that have already been escalated
+	if data["escalated"] == "true" {
+		return false, nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, data["issueCreatedAt"])
+	if err != nil {
+		return false, fmt.Errorf("invalid issue creation time %q: %w", data["issueCreatedAt"], err)
+	}
+
+	openFor := time.Since(createdAt)
+	if openFor < e.config.EscalationAfter {
+		return false, nil
+	}
+
+	projectID, err := strconv.Atoi(issueProjectFromData(data))
+	if err != nil {
+		return false, fmt.Errorf("invalid project ID: %w", err)
+	}
+
+	isOpen, err := e.issueTracker.GetIssueStatus(ctx, projectID, issueID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check issue status: %w", err)
+	}
+
+	if !isOpen {
+		slog.Debug("Issue no longer open, removing from open issue index", "key", key, "issue_id", issueID)
+		return false, e.storage.RemoveOpenIssue(ctx, key)
+	}
+
+	annotator, ok := e.issueTracker.(client.IssueAnnotator)
+	if !ok {
+		return false, nil
+	}
+
+	// Claim the escalation before notifying so concurrent checkers and retries never repeat it
+	claimed, err := e.storage.SetFieldIfEmpty(ctx, key, "escalated", "true")
+	if err != nil {
+		return false, fmt.Errorf("failed to record escalation: %w", err)
+	}
+	if !claimed {
+		slog.Debug("Escalation already claimed, skipping", "key", key, "issue_id", issueID)
+		return false, nil
+	}
+
+	slog.Warn("Escalating drift issue open beyond escalation window",
+		"key", key,
+		"issue_id", issueID,
+		"project_id", projectID,
+		"open_for", openFor.Round(time.Minute).String(),
+	)
+
+	if err := e.notify(ctx, annotator, projectID, issueID); err != nil {
+		// Release the claim so a later check can retry the escalation
+		if releaseErr := e.storage.SetField(ctx, key, "escalated", ""); releaseErr != nil {
+			slog.Error("Failed to release escalation claim", "error", releaseErr, "key", key)
+		}
+		return false, err
+	}
+
+	repoName, environment := environmentNames(key, data)
+	e.auditor.Record(audit.WithPrincipal(ctx, audit.SystemPrincipal), audit.Entry{
+		Action:      audit.ActionIssueEscalate,
+		RepoName:    repoName,
+		Environment: environment,
+		Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issueID), "openFor": openFor.Round(time.Minute).String()},
+	})
+
+	slog.Info("Drift issue escalated", "key", key, "issue_id", issueID)
+	return true, nil
+}
+
+// notify applies the escalation label and posts the escalation comment
+func (e *EscalationChecker) notify(ctx context.Context, annotator client.IssueAnnotator, projectID, issueID int) error {
+	err := annotator.AddIssueLabels(ctx, projectID, issueID, []string{e.config.EscalationLabel})
+	if err != nil {
+		return fmt.Errorf("failed to add escalation label: %w", err)
+	}
+
+	err = annotator.AddIssueComment(ctx, projectID, issueID, fmt.Sprintf(
+		"**Drift Escalated** - This drift issue has been open for more than %s without being resolved. Escalated automatically by Drift Guardian.",
+		e.config.EscalationAfter))
+	if err != nil {
+		return fmt.Errorf("failed to add escalation comment: %w", err)
+	}
+
+	return nil
+}
+
+// issueProjectFromData returns the project an environment's issue was filed in, which may
+// differ from the project the environment belongs to
+func issueProjectFromData(data map[string]string) string {
+	if data["issueProjectID"] != "" {
+		return data["issueProjectID"]
+	}
+	return data["projectID"]
+}
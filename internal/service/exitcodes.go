@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"drift-guardian/internal/repository"
+)
+
+// recordExitCode adds the operation's exit code to the environment's series per EXIT_CODE_SERIES_LENGTH.
+// The series only feeds trend charts, so a failure to record it does not fail the report.
+func (d *DriftServiceImpl) recordExitCode(ctx context.Context, payload Payload, key, timestamp string) {
+	if d.config.ExitCodeSeriesLength <= 0 {
+		return
+	}
+
+	recordedAt, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		recordedAt = time.Now()
+	}
+
+	point := repository.ExitCodePoint{Timestamp: recordedAt.UTC(), Operation: payload.Operation, ExitCode: payload.ExitCode}
+	if err := d.storage.RecordExitCode(ctx, key, point, d.config.ExitCodeSeriesLength); err != nil {
+		slog.Warn("Failed to record exit code", "error", err, "key", key, "operation", payload.Operation)
+	}
+}
+
+// GetExitCodeSeries returns the recorded exit codes of an environment's operations, oldest first
+func (d *DriftServiceImpl) GetExitCodeSeries(ctx context.Context, repoName, environment string) (*ExitCodeSeries, error) {
+	if d.config.ExitCodeSeriesLength <= 0 {
+		return nil, ErrExitCodeSeriesDisabled
+	}
+
+	// The series is read-only, so it can be served by a read replica
+	ctx = repository.WithReplicaReads(ctx)
+	key := d.lookupEnvironmentKey(ctx, repoName, environment)
+
+	points, err := d.storage.ListExitCodes(ctx, key)
+	if err != nil {
+		slog.Error("Failed to list exit codes", "error", err, "repo", repoName, "environment", environment)
+		return nil, fmt.Errorf("failed to list exit codes: %w", err)
+	}
+
+	// An empty series is only reported for environments that exist
+	if len(points) == 0 {
+		if _, err := d.storage.GetEnvironmentData(ctx, key); errors.Is(err, repository.ErrEnvironmentNotFound) {
+			return nil, ErrEnvironmentNotFound
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get environment data: %w", err)
+		}
+		points = []repository.ExitCodePoint{}
+	}
+
+	return &ExitCodeSeries{RepoName: repoName, Environment: environment, Points: points}, nil
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+)
+
+// changeFreezeActive reports whether a FREEZE_WINDOWS change freeze is in effect, logging that the
+// breach goes unreported. Drift keeps counting, so the first breach after the freeze files the issue.
+func (d *DriftServiceImpl) changeFreezeActive(env EnvironmentInfo, driftCount int) bool {
+	window, active := d.config.ActiveFreeze(time.Now())
+	if !active {
+		return false
+	}
+
+	slog.Warn("Change freeze active, suppressing drift issue management",
+		"key", env.Key,
+		"drift_count", driftCount,
+		"freeze_start", window.Start,
+		"freeze_end", window.End,
+		"repo", env.RepoName,
+		"environment", env.Environment,
+	)
+	d.metrics.Count("issue.frozen", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+	return true
+}
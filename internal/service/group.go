@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+
+	"drift-guardian/internal/repository"
+)
+
+// groupAggregator combines the drift counts of a group's members into the group's drift count
+type groupAggregator func(counts []int) int
+
+// groupAggregators are the supported GROUP_AGGREGATION values
+var groupAggregators = map[string]groupAggregator{
+	"any": maxDrift, // The group drifts when any member does, reporting the most drifted member
+	"sum": sumDrift, // The group's drift is the total of its members'
+}
+
+// maxDrift returns the largest member drift count
+func maxDrift(counts []int) int {
+	result := 0
+	for _, count := range counts {
+		result = max(result, count)
+	}
+	return result
+}
+
+// sumDrift returns the total of the member drift counts
+func sumDrift(counts []int) int {
+	result := 0
+	for _, count := range counts {
+		result += count
+	}
+	return result
+}
+
+// recordGroupMembership adds the environment to every configured group whose patterns match
+// repoName/environment; failures are logged and the report is still processed
+func (d *DriftServiceImpl) recordGroupMembership(ctx context.Context, repoName, environment, key string) {
+	member := repoName + "/" + environment
+	for group, patterns := range d.config.EnvironmentGroups {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, member); !matched {
+				continue
+			}
+			if err := d.storage.AddGroupMember(ctx, group, key); err != nil {
+				slog.Warn("Failed to record environment group membership", "error", err, "group", group, "key", key)
+			}
+			break
+		}
+	}
+}
+
+// GetGroupDrift aggregates the drift of an environment group's members; an empty aggregation uses the configured default
+func (d *DriftServiceImpl) GetGroupDrift(ctx context.Context, group, aggregation string) (*GroupDrift, error) {
+	if _, configured := d.config.EnvironmentGroups[group]; !configured {
+		return nil, ErrGroupNotFound
+	}
+
+	if aggregation == "" {
+		aggregation = d.config.GroupAggregation
+	}
+	if aggregation == "" {
+		aggregation = "any"
+	}
+	aggregate, ok := groupAggregators[aggregation]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAggregation, aggregation)
+	}
+
+	// The report is read-only, so it can be served by a read replica
+	ctx = repository.WithReplicaReads(ctx)
+
+	keys, err := d.storage.ListGroupMembers(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	sort.Strings(keys)
+
+	members := make([]GroupMember, 0, len(keys))
+	counts := make([]int, 0, len(keys))
+	for _, key := range keys {
+		data, err := d.storage.GetEnvironmentData(ctx, key)
+		if errors.Is(err, repository.ErrEnvironmentNotFound) {
+			// Members whose state expired no longer report for the group
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get environment data: %w", err)
+		}
+
+		driftCount, _ := strconv.Atoi(data["driftIncrement"])
+		repoName, environment := environmentNames(key, data)
+		members = append(members, GroupMember{
+			RepoName:       repoName,
+			Environment:    environment,
+			DriftIncrement: driftCount,
+			IssueURL:       data["issueURL"],
+		})
+		counts = append(counts, driftCount)
+	}
+
+	driftCount := aggregate(counts)
+	return &GroupDrift{
+		Group:       group,
+		Aggregation: aggregation,
+		Drifted:     driftCount > 0,
+		DriftCount:  driftCount,
+		Members:     members,
+	}, nil
+}
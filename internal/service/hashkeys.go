@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+
+	"drift-guardian/internal/repository"
+)
+
+// hashedKeyPrefix marks environment keys hashed with HASH_KEYS
+const hashedKeyPrefix = "sha256:"
+
+// nameIndexKey is the hash mapping environment names to their hashed keys. The prefix keeps it
+// out of the environment keyspace, where it would read as repo "index", environment "names".
+const nameIndexKey = "drift-guardian:index:names"
+
+// hashedKey returns the fixed-length key of an environment name, bounding key size for
+// arbitrarily long repo and environment names
+func hashedKey(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hashedKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// environmentKey returns the key of an environment looked up by name, carrying over state stored
+// under an earlier key format. With HASH_KEYS, environments already stored under their hashed key
+// are found through the name index without probing the earlier formats.
+func (d *DriftServiceImpl) environmentKey(ctx context.Context, repoName, environment string) string {
+	if d.config.HashKeys {
+		key, err := d.storage.GetField(ctx, nameIndexKey, environmentName(repoName, environment))
+		if err == nil && key != "" {
+			return key
+		}
+	}
+
+	key := d.GenerateKey(repoName, environment)
+	d.migrateLegacyKey(ctx, repoName, environment, key)
+	return key
+}
+
+// lookupEnvironmentKey returns the key an environment's state is stored under for read-only
+// lookups. Unlike environmentKey it never migrates state, so state still stored under an earlier
+// key format is read where it is; the current key is returned when nothing is stored.
+func (d *DriftServiceImpl) lookupEnvironmentKey(ctx context.Context, repoName, environment string) string {
+	if d.config.HashKeys {
+		key, err := d.storage.GetField(ctx, nameIndexKey, environmentName(repoName, environment))
+		if err == nil && key != "" {
+			return key
+		}
+	}
+
+	key := d.GenerateKey(repoName, environment)
+	previousKeys := d.previousKeys(repoName, environment)
+	if len(previousKeys) == 0 {
+		return key
+	}
+	if _, err := d.storage.GetEnvironmentData(ctx, key); !errors.Is(err, repository.ErrEnvironmentNotFound) {
+		return key // Stored under the current key, or storage is unavailable
+	}
+	for _, oldKey := range previousKeys {
+		if _, err := d.storage.GetEnvironmentData(ctx, oldKey); err == nil {
+			return oldKey
+		}
+	}
+	return key
+}
+
+// recordEnvironmentName stores the environment's name in its hashed key's state and in the name
+// index; failures are logged, leaving the environment to be found by recomputing its key
+func (d *DriftServiceImpl) recordEnvironmentName(ctx context.Context, repoName, environment, key string) {
+	fields := map[string]string{"repoName": repoName, "environment": environment}
+	for field, value := range fields {
+		if err := d.storage.SetField(ctx, key, field, value); err != nil {
+			slog.Warn("Failed to record environment name", "error", err, "key", key, "field", field)
+		}
+	}
+
+	if err := d.storage.SetField(ctx, nameIndexKey, environmentName(repoName, environment), key); err != nil {
+		slog.Warn("Failed to index environment name", "error", err, "key", key)
+	}
+}
+
+// environmentNames returns the repo name and environment of stored state, read from the
+// recorded name fields for hashed keys
+func environmentNames(key string, data map[string]string) (string, string) {
+	if data["repoName"] != "" {
+		return data["repoName"], data["environment"]
+	}
+	return splitKey(key)
+}
@@ -2,39 +2,158 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"drift-guardian/internal/repository"
 )
 
+// ErrEnvironmentNotFound is returned when no state is stored for the requested environment
+var ErrEnvironmentNotFound = errors.New("environment not found")
+
+// ErrInvalidAcknowledgement is returned when an acknowledgement request is malformed
+var ErrInvalidAcknowledgement = errors.New("invalid acknowledgement")
+
+// ErrGroupNotFound is returned when the requested environment group is not configured
+var ErrGroupNotFound = errors.New("environment group not found")
+
+// ErrInvalidAggregation is returned when the requested group aggregation is unknown
+var ErrInvalidAggregation = errors.New("invalid group aggregation")
+
+// ErrProjectMismatch is returned when VERIFY_PROJECT=true and the payload's projectId belongs to another repository
+var ErrProjectMismatch = errors.New("projectId does not belong to the reported repository")
+
+// ErrExitCodeSeriesDisabled is returned when exit code series are requested without EXIT_CODE_SERIES_LENGTH
+var ErrExitCodeSeriesDisabled = errors.New("exit code series is not enabled")
+
+// ErrStalenessDisabled is returned when stale environments are requested without STALE_AFTER
+var ErrStalenessDisabled = errors.New("staleness tracking is not enabled")
+
 // Payload represents the JSON structure expected in the environment endpoint
 type Payload struct {
+	RepoName        string            `json:"repoName"`
+	Branch          string            `json:"branchName"`
+	RefType         string            `json:"refType,omitempty"` // Whether branchName is a branch or a tag; empty means branch
+	Environment     string            `json:"environment"`
+	EnvironmentTier string            `json:"environmentTier"`
+	DriftThreshold  string            `json:"driftThreshold"`
+	ProjectID       string            `json:"projectId"`
+	IssueProjectID  string            `json:"issueProjectId,omitempty"` // Project that receives drift issues; defaults to ProjectID
+	Operation       string            `json:"operation"`
+	MergeRequestIID string            `json:"mergeRequestIid,omitempty"` // Merge request decorated with preview results
+	ExitCode        int               `json:"exitCode"`
+	Scheduled       bool              `json:"scheduled"`
+	Timestamp       string            `json:"timestamp"`
+	PlanOutput      string            `json:"planOutput,omitempty"`
+	CommitSHA       string            `json:"commitSha,omitempty"`      // Commit that was planned
+	StateLockError  bool              `json:"stateLockError,omitempty"` // Terraform failed to acquire the state lock
+	Metadata        map[string]string `json:"metadata,omitempty"`       // CI-provided labels such as team or region
+	DriftWeight     int               `json:"driftWeight,omitempty"`    // Criticality of the drifted resources; zero counts as 1
+	Replayed        bool              `json:"replayed,omitempty"`       // Resent from the CLI backlog after a failed delivery
+	PipelineSource  string            `json:"pipelineSource,omitempty"` // What triggered the CI run, e.g. schedule, push or web
+	InitFailed      bool              `json:"initFailed,omitempty"`     // AUTO_INIT's terraform init failed, so the operation never ran
+	CommitAuthor    string            `json:"commitAuthor,omitempty"`   // Commit author of an apply, "Name <email>", recorded with APPLY_AUTHOR
+	PlanChecksum    string            `json:"planChecksum,omitempty"`   // SHA256 of the drifted plan, computed by the CLI with PLAN_CHECKSUM
+	ResourceLabel   string            `json:"resourceLabel,omitempty"`  // Issue label for the dominant drifted resource type, from the CLI's DRIFT_RESOURCE_LABELS
+	Heartbeat       bool              `json:"heartbeat,omitempty"`      // Only marks the environment as seen, sent by the CLI with SEND_HEARTBEAT
+}
+
+// DriftResult represents the result of drift detection processing
+type DriftResult struct {
+	EnvironmentTier  string            `json:"environmentTier"`
+	ProjectID        string            `json:"projectID"`
+	DriftIncrement   string            `json:"driftIncrement"`
+	IssueID          string            `json:"issueID"`
+	IssueURL         string            `json:"issueURL"`
+	Log              map[string]string `json:"log"`
+	LastError        string            `json:"lastError,omitempty"`
+	LastErrorAt      string            `json:"lastErrorTimestamp,omitempty"`
+	CommitSHA        string            `json:"commitSha,omitempty"`
+	ComparisonBranch string            `json:"comparisonBranch,omitempty"` // Branch drift is measured against
+	AckUntil         string            `json:"ackUntil,omitempty"`
+	AckResolveBy     string            `json:"ackResolveBy,omitempty"`
+	FirstBreachAt    string            `json:"firstBreachAt,omitempty"`            // When the current drift first crossed the threshold
+	DriftDuration    string            `json:"lastDriftDurationSeconds,omitempty"` // How long the last resolved drift lasted
+	LastPlanErrorAt  string            `json:"lastPlanErrorAt,omitempty"`          // When a comparison-branch plan last failed; recorded with PLAN_ERROR_ISSUES
+	PlanErrorIssueID string            `json:"planErrorIssueID,omitempty"`         // Issue filed for a failed comparison-branch plan
+	ChangedResources string            `json:"changedResources,omitempty"`         // Resources changed by the latest drifted plan; recorded with DRIFT_RESOURCES_HEADER
+	PlanChecksum     string            `json:"planChecksum,omitempty"`             // Checksum of the latest drifted plan, when the CLI reports one
+	DriftUnchanged   bool              `json:"driftUnchanged,omitempty"`           // The latest drifted plan matches the previous one's checksum
+	LastSuccess      string            `json:"lastSuccessfulProcessing,omitempty"` // When a report was last processed without error; recorded with STALE_AFTER
+	LastSeen         string            `json:"lastSeen,omitempty"`                 // When the CLI last sent a heartbeat; recorded with STALE_AFTER
+}
+
+// Acknowledgement silences drift issue updates for an environment until AckUntil. If ResolveBy is
+// set and drift persists past it, the issue is escalated; drift that resolves first ends the
+// acknowledgement silently.
+type Acknowledgement struct {
+	RepoName    string     `json:"repoName"`
+	Environment string     `json:"environment"`
+	AckUntil    time.Time  `json:"ackUntil"`
+	ResolveBy   *time.Time `json:"resolveBy,omitempty"`
+}
+
+// GroupMember is the drift state of one environment in an environment group
+type GroupMember struct {
+	RepoName       string `json:"repoName"`
+	Environment    string `json:"environment"`
+	DriftIncrement int    `json:"driftIncrement"`
+	IssueURL       string `json:"issueURL,omitempty"`
+}
+
+// GroupDrift is the aggregate drift of the environments reporting for one logical environment
+type GroupDrift struct {
+	Group       string        `json:"group"`
+	Aggregation string        `json:"aggregation"`
+	Drifted     bool          `json:"drifted"`
+	DriftCount  int           `json:"driftCount"`
+	Members     []GroupMember `json:"members"`
+}
+
+// ExitCodeSeries is an environment's operation exit codes over time, oldest first
+type ExitCodeSeries struct {
+	RepoName    string                     `json:"repoName"`
+	Environment string                     `json:"environment"`
+	Points      []repository.ExitCodePoint `json:"points"`
+}
+
+// StaleEnvironment is an environment whose last successful report is older than STALE_AFTER
+type StaleEnvironment struct {
 	RepoName        string `json:"repoName"`
-	Branch          string `json:"branchName"`
 	Environment     string `json:"environment"`
 	EnvironmentTier string `json:"environmentTier"`
-	DriftThreshold  string `json:"driftThreshold"`
-	ProjectID       string `json:"projectId"`
-	Operation       string `json:"operation"`
-	ExitCode        int    `json:"exitCode"`
-	Scheduled       bool   `json:"scheduled"`
-	Timestamp       string `json:"timestamp"`
-	PlanOutput      string `json:"planOutput,omitempty"`
+	LastSuccess     string `json:"lastSuccessfulProcessing,omitempty"`
+	LastSeen        string `json:"lastSeen,omitempty"`
 }
 
-// DriftResult represents the result of drift detection processing
-type DriftResult struct {
-	EnvironmentTier string            `json:"environmentTier"`
-	ProjectID       string            `json:"projectID"`
-	DriftIncrement  string            `json:"driftIncrement"`
-	IssueID         string            `json:"issueID"`
-	IssueURL        string            `json:"issueURL"`
-	Log             map[string]string `json:"log"`
+// EnvironmentStats summarizes stored environments, listing the stale ones longest silent first
+type EnvironmentStats struct {
+	Environments int                `json:"environments"`
+	StaleAfter   string             `json:"staleAfter"`
+	Stale        []StaleEnvironment `json:"stale"`
 }
 
 // EnvironmentInfo contains environment identification data
 type EnvironmentInfo struct {
-	RepoName    string
-	Environment string
-	ProjectID   string
-	Key         string
+	RepoName        string
+	Environment     string
+	EnvironmentTier string
+	ProjectID       string
+	IssueProjectID  string // Project that receives drift issues; empty means ProjectID
+	Key             string
+	Scheduled       bool   // Whether the detecting run was scheduled
+	PipelineSource  string // What triggered the detecting run; empty when the CLI did not report it
+	DriftWeight     int    // Weight of the detecting run; zero when unknown
+	DriftUnchanged  bool   // The detecting run's plan checksum matches the previous drifted run's
+	ResourceLabel   string // Label routing the issue by the detecting run's dominant drifted resource type
+}
+
+// issueProject returns the project drift issues are filed in
+func (e EnvironmentInfo) issueProject() string {
+	if e.IssueProjectID != "" {
+		return e.IssueProjectID
+	}
+	return e.ProjectID
 }
 
 // DriftService defines the core business logic interface for drift detection
@@ -42,6 +161,21 @@ type DriftService interface {
 	// ProcessDriftDetection handles the complete drift detection workflow
 	ProcessDriftDetection(ctx context.Context, payload Payload) (*DriftResult, error)
 
+	// GetEnvironmentState returns the stored state of an environment without modifying it
+	GetEnvironmentState(ctx context.Context, repoName, environment string) (*DriftResult, error)
+
+	// AcknowledgeDrift records an acknowledgement of an environment's drift
+	AcknowledgeDrift(ctx context.Context, ack Acknowledgement) error
+
+	// GetGroupDrift aggregates the drift of an environment group's members; an empty aggregation uses the configured default
+	GetGroupDrift(ctx context.Context, group, aggregation string) (*GroupDrift, error)
+
+	// GetExitCodeSeries returns the recorded exit codes of an environment's operations, oldest first
+	GetExitCodeSeries(ctx context.Context, repoName, environment string) (*ExitCodeSeries, error)
+
+	// GetEnvironmentStats scans stored environments for those that have not reported successfully within STALE_AFTER
+	GetEnvironmentStats(ctx context.Context) (*EnvironmentStats, error)
+
 	// ValidatePayload ensures payload contains all required fields
 	ValidatePayload(payload *Payload) error
 
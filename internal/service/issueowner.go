@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// issueOwnerField holds the environment key that created an issue
+const issueOwnerField = "environmentKey"
+
+// IssueOwnerKey creates the Redis key for the reverse index from an issue to its environment.
+// Like digest keys it has two separators, so it cannot collide with an environment key.
+func IssueOwnerKey(projectID, issueID int) string {
+	return fmt.Sprintf("issue:%d:%d", projectID, issueID)
+}
+
+// recordIssueOwner points the issue's reverse index at the environment that created it, expiring
+// it with the environment under the tier's retention
+func (d *DriftServiceImpl) recordIssueOwner(ctx context.Context, env EnvironmentInfo, projectID, issueID int) {
+	if err := d.storage.SetField(ctx, IssueOwnerKey(projectID, issueID), issueOwnerField, env.Key); err != nil {
+		slog.Warn("Failed to record issue owner", "error", err, "key", env.Key, "issue_id", issueID)
+		return
+	}
+	if ttl := d.retentionFor(env.EnvironmentTier); ttl > 0 {
+		if err := d.storage.Expire(ctx, IssueOwnerKey(projectID, issueID), ttl); err != nil {
+			slog.Warn("Failed to set issue owner retention", "error", err, "key", env.Key, "issue_id", issueID)
+		}
+	}
+}
+
+// refreshIssueOwner slides the retention of the reverse index of the environment's open issue
+// forward with the environment's own, so the index does not outlive it
+func (d *DriftServiceImpl) refreshIssueOwner(ctx context.Context, key string, ttl time.Duration) {
+	data, err := d.storage.GetEnvironmentData(ctx, key)
+	if err != nil {
+		return
+	}
+	projectID, err := strconv.Atoi(issueProjectFromData(data))
+	if err != nil {
+		return
+	}
+	issueID, err := strconv.Atoi(data["issueID"])
+	if err != nil {
+		return
+	}
+
+	ownerKey := IssueOwnerKey(projectID, issueID)
+	owner, err := d.storage.GetField(ctx, ownerKey, issueOwnerField)
+	if err != nil || owner != key {
+		return
+	}
+	if err := d.storage.Expire(ctx, ownerKey, ttl); err != nil {
+		slog.Warn("Failed to refresh issue owner retention", "error", err, "key", key, "issue_id", issueID)
+	}
+}
+
+// clearIssueOwner removes the issue's reverse index once the issue is closed
+func (d *DriftServiceImpl) clearIssueOwner(ctx context.Context, env EnvironmentInfo, projectID, issueID int) {
+	if err := d.storage.Expire(ctx, IssueOwnerKey(projectID, issueID), 0); err != nil {
+		slog.Warn("Failed to clear issue owner", "error", err, "key", env.Key, "issue_id", issueID)
+	}
+}
+
+// ownsIssue reports whether the issue's reverse index points back to the environment. Issues
+// created before the index existed have no owner and are treated as owned.
+func (d *DriftServiceImpl) ownsIssue(ctx context.Context, env EnvironmentInfo, projectID, issueID int) (bool, error) {
+	owner, err := d.storage.GetField(ctx, IssueOwnerKey(projectID, issueID), issueOwnerField)
+	if err != nil {
+		return false, fmt.Errorf("failed to get issue owner: %w", err)
+	}
+	return owner == "" || owner == env.Key, nil
+}
+
+// moveIssueOwner points the reverse index of an environment's open issue at key once its state
+// has moved there from oldKey; an index pointing at another environment is left alone
+func (d *DriftServiceImpl) moveIssueOwner(ctx context.Context, data map[string]string, oldKey, key string) {
+	projectID, err := strconv.Atoi(issueProjectFromData(data))
+	if err != nil {
+		return
+	}
+	issueID, err := strconv.Atoi(data["issueID"])
+	if err != nil {
+		return
+	}
+
+	ownerKey := IssueOwnerKey(projectID, issueID)
+	owner, err := d.storage.GetField(ctx, ownerKey, issueOwnerField)
+	if err != nil || owner != oldKey {
+		return
+	}
+	if err := d.storage.SetField(ctx, ownerKey, issueOwnerField, key); err != nil {
+		slog.Warn("Failed to move issue owner", "error", err, "previous_key", oldKey, "key", key, "issue_id", issueID)
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxMetadataEntries limits how many metadata entries a payload may carry
+const maxMetadataEntries = 20
+
+// maxMetadataValueLength limits metadata values, which become part of GitLab label names
+const maxMetadataValueLength = 100
+
+// metadataKeyPattern restricts metadata keys to characters that form a valid GitLab label scope
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,49}$`)
+
+// validateMetadata checks that every metadata entry can be rendered as a key::value GitLab label
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataEntries {
+		return fmt.Errorf("at most %d entries are allowed", maxMetadataEntries)
+	}
+
+	for key, value := range metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("key %q must start with a letter or digit and contain only letters, digits, '_', '.' or '-'", key)
+		}
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("value for key %q must not be empty", key)
+		}
+		if len(value) > maxMetadataValueLength {
+			return fmt.Errorf("value for key %q exceeds maximum length of %d characters", key, maxMetadataValueLength)
+		}
+		// Commas separate GitLab labels and pipes or line breaks would break the issue table
+		if strings.ContainsAny(value, ",|\r\n") || strings.Contains(value, "::") {
+			return fmt.Errorf("value for key %q must not contain ',', '|', '::' or line breaks", key)
+		}
+	}
+
+	return nil
+}
+
+// storeMetadata records the payload metadata as JSON; empty metadata clears the previous value
+func (d *DriftServiceImpl) storeMetadata(ctx context.Context, key string, metadata map[string]string) error {
+	encoded := ""
+	if len(metadata) > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		encoded = string(data)
+	}
+	return d.storage.SetField(ctx, key, "metadata", encoded)
+}
+
+// storedMetadata returns the metadata recorded with the environment's latest detection
+func (d *DriftServiceImpl) storedMetadata(ctx context.Context, key string) map[string]string {
+	encoded, err := d.storage.GetField(ctx, key, "metadata")
+	if err != nil || encoded == "" {
+		return nil
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(encoded), &metadata); err != nil {
+		slog.Warn("Ignoring malformed stored metadata", "error", err, "key", key)
+		return nil
+	}
+	return metadata
+}
+
+// metadataLabels returns key::value scoped labels for the metadata when METADATA_LABELS is set
+func (d *DriftServiceImpl) metadataLabels(metadata map[string]string) []string {
+	if !d.config.MetadataLabels {
+		return nil
+	}
+
+	labels := make([]string, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		labels = append(labels, key+"::"+strings.TrimSpace(metadata[key]))
+	}
+	return labels
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"drift-guardian/internal/client"
+)
+
+// WithNotifier announces threshold breaches through notifier after the drift issue is created or
+// updated; without one, breaches are only tracked in issues
+func (d *DriftServiceImpl) WithNotifier(notifier client.Notifier) *DriftServiceImpl {
+	d.notifier = notifier
+	return d
+}
+
+// notifyDrift announces a breach tracked by the issue at issueURL, read from the environment's stored
+// issue when empty; failures are logged so a chat outage does not fail the report
+func (d *DriftServiceImpl) notifyDrift(ctx context.Context, env EnvironmentInfo, driftCount, threshold int, issueURL string, updated bool) {
+	if d.notifier == nil {
+		return
+	}
+	if issueURL == "" {
+		issueURL, _ = d.storage.GetField(ctx, env.Key, "issueURL")
+	}
+
+	err := d.notifier.NotifyDrift(ctx, client.DriftNotification{
+		RepoName:    env.RepoName,
+		Environment: env.Environment,
+		DriftCount:  driftCount,
+		Threshold:   threshold,
+		IssueURL:    issueURL,
+		Updated:     updated,
+	})
+	if err != nil {
+		slog.Warn("Failed to send drift notification", "error", err, "repo", env.RepoName, "environment", env.Environment)
+		d.metrics.Count("notification.failed", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+	"drift-guardian/internal/repository"
+)
+
+// overviewFieldPrefix marks overview hash fields that hold an environment's drift count, keyed by environment name
+const overviewFieldPrefix = "env:"
+
+// OverviewKey creates the Redis key for a project's Drift Overview. The prefix keeps it out of the
+// environment keyspace, where "overview:<projectID>" would be the key of repo "overview".
+func OverviewKey(projectID int) string {
+	return "drift-guardian:overview:" + strconv.Itoa(projectID)
+}
+
+// updateProjectOverview records the environment's drift count in its project's overview and creates
+// or updates the Drift Overview issue per PROJECT_OVERVIEW_ISSUE. The overview is informational, so
+// failures are logged without failing the report.
+func (d *DriftServiceImpl) updateProjectOverview(ctx context.Context, payload Payload, key, driftIncrement string) {
+	if !d.config.ProjectOverview {
+		return
+	}
+
+	gitlabClient, ok := d.issueTracker.(*client.GitLabClient)
+	if !ok {
+		return
+	}
+
+	env := EnvironmentInfo{ProjectID: payload.ProjectID, IssueProjectID: payload.IssueProjectID}
+	projectID, err := strconv.Atoi(env.issueProject())
+	if err != nil {
+		slog.Warn("Invalid project ID, skipping drift overview", "project_id", env.issueProject(), "key", key)
+		return
+	}
+	overviewKey := OverviewKey(projectID)
+
+	overviewData, err := d.storage.GetEnvironmentData(ctx, overviewKey)
+	if errors.Is(err, repository.ErrEnvironmentNotFound) {
+		overviewData = map[string]string{} // The first report for the project starts the overview
+	} else if err != nil {
+		slog.Warn("Failed to get drift overview", "error", err, "overview_key", overviewKey)
+		return
+	}
+
+	// Only a changed count needs the issue rewritten
+	name := environmentName(payload.RepoName, payload.Environment)
+	issueID, _ := strconv.Atoi(overviewData["issueID"])
+	if issueID > 0 && overviewData[overviewFieldPrefix+name] == driftIncrement {
+		return
+	}
+
+	overviewData[overviewFieldPrefix+name] = driftIncrement
+
+	var environments []client.DigestEntry
+	for field, value := range overviewData {
+		envName, ok := strings.CutPrefix(field, overviewFieldPrefix)
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			slog.Warn("Invalid overview drift count, skipping", "field", field, "value", value, "overview_key", overviewKey)
+			continue
+		}
+		repoName, environment := splitKey(envName)
+		environments = append(environments, client.DigestEntry{RepoName: repoName, Environment: environment, DriftCount: count})
+	}
+
+	if err := d.writeOverviewIssue(ctx, gitlabClient, projectID, issueID, overviewKey, environments); err != nil {
+		slog.Warn("Failed to write overview issue", "error", err, "overview_key", overviewKey)
+		return
+	}
+
+	// The count is recorded once the issue shows it, so a failed write is retried on the next report
+	if err := d.storage.SetField(ctx, overviewKey, overviewFieldPrefix+name, driftIncrement); err != nil {
+		slog.Warn("Failed to record environment in drift overview", "error", err, "overview_key", overviewKey)
+	}
+}
+
+// writeOverviewIssue updates the project's overview issue while it is open, and creates a new one when
+// there is none or it was closed
+func (d *DriftServiceImpl) writeOverviewIssue(ctx context.Context, gitlabClient *client.GitLabClient, projectID, issueID int, overviewKey string, environments []client.DigestEntry) error {
+	if issueID > 0 {
+		isOpen, err := d.issueTracker.GetIssueStatus(ctx, projectID, issueID)
+		if err != nil {
+			return fmt.Errorf("failed to check overview issue status: %w", err)
+		}
+
+		if isOpen {
+			return gitlabClient.UpdateOverviewIssue(ctx, projectID, issueID, environments)
+		}
+
+		slog.Info("Overview issue is closed, will create new overview issue", "issue_id", issueID)
+	}
+
+	issue, err := gitlabClient.CreateOverviewIssue(ctx, projectID, environments)
+	if err != nil {
+		return fmt.Errorf("failed to create overview issue: %w", err)
+	}
+	d.auditor.Record(ctx, audit.Entry{
+		Action:  audit.ActionIssueCreate,
+		Details: map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issue.ID), "issueType": "overview"},
+	})
+
+	slog.Info("Overview issue created successfully", "issue_id", issue.ID, "issue_url", issue.WebURL, "overview_key", overviewKey)
+
+	if err := d.storage.SetField(ctx, overviewKey, "issueID", strconv.Itoa(issue.ID)); err != nil {
+		return fmt.Errorf("failed to store overview issue ID: %w", err)
+	}
+	return nil
+}
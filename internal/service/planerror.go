@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+)
+
+// isPlanError reports whether the payload is a comparison-branch plan that failed outright. Such a
+// plan is a terraform error, e.g. broken configuration or credentials, and says nothing about drift.
+func (d *DriftServiceImpl) isPlanError(payload Payload) bool {
+	return payload.Operation == "plan" && payload.ExitCode == 1 && d.onComparisonRef(payload)
+}
+
+// recordPlanError surfaces a failed comparison-branch plan without touching the drift counter. With
+// PLAN_ERROR_ISSUES an open drift issue is labelled plan-error; otherwise a separate plan-error
+// issue is filed, once per outage.
+func (d *DriftServiceImpl) recordPlanError(ctx context.Context, payload Payload, key string) error {
+	slog.Warn("Terraform plan failed on the comparison branch, skipping drift counting",
+		"key", key,
+		"exit_code", payload.ExitCode,
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+		"branch", payload.Branch,
+	)
+	d.metrics.Count("plan.error", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+
+	if !d.config.PlanErrorIssues {
+		return nil
+	}
+
+	if err := d.storage.SetField(ctx, key, "lastPlanErrorAt", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record plan error time", "error", err, "key", key)
+	}
+
+	gitlabClient, ok := d.issueTracker.(*client.GitLabClient)
+	if !ok {
+		return nil
+	}
+
+	env := EnvironmentInfo{
+		RepoName:        payload.RepoName,
+		Environment:     payload.Environment,
+		EnvironmentTier: payload.EnvironmentTier,
+		ProjectID:       payload.ProjectID,
+		IssueProjectID:  payload.IssueProjectID,
+		Key:             key,
+	}
+
+	// An open drift issue already has responders' attention, so it is labelled rather than joined by another issue
+	if issueIDStr, _ := d.storage.GetField(ctx, key, "issueID"); issueIDStr != "" {
+		if issueID, err := strconv.Atoi(issueIDStr); err == nil && issueID > 0 {
+			projectID, err := d.storedIssueProject(ctx, env)
+			if err != nil {
+				slog.Warn("Invalid issue project ID, skipping plan error label", "error", err, "key", key)
+				return nil
+			}
+
+			// The label is advisory, so a failure to apply it must not fail the report
+			if err := gitlabClient.AddIssueLabels(ctx, projectID, issueID, []string{client.PlanErrorLabel}); err != nil {
+				slog.Warn("Failed to add plan error label", "error", err, "key", key, "issue_id", issueID)
+				return nil
+			}
+			slog.Info("Drift issue labelled as plan-error", "key", key, "issue_id", issueID)
+			return nil
+		}
+	}
+
+	return d.ensurePlanErrorIssue(ctx, gitlabClient, env, payload)
+}
+
+// ensurePlanErrorIssue files a plan-error issue unless the environment's previous one is still open
+func (d *DriftServiceImpl) ensurePlanErrorIssue(ctx context.Context, gitlabClient *client.GitLabClient, env EnvironmentInfo, payload Payload) error {
+	data, err := d.storage.GetEnvironmentData(ctx, env.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	if issueID, err := strconv.Atoi(data["planErrorIssueID"]); err == nil && issueID > 0 {
+		projectID, err := strconv.Atoi(data["planErrorIssueProjectID"])
+		if err == nil {
+			isOpen, err := gitlabClient.GetIssueStatus(ctx, projectID, issueID)
+			if err != nil {
+				return fmt.Errorf("failed to check plan error issue status: %w", err)
+			}
+			if isOpen {
+				slog.Info("Plan error issue already open", "key", env.Key, "issue_id", issueID)
+				return nil
+			}
+		}
+	}
+
+	projectID, err := strconv.Atoi(env.issueProject())
+	if err != nil {
+		return fmt.Errorf("invalid issue project ID: %w", err)
+	}
+
+	planOutput := payload.PlanOutput
+	if d.config.StripANSI {
+		planOutput = StripANSI(planOutput)
+	}
+
+	issue, err := gitlabClient.CreatePlanErrorIssue(ctx, projectID, client.DriftDetails{
+		RepoName:         env.RepoName,
+		Environment:      env.Environment,
+		PlanOutput:       planOutput,
+		CommitSHA:        payload.CommitSHA,
+		ComparisonBranch: payload.Branch,
+		ComparisonRef:    payload.RefType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create plan error issue: %w", err)
+	}
+	d.auditor.Record(ctx, audit.Entry{
+		Action:      audit.ActionIssueCreate,
+		RepoName:    env.RepoName,
+		Environment: env.Environment,
+		Details:     map[string]string{"projectID": strconv.Itoa(projectID), "issueID": strconv.Itoa(issue.ID), "issueType": "planError"},
+	})
+
+	for field, value := range map[string]string{
+		"planErrorIssueID":        strconv.Itoa(issue.ID),
+		"planErrorIssueProjectID": strconv.Itoa(projectID),
+	} {
+		if err := d.storage.SetField(ctx, env.Key, field, value); err != nil {
+			return fmt.Errorf("failed to store plan error issue: %w", err)
+		}
+	}
+
+	slog.Info("Plan error issue created", "key", env.Key, "issue_id", issue.ID, "issue_url", issue.WebURL)
+	return nil
+}
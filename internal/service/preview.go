@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"drift-guardian/internal/client"
+)
+
+// previewRetention is how long a feature-branch preview result is kept before it expires
+const previewRetention = 7 * 24 * time.Hour
+
+// PreviewKey creates the Redis key for a branch's preview result of an environment.
+// It has more separators than environment and digest keys, so it cannot collide with them.
+func PreviewKey(key, branch string) string {
+	return "preview:" + key + ":" + keyComponentEscaper.Replace(branch)
+}
+
+// isPreview reports whether the payload is a feature-branch plan recorded as a preview
+func (d *DriftServiceImpl) isPreview(payload Payload) bool {
+	return d.config.PreviewMode && payload.Operation == "plan" && !d.onComparisonRef(payload)
+}
+
+// recordPreview stores a feature-branch plan result for PR decoration without touching the drift counter
+func (d *DriftServiceImpl) recordPreview(ctx context.Context, payload Payload, key string) error {
+	previewKey := PreviewKey(key, payload.Branch)
+
+	slog.Info("Recording feature-branch plan preview",
+		"preview_key", previewKey,
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+		"branch", payload.Branch,
+		"exit_code", payload.ExitCode,
+	)
+
+	planOutput := payload.PlanOutput
+	if d.config.StripANSI {
+		planOutput = StripANSI(planOutput)
+	}
+
+	fields := []struct{ name, value string }{
+		{"branch", payload.Branch},
+		{"exitCode", strconv.Itoa(payload.ExitCode)},
+		{"driftDetected", strconv.FormatBool(payload.ExitCode == 2)},
+		{"timestamp", payload.Timestamp},
+		{"planOutput", planOutput},
+	}
+	for _, field := range fields {
+		if err := d.storage.SetField(ctx, previewKey, field.name, field.value); err != nil {
+			slog.Error("Failed to store preview result", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+			return fmt.Errorf("failed to store preview result: %w", err)
+		}
+	}
+
+	// Previews are only useful while the branch is under review
+	if err := d.storage.Expire(ctx, previewKey, previewRetention); err != nil {
+		slog.Warn("Failed to set preview expiry", "error", err, "preview_key", previewKey)
+	}
+
+	// Decoration is best effort, so a failed note never fails the run
+	if payload.MergeRequestIID != "" {
+		if err := d.decorateMergeRequest(ctx, payload, planOutput); err != nil {
+			slog.Warn("Failed to decorate merge request", "error", err, "repo", payload.RepoName, "merge_request_iid", payload.MergeRequestIID)
+		}
+	}
+
+	return nil
+}
+
+// decorateMergeRequest posts the preview plan summary as a note on the payload's merge request
+func (d *DriftServiceImpl) decorateMergeRequest(ctx context.Context, payload Payload, planOutput string) error {
+	gitlabClient, ok := d.issueTracker.(*client.GitLabClient)
+	if !ok {
+		return fmt.Errorf("merge request notes require the gitlab issue provider")
+	}
+
+	projectID, err := strconv.Atoi(payload.ProjectID)
+	if err != nil {
+		return fmt.Errorf("invalid project ID: %w", err)
+	}
+
+	mergeRequestIID, err := strconv.Atoi(payload.MergeRequestIID)
+	if err != nil {
+		return fmt.Errorf("invalid merge request IID: %w", err)
+	}
+
+	slog.Info("Decorating merge request with plan preview",
+		"project_id", projectID,
+		"merge_request_iid", mergeRequestIID,
+		"environment", payload.Environment,
+	)
+
+	return gitlabClient.AddPlanPreviewNote(ctx, projectID, mergeRequestIID, payload.Environment, payload.Branch, payload.ExitCode == 2, planOutput)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"drift-guardian/internal/client"
+)
+
+// verifyProject checks per VERIFY_PROJECT that the payload's projectId belongs to the reported
+// repository, catching CI that files drift against another project. A mismatch is logged with warn
+// and rejected with true. It reports whether the project was newly verified, so the environment can
+// skip the lookup on later reports; lookups that fail let the report through.
+func (d *DriftServiceImpl) verifyProject(ctx context.Context, payload Payload, key string) (bool, error) {
+	if d.config.VerifyProject != "warn" && d.config.VerifyProject != "true" {
+		return false, nil
+	}
+
+	gitlabClient, ok := d.issueTracker.(*client.GitLabClient)
+	if !ok {
+		return false, nil
+	}
+
+	if verified, _ := d.storage.GetField(ctx, key, "verifiedProjectID"); verified == payload.ProjectID {
+		return false, nil
+	}
+
+	var project *client.Project
+	projectID, err := strconv.Atoi(payload.ProjectID)
+	if err == nil {
+		project, err = gitlabClient.GetProject(ctx, projectID)
+		if err != nil {
+			slog.Warn("Failed to look up project, skipping project verification", "error", err, "project_id", projectID, "repo", payload.RepoName)
+			return false, nil
+		}
+	}
+
+	if project != nil && projectMatchesRepo(project, payload.RepoName) {
+		return true, nil
+	}
+
+	path := ""
+	if project != nil {
+		path = project.PathWithNamespace
+	}
+	slog.Warn("Project ID does not belong to the reported repository",
+		"project_id", payload.ProjectID,
+		"project_path", path,
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+		"rejected", d.config.VerifyProject == "true",
+	)
+	d.metrics.Count("project.mismatch", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+
+	if d.config.VerifyProject == "true" {
+		if path == "" {
+			return false, fmt.Errorf("%w: project %q was not found", ErrProjectMismatch, payload.ProjectID)
+		}
+		return false, fmt.Errorf("%w: project %q is %s", ErrProjectMismatch, payload.ProjectID, path)
+	}
+	return false, nil
+}
+
+// projectMatchesRepo reports whether the project's path contains the repository name, ignoring case;
+// the CI project name may differ from its path slug, so an exact name match is also accepted
+func projectMatchesRepo(project *client.Project, repoName string) bool {
+	return strings.Contains(strings.ToLower(project.PathWithNamespace), strings.ToLower(repoName)) ||
+		strings.EqualFold(project.Name, repoName)
+}
+
+// recordVerifiedProject remembers the project ID verified for the environment
+func (d *DriftServiceImpl) recordVerifiedProject(ctx context.Context, payload Payload, key string) {
+	if err := d.storage.SetField(ctx, key, "verifiedProjectID", payload.ProjectID); err != nil {
+		slog.Warn("Failed to store verified project ID", "error", err, "key", key)
+	}
+}
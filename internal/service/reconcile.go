@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"drift-guardian/internal/client"
+	"drift-guardian/internal/config"
+	"drift-guardian/internal/metrics"
+	"drift-guardian/internal/repository"
+)
+
+// ReconcileResult summarizes a single reconciliation pass
+type ReconcileResult struct {
+	Checked  int
+	Dangling int
+}
+
+// IssueReconciler clears stored issue references whose issue was deleted or closed outside Drift Guardian
+type IssueReconciler struct {
+	storage      repository.StorageRepository
+	issueTracker client.IssueTracker
+	metrics      metrics.Recorder
+	config       *config.Config
+}
+
+// NewIssueReconciler creates a new issue reconciler instance
+func NewIssueReconciler(
+	storage repository.StorageRepository,
+	issueTracker client.IssueTracker,
+	recorder metrics.Recorder,
+	cfg *config.Config,
+) *IssueReconciler {
+	return &IssueReconciler{
+		storage:      storage,
+		issueTracker: issueTracker,
+		metrics:      recorder,
+		config:       cfg,
+	}
+}
+
+// Start runs reconciliation on the configured interval until the context is cancelled
+func (r *IssueReconciler) Start(ctx context.Context) {
+	slog.Info("Issue reconciler started", "interval", r.config.IssueReconcileInterval)
+
+	ticker := time.NewTicker(r.config.IssueReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Issue reconciler stopped")
+			return
+		case <-ticker.C:
+			result, err := r.Reconcile(ctx)
+			if err != nil {
+				slog.Error("Issue reconciliation failed", "error", err)
+				continue
+			}
+			slog.Debug("Issue reconciliation completed", "checked", result.Checked, "dangling", result.Dangling)
+		}
+	}
+}
+
+// Reconcile verifies every indexed issue once and clears references to issues that are no longer open
+func (r *IssueReconciler) Reconcile(ctx context.Context) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	keys, err := r.storage.ListOpenIssues(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list open issues: %w", err)
+	}
+
+	statuses := r.prefetchIssueStatuses(ctx, keys)
+	for _, key := range keys {
+		dangling, err := r.reconcileEnvironment(ctx, key, statuses)
+		if err != nil {
+			slog.Warn("Failed to reconcile environment issue", "error", err, "key", key)
+			continue
+		}
+		result.Checked++
+		if dangling {
+			result.Dangling++
+		}
+	}
+
+	r.metrics.Count("issue.reconciled", int64(result.Checked), nil)
+	r.metrics.Count("issue.dangling", int64(result.Dangling), nil)
+
+	return result, nil
+}
+
+// issueRef identifies an issue across projects
+type issueRef struct {
+	projectID int
+	issueID   int
+}
+
+// prefetchIssueStatuses checks the issues of all keys with batched list requests, per
+// ISSUE_STATUS_BATCH_SIZE. Issues it could not check are left out and checked one at a time.
+func (r *IssueReconciler) prefetchIssueStatuses(ctx context.Context, keys []string) map[issueRef]bool {
+	gitlabClient, ok := r.issueTracker.(*client.GitLabClient)
+	if r.config.IssueStatusBatchSize <= 0 || !ok {
+		return nil
+	}
+
+	issuesByProject := make(map[int][]int)
+	for _, key := range keys {
+		data, err := r.storage.GetEnvironmentData(ctx, key)
+		if err != nil {
+			continue
+		}
+		issueID, err := strconv.Atoi(data["issueID"])
+		if err != nil || issueID <= 0 {
+			continue
+		}
+		projectID, err := strconv.Atoi(issueProjectFromData(data))
+		if err != nil {
+			continue
+		}
+		issuesByProject[projectID] = append(issuesByProject[projectID], issueID)
+	}
+
+	statuses := make(map[issueRef]bool)
+	for projectID, issueIDs := range issuesByProject {
+		projectStatuses, err := gitlabClient.GetIssueStatuses(ctx, projectID, issueIDs, r.config.IssueStatusBatchSize)
+		if err != nil {
+			slog.Warn("Failed to batch check issue statuses, checking them one at a time", "error", err, "project_id", projectID)
+			continue
+		}
+		for issueID, isOpen := range projectStatuses {
+			statuses[issueRef{projectID: projectID, issueID: issueID}] = isOpen
+		}
+	}
+	return statuses
+}
+
+// reconcileEnvironment checks a single environment's issue and reports whether its reference was
+// dangling, using a prefetched status when there is one
+func (r *IssueReconciler) reconcileEnvironment(ctx context.Context, key string, statuses map[issueRef]bool) (bool, error) {
+	data, err := r.storage.GetEnvironmentData(ctx, key)
+	if errors.Is(err, repository.ErrEnvironmentNotFound) {
+		// Retention expired the environment, and its issue reference with it
+		slog.Warn("Environment expired with an open issue, removing from open issue index", "key", key)
+		return false, r.storage.RemoveOpenIssue(ctx, key)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	issueID, err := strconv.Atoi(data["issueID"])
+	if err != nil || issueID <= 0 {
+		slog.Debug("Environment has no issue, removing from open issue index", "key", key)
+		return false, r.storage.RemoveOpenIssue(ctx, key)
+	}
+
+	projectID, err := strconv.Atoi(issueProjectFromData(data))
+	if err != nil {
+		return false, fmt.Errorf("invalid project ID: %w", err)
+	}
+
+	// Deleted issues report as not open, just like closed ones
+	isOpen, prefetched := statuses[issueRef{projectID: projectID, issueID: issueID}]
+	if !prefetched {
+		isOpen, err = r.issueTracker.GetIssueStatus(ctx, projectID, issueID)
+		if err != nil {
+			return false, fmt.Errorf("failed to check issue status: %w", err)
+		}
+	}
+	if isOpen {
+		return false, nil
+	}
+
+	slog.Warn("Clearing dangling issue reference",
+		"key", key,
+		"issue_id", issueID,
+		"project_id", projectID,
+	)
+
+	if err := r.storage.SetField(ctx, key, "issueID", ""); err != nil {
+		return false, fmt.Errorf("failed to clear issue ID: %w", err)
+	}
+	if err := r.storage.SetField(ctx, key, "issueURL", ""); err != nil {
+		return false, fmt.Errorf("failed to clear issue URL: %w", err)
+	}
+	if err := r.storage.RemoveOpenIssue(ctx, key); err != nil {
+		return false, fmt.Errorf("failed to remove issue from open issue index: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"drift-guardian/internal/repository"
+)
+
+// staleReplay reports whether a report replayed from the CLI backlog is no newer than the
+// environment's latest recorded operation. Applying it would roll drift back to an outdated state,
+// e.g. recount drift that a later apply already resolved, so it is acknowledged without effect.
+func (d *DriftServiceImpl) staleReplay(ctx context.Context, payload Payload, key string) bool {
+	if !payload.Replayed {
+		return false
+	}
+
+	stored, _ := d.storage.GetField(ctx, key, "log")
+	var latest repository.OperationLogEntry
+	if err := json.Unmarshal([]byte(stored), &latest); err != nil {
+		return false
+	}
+	latestAt, err := time.Parse(time.RFC3339, latest.Timestamp)
+	if err != nil {
+		return false
+	}
+
+	reportedAt, _ := time.Parse(time.RFC3339, payload.Timestamp) // Checked by ValidatePayload
+	if reportedAt.After(latestAt) {
+		slog.Info("Replaying missed report", "key", key, "reported_at", payload.Timestamp, "operation", payload.Operation)
+		return false
+	}
+
+	slog.Info("Ignoring replayed report older than the latest recorded operation",
+		"key", key,
+		"reported_at", payload.Timestamp,
+		"latest_operation_at", latest.Timestamp,
+		"operation", payload.Operation,
+	)
+	d.metrics.Count("report.stale", 1, metricTags(payload.RepoName, payload.Environment, payload.EnvironmentTier))
+	return true
+}
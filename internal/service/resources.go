@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"drift-guardian/internal/client"
+)
+
+// planSummaryPattern matches the summary line of a terraform plan, e.g. "Plan: 1 to add, 2 to change,
+// 0 to destroy."; plans with imports list them first, which the pattern skips
+var planSummaryPattern = regexp.MustCompile(`(\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+// resourceChangePattern matches the heading terraform prints above each resource a plan changes, e.g.
+// "  # aws_instance.web will be updated in-place"; deposed objects are listed under their resource's address
+var resourceChangePattern = regexp.MustCompile(`(?m)^\s*# (.+?)(?: \(deposed object \w+\))? (will be created|will be updated in-place|will be destroyed|must be replaced|will be replaced, as requested|is tainted, so must be replaced|will be imported)\s*$`)
+
+// resourceActions maps terraform's resource change headings to their action
+var resourceActions = map[string]string{
+	"will be created":                 "create",
+	"will be updated in-place":        "update",
+	"will be destroyed":               "destroy",
+	"must be replaced":                "replace",
+	"will be replaced, as requested":  "replace",
+	"is tainted, so must be replaced": "replace",
+	"will be imported":                "import",
+}
+
+// maxPlanSummaryResources bounds the resource addresses kept from a plan; the counts stay exact
+const maxPlanSummaryResources = 50
+
+// parsePlanSummary extracts the change counts and changed resource addresses from plan output,
+// reporting false when the output has no plan summary, such as a failed plan. A plan without
+// changes has an empty summary.
+func parsePlanSummary(planOutput string) (client.PlanSummary, bool) {
+	planOutput = StripANSI(planOutput)
+	if strings.Contains(planOutput, "No changes.") {
+		return client.PlanSummary{}, true
+	}
+
+	match := planSummaryPattern.FindStringSubmatch(planOutput)
+	if match == nil {
+		return client.PlanSummary{}, false
+	}
+
+	var summary client.PlanSummary
+	summary.ToAdd, _ = strconv.Atoi(match[1])
+	summary.ToChange, _ = strconv.Atoi(match[2])
+	summary.ToDestroy, _ = strconv.Atoi(match[3])
+	for _, resource := range resourceChangePattern.FindAllStringSubmatch(planOutput, maxPlanSummaryResources) {
+		summary.Resources = append(summary.Resources, client.ResourceChange{Address: resource[1], Action: resourceActions[resource[2]]})
+	}
+	return summary, true
+}
+
+// changedResources returns how many resources a plan adds, changes or destroys, reporting false when
+// the output has no plan summary to parse
+func changedResources(planOutput string) (int, bool) {
+	summary, ok := parsePlanSummary(planOutput)
+	return summary.ToAdd + summary.ToChange + summary.ToDestroy, ok
+}
+
+// recordChangedResources stores the changed resource count of the latest drifted plan per
+// DRIFT_RESOURCES_HEADER. Output without a plan summary clears it, so a stale count is never reported.
+func (d *DriftServiceImpl) recordChangedResources(ctx context.Context, key, planOutput string) {
+	if !d.config.ResourcesHeader {
+		return
+	}
+
+	value := ""
+	if count, ok := changedResources(planOutput); ok {
+		value = strconv.Itoa(count)
+	}
+	if err := d.storage.SetField(ctx, key, "changedResources", value); err != nil {
+		slog.Warn("Failed to store changed resource count", "error", err, "key", key)
+	}
+}
+
+// clearChangedResources removes the changed resource count once drift resolves
+func (d *DriftServiceImpl) clearChangedResources(ctx context.Context, key string) {
+	if !d.config.ResourcesHeader {
+		return
+	}
+	if err := d.storage.SetField(ctx, key, "changedResources", ""); err != nil {
+		slog.Warn("Failed to clear changed resource count", "error", err, "key", key)
+	}
+}
+
+// planSummaryFields lists the fields the latest drifted plan's summary is stored in with PLAN_SUMMARY
+var planSummaryFields = []string{"planToAdd", "planToChange", "planToDestroy", "planResources"}
+
+// recordPlanSummary stores the latest drifted plan's change counts and changed resources per
+// PLAN_SUMMARY. Output without a plan summary clears them, so a stale summary is never shown.
+func (d *DriftServiceImpl) recordPlanSummary(ctx context.Context, key, planOutput string) {
+	if !d.config.PlanSummary {
+		return
+	}
+
+	values := map[string]string{}
+	if summary, ok := parsePlanSummary(planOutput); ok {
+		values["planToAdd"] = strconv.Itoa(summary.ToAdd)
+		values["planToChange"] = strconv.Itoa(summary.ToChange)
+		values["planToDestroy"] = strconv.Itoa(summary.ToDestroy)
+		if len(summary.Resources) > 0 {
+			encoded, _ := json.Marshal(summary.Resources) // Marshalling a slice of string structs cannot fail
+			values["planResources"] = string(encoded)
+		}
+	}
+	for _, field := range planSummaryFields {
+		if err := d.storage.SetField(ctx, key, field, values[field]); err != nil {
+			slog.Warn("Failed to store plan summary", "error", err, "key", key, "field", field)
+		}
+	}
+}
+
+// storedPlanSummary returns the summary of the latest drifted plan for its issue, or nil without
+// PLAN_SUMMARY or when the plan had no summary
+func (d *DriftServiceImpl) storedPlanSummary(ctx context.Context, key string) *client.PlanSummary {
+	if !d.config.PlanSummary {
+		return nil
+	}
+
+	data, err := d.storage.GetEnvironmentData(ctx, key)
+	if err != nil || data["planToAdd"] == "" {
+		return nil
+	}
+
+	var summary client.PlanSummary
+	summary.ToAdd, _ = strconv.Atoi(data["planToAdd"])
+	summary.ToChange, _ = strconv.Atoi(data["planToChange"])
+	summary.ToDestroy, _ = strconv.Atoi(data["planToDestroy"])
+	if data["planResources"] != "" {
+		if err := json.Unmarshal([]byte(data["planResources"]), &summary.Resources); err != nil {
+			slog.Warn("Ignoring malformed stored plan resources", "error", err, "key", key)
+		}
+	}
+	return &summary
+}
+
+// clearPlanSummary removes the plan summary once drift resolves
+func (d *DriftServiceImpl) clearPlanSummary(ctx context.Context, key string) {
+	if !d.config.PlanSummary {
+		return
+	}
+	for _, field := range planSummaryFields {
+		if err := d.storage.SetField(ctx, key, field, ""); err != nil {
+			slog.Warn("Failed to clear plan summary", "error", err, "key", key, "field", field)
+		}
+	}
+}
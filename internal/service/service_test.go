@@ -3,16 +3,222 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"drift-guardian/internal/audit"
+	"drift-guardian/internal/client"
+	"drift-guardian/internal/config"
+	"drift-guardian/internal/metrics"
+	"drift-guardian/internal/repository"
 )
 
+// MockIssueTracker is a mock implementation of IssueTracker
+type MockIssueTracker struct {
+	mock.Mock
+}
+
+func (m *MockIssueTracker) CreateIssue(ctx context.Context, projectID int, title, description string) (*client.Issue, error) {
+	args := m.Called(ctx, projectID, title, description)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*client.Issue), args.Error(1)
+}
+
+func (m *MockIssueTracker) CloseIssue(ctx context.Context, projectID, issueID int, operation string, driftDuration time.Duration) error {
+	args := m.Called(ctx, projectID, issueID, operation, driftDuration)
+	return args.Error(0)
+}
+
+func (m *MockIssueTracker) GetIssueStatus(ctx context.Context, projectID, issueID int) (bool, error) {
+	args := m.Called(ctx, projectID, issueID)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockStorageRepository is a mock implementation of StorageRepository
+type MockStorageRepository struct {
+	mock.Mock
+}
+
+func (m *MockStorageRepository) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error) {
+	args := m.Called(ctx, key, tier, projectID, threshold, comparisonBranch)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorageRepository) UpdateOperationLog(ctx context.Context, key string, entry repository.OperationLogEntry) error {
+	args := m.Called(ctx, key, entry)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) IncrementDrift(ctx context.Context, key string, amount int) (int, error) {
+	args := m.Called(ctx, key, amount)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorageRepository) IncrementAndCheck(ctx context.Context, key string, amount int) (int, bool, error) {
+	args := m.Called(ctx, key, amount)
+	return args.Int(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStorageRepository) ResetDrift(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) GetEnvironmentData(ctx context.Context, key string) (map[string]string, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockStorageRepository) SetField(ctx context.Context, key, field, value string) error {
+	args := m.Called(ctx, key, field, value)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) SetFieldIfEmpty(ctx context.Context, key, field, value string) (bool, error) {
+	args := m.Called(ctx, key, field, value)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorageRepository) GetField(ctx context.Context, key, field string) (string, error) {
+	args := m.Called(ctx, key, field)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStorageRepository) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	args := m.Called(ctx, key, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) AddOpenIssue(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) RemoveOpenIssue(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) ListOpenIssues(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStorageRepository) ListEnvironments(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStorageRepository) AcquireLock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, name, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorageRepository) AddGroupMember(ctx context.Context, group, key string) error {
+	args := m.Called(ctx, group, key)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) ListGroupMembers(ctx context.Context, group string) ([]string, error) {
+	args := m.Called(ctx, group)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStorageRepository) RecordExitCode(ctx context.Context, key string, point repository.ExitCodePoint, limit int) error {
+	args := m.Called(ctx, key, point, limit)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) ListExitCodes(ctx context.Context, key string) ([]repository.ExitCodePoint, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]repository.ExitCodePoint), args.Error(1)
+}
+
+func (m *MockStorageRepository) StorePlanOutput(ctx context.Context, key, planOutput string) error {
+	args := m.Called(ctx, key, planOutput)
+	return args.Error(0)
+}
+
+func (m *MockStorageRepository) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// noopMetrics discards metrics emitted during tests
+var noopMetrics, _ = metrics.NewStatsdClient("", "")
+
+// recordingMetrics is a metrics.Recorder that keeps every emitted metric
+type recordingMetrics struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (r *recordingMetrics) Count(name string, value int64, tags []string) {
+	r.record(fmt.Sprintf("count %s %d %s", name, value, strings.Join(tags, ",")))
+}
+
+func (r *recordingMetrics) Gauge(name string, value float64, tags []string) {
+	r.record(fmt.Sprintf("gauge %s %g %s", name, value, strings.Join(tags, ",")))
+}
+
+func (r *recordingMetrics) Histogram(name string, value float64, tags []string) {
+	r.record(fmt.Sprintf("histogram %s %g %s", name, value, strings.Join(tags, ",")))
+}
+
+func (r *recordingMetrics) record(entry string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// MockThresholdManager is a mock implementation of ThresholdManager
+type MockThresholdManager struct {
+	mock.Mock
+}
+
+func (m *MockThresholdManager) CheckThreshold(ctx context.Context, key string, currentDrift int) (bool, error) {
+	args := m.Called(ctx, key, currentDrift)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockThresholdManager) GetThreshold(ctx context.Context, key string) (int, error) {
+	args := m.Called(ctx, key)
+	return args.Int(0), args.Error(1)
+}
+
 // TestPayloadValidator tests payload validation logic comprehensively
 func TestPayloadValidator(t *testing.T) {
 	// Create a minimal service instance for testing validation
-	service := &DriftServiceImpl{}
+	service := &DriftServiceImpl{config: &config.Config{}}
 
 	tests := []struct {
 		name          string
@@ -115,6 +321,134 @@ func TestPayloadValidator(t *testing.T) {
 			},
 			expectedError: "missing environment in payload",
 		},
+		{
+			name: "repoName too long",
+			payload: Payload{
+				RepoName:        strings.Repeat("r", 256),
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "repoName exceeds maximum length",
+		},
+		{
+			name: "environment too long",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     strings.Repeat("e", 256),
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "environment exceeds maximum length",
+		},
+		{
+			name: "zero driftThreshold",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				DriftThreshold:  "0",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "invalid driftThreshold in payload",
+		},
+		{
+			name: "non-numeric issueProjectId",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				IssueProjectID:  "infra-issues",
+				Operation:       "plan",
+			},
+			expectedError: "invalid issueProjectId in payload",
+		},
+		{
+			name: "unknown refType",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "v1.2.0",
+				RefType:         "release",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "invalid refType in payload",
+		},
+		{
+			name: "multi-line commitAuthor",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "apply",
+				CommitAuthor:    "Jane Doe <jane@example.com>\n# Injected heading",
+			},
+			expectedError: "invalid commitAuthor in payload",
+		},
+		{
+			name: "malformed planChecksum",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				PlanChecksum:    "not-a-checksum",
+			},
+			expectedError: "invalid planChecksum in payload",
+		},
+		{
+			name: "resourceLabel with a comma",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				ResourceLabel:   "drift:network,urgent",
+			},
+			expectedError: "invalid resourceLabel in payload",
+		},
+		{
+			name: "negative driftThreshold",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				DriftThreshold:  "-2",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "invalid driftThreshold in payload",
+		},
+		{
+			name: "non-numeric driftThreshold",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				DriftThreshold:  "three",
+				ProjectID:       "12345",
+				Operation:       "plan",
+			},
+			expectedError: "invalid driftThreshold in payload",
+		},
 		{
 			name: "missing environmentTier",
 			payload: Payload{
@@ -184,6 +518,96 @@ func TestPayloadValidator(t *testing.T) {
 			},
 			expectedError: "invalid terraform operation in payload",
 		},
+		{
+			name: "valid metadata",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				Metadata:        map[string]string{"team": "payments", "cost-center": "CC 1234"},
+			},
+			expectedError: "",
+		},
+		{
+			name: "metadata key not usable as a label scope",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				Metadata:        map[string]string{"team name": "payments"},
+			},
+			expectedError: "invalid metadata in payload",
+		},
+		{
+			name: "metadata value with a comma",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				Metadata:        map[string]string{"region": "eu,us"},
+			},
+			expectedError: "invalid metadata in payload",
+		},
+		{
+			name: "valid drift weight",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				DriftWeight:     10,
+			},
+		},
+		{
+			name: "drift weight above maximum",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				DriftWeight:     1000,
+			},
+			expectedError: "invalid driftWeight in payload",
+		},
+		{
+			name: "replayed report without timestamp",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				Replayed:        true,
+			},
+			expectedError: "invalid timestamp in payload",
+		},
+		{
+			name: "invalid pipeline source",
+			payload: Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "12345",
+				Operation:       "plan",
+				PipelineSource:  "schedule\n## Injected",
+			},
+			expectedError: "invalid pipelineSource in payload",
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,7 +625,7 @@ func TestPayloadValidator(t *testing.T) {
 
 // TestGenerateKey tests Redis key generation
 func TestGenerateKey(t *testing.T) {
-	service := &DriftServiceImpl{}
+	service := &DriftServiceImpl{config: &config.Config{}}
 
 	tests := []struct {
 		name        string
@@ -251,7 +675,7 @@ func TestGenerateKey(t *testing.T) {
 
 // TestGenerateKey_EdgeCases tests edge cases for Redis key generation
 func TestGenerateKey_EdgeCases(t *testing.T) {
-	service := &DriftServiceImpl{}
+	service := &DriftServiceImpl{config: &config.Config{}}
 
 	tests := []struct {
 		name        string
@@ -281,13 +705,19 @@ func TestGenerateKey_EdgeCases(t *testing.T) {
 			name:        "repo name with colon",
 			repoName:    "repo:with:colons",
 			environment: "prod",
-			expected:    "repo:with:colons:prod",
+			expected:    "repo%3Awith%3Acolons:prod",
 		},
 		{
 			name:        "environment with colon",
 			repoName:    "repo",
 			environment: "env:with:colons",
-			expected:    "repo:env:with:colons",
+			expected:    "repo:env%3Awith%3Acolons",
+		},
+		{
+			name:        "repo name with percent",
+			repoName:    "repo%3A",
+			environment: "prod",
+			expected:    "repo%253A:prod",
 		},
 	}
 
@@ -299,16 +729,122 @@ func TestGenerateKey_EdgeCases(t *testing.T) {
 	}
 }
 
-// TestProjectIDConversion tests project ID string to int conversion used in service layer
-func TestProjectIDConversion(t *testing.T) {
-	tests := []struct {
-		name         string
-		projectIDStr string
-		expected     int
-		expectError  bool
+// TestGenerateKey_NoCollisions tests that distinct inputs never produce the same key
+func TestGenerateKey_NoCollisions(t *testing.T) {
+	service := &DriftServiceImpl{config: &config.Config{}}
+
+	pairs := []struct {
+		name string
+		a    [2]string
+		b    [2]string
 	}{
 		{
-			name:         "valid project ID",
+			name: "colon moved between repo and environment",
+			a:    [2]string{"repo:with", "prod"},
+			b:    [2]string{"repo", "with:prod"},
+		},
+		{
+			name: "escaped colon in input",
+			a:    [2]string{"repo%3Aa", "prod"},
+			b:    [2]string{"repo:a", "prod"},
+		},
+		{
+			name: "empty components",
+			a:    [2]string{"", "a:b"},
+			b:    [2]string{"a", ":b"},
+		},
+	}
+
+	for _, tt := range pairs {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA := service.GenerateKey(tt.a[0], tt.a[1])
+			keyB := service.GenerateKey(tt.b[0], tt.b[1])
+			assert.NotEqual(t, keyA, keyB, "Different inputs must not generate the same key")
+		})
+	}
+
+	t.Run("never produces internal index keys", func(t *testing.T) {
+		assert.NotEqual(t, "drift-guardian:index:open-issues", service.GenerateKey("drift-guardian:index", "open-issues"))
+		assert.NotEqual(t, "drift-guardian:index:open-issues", service.GenerateKey("drift-guardian", "index:open-issues"))
+	})
+}
+
+// TestMigrateLegacyKey tests that state stored under the unescaped key format is carried over
+func TestMigrateLegacyKey(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("moves legacy state to the escaped key", func(t *testing.T) {
+		storage, err := repository.NewMemoryRepository("", 1)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{})
+
+		_, err = storage.InitializeEnvironment(ctx, "org:repo:production", "prod", "123", "3", "main")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.SetField(ctx, "org:repo:production", "driftIncrement", "2"))
+		assert.NoError(t, storage.SetField(ctx, "org:repo:production", "issueID", "7"))
+		assert.NoError(t, storage.AddOpenIssue(ctx, "org:repo:production"))
+
+		// Reads find the legacy state without moving it
+		result, err := service.GetEnvironmentState(ctx, "org:repo", "production")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", result.DriftIncrement)
+		_, err = storage.GetEnvironmentData(ctx, "org:repo:production")
+		assert.NoError(t, err, "A read should not migrate state")
+
+		assert.Equal(t, "org%3Arepo:production", service.environmentKey(ctx, "org:repo", "production"))
+		result, err = service.GetEnvironmentState(ctx, "org:repo", "production")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", result.DriftIncrement, "Drift count should survive the key change")
+		assert.Equal(t, "7", result.IssueID, "Issue ID should survive the key change")
+
+		openIssues, err := storage.ListOpenIssues(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"org%3Arepo:production"}, openIssues, "Open issue index should point at the new key")
+
+		_, err = storage.GetEnvironmentData(ctx, "org:repo:production")
+		assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound, "Legacy key should be removed")
+	})
+
+	t.Run("does not overwrite existing state", func(t *testing.T) {
+		storage, err := repository.NewMemoryRepository("", 1)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{})
+
+		assert.NoError(t, storage.SetField(ctx, "org:repo:production", "driftIncrement", "5"))
+		assert.NoError(t, storage.SetField(ctx, "org%3Arepo:production", "driftIncrement", "1"))
+
+		result, err := service.GetEnvironmentState(ctx, "org:repo", "production")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", result.DriftIncrement)
+	})
+
+	t.Run("ignores legacy keys that belong to another environment", func(t *testing.T) {
+		storage, err := repository.NewMemoryRepository("", 1)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{})
+
+		// "repo%3Aa:prod" is the escaped key of repo "repo:a", not a legacy key
+		assert.NoError(t, storage.SetField(ctx, "repo%3Aa:prod", "driftIncrement", "4"))
+
+		_, err = service.GetEnvironmentState(ctx, "repo%3Aa", "prod")
+		assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+		value, err := storage.GetField(ctx, "repo%3Aa:prod", "driftIncrement")
+		assert.NoError(t, err)
+		assert.Equal(t, "4", value, "Another environment's state must be left alone")
+	})
+}
+
+// TestProjectIDConversion tests project ID string to int conversion used in service layer
+func TestProjectIDConversion(t *testing.T) {
+	tests := []struct {
+		name         string
+		projectIDStr string
+		expected     int
+		expectError  bool
+	}{
+		{
+			name:         "valid project ID",
 			projectIDStr: "12345",
 			expected:     12345,
 			expectError:  false,
@@ -353,3 +889,3770 @@ func TestProjectIDConversion(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleThresholdBreach_IssueTiers tests that issue management is skipped for excluded tiers
+func TestHandleThresholdBreach_IssueTiers(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		issueTiers []string
+		tier       string
+		expectCall bool
+	}{
+		{
+			name:       "all tiers enabled by default",
+			issueTiers: nil,
+			tier:       "nonprod",
+			expectCall: true,
+		},
+		{
+			name:       "tier included in issue tiers",
+			issueTiers: []string{"prod"},
+			tier:       "prod",
+			expectCall: true,
+		},
+		{
+			name:       "tier excluded from issue tiers",
+			issueTiers: []string{"prod"},
+			tier:       "nonprod",
+			expectCall: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTracker := new(MockIssueTracker)
+			mockThreshold := new(MockThresholdManager)
+			service := NewDriftService(nil, mockTracker, mockThreshold, noopMetrics, &config.Config{IssueTiers: tt.issueTiers})
+
+			env := EnvironmentInfo{
+				RepoName:        "test-repo",
+				Environment:     "staging",
+				EnvironmentTier: tt.tier,
+				ProjectID:       "123",
+				Key:             "test-repo:staging",
+			}
+
+			if tt.expectCall {
+				mockThreshold.On("CheckThreshold", ctx, env.Key, 1).Return(false, nil).Once()
+			}
+
+			err := service.HandleThresholdBreach(ctx, env, 1)
+			assert.NoError(t, err, "Threshold breach handling should not fail")
+
+			mockThreshold.AssertExpectations(t)
+			mockTracker.AssertNotCalled(t, "CreateIssue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockTracker.AssertNotCalled(t, "GetIssueStatus", mock.Anything, mock.Anything, mock.Anything)
+			if !tt.expectCall {
+				mockThreshold.AssertNotCalled(t, "CheckThreshold", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// TestProcessDriftDetection_LastError tests that failures are recorded and cleared on success
+func TestProcessDriftDetection_LastError(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:staging"
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "feature",
+		Environment:     "staging",
+		EnvironmentTier: "nonprod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		Timestamp:       "2025-01-31T10:30:00Z",
+	}
+
+	t.Run("failure records last error", func(t *testing.T) {
+		mockStorage := new(MockStorageRepository)
+		service := NewDriftService(mockStorage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{ComparisonBranch: "main", DriftThreshold: 1})
+
+		mockStorage.On("InitializeEnvironment", ctx, key, "nonprod", "123", "1", "main").Return(false, nil).Once()
+		mockStorage.On("UpdateOperationLog", ctx, key, repository.OperationLogEntry{Timestamp: payload.Timestamp, Operation: "plan", ExitCode: payload.ExitCode, Branch: payload.Branch}).Return(assert.AnError).Once()
+		mockStorage.On("SetField", ctx, key, "lastError", mock.MatchedBy(func(v string) bool { return v != "" })).Return(nil).Once()
+		mockStorage.On("SetField", ctx, key, "lastErrorTimestamp", mock.AnythingOfType("string")).Return(nil).Once()
+
+		result, err := service.ProcessDriftDetection(ctx, payload)
+		assert.Error(t, err, "Processing should fail when the operation log cannot be updated")
+		assert.Nil(t, result, "Result should be nil on failure")
+
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("success clears last error", func(t *testing.T) {
+		mockStorage := new(MockStorageRepository)
+		service := NewDriftService(mockStorage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{ComparisonBranch: "main", DriftThreshold: 1})
+
+		mockStorage.On("InitializeEnvironment", ctx, key, "nonprod", "123", "1", "main").Return(false, nil).Once()
+		mockStorage.On("UpdateOperationLog", ctx, key, repository.OperationLogEntry{Timestamp: payload.Timestamp, Operation: "plan", ExitCode: payload.ExitCode, Branch: payload.Branch}).Return(nil).Once()
+		mock.InOrder(
+			mockStorage.On("GetField", ctx, key, "lastError").Return("failed to update operation log", nil).Once(),
+			mockStorage.On("SetField", ctx, key, "lastError", "").Return(nil).Once(),
+			mockStorage.On("SetField", ctx, key, "lastErrorTimestamp", "").Return(nil).Once(),
+			mockStorage.On("GetEnvironmentData", ctx, key).Return(map[string]string{
+				"environmentTier":    "nonprod",
+				"projectID":          "123",
+				"driftIncrement":     "0",
+				"lastError":          "",
+				"lastErrorTimestamp": "",
+			}, nil).Once(),
+		)
+
+		result, err := service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err, "Processing should succeed")
+		assert.Empty(t, result.LastError, "Result should reflect the cleared error")
+
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+// TestProcessDriftDetection_Retention tests that the environment TTL follows its tier
+func TestProcessDriftDetection_Retention(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		tier        string
+		expectedTTL time.Duration
+	}{
+		{name: "prod tier", tier: "prod", expectedTTL: 90 * 24 * time.Hour},
+		{name: "production tier", tier: "production", expectedTTL: 90 * 24 * time.Hour},
+		{name: "nonprod tier", tier: "nonprod", expectedTTL: 7 * 24 * time.Hour},
+		{name: "other tiers use nonprod", tier: "staging", expectedTTL: 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "test-repo:production"
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 1, RetentionProd: 90 * 24 * time.Hour, RetentionNonprod: 7 * 24 * time.Hour}
+			mockStorage := new(MockStorageRepository)
+			service := NewDriftService(mockStorage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, cfg)
+
+			payload := Payload{
+				RepoName:        "test-repo",
+				Branch:          "feature",
+				Environment:     "production",
+				EnvironmentTier: tt.tier,
+				ProjectID:       "123",
+				Operation:       "plan",
+				Timestamp:       "2025-01-31T10:30:00Z",
+			}
+
+			mockStorage.On("InitializeEnvironment", ctx, key, tt.tier, "123", "1", "main").Return(false, nil).Once()
+			mockStorage.On("Expire", ctx, key, tt.expectedTTL).Return(nil).Once()
+			mockStorage.On("UpdateOperationLog", ctx, key, mock.Anything).Return(nil).Once()
+			mockStorage.On("GetField", ctx, key, "lastError").Return("", nil).Once()
+			mockStorage.On("GetEnvironmentData", ctx, key).Return(map[string]string{"driftIncrement": "0"}, nil).Twice()
+
+			_, err := service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+
+	t.Run("zero retention keeps data", func(t *testing.T) {
+		service := NewDriftService(new(MockStorageRepository), new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{RetentionNonprod: 7 * 24 * time.Hour})
+		assert.Equal(t, time.Duration(0), service.retentionFor("prod"))
+	})
+
+	t.Run("issue owner expires with its environment", func(t *testing.T) {
+		key := "test-repo:production"
+		cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, RetentionProd: 20 * time.Millisecond}
+		storage, err := repository.NewMemoryRepository("", 5)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, new(MockIssueTracker), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+		_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "5", "main")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+		assert.NoError(t, storage.SetField(ctx, IssueOwnerKey(123, 7), "environmentKey", key))
+
+		_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "feature", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "plan"})
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		_, err = storage.GetEnvironmentData(ctx, key)
+		assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound)
+		owner, err := storage.GetField(ctx, IssueOwnerKey(123, 7), "environmentKey")
+		assert.NoError(t, err)
+		assert.Empty(t, owner, "The reverse index does not outlive the environment")
+	})
+
+	t.Run("environment TTL applies to tiers without retention", func(t *testing.T) {
+		cfg := &config.Config{RetentionProd: 90 * 24 * time.Hour, EnvironmentTTL: 30 * 24 * time.Hour}
+		service := NewDriftService(new(MockStorageRepository), new(MockIssueTracker), new(MockThresholdManager), noopMetrics, cfg)
+		assert.Equal(t, 90*24*time.Hour, service.retentionFor("prod"))
+		assert.Equal(t, 30*24*time.Hour, service.retentionFor("review"))
+	})
+}
+
+// TestProcessDriftDetection_ApplyResult tests that only a successful apply from an allowed branch resets drift
+func TestProcessDriftDetection_ApplyResult(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name               string
+		branch             string
+		exitCode           int
+		failedApplyAsDrift bool
+		applyResetBranches []string
+		expectedDrift      string
+		expectClose        bool
+	}{
+		{name: "successful apply resets drift", exitCode: 0, expectedDrift: "0", expectClose: true},
+		{name: "failed apply keeps drift", exitCode: 1, expectedDrift: "2"},
+		{name: "failed apply counts as drift when enabled", exitCode: 1, failedApplyAsDrift: true, expectedDrift: "3"},
+		{name: "successful apply resets drift when enabled", exitCode: 0, failedApplyAsDrift: true, expectedDrift: "0", expectClose: true},
+		{name: "apply from any branch resets by default", branch: "feature/tmp", exitCode: 0, expectedDrift: "0", expectClose: true},
+		{name: "apply from allowed branch resets", branch: "release/1.2", applyResetBranches: []string{"main", "release/*"}, expectedDrift: "0", expectClose: true},
+		{name: "apply from other branch keeps drift", branch: "feature/tmp", applyResetBranches: []string{"main", "release/*"}, expectedDrift: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branch := tt.branch
+			if branch == "" {
+				branch = "main"
+			}
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, FailedApplyAsDrift: tt.failedApplyAsDrift, ApplyResetBranches: tt.applyResetBranches}
+			storage, err := repository.NewMemoryRepository("", 5)
+			assert.NoError(t, err)
+			mockTracker := new(MockIssueTracker)
+			service := NewDriftService(storage, mockTracker, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "5", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "2"))
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+			if tt.expectClose {
+				mockTracker.On("GetIssueStatus", ctx, 123, 7).Return(true, nil).Once()
+				mockTracker.On("CloseIssue", ctx, 123, 7, "apply", time.Duration(0)).Return(nil).Once()
+			}
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          branch,
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "apply",
+				ExitCode:        tt.exitCode,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+// TestProcessDriftDetection_ResolveOperations tests that a custom RESOLVE_OPERATIONS set decides which
+// operations and exit codes reset drift
+func TestProcessDriftDetection_ResolveOperations(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	resolveOperations := map[string][]int{"apply": {0}, "destroy": {0}, "import": {0, 2}}
+
+	tests := []struct {
+		name          string
+		operations    map[string][]int
+		operation     string
+		exitCode      int
+		expectedDrift string
+		expectClose   bool
+	}{
+		{name: "destroy ignored by default", operation: "destroy", expectedDrift: "2"},
+		{name: "apply resolves by default", operation: "apply", expectedDrift: "0", expectClose: true},
+		{name: "successful destroy resolves", operations: resolveOperations, operation: "destroy", expectedDrift: "0", expectClose: true},
+		{name: "failed destroy keeps drift", operations: resolveOperations, operation: "destroy", exitCode: 1, expectedDrift: "2"},
+		{name: "import resolves on listed exit code", operations: resolveOperations, operation: "import", exitCode: 2, expectedDrift: "0", expectClose: true},
+		{name: "apply omitted from set keeps drift", operations: map[string][]int{"destroy": {0}}, operation: "apply", expectedDrift: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, ResolveOperations: tt.operations}
+			storage, err := repository.NewMemoryRepository("", 5)
+			assert.NoError(t, err)
+			mockTracker := new(MockIssueTracker)
+			service := NewDriftService(storage, mockTracker, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "5", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "2"))
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+			if tt.expectClose {
+				mockTracker.On("GetIssueStatus", ctx, 123, 7).Return(true, nil).Once()
+				mockTracker.On("CloseIssue", ctx, 123, 7, tt.operation, time.Duration(0)).Return(nil).Once()
+			}
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       tt.operation,
+				ExitCode:        tt.exitCode,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+// TestProcessDriftDetection_FailedApplyKeepsIssueOpen is a regression test: a failed apply must
+// neither reset the drift counter nor close the open drift issue
+func TestProcessDriftDetection_FailedApplyKeepsIssueOpen(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	var requests []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 3}
+	storage, err := repository.NewMemoryRepository("", 3)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "3", "main")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "3"))
+	assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+	assert.NoError(t, storage.AddOpenIssue(ctx, key))
+
+	result, err := service.ProcessDriftDetection(ctx, Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "apply",
+		ExitCode:        1,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "3", result.DriftIncrement, "Failed apply should not reset the drift counter")
+	assert.Equal(t, "7", result.IssueID, "Failed apply should keep the issue reference")
+	assert.Empty(t, requests, "Failed apply should not touch the GitLab issue")
+
+	keys, err := storage.ListOpenIssues(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys, "Issue should stay in the open-issue index")
+}
+
+// TestProcessDriftDetection_Metrics tests that drift and issue metrics are emitted
+func TestProcessDriftDetection_Metrics(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	tags := "repo:test-repo,environment:production,tier:prod"
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "web_url": "https://gitlab.example.com/issues/8"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 3, GitLabBaseURL: mockServer.URL, GitLabToken: "test-token"}
+	mockStorage := new(MockStorageRepository)
+	mockThreshold := new(MockThresholdManager)
+	recorder := &recordingMetrics{}
+	service := NewDriftService(mockStorage, client.NewGitLabClient(cfg), mockThreshold, recorder, cfg)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		Timestamp:       "2025-01-31T10:30:00Z",
+	}
+
+	mockStorage.On("InitializeEnvironment", ctx, key, "prod", "123", "3", "main").Return(false, nil).Once()
+	mockStorage.On("UpdateOperationLog", ctx, key, repository.OperationLogEntry{Timestamp: payload.Timestamp, Operation: "plan", ExitCode: payload.ExitCode, Branch: payload.Branch}).Return(nil).Once()
+	mockStorage.On("IncrementAndCheck", ctx, key, 1).Return(3, true, nil).Once()
+	mockStorage.On("GetField", ctx, key, "ackUntil").Return("", nil).Once()
+	mockStorage.On("GetField", ctx, key, "issueID").Return("", nil).Once()
+	mockStorage.On("GetField", ctx, key, "planOutput").Return("", nil).Once()
+	mockStorage.On("GetField", ctx, key, "commitSHA").Return("", nil).Once()
+	mockStorage.On("GetField", ctx, key, "comparisonBranch").Return("main", nil).Once()
+	mockStorage.On("GetField", ctx, key, "metadata").Return("", nil).Once()
+	mockThreshold.On("GetThreshold", ctx, key).Return(3, nil).Once()
+	mockStorage.On("SetField", ctx, key, mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("AddOpenIssue", ctx, key).Return(nil).Once()
+	mockStorage.On("SetField", ctx, IssueOwnerKey(123, 8), "environmentKey", key).Return(nil).Once()
+	mockStorage.On("GetField", ctx, key, "lastError").Return("", nil).Once()
+	mockStorage.On("GetEnvironmentData", ctx, key).Return(map[string]string{"driftIncrement": "3", "issueID": "8"}, nil).Once()
+
+	_, err := service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"count drift.increment 1 " + tags,
+		"count issue.created 1 " + tags,
+		"gauge drift.current 3 " + tags,
+	}, recorder.entries)
+	mockStorage.AssertExpectations(t)
+}
+
+// TestProcessDriftDetection_PreviewMode tests that feature-branch plans are recorded as previews
+func TestProcessDriftDetection_PreviewMode(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name          string
+		previewMode   bool
+		branch        string
+		expectDrift   string
+		expectPreview bool
+	}{
+		{name: "feature-branch plan is a preview", previewMode: true, branch: "feature/vpc", expectDrift: "0", expectPreview: true},
+		{name: "comparison-branch plan is tracked", previewMode: true, branch: "main", expectDrift: "1", expectPreview: false},
+		{name: "feature-branch plan is ignored without preview mode", previewMode: false, branch: "feature/vpc", expectDrift: "0", expectPreview: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := repository.NewMemoryRepository("", 5)
+			assert.NoError(t, err)
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, PreviewMode: tt.previewMode}
+			service := NewDriftService(storage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, cfg)
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          tt.branch,
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				Timestamp:       "2025-01-31T10:30:00Z",
+				PlanOutput:      "Plan: 1 to add",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectDrift, result.DriftIncrement, "Only comparison-branch plans affect the drift counter")
+
+			preview, err := storage.GetEnvironmentData(ctx, PreviewKey(key, tt.branch))
+			if tt.expectPreview {
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]string{
+					"branch":        "feature/vpc",
+					"exitCode":      "2",
+					"driftDetected": "true",
+					"timestamp":     "2025-01-31T10:30:00Z",
+					"planOutput":    "Plan: 1 to add",
+				}, preview)
+			} else {
+				assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound, "Tracked runs should not store a preview")
+			}
+		})
+	}
+}
+
+// TestProcessDriftDetection_PreviewNote tests merge request decoration for preview plans
+func TestProcessDriftDetection_PreviewNote(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		mergeRequestIID string
+		expectedPaths   []string
+	}{
+		{name: "note posted on merge request", mergeRequestIID: "42", expectedPaths: []string{"POST /projects/123/merge_requests/42/notes"}},
+		{name: "skipped without merge request", mergeRequestIID: "", expectedPaths: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer mockServer.Close()
+
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 1, PreviewMode: true, GitLabBaseURL: mockServer.URL, GitLabToken: "test-token"}
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), new(MockThresholdManager), noopMetrics, cfg)
+
+			_, err = service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "feature/vpc",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				IssueProjectID:  "999",
+				MergeRequestIID: tt.mergeRequestIID,
+				Operation:       "plan",
+				ExitCode:        2,
+				Timestamp:       "2025-01-31T10:30:00Z",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPaths, requests, "Notes go to the repository's own merge request, never an issue")
+		})
+	}
+}
+
+// TestGetEnvironmentState tests read-only environment lookups
+func TestGetEnvironmentState(t *testing.T) {
+	ctx := context.Background()
+	// Lookups are read-only, so they may be served by a read replica
+	replicaCtx := repository.WithReplicaReads(ctx)
+
+	t.Run("returns stored state including last error", func(t *testing.T) {
+		mockStorage := new(MockStorageRepository)
+		service := NewDriftService(mockStorage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{})
+
+		mockStorage.On("GetEnvironmentData", replicaCtx, "test-repo:production").Return(map[string]string{
+			"environmentTier":    "prod",
+			"driftIncrement":     "2",
+			"lastError":          "failed to create drift issue: boom",
+			"lastErrorTimestamp": "2025-01-31T10:00:00Z",
+		}, nil).Once()
+
+		result, err := service.GetEnvironmentState(ctx, "test-repo", "production")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", result.DriftIncrement)
+		assert.Equal(t, "failed to create drift issue: boom", result.LastError)
+		assert.Equal(t, "2025-01-31T10:00:00Z", result.LastErrorAt)
+
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("unknown environment", func(t *testing.T) {
+		mockStorage := new(MockStorageRepository)
+		service := NewDriftService(mockStorage, new(MockIssueTracker), new(MockThresholdManager), noopMetrics, &config.Config{})
+
+		mockStorage.On("GetEnvironmentData", replicaCtx, "test-repo:missing").Return(nil, fmt.Errorf("%w: test-repo:missing", repository.ErrEnvironmentNotFound)).Once()
+
+		_, err := service.GetEnvironmentState(ctx, "test-repo", "missing")
+		assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+// TestHandleThresholdBreach_DigestMode tests digest issue creation and update
+func TestHandleThresholdBreach_DigestMode(t *testing.T) {
+	ctx := context.Background()
+	day := time.Now().UTC().Format("2006-01-02")
+	digestKey := DigestKey(123, day)
+	env := EnvironmentInfo{
+		RepoName:        "test-repo",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Key:             "test-repo:production",
+	}
+
+	tests := []struct {
+		name           string
+		digestData     map[string]string
+		expectedMethod string
+		expectedPath   string
+		claimResult    *bool
+		expectStore    bool
+		expectedRows   []string
+	}{
+		{
+			name: "creates digest issue when none exists",
+			digestData: map[string]string{
+				"drift:test-repo:production": "3",
+			},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/projects/123/issues",
+			claimResult:    boolPtr(true),
+			expectStore:    true,
+			expectedRows:   []string{"| `test-repo` | `production` | 3 |"},
+		},
+		{
+			name: "skips creation claimed by a concurrent breach",
+			digestData: map[string]string{
+				"drift:test-repo:production": "3",
+			},
+			claimResult: boolPtr(false),
+		},
+		{
+			name: "updates open digest issue",
+			digestData: map[string]string{
+				"drift:test-repo:production":    "3",
+				"drift:test-repo:staging":       "2",
+				"drift:other%3Arepo:production": "4",
+				"issueID":                       "7",
+			},
+			expectedMethod: http.MethodPut,
+			expectedPath:   "/projects/123/issues/7",
+			expectStore:    false,
+			expectedRows: []string{
+				"| `other:repo` | `production` | 4 |",
+				"| `test-repo` | `production` | 3 |",
+				"| `test-repo` | `staging` | 2 |",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			var description string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				switch r.Method {
+				case http.MethodGet:
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+				default:
+					var body map[string]interface{}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					description, _ = body["description"].(string)
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "web_url": "https://gitlab.example.com/issues/8"})
+				}
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{DigestMode: true, GitLabBaseURL: mockServer.URL, GitLabToken: "test-token"}
+			mockStorage := new(MockStorageRepository)
+			mockThreshold := new(MockThresholdManager)
+			service := NewDriftService(mockStorage, client.NewGitLabClient(cfg), mockThreshold, noopMetrics, cfg)
+
+			mockThreshold.On("CheckThreshold", ctx, env.Key, 3).Return(true, nil).Once()
+			mockStorage.On("SetField", ctx, digestKey, "drift:test-repo:production", "3").Return(nil).Once()
+			mockStorage.On("Expire", ctx, digestKey, digestRetention).Return(nil).Once()
+			mockStorage.On("GetEnvironmentData", ctx, digestKey).Return(tt.digestData, nil).Once()
+			if tt.claimResult != nil {
+				mockStorage.On("SetFieldIfEmpty", ctx, digestKey, "issueClaim:", "true").Return(*tt.claimResult, nil).Once()
+			}
+			if tt.expectStore {
+				mockStorage.On("SetField", ctx, digestKey, "issueID", "8").Return(nil).Once()
+				mockStorage.On("SetField", ctx, digestKey, "issueURL", "https://gitlab.example.com/issues/8").Return(nil).Once()
+			}
+
+			err := service.HandleThresholdBreach(ctx, env, 3)
+			assert.NoError(t, err, "Digest handling should not fail")
+
+			if tt.expectedMethod != "" {
+				assert.Contains(t, requests, tt.expectedMethod+" "+tt.expectedPath)
+			} else {
+				assert.Empty(t, requests, "Only the claiming breach creates the digest issue")
+			}
+			for _, row := range tt.expectedRows {
+				assert.Contains(t, description, row, "Digest should list every drifted environment with its repository")
+			}
+			mockStorage.AssertNotCalled(t, "SetField", ctx, env.Key, "issueID", mock.Anything)
+			mockStorage.AssertExpectations(t)
+			mockThreshold.AssertExpectations(t)
+		})
+	}
+}
+
+// TestProcessDriftDetection_CommitSHA tests that the planned commit is stored and shown in the issue description
+func TestProcessDriftDetection_CommitSHA(t *testing.T) {
+	ctx := context.Background()
+
+	var descriptions []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if description, ok := body["description"].(string); ok {
+			descriptions = append(descriptions, description)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "state": "opened", "web_url": "https://gitlab.example.com/issues/8"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		Timestamp:       "2025-01-31T10:30:00Z",
+	}
+
+	for _, sha := range []string{"1111111111111111111111111111111111111111", "2222222222222222222222222222222222222222", ""} {
+		payload.CommitSHA = sha
+		_, err := service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+
+		state, err := service.GetEnvironmentState(ctx, "test-repo", "production")
+		assert.NoError(t, err)
+		assert.Equal(t, sha, state.CommitSHA, "Stored commit should follow the latest detection")
+	}
+
+	if assert.Len(t, descriptions, 3, "Issue should be created once and then updated") {
+		assert.Contains(t, descriptions[0], "Detected at commit `1111111111111111111111111111111111111111`")
+		assert.Contains(t, descriptions[1], "Detected at commit `2222222222222222222222222222222222222222`")
+		assert.NotContains(t, descriptions[2], "Detected at commit", "An absent SHA should not leave a stale commit")
+	}
+}
+
+// TestProcessDriftDetection_DetectionLabels tests that new issues are labelled by whether a scheduled run detected the drift
+func TestProcessDriftDetection_DetectionLabels(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		detectionLabels bool
+		payload         Payload
+		expectedLabels  []interface{}
+	}{
+		{
+			name:            "scheduled plan",
+			detectionLabels: true,
+			payload:         Payload{Branch: "main", Operation: "plan", ExitCode: 2, Scheduled: true},
+			expectedLabels:  []interface{}{"drift-alert", "automation", "detection:scheduled"},
+		},
+		{
+			name:            "manual failed apply",
+			detectionLabels: true,
+			payload:         Payload{Branch: "main", Operation: "apply", ExitCode: 1, Scheduled: false},
+			expectedLabels:  []interface{}{"drift-alert", "automation", "detection:manual"},
+		},
+		{
+			name:           "disabled",
+			payload:        Payload{Branch: "main", Operation: "plan", ExitCode: 2, Scheduled: true},
+			expectedLabels: []interface{}{"drift-alert", "automation"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []interface{}
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if r.Method == http.MethodPost {
+					labels, _ = body["labels"].([]interface{})
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 5, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, FailedApplyAsDrift: true, DetectionLabels: tt.detectionLabels}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			payload := tt.payload
+			payload.RepoName = "test-repo"
+			payload.Environment = "production"
+			payload.EnvironmentTier = "prod"
+			payload.ProjectID = "123"
+
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLabels, labels)
+		})
+	}
+}
+
+// TestProcessDriftDetection_MetadataLabels tests that payload metadata reaches the issue as labels and a description section
+func TestProcessDriftDetection_MetadataLabels(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		metadataLabels bool
+		expectedLabels []interface{}
+	}{
+		{name: "rendered as scoped labels", metadataLabels: true, expectedLabels: []interface{}{"drift-alert", "automation", "region::eu", "team::payments"}},
+		{name: "labels disabled", metadataLabels: false, expectedLabels: []interface{}{"drift-alert", "automation"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []interface{}
+			var description string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if r.Method == http.MethodPost {
+					labels, _ = body["labels"].([]interface{})
+					description, _ = body["description"].(string)
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 5, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, MetadataLabels: tt.metadataLabels}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				Metadata:        map[string]string{"team": "payments", "region": "eu"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLabels, labels)
+			assert.Contains(t, description, "| `team` | payments |", "Metadata should be shown in the issue")
+
+			stored, err := storage.GetField(ctx, "test-repo:production", "metadata")
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"team":"payments","region":"eu"}`, stored)
+		})
+	}
+}
+
+// TestProcessDriftDetection_DriftWeight tests that the drift counter grows by the payload weight
+func TestProcessDriftDetection_DriftWeight(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		weight        int
+		expectedDrift string
+		expectIssue   bool
+	}{
+		{name: "unweighted counts as one", weight: 0, expectedDrift: "1", expectIssue: false},
+		{name: "low weight stays below threshold", weight: 2, expectedDrift: "2", expectIssue: false},
+		{name: "critical weight crosses threshold", weight: 5, expectedDrift: "5", expectIssue: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				created = created || r.Method == http.MethodPost
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 5, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 3}
+			storage, err := repository.NewMemoryRepository("", 3)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				DriftWeight:     tt.weight,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+			assert.Equal(t, tt.expectIssue, created)
+		})
+	}
+}
+
+// TestProcessDriftDetection_TierDriftWeights tests that the drift counter grows by the tier's weight
+// times the payload weight
+func TestProcessDriftDetection_TierDriftWeights(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		tier          string
+		weight        int
+		expectedDrift string
+	}{
+		{name: "weighted tier counts double", tier: "prod", expectedDrift: "2"},
+		{name: "tier matches case-insensitively", tier: "PROD", expectedDrift: "2"},
+		{name: "listed tier of weight one", tier: "nonprod", expectedDrift: "1"},
+		{name: "unlisted tier counts one", tier: "sandbox", expectedDrift: "1"},
+		{name: "payload weight multiplies tier weight", tier: "prod", weight: 3, expectedDrift: "6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 100, TierDriftWeights: map[string]int{"prod": 2, "nonprod": 1}}
+			storage, err := repository.NewMemoryRepository("", 100)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: tt.tier,
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				DriftWeight:     tt.weight,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+		})
+	}
+}
+
+// TestProcessDriftDetection_NotificationThrottle tests that notifications are limited to one per
+// throttle window unless the drift is critical, while the issue is updated every time
+func TestProcessDriftDetection_NotificationThrottle(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name           string
+		lastNotifiedAt string
+		weight         int
+		expectNotify   bool
+	}{
+		{name: "first notification is sent", expectNotify: true},
+		{name: "notification within window is throttled", lastNotifiedAt: time.Now().Add(-10 * time.Minute).Format(time.RFC3339), expectNotify: false},
+		{name: "notification after window is sent", lastNotifiedAt: time.Now().Add(-2 * time.Hour).Format(time.RFC3339), expectNotify: true},
+		{name: "critical drift bypasses the window", lastNotifiedAt: time.Now().Add(-10 * time.Minute).Format(time.RFC3339), weight: 10, expectNotify: true},
+		{name: "drift below critical weight is throttled", lastNotifiedAt: time.Now().Add(-10 * time.Minute).Format(time.RFC3339), weight: 5, expectNotify: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				updated = updated || r.Method == http.MethodPut
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, NotificationThrottle: time.Hour, CriticalDriftWeight: 10}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			notifier := &recordingNotifier{}
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg).WithNotifier(notifier)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+			assert.NoError(t, storage.SetField(ctx, key, "lastNotifiedAt", tt.lastNotifiedAt))
+
+			_, err = service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				DriftWeight:     tt.weight,
+			})
+			assert.NoError(t, err)
+			assert.True(t, updated, "The issue is updated whether or not the notification is throttled")
+			assert.Equal(t, tt.expectNotify, len(notifier.notifications) == 1)
+
+			lastNotifiedAt, err := storage.GetField(ctx, key, "lastNotifiedAt")
+			assert.NoError(t, err)
+			if tt.expectNotify {
+				assert.NotEqual(t, tt.lastNotifiedAt, lastNotifiedAt, "A sent notification should restart the window")
+			} else {
+				assert.Equal(t, tt.lastNotifiedAt, lastNotifiedAt, "A throttled notification should not restart the window")
+			}
+		})
+	}
+
+	// Drift returning within the window after it was resolved files a new issue and notifies
+	t.Run("resolved drift is not throttled", func(t *testing.T) {
+		var created int
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				created++
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "state": "closed"})
+		}))
+		defer mockServer.Close()
+
+		cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, NotificationThrottle: time.Hour}
+		storage, err := repository.NewMemoryRepository("", 1)
+		assert.NoError(t, err)
+		notifier := &recordingNotifier{}
+		service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg).WithNotifier(notifier)
+
+		_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.SetField(ctx, key, "lastNotifiedAt", time.Now().Add(-10*time.Minute).Format(time.RFC3339)))
+
+		env := EnvironmentInfo{RepoName: "test-repo", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Key: key}
+		assert.NoError(t, service.ResetDriftIncrement(ctx, env, "apply"))
+		lastNotifiedAt, err := storage.GetField(ctx, key, "lastNotifiedAt")
+		assert.NoError(t, err)
+		assert.Empty(t, lastNotifiedAt, "Resetting drift ends the throttle window")
+
+		_, err = service.ProcessDriftDetection(ctx, Payload{
+			RepoName:        "test-repo",
+			Branch:          "main",
+			Environment:     "production",
+			EnvironmentTier: "prod",
+			ProjectID:       "123",
+			Operation:       "plan",
+			ExitCode:        2,
+			Scheduled:       true,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, created, "Returning drift files a new issue")
+		assert.Len(t, notifier.notifications, 1)
+	})
+}
+
+// TestProcessDriftDetection_IssueAfterBreaches tests that issue creation waits for the configured
+// number of threshold breaches and that a clean plan clears the count
+func TestProcessDriftDetection_IssueAfterBreaches(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name                string
+		issueAfterBreaches  int
+		expectedIssuePerRun []int
+	}{
+		{name: "default files on first breach", issueAfterBreaches: 0, expectedIssuePerRun: []int{1, 1, 1}},
+		{name: "immediate creation", issueAfterBreaches: 1, expectedIssuePerRun: []int{1, 1, 1}},
+		{name: "third breach files issue", issueAfterBreaches: 3, expectedIssuePerRun: []int{0, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					created++
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 5, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, IssueAfterBreaches: tt.issueAfterBreaches}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			payload := Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+			}
+
+			for run, expected := range tt.expectedIssuePerRun {
+				_, err = service.ProcessDriftDetection(ctx, payload)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, created, "Unexpected issues created after run %d", run+1)
+			}
+
+			breachCount, err := storage.GetField(ctx, key, "breachCount")
+			assert.NoError(t, err)
+			assert.Empty(t, breachCount, "Breach count should be cleared once the issue is filed")
+		})
+	}
+
+	t.Run("clean plan clears breach count", func(t *testing.T) {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("Unexpected GitLab request: %s %s", r.Method, r.URL.Path)
+		}))
+		defer mockServer.Close()
+
+		cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, IssueAfterBreaches: 2}
+		storage, err := repository.NewMemoryRepository("", 1)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+		payload := Payload{
+			RepoName:        "test-repo",
+			Branch:          "main",
+			Environment:     "production",
+			EnvironmentTier: "prod",
+			ProjectID:       "123",
+			Operation:       "plan",
+			ExitCode:        2,
+			Scheduled:       true,
+		}
+
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+		breachCount, err := storage.GetField(ctx, key, "breachCount")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", breachCount)
+
+		payload.ExitCode = 0
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+		breachCount, err = storage.GetField(ctx, key, "breachCount")
+		assert.NoError(t, err)
+		assert.Empty(t, breachCount, "A clean plan should clear the breach count")
+
+		// The next breach starts counting again rather than filing an issue
+		payload.ExitCode = 2
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+	})
+}
+
+// TestProcessDriftDetection_StateLockError tests that state lock failures skip drift counting and
+// label the environment's open issue
+func TestProcessDriftDetection_StateLockError(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name             string
+		issueID          string
+		exitCode         int
+		expectedRequests []string
+	}{
+		{name: "open issue is labelled", issueID: "7", exitCode: 1, expectedRequests: []string{"PUT /projects/123/issues/7 add_labels=state-locked"}},
+		{name: "no issue to label", exitCode: 1},
+		{name: "drift exit code is not counted", issueID: "7", exitCode: 2, expectedRequests: []string{"PUT /projects/123/issues/7 add_labels=state-locked"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				requests = append(requests, r.Method+" "+r.URL.Path+" add_labels="+body["add_labels"])
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, FailedApplyAsDrift: true}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "2"))
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", tt.issueID))
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        tt.exitCode,
+				Scheduled:       true,
+				StateLockError:  true,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "2", result.DriftIncrement, "A state lock failure must not change the drift count")
+			assert.Equal(t, tt.expectedRequests, requests)
+		})
+	}
+}
+
+// TestProcessDriftDetection_SkipUnchangedPlan tests that identical plans do not update the issue
+func TestProcessDriftDetection_SkipUnchangedPlan(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name             string
+		skipUnchanged    bool
+		plans            []string
+		expectedRequests []string
+	}{
+		{
+			name:          "identical plan skips update",
+			skipUnchanged: true,
+			plans:         []string{"Plan: 1 to add", "Plan: 1 to add"},
+			expectedRequests: []string{
+				"POST /projects/123/issues",
+				"GET /projects/123/issues/8",
+			},
+		},
+		{
+			name:          "changed plan updates issue",
+			skipUnchanged: true,
+			plans:         []string{"Plan: 1 to add", "Plan: 1 to add", "Plan: 2 to add"},
+			expectedRequests: []string{
+				"POST /projects/123/issues",
+				"GET /projects/123/issues/8",
+				"GET /projects/123/issues/8",
+				"PUT /projects/123/issues/8",
+			},
+		},
+		{
+			name:  "identical plan updates when disabled",
+			plans: []string{"Plan: 1 to add", "Plan: 1 to add"},
+			expectedRequests: []string{
+				"POST /projects/123/issues",
+				"GET /projects/123/issues/8",
+				"PUT /projects/123/issues/8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "state": "opened", "web_url": "https://gitlab.example.com/issues/8"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, SkipUnchangedPlan: tt.skipUnchanged}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			for _, plan := range tt.plans {
+				_, err := service.ProcessDriftDetection(ctx, Payload{
+					RepoName:        "test-repo",
+					Branch:          "main",
+					Environment:     "production",
+					EnvironmentTier: "prod",
+					ProjectID:       "123",
+					Operation:       "plan",
+					ExitCode:        2,
+					Scheduled:       true,
+					PlanOutput:      plan,
+				})
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedRequests, requests)
+
+			state, err := service.GetEnvironmentState(ctx, "test-repo", "production")
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(len(tt.plans)), state.DriftIncrement, "Counter should increment even when the update is skipped")
+		})
+	}
+}
+
+// TestHandleThresholdBreach_IssueProjectOverride tests that issues are routed to the issue project
+func TestHandleThresholdBreach_IssueProjectOverride(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name             string
+		issueProjectID   string
+		existingIssueID  string
+		storedProjectID  string
+		expectedRequests []string
+		expectStore      string
+	}{
+		{
+			name:             "override routes new issue to issue project",
+			issueProjectID:   "999",
+			expectedRequests: []string{"POST /projects/999/issues"},
+			expectStore:      "999",
+		},
+		{
+			name:             "falls back to project ID",
+			expectedRequests: []string{"POST /projects/123/issues"},
+			expectStore:      "123",
+		},
+		{
+			name:             "existing issue is updated in the project it was filed in",
+			existingIssueID:  "7",
+			storedProjectID:  "999",
+			expectedRequests: []string{"GET /projects/999/issues/7", "PUT /projects/999/issues/7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 8, "state": "opened", "web_url": "https://gitlab.example.com/issues/8"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token"}
+			mockStorage := new(MockStorageRepository)
+			mockThreshold := new(MockThresholdManager)
+			service := NewDriftService(mockStorage, client.NewGitLabClient(cfg), mockThreshold, noopMetrics, cfg)
+
+			env := EnvironmentInfo{
+				RepoName:        "test-repo",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				IssueProjectID:  tt.issueProjectID,
+				Key:             key,
+			}
+
+			mockThreshold.On("CheckThreshold", ctx, key, 3).Return(true, nil).Once()
+			mockThreshold.On("GetThreshold", ctx, key).Return(3, nil).Once()
+			mockStorage.On("GetField", ctx, key, "ackUntil").Return("", nil).Once()
+			mockStorage.On("GetField", ctx, key, "issueID").Return(tt.existingIssueID, nil).Once()
+			mockStorage.On("GetField", ctx, key, "planOutput").Return("", nil).Once()
+			mockStorage.On("GetField", ctx, key, "commitSHA").Return("", nil).Once()
+			mockStorage.On("GetField", ctx, key, "comparisonBranch").Return("", nil).Once()
+			mockStorage.On("GetField", ctx, key, "metadata").Return("", nil).Once()
+			if tt.existingIssueID != "" {
+				mockStorage.On("GetField", ctx, key, "issueProjectID").Return(tt.storedProjectID, nil).Once()
+			}
+			if tt.expectStore != "" {
+				mockStorage.On("SetField", ctx, key, "issueID", "8").Return(nil).Once()
+				mockStorage.On("SetField", ctx, key, "issueURL", "https://gitlab.example.com/issues/8").Return(nil).Once()
+				mockStorage.On("SetField", ctx, key, "issueProjectID", tt.expectStore).Return(nil).Once()
+				mockStorage.On("SetField", ctx, key, "issueCreatedAt", mock.AnythingOfType("string")).Return(nil).Once()
+				mockStorage.On("SetField", ctx, key, "escalated", "").Return(nil).Once()
+				mockStorage.On("AddOpenIssue", ctx, key).Return(nil).Once()
+				projectID, _ := strconv.Atoi(tt.expectStore)
+				mockStorage.On("SetField", ctx, IssueOwnerKey(projectID, 8), "environmentKey", key).Return(nil).Once()
+			}
+
+			err := service.HandleThresholdBreach(ctx, env, 3)
+			assert.NoError(t, err, "Threshold breach handling should not fail")
+
+			assert.Equal(t, tt.expectedRequests, requests)
+			mockStorage.AssertExpectations(t)
+			mockThreshold.AssertExpectations(t)
+		})
+	}
+}
+
+// TestResetDriftIncrement_IssueProjectOverride tests that issues are closed in the project they were filed in
+func TestResetDriftIncrement_IssueProjectOverride(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	mockStorage := new(MockStorageRepository)
+	mockTracker := new(MockIssueTracker)
+	service := NewDriftService(mockStorage, mockTracker, new(MockThresholdManager), noopMetrics, &config.Config{})
+
+	env := EnvironmentInfo{RepoName: "test-repo", Environment: "production", ProjectID: "123", Key: key}
+
+	mockStorage.On("ResetDrift", ctx, key).Return(nil).Once()
+	mockStorage.On("GetField", ctx, key, "ackUntil").Return("", nil).Once()
+	mockStorage.On("GetField", ctx, key, "issueID").Return("7", nil).Once()
+	mockStorage.On("GetField", ctx, key, "issueProjectID").Return("999", nil).Once()
+	mockStorage.On("GetField", ctx, IssueOwnerKey(999, 7), "environmentKey").Return(key, nil).Once()
+	mockTracker.On("GetIssueStatus", ctx, 999, 7).Return(true, nil).Once()
+	mockTracker.On("CloseIssue", ctx, 999, 7, "apply", time.Duration(0)).Return(nil).Once()
+	mockStorage.On("SetField", ctx, key, "issueID", "").Return(nil).Once()
+	mockStorage.On("SetField", ctx, key, "issueURL", "").Return(nil).Once()
+	mockStorage.On("RemoveOpenIssue", ctx, key).Return(nil).Once()
+	mockStorage.On("Expire", ctx, IssueOwnerKey(999, 7), time.Duration(0)).Return(nil).Once()
+
+	assert.NoError(t, service.ResetDriftIncrement(ctx, env, "apply"))
+
+	mockStorage.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+// TestResetDriftIncrement_IssueOwner tests that an issue is only closed by the environment that created it
+func TestResetDriftIncrement_IssueOwner(t *testing.T) {
+	ctx := context.Background()
+	ownerKey := "test-repo:production"
+	otherKey := "test-repo:staging"
+
+	tests := []struct {
+		name        string
+		owner       string
+		expectClose bool
+	}{
+		{name: "Owner mismatch skips close", owner: ownerKey, expectClose: false},
+		{name: "Matching owner closes", owner: otherKey, expectClose: true},
+		{name: "Legacy issue without owner closes", owner: "", expectClose: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			mockTracker := new(MockIssueTracker)
+			service := NewDriftService(storage, mockTracker, new(MockThresholdManager), noopMetrics, &config.Config{})
+
+			// Both environments reference issue 7
+			for _, key := range []string{ownerKey, otherKey} {
+				_, err := storage.InitializeEnvironment(ctx, key, "prod", "123", "3", "main")
+				assert.NoError(t, err)
+				assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+				assert.NoError(t, storage.AddOpenIssue(ctx, key))
+			}
+			if tt.owner != "" {
+				assert.NoError(t, storage.SetField(ctx, IssueOwnerKey(123, 7), "environmentKey", tt.owner))
+			}
+			if tt.expectClose {
+				mockTracker.On("GetIssueStatus", ctx, 123, 7).Return(true, nil).Once()
+				mockTracker.On("CloseIssue", ctx, 123, 7, "apply", time.Duration(0)).Return(nil).Once()
+			}
+
+			env := EnvironmentInfo{RepoName: "test-repo", Environment: "staging", ProjectID: "123", Key: otherKey}
+			assert.NoError(t, service.ResetDriftIncrement(ctx, env, "apply"))
+
+			issueID, err := storage.GetField(ctx, otherKey, "issueID")
+			assert.NoError(t, err)
+			assert.Empty(t, issueID, "Resetting environment should drop its reference either way")
+
+			issueID, err = storage.GetField(ctx, ownerKey, "issueID")
+			assert.NoError(t, err)
+			assert.Equal(t, "7", issueID, "Other environment's state should be untouched")
+
+			owner, err := storage.GetField(ctx, IssueOwnerKey(123, 7), "environmentKey")
+			assert.NoError(t, err)
+			if tt.expectClose {
+				assert.Empty(t, owner, "Reverse index should be cleared once the issue is closed")
+			} else {
+				assert.Equal(t, ownerKey, owner, "Reverse index should still point at the owner")
+			}
+
+			keys, err := storage.ListOpenIssues(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{ownerKey}, keys)
+			mockTracker.AssertExpectations(t)
+		})
+	}
+}
+
+// TestEscalationChecker_CheckEscalations tests escalation trigger and idempotency
+func TestEscalationChecker_CheckEscalations(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	overdue := map[string]string{
+		"issueID":        "7",
+		"projectID":      "123",
+		"issueCreatedAt": time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		name             string
+		data             map[string]string
+		claimResult      *bool
+		failComment      bool
+		issueProject     string
+		expectEscalation bool
+		expectNotify     bool
+		expectRelease    bool
+	}{
+		{
+			name:             "issue past escalation window is escalated",
+			data:             overdue,
+			claimResult:      boolPtr(true),
+			expectEscalation: true,
+			expectNotify:     true,
+		},
+		{
+			name: "issue within escalation window is not escalated",
+			data: map[string]string{
+				"issueID":        "7",
+				"projectID":      "123",
+				"issueCreatedAt": time.Now().Add(-30 * time.Minute).Format(time.RFC3339),
+			},
+		},
+		{
+			name: "already escalated issue is not escalated again",
+			data: map[string]string{
+				"issueID":        "7",
+				"projectID":      "123",
+				"issueCreatedAt": time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+				"escalated":      "true",
+			},
+		},
+		{
+			name:        "escalation claimed by another checker is skipped",
+			data:        overdue,
+			claimResult: boolPtr(false),
+		},
+		{
+			name: "issue filed in the issue project is escalated there",
+			data: map[string]string{
+				"issueID":        "7",
+				"projectID":      "123",
+				"issueProjectID": "999",
+				"issueCreatedAt": time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+			},
+			claimResult:      boolPtr(true),
+			issueProject:     "999",
+			expectEscalation: true,
+			expectNotify:     true,
+		},
+		{
+			name:          "failed notification releases the claim",
+			data:          overdue,
+			claimResult:   boolPtr(true),
+			failComment:   true,
+			expectNotify:  true,
+			expectRelease: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				if tt.failComment && strings.HasSuffix(r.URL.Path, "/notes") {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{
+				GitLabBaseURL:   mockServer.URL,
+				GitLabToken:     "test-token",
+				EscalationAfter: time.Hour,
+				EscalationLabel: "drift-escalated",
+			}
+			mockStorage := new(MockStorageRepository)
+			auditor := &recordingAuditLogger{}
+			checker := NewEscalationChecker(mockStorage, client.NewGitLabClient(cfg), cfg).WithAuditLogger(auditor)
+
+			mockStorage.On("ListOpenIssues", ctx).Return([]string{key}, nil).Once()
+			mockStorage.On("GetEnvironmentData", ctx, key).Return(tt.data, nil).Once()
+			if tt.claimResult != nil {
+				mockStorage.On("SetFieldIfEmpty", ctx, key, "escalated", "true").Return(*tt.claimResult, nil).Once()
+			}
+			if tt.expectRelease {
+				mockStorage.On("SetField", ctx, key, "escalated", "").Return(nil).Once()
+			}
+
+			escalated, err := checker.CheckEscalations(ctx)
+			assert.NoError(t, err, "Escalation check should not fail")
+
+			if tt.expectEscalation {
+				assert.Equal(t, 1, escalated)
+				if assert.Len(t, auditor.entries, 1, "The escalation should be audited") {
+					assert.Equal(t, audit.ActionIssueEscalate, auditor.entries[0].Action)
+					assert.Equal(t, audit.SystemPrincipal, auditor.entries[0].Principal)
+					assert.Equal(t, "test-repo", auditor.entries[0].RepoName)
+				}
+			} else {
+				assert.Equal(t, 0, escalated)
+				assert.Empty(t, auditor.entries)
+			}
+
+			issueProject := tt.issueProject
+			if issueProject == "" {
+				issueProject = "123"
+			}
+
+			if tt.expectNotify {
+				assert.Contains(t, requests, "PUT /projects/"+issueProject+"/issues/7", "Escalation label should be applied")
+				assert.Contains(t, requests, "POST /projects/"+issueProject+"/issues/7/notes", "Escalation notification should be sent")
+			} else {
+				assert.NotContains(t, requests, "PUT /projects/123/issues/7", "No escalation label expected")
+				assert.NotContains(t, requests, "POST /projects/123/issues/7/notes", "No escalation notification expected")
+			}
+			mockStorage.AssertNotCalled(t, "SetField", ctx, key, "escalated", "true")
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+// TestAckStatus tests evaluating stored acknowledgement times
+func TestAckStatus(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour).Format(time.RFC3339)
+	past := now.Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		ackUntil  string
+		resolveBy string
+		expected  ackState
+	}{
+		{name: "no acknowledgement", expected: ackNone},
+		{name: "active without resolve-by", ackUntil: future, expected: ackActive},
+		{name: "active before resolve-by", ackUntil: future, resolveBy: future, expected: ackActive},
+		{name: "resolve-by passed", ackUntil: future, resolveBy: past, expected: ackOverdue},
+		{name: "lapsed", ackUntil: past, expected: ackExpired},
+		{name: "malformed ack time lapses", ackUntil: "tomorrow", expected: ackExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ackStatus(tt.ackUntil, tt.resolveBy, now))
+		})
+	}
+}
+
+// TestAcknowledgeDrift tests validating and storing acknowledgements
+func TestAcknowledgeDrift(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		ack         Acknowledgement
+		expectedErr error
+	}{
+		{name: "ack with resolve-by", ack: Acknowledgement{RepoName: "test-repo", Environment: "production", AckUntil: now.Add(48 * time.Hour), ResolveBy: timePtr(now.Add(24 * time.Hour))}},
+		{name: "ack without resolve-by", ack: Acknowledgement{RepoName: "test-repo", Environment: "production", AckUntil: now.Add(time.Hour)}},
+		{name: "ack in the past", ack: Acknowledgement{RepoName: "test-repo", Environment: "production", AckUntil: now.Add(-time.Hour)}, expectedErr: ErrInvalidAcknowledgement},
+		{name: "ack beyond maximum duration", ack: Acknowledgement{RepoName: "test-repo", Environment: "production", AckUntil: now.Add(30 * 24 * time.Hour)}, expectedErr: ErrInvalidAcknowledgement},
+		{name: "resolve-by after ack ends", ack: Acknowledgement{RepoName: "test-repo", Environment: "production", AckUntil: now.Add(time.Hour), ResolveBy: timePtr(now.Add(2 * time.Hour))}, expectedErr: ErrInvalidAcknowledgement},
+		{name: "missing environment", ack: Acknowledgement{RepoName: "test-repo", AckUntil: now.Add(time.Hour)}, expectedErr: ErrInvalidAcknowledgement},
+		{name: "unknown environment", ack: Acknowledgement{RepoName: "test-repo", Environment: "staging", AckUntil: now.Add(time.Hour)}, expectedErr: ErrEnvironmentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 1, AckMaxDuration: 7 * 24 * time.Hour}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+
+			err = service.AcknowledgeDrift(ctx, tt.ack)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			result, err := service.GetEnvironmentState(ctx, "test-repo", "production")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ack.AckUntil.UTC().Format(time.RFC3339), result.AckUntil)
+			if tt.ack.ResolveBy == nil {
+				assert.Empty(t, result.AckResolveBy)
+			} else {
+				assert.Equal(t, tt.ack.ResolveBy.UTC().Format(time.RFC3339), result.AckResolveBy)
+			}
+		})
+	}
+}
+
+// TestProcessDriftDetection_Acknowledgement tests that acknowledged drift is silenced until its
+// resolve-by time, escalated after it, and ended silently when the drift resolves
+func TestProcessDriftDetection_Acknowledgement(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	now := time.Now()
+
+	tests := []struct {
+		name             string
+		ackUntil         time.Time
+		resolveBy        time.Time
+		exitCode         int
+		expectSilenced   bool
+		expectEscalation bool
+		expectAckCleared bool
+	}{
+		{
+			name:           "active acknowledgement silences the breach",
+			ackUntil:       now.Add(24 * time.Hour),
+			resolveBy:      now.Add(12 * time.Hour),
+			exitCode:       2,
+			expectSilenced: true,
+		},
+		{
+			name:             "drift past resolve-by is escalated",
+			ackUntil:         now.Add(24 * time.Hour),
+			resolveBy:        now.Add(-time.Hour),
+			exitCode:         2,
+			expectEscalation: true,
+			expectAckCleared: true,
+		},
+		{
+			name:             "lapsed acknowledgement resumes issue updates",
+			ackUntil:         now.Add(-time.Hour),
+			exitCode:         2,
+			expectAckCleared: true,
+		},
+		{
+			name:             "drift resolved before resolve-by ends the acknowledgement silently",
+			ackUntil:         now.Add(24 * time.Hour),
+			resolveBy:        now.Add(12 * time.Hour),
+			exitCode:         0,
+			expectAckCleared: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			escalated := false
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				if labels, _ := body["add_labels"].(string); labels == "drift-escalated" {
+					escalated = true
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, EscalationLabel: "drift-escalated"}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+			assert.NoError(t, storage.SetField(ctx, key, "ackUntil", tt.ackUntil.UTC().Format(time.RFC3339)))
+			if !tt.resolveBy.IsZero() {
+				assert.NoError(t, storage.SetField(ctx, key, "ackResolveBy", tt.resolveBy.UTC().Format(time.RFC3339)))
+			}
+
+			_, err = service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        tt.exitCode,
+				Scheduled:       true,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectEscalation, escalated, "Unexpected escalation")
+			if tt.expectSilenced {
+				assert.Empty(t, requests, "Acknowledged drift should not touch the issue")
+			} else {
+				assert.NotEmpty(t, requests, "The issue should be managed as usual")
+			}
+
+			ackUntil, err := storage.GetField(ctx, key, "ackUntil")
+			assert.NoError(t, err)
+			if tt.expectAckCleared {
+				assert.Empty(t, ackUntil, "Acknowledgement should be cleared")
+			} else {
+				assert.NotEmpty(t, ackUntil, "Acknowledgement should remain")
+			}
+		})
+	}
+}
+
+// TestEscalationChecker_ExpiredEnvironment tests that environments expired by retention leave the
+// open issue index instead of failing every check
+func TestEscalationChecker_ExpiredEnvironment(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	cfg := &config.Config{EscalationAfter: time.Hour, EscalationLabel: "drift-escalated"}
+	mockStorage := new(MockStorageRepository)
+	checker := NewEscalationChecker(mockStorage, new(MockIssueTracker), cfg)
+
+	mockStorage.On("ListOpenIssues", ctx).Return([]string{key}, nil).Once()
+	mockStorage.On("GetEnvironmentData", ctx, key).Return(nil, repository.ErrEnvironmentNotFound).Once()
+	mockStorage.On("RemoveOpenIssue", ctx, key).Return(nil).Once()
+
+	escalated, err := checker.CheckEscalations(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, escalated)
+	mockStorage.AssertExpectations(t)
+}
+
+// TestAckChecker_CheckAcknowledgements tests that the background check escalates overdue acknowledgements
+func TestAckChecker_CheckAcknowledgements(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	var requests []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", EscalationLabel: "drift-escalated"}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	checker := NewAckChecker(storage, client.NewGitLabClient(cfg), cfg)
+
+	environments := map[string]map[string]string{
+		"test-repo:overdue": {"issueID": "7", "ackUntil": now.Add(time.Hour).Format(time.RFC3339), "ackResolveBy": now.Add(-time.Minute).Format(time.RFC3339)},
+		"test-repo:active":  {"issueID": "8", "ackUntil": now.Add(time.Hour).Format(time.RFC3339), "ackResolveBy": now.Add(time.Minute).Format(time.RFC3339)},
+		"test-repo:lapsed":  {"issueID": "9", "ackUntil": now.Add(-time.Minute).Format(time.RFC3339)},
+	}
+	for key, fields := range environments {
+		_, err := storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+		assert.NoError(t, err)
+		for field, value := range fields {
+			assert.NoError(t, storage.SetField(ctx, key, field, value))
+		}
+		assert.NoError(t, storage.AddOpenIssue(ctx, key))
+	}
+
+	escalated, err := checker.CheckAcknowledgements(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, escalated)
+	assert.Equal(t, []string{"PUT /projects/123/issues/7", "POST /projects/123/issues/7/notes"}, requests)
+
+	for key, expectAck := range map[string]bool{"test-repo:overdue": false, "test-repo:active": true, "test-repo:lapsed": false} {
+		ackUntil, err := storage.GetField(ctx, key, "ackUntil")
+		assert.NoError(t, err)
+		assert.Equal(t, expectAck, ackUntil != "", "Unexpected acknowledgement state for %s", key)
+	}
+}
+
+// TestIssueReconciler_Reconcile tests that dangling issue references are cleared
+func TestIssueReconciler_Reconcile(t *testing.T) {
+	ctx := context.Background()
+
+	mockStorage := new(MockStorageRepository)
+	mockTracker := new(MockIssueTracker)
+	recorder := &recordingMetrics{}
+	reconciler := NewIssueReconciler(mockStorage, mockTracker, recorder, &config.Config{})
+
+	mockStorage.On("ListOpenIssues", ctx).Return([]string{"repo:deleted", "repo:open", "repo:no-issue", "repo:expired"}, nil).Once()
+
+	// Issue deleted in GitLab: the 404 reports as not open and the reference is cleared
+	mockStorage.On("GetEnvironmentData", ctx, "repo:deleted").Return(map[string]string{"issueID": "7", "projectID": "123", "issueProjectID": "999"}, nil).Once()
+	mockTracker.On("GetIssueStatus", ctx, 999, 7).Return(false, nil).Once()
+	mockStorage.On("SetField", ctx, "repo:deleted", "issueID", "").Return(nil).Once()
+	mockStorage.On("SetField", ctx, "repo:deleted", "issueURL", "").Return(nil).Once()
+	mockStorage.On("RemoveOpenIssue", ctx, "repo:deleted").Return(nil).Once()
+
+	// Open issue is left alone
+	mockStorage.On("GetEnvironmentData", ctx, "repo:open").Return(map[string]string{"issueID": "8", "projectID": "123"}, nil).Once()
+	mockTracker.On("GetIssueStatus", ctx, 123, 8).Return(true, nil).Once()
+
+	// Stale index entry without an issue is dropped from the index
+	mockStorage.On("GetEnvironmentData", ctx, "repo:no-issue").Return(map[string]string{"projectID": "123"}, nil).Once()
+	mockStorage.On("RemoveOpenIssue", ctx, "repo:no-issue").Return(nil).Once()
+
+	// Environment expired by retention is dropped from the index
+	mockStorage.On("GetEnvironmentData", ctx, "repo:expired").Return(nil, repository.ErrEnvironmentNotFound).Once()
+	mockStorage.On("RemoveOpenIssue", ctx, "repo:expired").Return(nil).Once()
+
+	result, err := reconciler.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ReconcileResult{Checked: 4, Dangling: 1}, result)
+	assert.Equal(t, []string{"count issue.reconciled 4 ", "count issue.dangling 1 "}, recorder.entries)
+
+	mockStorage.AssertNotCalled(t, "SetField", ctx, "repo:open", "issueID", "")
+	mockStorage.AssertExpectations(t)
+	mockTracker.AssertExpectations(t)
+}
+
+// TestIssueReconciler_BatchedStatuses tests that reconciliation checks issues with batched list
+// requests per project when ISSUE_STATUS_BATCH_SIZE is set
+func TestIssueReconciler_BatchedStatuses(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iids := r.URL.Query()["iids[]"]
+		sort.Strings(iids)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+strings.Join(iids, ","))
+		switch r.URL.Path {
+		case "/projects/123/issues":
+			_, _ = w.Write([]byte(`[{"iid": 7, "state": "opened"}, {"iid": 8, "state": "closed"}]`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", IssueStatusBatchSize: 50}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	for key, issue := range map[string][2]string{"repo:open": {"7", "123"}, "repo:closed": {"8", "123"}, "repo:other": {"9", "456"}} {
+		_, err := storage.InitializeEnvironment(ctx, key, "prod", issue[1], "1", "main")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.SetField(ctx, key, "issueID", issue[0]))
+		assert.NoError(t, storage.AddOpenIssue(ctx, key))
+	}
+
+	reconciler := NewIssueReconciler(storage, client.NewGitLabClient(cfg), noopMetrics, cfg)
+	result, err := reconciler.Reconcile(ctx)
+	assert.NoError(t, err)
+
+	// Project 456's batch fails and its issue falls back to a single check, which fails too
+	assert.Equal(t, ReconcileResult{Checked: 2, Dangling: 1}, result)
+	assert.ElementsMatch(t, []string{
+		"GET /projects/123/issues 7,8",
+		"GET /projects/456/issues 9",
+		"GET /projects/456/issues/9 ",
+	}, requests)
+
+	closedIssue, _ := storage.GetField(ctx, "repo:closed", "issueID")
+	assert.Empty(t, closedIssue)
+	openIssue, _ := storage.GetField(ctx, "repo:open", "issueID")
+	assert.Equal(t, "7", openIssue)
+}
+
+// boolPtr returns a pointer to b
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// TestStripANSI tests removal of terminal escape sequences from plan output
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "plain output unchanged",
+			input:    "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expected: "Plan: 1 to add, 0 to change, 0 to destroy.",
+		},
+		{
+			name:     "colour codes removed",
+			input:    "\x1b[1m\x1b[32m+\x1b[0m\x1b[0m resource \"aws_instance\" \"example\" {",
+			expected: "+ resource \"aws_instance\" \"example\" {",
+		},
+		{
+			name:     "bold summary removed",
+			input:    "\x1b[0m\x1b[1mPlan:\x1b[0m 0 to add, 1 to change, 0 to destroy.\x1b[0m",
+			expected: "Plan: 0 to add, 1 to change, 0 to destroy.",
+		},
+		{
+			name:     "hyperlink sequence removed",
+			input:    "\x1b]8;;https://example.com\x07link\x1b]8;;\x07",
+			expected: "link",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StripANSI(tt.input), "ANSI sequences should be stripped")
+		})
+	}
+}
+
+// TestThresholdManager_GetThreshold tests stored threshold handling including zero and negative values
+func TestThresholdManager_GetThreshold(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name        string
+		stored      string
+		expected    int
+		expectError bool
+	}{
+		{name: "stored threshold used", stored: "3", expected: 3},
+		{name: "threshold of one alerts immediately", stored: "1", expected: 1},
+		{name: "missing threshold uses default", stored: "", expected: 2},
+		{name: "zero threshold uses default", stored: "0", expected: 2},
+		{name: "negative threshold uses default", stored: "-1", expected: 2},
+		{name: "non-numeric threshold fails", stored: "abc", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorageRepository)
+			manager := NewThresholdManager(mockStorage, &config.Config{DriftThreshold: 2})
+
+			mockStorage.On("GetField", ctx, key, "driftThreshold").Return(tt.stored, nil).Once()
+
+			threshold, err := manager.GetThreshold(ctx, key)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, threshold)
+
+			// Zero drift never meets a valid threshold
+			mockStorage.On("GetField", ctx, key, "driftThreshold").Return(tt.stored, nil).Once()
+			exceeded, err := manager.CheckThreshold(ctx, key, 0)
+			assert.NoError(t, err)
+			assert.False(t, exceeded, "Zero drift should never exceed the threshold")
+		})
+	}
+}
+
+// TestGetGroupDrift tests membership tracking and aggregation of environment group drift
+func TestGetGroupDrift(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		GitLabBaseURL:     "http://localhost",
+		ComparisonBranch:  "main",
+		DriftThreshold:    10,
+		EnvironmentGroups: map[string][]string{"shop-prod": {"shop/prod-*", "shop-data/prod"}},
+		GroupAggregation:  "any",
+	}
+	storage, err := repository.NewMemoryRepository("", 10)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	reports := []struct {
+		repoName    string
+		environment string
+		exitCode    int
+	}{
+		{"shop", "prod-network", 2},
+		{"shop", "prod-network", 2},
+		{"shop", "prod-compute", 2},
+		{"shop-data", "prod", 0},
+		{"shop", "staging-network", 2},
+	}
+	for _, report := range reports {
+		_, err := service.ProcessDriftDetection(ctx, Payload{
+			RepoName:        report.repoName,
+			Branch:          "main",
+			Environment:     report.environment,
+			EnvironmentTier: "prod",
+			ProjectID:       "123",
+			Operation:       "plan",
+			ExitCode:        report.exitCode,
+			Scheduled:       true,
+		})
+		assert.NoError(t, err)
+	}
+
+	members := []GroupMember{
+		{RepoName: "shop-data", Environment: "prod", DriftIncrement: 0},
+		{RepoName: "shop", Environment: "prod-compute", DriftIncrement: 1},
+		{RepoName: "shop", Environment: "prod-network", DriftIncrement: 2},
+	}
+
+	t.Run("any reports the most drifted member", func(t *testing.T) {
+		result, err := service.GetGroupDrift(ctx, "shop-prod", "")
+		assert.NoError(t, err)
+		assert.Equal(t, &GroupDrift{Group: "shop-prod", Aggregation: "any", Drifted: true, DriftCount: 2, Members: members}, result)
+	})
+
+	t.Run("sum reports the total", func(t *testing.T) {
+		result, err := service.GetGroupDrift(ctx, "shop-prod", "sum")
+		assert.NoError(t, err)
+		assert.Equal(t, &GroupDrift{Group: "shop-prod", Aggregation: "sum", Drifted: true, DriftCount: 3, Members: members}, result)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		_, err := service.GetGroupDrift(ctx, "shop-staging", "")
+		assert.ErrorIs(t, err, ErrGroupNotFound)
+	})
+
+	t.Run("unknown aggregation", func(t *testing.T) {
+		_, err := service.GetGroupDrift(ctx, "shop-prod", "average")
+		assert.ErrorIs(t, err, ErrInvalidAggregation)
+	})
+
+	t.Run("expired members are left out", func(t *testing.T) {
+		assert.NoError(t, storage.Expire(ctx, "shop:prod-network", 0))
+		assert.NoError(t, storage.Expire(ctx, "shop:prod-compute", 0))
+
+		result, err := service.GetGroupDrift(ctx, "shop-prod", "sum")
+		assert.NoError(t, err)
+		assert.Equal(t, &GroupDrift{Group: "shop-prod", Aggregation: "sum", Members: []GroupMember{{RepoName: "shop-data", Environment: "prod"}}}, result)
+	})
+}
+
+// TestProcessDriftDetection_Replayed tests that replayed reports only apply when newer than the latest operation
+func TestProcessDriftDetection_Replayed(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		reportedAt    time.Time
+		expectedDrift string
+		expectedLogAt time.Time
+	}{
+		{name: "missed report is counted", reportedAt: now.Add(-time.Hour), expectedDrift: "2", expectedLogAt: now.Add(-time.Hour)},
+		{name: "report older than the latest operation is ignored", reportedAt: now.Add(-3 * time.Hour), expectedDrift: "1", expectedLogAt: now.Add(-2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{GitLabBaseURL: "http://localhost", ComparisonBranch: "main", DriftThreshold: 10}
+			storage, err := repository.NewMemoryRepository("", 10)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			payload := Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				Timestamp:       now.Add(-2 * time.Hour).Format(time.RFC3339),
+			}
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+
+			payload.Replayed = true
+			payload.Timestamp = tt.reportedAt.Format(time.RFC3339)
+			result, err := service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+
+			logEntry, _ := storage.GetField(ctx, key, "log")
+			assert.Contains(t, logEntry, tt.expectedLogAt.Format(time.RFC3339), "The latest operation stays recorded")
+		})
+	}
+}
+
+// TestProcessDriftDetection_PipelineSource tests that the pipeline source reaches the operation log and the issue
+func TestProcessDriftDetection_PipelineSource(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	var description string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if d, ok := body["description"].(string); ok {
+			description = d
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "web_url": "https://gitlab.example.com/issues/7"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	_, err = service.ProcessDriftDetection(ctx, Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		PipelineSource:  "schedule",
+	})
+	assert.NoError(t, err)
+
+	logValue, _ := storage.GetField(ctx, key, "log")
+	var entry repository.OperationLogEntry
+	assert.NoError(t, json.Unmarshal([]byte(logValue), &entry))
+	assert.Equal(t, "schedule", entry.PipelineSource)
+
+	assert.Contains(t, description, "Triggered by a `schedule` pipeline.")
+}
+
+// TestProcessDriftDetection_IssueUpdateInterval tests that replicas sharing storage update an issue once per interval
+func TestProcessDriftDetection_IssueUpdateInterval(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name            string
+		interval        time.Duration
+		expectedUpdates int
+	}{
+		{name: "second replica skips the update", interval: time.Hour, expectedUpdates: 1},
+		{name: "no interval updates on every report", interval: 0, expectedUpdates: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					updates++
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, IssueUpdateInterval: tt.interval}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+
+			for replica := 0; replica < 2; replica++ {
+				service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+				_, err = service.ProcessDriftDetection(ctx, Payload{
+					RepoName:        "test-repo",
+					Branch:          "main",
+					Environment:     "production",
+					EnvironmentTier: "prod",
+					ProjectID:       "123",
+					Operation:       "plan",
+					ExitCode:        2,
+					Scheduled:       true,
+				})
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedUpdates, updates)
+		})
+	}
+}
+
+// TestClaimIssueUpdate_LockFailure tests that a storage failure does not block the issue update
+func TestClaimIssueUpdate_LockFailure(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(MockStorageRepository)
+	mockStorage.On("AcquireLock", ctx, "issue-update:test-repo:production", time.Minute).Return(false, assert.AnError).Once()
+
+	service := &DriftServiceImpl{storage: mockStorage, metrics: noopMetrics, config: &config.Config{IssueUpdateInterval: time.Minute}}
+	assert.True(t, service.claimIssueUpdate(ctx, EnvironmentInfo{Key: "test-repo:production"}, 7))
+	mockStorage.AssertExpectations(t)
+}
+
+// TestProcessDriftDetection_InitFailed tests that runs whose terraform init failed are logged
+// without counting drift or touching the issue
+func TestProcessDriftDetection_InitFailed(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	for _, operation := range []string{"plan", "apply"} {
+		t.Run(operation, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, FailedApplyAsDrift: true}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "2"))
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", "7"))
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       operation,
+				ExitCode:        1,
+				Scheduled:       true,
+				InitFailed:      true,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "2", result.DriftIncrement, "A failed init must not change the drift count")
+			assert.Empty(t, requests)
+
+			logValue, _ := storage.GetField(ctx, key, "log")
+			var entry repository.OperationLogEntry
+			assert.NoError(t, json.Unmarshal([]byte(logValue), &entry))
+			assert.Equal(t, operation, entry.Operation)
+			assert.Equal(t, 1, entry.ExitCode)
+		})
+	}
+}
+
+// TestProcessDriftDetection_DriftDuration tests timing drift from its first breach to its resolution
+func TestProcessDriftDetection_DriftDuration(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+
+	tests := []struct {
+		name            string
+		track           bool
+		expectedComment string
+		expectedMetrics []string
+	}{
+		{
+			name:            "duration recorded at resolution",
+			track:           true,
+			expectedComment: "The environment was drifted for 1h 30m.",
+			expectedMetrics: []string{"histogram drift.resolution_seconds 5400 repo:test-repo,environment:production,tier:prod"},
+		},
+		{name: "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var closeComment string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/notes") {
+					var body map[string]string
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					closeComment = body["body"]
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "state": "opened", "web_url": "https://gitlab.example.com/issues/7"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, TrackDriftDuration: tt.track}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			recorder := &recordingMetrics{}
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), recorder, cfg)
+
+			// The first breach is timed; a repeated breach keeps its time
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			firstBreachAt, _ := storage.GetField(ctx, key, "firstBreachAt")
+			assert.Equal(t, tt.track, firstBreachAt != "")
+
+			if tt.track {
+				firstBreachAt = time.Now().Add(-90 * time.Minute).UTC().Format(time.RFC3339)
+				assert.NoError(t, storage.SetField(ctx, key, "firstBreachAt", firstBreachAt))
+			}
+			result, err := service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			assert.Equal(t, firstBreachAt, result.FirstBreachAt)
+
+			apply := payload
+			apply.Operation, apply.ExitCode, apply.Scheduled = "apply", 0, false
+			recorder.entries = nil
+			result, err = service.ProcessDriftDetection(ctx, apply)
+			assert.NoError(t, err)
+
+			assert.Empty(t, result.FirstBreachAt, "Resolution should clear the first breach")
+			assert.Contains(t, closeComment, "**Drift Resolved**")
+			var histograms []string
+			for _, entry := range recorder.entries {
+				if strings.HasPrefix(entry, "histogram") {
+					histograms = append(histograms, entry)
+				}
+			}
+			assert.Equal(t, tt.expectedMetrics, histograms)
+
+			if tt.track {
+				assert.Equal(t, "5400", result.DriftDuration)
+				assert.Contains(t, closeComment, tt.expectedComment)
+			} else {
+				assert.Empty(t, result.DriftDuration)
+				assert.NotContains(t, closeComment, "drifted for")
+			}
+		})
+	}
+}
+
+// TestValidatePayload_ClockSkew tests holding payload timestamps to MAX_CLOCK_SKEW of server time
+func TestValidatePayload_ClockSkew(t *testing.T) {
+	now := time.Now().UTC()
+	at := func(offset time.Duration) string { return now.Add(offset).Format(time.RFC3339) }
+
+	tests := []struct {
+		name          string
+		maxSkew       time.Duration
+		action        string
+		timestamp     string
+		replayed      bool
+		noReplayAge   bool
+		expectedError string
+		clamped       bool
+	}{
+		{name: "in window", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(-2 * time.Minute)},
+		{name: "future rejected", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(time.Hour), expectedError: "invalid timestamp in payload: must be an RFC3339 time within 5m0s of server time"},
+		{name: "far past rejected", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(-72 * time.Hour), expectedError: "invalid timestamp in payload"},
+		{name: "unparsable rejected", maxSkew: 5 * time.Minute, action: "reject", timestamp: "yesterday", expectedError: "invalid timestamp in payload"},
+		{name: "replayed past allowed", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(-72 * time.Hour), replayed: true},
+		{name: "replayed beyond max age rejected", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(-30 * 24 * time.Hour), replayed: true, expectedError: "invalid timestamp in payload: replayed reports must be an RFC3339 time at most 168h0m0s old"},
+		{name: "replayed without max age held to the skew", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(-72 * time.Hour), replayed: true, noReplayAge: true, expectedError: "invalid timestamp in payload"},
+		{name: "replayed future rejected", maxSkew: 5 * time.Minute, action: "reject", timestamp: at(time.Hour), replayed: true, expectedError: "invalid timestamp in payload"},
+		{name: "future clamped", maxSkew: 5 * time.Minute, action: "clamp", timestamp: at(time.Hour), clamped: true},
+		{name: "far past clamped", maxSkew: 5 * time.Minute, action: "clamp", timestamp: at(-72 * time.Hour), clamped: true},
+		{name: "missing timestamp allowed", maxSkew: 5 * time.Minute, action: "reject"},
+		{name: "disabled", timestamp: at(-72 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MaxClockSkew: tt.maxSkew, ClockSkewAction: tt.action, ReplayMaxAge: 7 * 24 * time.Hour}
+			if tt.noReplayAge {
+				cfg.ReplayMaxAge = 0
+			}
+			service := &DriftServiceImpl{config: cfg}
+			payload := &Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				Timestamp:       tt.timestamp,
+				Replayed:        tt.replayed,
+			}
+
+			err := service.ValidatePayload(payload)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			if tt.clamped {
+				clampedAt, err := time.Parse(time.RFC3339, payload.Timestamp)
+				assert.NoError(t, err)
+				assert.WithinDuration(t, time.Now(), clampedAt, time.Minute, "Clamped timestamps should be server time")
+			} else {
+				assert.Equal(t, tt.timestamp, payload.Timestamp)
+			}
+		})
+	}
+}
+
+// TestProcessDriftDetection_ChangeFreeze tests that drift is counted but not filed during a change
+// freeze, and that the issue is filed on the first breach after it
+func TestProcessDriftDetection_ChangeFreeze(t *testing.T) {
+	ctx := context.Background()
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+
+	var requests []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 7, "web_url": "https://gitlab.example.com/issues/7"})
+	}))
+	defer mockServer.Close()
+
+	now := time.Now()
+	cfg := &config.Config{
+		GitLabBaseURL:    mockServer.URL,
+		GitLabToken:      "test-token",
+		ComparisonBranch: "main",
+		DriftThreshold:   1,
+		FreezeWindows:    []config.FreezeWindow{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}},
+	}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	recorder := &recordingMetrics{}
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), recorder, cfg)
+
+	for range 2 {
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+	}
+	result, err := service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result.DriftIncrement, "Drift should keep counting during a freeze")
+	assert.Empty(t, result.IssueID)
+	assert.Empty(t, requests, "No issue should be filed during a freeze")
+	assert.Contains(t, recorder.entries, "count issue.frozen 1 repo:test-repo,environment:production,tier:prod")
+
+	// The freeze ends
+	cfg.FreezeWindows = []config.FreezeWindow{{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}}
+	result, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", result.DriftIncrement)
+	assert.Equal(t, "7", result.IssueID, "The first breach after the freeze should file the issue")
+	assert.Equal(t, []string{"POST /projects/123/issues"}, requests)
+}
+
+// TestProcessDriftDetection_PlanError tests that a failed comparison-branch plan is surfaced apart from drift
+func TestProcessDriftDetection_PlanError(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name               string
+		enabled            bool
+		branch             string
+		issueID            string
+		planErrorIssueID   string
+		expectedRequests   []string
+		expectedPlanIssue  string
+		expectedPlanMetric bool
+	}{
+		{name: "disabled only records metric", branch: "main", expectedPlanMetric: true},
+		{
+			name:               "open drift issue is labelled",
+			enabled:            true,
+			branch:             "main",
+			issueID:            "7",
+			expectedRequests:   []string{"PUT /projects/123/issues/7 add_labels=plan-error"},
+			expectedPlanMetric: true,
+		},
+		{
+			name:               "plan error issue is created",
+			enabled:            true,
+			branch:             "main",
+			expectedRequests:   []string{"POST /projects/123/issues title=Plan error: production"},
+			expectedPlanIssue:  "9",
+			expectedPlanMetric: true,
+		},
+		{
+			name:               "open plan error issue is not duplicated",
+			enabled:            true,
+			branch:             "main",
+			planErrorIssueID:   "9",
+			expectedRequests:   []string{"GET /projects/123/issues/9"},
+			expectedPlanIssue:  "9",
+			expectedPlanMetric: true,
+		},
+		{name: "feature branch plan is ignored", enabled: true, branch: "feature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				request := r.Method + " " + r.URL.Path
+				switch r.Method {
+				case http.MethodPut:
+					request += fmt.Sprintf(" add_labels=%v", body["add_labels"])
+				case http.MethodPost:
+					request += fmt.Sprintf(" title=%v", body["title"])
+				}
+				requests = append(requests, request)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 9, "state": "opened"})
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, PlanErrorIssues: tt.enabled}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			recorder := &recordingMetrics{}
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), recorder, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "1", "main")
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "2"))
+			assert.NoError(t, storage.SetField(ctx, key, "issueID", tt.issueID))
+			if tt.planErrorIssueID != "" {
+				assert.NoError(t, storage.SetField(ctx, key, "planErrorIssueID", tt.planErrorIssueID))
+				assert.NoError(t, storage.SetField(ctx, key, "planErrorIssueProjectID", "123"))
+			}
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          tt.branch,
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        1,
+				Scheduled:       true,
+				PlanOutput:      "Error: No valid credential sources found",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "2", result.DriftIncrement, "A failed plan must not change the drift count")
+			assert.Equal(t, tt.expectedRequests, requests)
+
+			state, err := service.GetEnvironmentState(ctx, "test-repo", "production")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPlanIssue, state.PlanErrorIssueID)
+			assert.Equal(t, tt.enabled && tt.branch == "main", state.LastPlanErrorAt != "")
+
+			if tt.expectedPlanMetric {
+				assert.Contains(t, recorder.entries, "count plan.error 1 repo:test-repo,environment:production,tier:prod")
+			} else {
+				assert.NotContains(t, recorder.entries, "count plan.error 1 repo:test-repo,environment:production,tier:prod")
+			}
+		})
+	}
+}
+
+// TestProcessDriftDetection_TagComparison tests that drift can be measured against tags matching a pattern
+func TestProcessDriftDetection_TagComparison(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	tests := []struct {
+		name          string
+		refTypeConfig string
+		branch        string
+		refType       string
+		exitCode      int
+		expectedDrift string
+	}{
+		{name: "matching tag counts drift", refTypeConfig: "tag", branch: "v1.2.0", refType: "tag", exitCode: 2, expectedDrift: "2"},
+		{name: "matching tag without changes resets drift", refTypeConfig: "tag", branch: "v1.2.0", refType: "tag", exitCode: 0, expectedDrift: "0"},
+		{name: "non-matching tag is ignored", refTypeConfig: "tag", branch: "nightly", refType: "tag", exitCode: 2, expectedDrift: "1"},
+		{name: "branch is ignored in tag mode", refTypeConfig: "tag", branch: "main", exitCode: 2, expectedDrift: "1"},
+		{name: "tag is ignored in branch mode", branch: "main", refType: "tag", exitCode: 2, expectedDrift: "1"},
+		{name: "branch counts drift in branch mode", branch: "main", refType: "branch", exitCode: 2, expectedDrift: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparisonRef := "main"
+			if tt.refTypeConfig == "tag" {
+				comparisonRef = "v*"
+			}
+			cfg := &config.Config{ComparisonBranch: comparisonRef, ComparisonRefType: tt.refTypeConfig, DriftThreshold: 10}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, &MockIssueTracker{}, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			_, err = storage.InitializeEnvironment(ctx, key, "prod", "123", "10", comparisonRef)
+			assert.NoError(t, err)
+			assert.NoError(t, storage.SetField(ctx, key, "driftIncrement", "1"))
+
+			result, err := service.ProcessDriftDetection(ctx, Payload{
+				RepoName:        "test-repo",
+				Branch:          tt.branch,
+				RefType:         tt.refType,
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        tt.exitCode,
+				Scheduled:       true,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, result.DriftIncrement)
+		})
+	}
+}
+
+// TestChangedResources tests parsing the changed resource count from plan summaries
+func TestChangedResources(t *testing.T) {
+	tests := []struct {
+		name          string
+		planOutput    string
+		expectedCount int
+		expectedOK    bool
+	}{
+		{name: "summary", planOutput: "  + resource\n\nPlan: 1 to add, 2 to change, 3 to destroy.", expectedCount: 6, expectedOK: true},
+		{name: "summary with imports", planOutput: "Plan: 2 to import, 1 to add, 0 to change, 0 to destroy.", expectedCount: 1, expectedOK: true},
+		{name: "colored summary", planOutput: "\x1b[1mPlan:\x1b[0m 1 to add, 0 to change, 0 to destroy.", expectedCount: 1, expectedOK: true},
+		{name: "no changes", planOutput: "No changes. Your infrastructure matches the configuration.", expectedCount: 0, expectedOK: true},
+		{name: "no summary", planOutput: "Error: Invalid provider configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok := changedResources(tt.planOutput)
+			assert.Equal(t, tt.expectedCount, count)
+			assert.Equal(t, tt.expectedOK, ok)
+		})
+	}
+}
+
+// TestParsePlanSummary tests extracting change counts and changed resource addresses from plans
+func TestParsePlanSummary(t *testing.T) {
+	plan := `Note: Objects have changed outside of Terraform
+
+  # aws_security_group.web has changed
+
+Terraform will perform the following actions:
+
+  # data.aws_iam_policy_document.assume will be read during apply
+  # aws_instance.web will be updated in-place
+  ~ resource "aws_instance" "web" {
+    }
+
+  # aws_s3_bucket.logs["eu west"] will be created
+  # module.db.aws_db_instance.main must be replaced
+  # aws_instance.old (deposed object 1a2b3c4d) will be destroyed
+  # aws_eip.web will be replaced, as requested
+
+Plan: 3 to add, 1 to change, 3 to destroy.`
+
+	tests := []struct {
+		name       string
+		planOutput string
+		expected   client.PlanSummary
+		expectedOK bool
+	}{
+		{
+			name:       "summary and resources",
+			planOutput: plan,
+			expected: client.PlanSummary{ToAdd: 3, ToChange: 1, ToDestroy: 3, Resources: []client.ResourceChange{
+				{Address: "aws_instance.web", Action: "update"},
+				{Address: `aws_s3_bucket.logs["eu west"]`, Action: "create"},
+				{Address: "module.db.aws_db_instance.main", Action: "replace"},
+				{Address: "aws_instance.old", Action: "destroy"},
+				{Address: "aws_eip.web", Action: "replace"},
+			}},
+			expectedOK: true,
+		},
+		{
+			name:       "colored summary",
+			planOutput: "\x1b[1m  # aws_instance.web\x1b[0m will be destroyed\n\x1b[1mPlan:\x1b[0m 0 to add, 0 to change, 1 to destroy.",
+			expected:   client.PlanSummary{ToDestroy: 1, Resources: []client.ResourceChange{{Address: "aws_instance.web", Action: "destroy"}}},
+			expectedOK: true,
+		},
+		{name: "summary with imports", planOutput: "Plan: 2 to import, 1 to add, 0 to change, 0 to destroy.", expected: client.PlanSummary{ToAdd: 1}, expectedOK: true},
+		{name: "no changes", planOutput: "No changes. Your infrastructure matches the configuration.", expectedOK: true},
+		{name: "no summary", planOutput: "  # aws_instance.web will be created\nError: Invalid provider configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, ok := parsePlanSummary(tt.planOutput)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expected, summary)
+		})
+	}
+
+	t.Run("resource addresses are capped", func(t *testing.T) {
+		var planOutput strings.Builder
+		for i := range maxPlanSummaryResources + 10 {
+			fmt.Fprintf(&planOutput, "  # aws_instance.web[%d] will be created\n", i)
+		}
+		fmt.Fprintf(&planOutput, "Plan: %d to add, 0 to change, 0 to destroy.", maxPlanSummaryResources+10)
+
+		summary, ok := parsePlanSummary(planOutput.String())
+		assert.True(t, ok)
+		assert.Equal(t, maxPlanSummaryResources+10, summary.ToAdd, "Counts stay exact")
+		assert.Len(t, summary.Resources, maxPlanSummaryResources)
+	})
+}
+
+// TestProcessDriftDetection_PlanSummary tests that the latest drifted plan's summary is stored and shown atop its issue
+func TestProcessDriftDetection_PlanSummary(t *testing.T) {
+	ctx := context.Background()
+	key := "test-repo:production"
+
+	var descriptions []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if description, ok := body["description"].(string); ok {
+			descriptions = append(descriptions, description)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": 5, "state": "opened"})
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, PlanSummary: true}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		PlanOutput:      "  # aws_instance.web will be updated in-place\n\nPlan: 0 to add, 1 to change, 0 to destroy.",
+	}
+
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	data, err := storage.GetEnvironmentData(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", data["planToAdd"])
+	assert.Equal(t, "1", data["planToChange"])
+	assert.Equal(t, "0", data["planToDestroy"])
+	assert.JSONEq(t, `[{"address": "aws_instance.web", "action": "update"}]`, data["planResources"])
+	if assert.Len(t, descriptions, 1) {
+		assert.Contains(t, descriptions[0], "## Plan Summary\n\n**0** to add, **1** to change, **0** to destroy.\n\n- **update** `aws_instance.web`")
+	}
+
+	// Output without a summary clears it, leaving the issue without one
+	payload.PlanOutput = "Error: Invalid provider configuration"
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	data, err = storage.GetEnvironmentData(ctx, key)
+	assert.NoError(t, err)
+	for _, field := range planSummaryFields {
+		assert.Empty(t, data[field], field)
+	}
+	if assert.Len(t, descriptions, 2) {
+		assert.NotContains(t, descriptions[1], "## Plan Summary")
+	}
+
+	// Resolved drift clears the summary
+	payload.PlanOutput = "Plan: 1 to add, 0 to change, 0 to destroy."
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	payload.Operation, payload.ExitCode, payload.PlanOutput = "apply", 0, ""
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	data, err = storage.GetEnvironmentData(ctx, key)
+	assert.NoError(t, err)
+	assert.Empty(t, data["planToAdd"], "Resolved drift clears the summary")
+}
+
+// TestProcessDriftDetection_ChangedResources tests that the changed resource count follows the latest drifted plan
+func TestProcessDriftDetection_ChangedResources(t *testing.T) {
+	ctx := context.Background()
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 10, ResourcesHeader: enabled}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, &MockIssueTracker{}, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			payload := Payload{
+				RepoName:        "test-repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       "123",
+				Operation:       "plan",
+				ExitCode:        2,
+				Scheduled:       true,
+				PlanOutput:      "Plan: 1 to add, 2 to change, 0 to destroy.",
+			}
+
+			result, err := service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			if enabled {
+				assert.Equal(t, "3", result.ChangedResources)
+			} else {
+				assert.Empty(t, result.ChangedResources, "Plans are not parsed unless enabled")
+			}
+
+			payload.PlanOutput = "Error: something unexpected"
+			result, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			assert.Empty(t, result.ChangedResources, "Output without a summary clears the count")
+
+			payload.PlanOutput = "Plan: 1 to add, 0 to change, 0 to destroy."
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+
+			payload.ExitCode, payload.PlanOutput = 0, ""
+			result, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			assert.Empty(t, result.ChangedResources, "Resolved drift clears the count")
+		})
+	}
+}
+
+// TestProcessDriftDetection_ApplyAuthor tests that an apply's commit author is stored and named on
+// the drift issue of a later breach
+func TestProcessDriftDetection_ApplyAuthor(t *testing.T) {
+	ctx := context.Background()
+
+	var issueBody map[string]interface{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			assert.Equal(t, "jane@example.com", r.URL.Query().Get("search"))
+			_, _ = w.Write([]byte(`[{"id": 42, "username": "jdoe"}]`))
+		case "/projects/123/issues":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&issueBody))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123, "web_url": "https://gitlab.example.com/issues/10"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1, ApplyAuthor: "assign"}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "apply",
+		CommitAuthor:    "Jane Doe <jane@example.com>",
+	}
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	// An apply without author data keeps the last known author
+	payload.CommitAuthor = ""
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	author, _ := storage.GetField(ctx, "test-repo:production", "lastApplyAuthor")
+	assert.Equal(t, "Jane Doe <jane@example.com>", author)
+
+	payload.Operation, payload.ExitCode, payload.Scheduled = "plan", 2, true
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, issueBody, "The breach should create an issue") {
+		assert.Contains(t, issueBody["description"], "Last applied by @jdoe.")
+		assert.Equal(t, []interface{}{float64(42)}, issueBody["assignee_ids"])
+	}
+}
+
+// TestProcessDriftDetection_ExitCodeSeries tests that each operation's exit code is added to the series
+func TestProcessDriftDetection_ExitCodeSeries(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, ExitCodeSeriesLength: 2}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, &MockIssueTracker{}, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	_, err = service.GetExitCodeSeries(ctx, "test-repo", "production")
+	assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+	}
+	for i, report := range []struct {
+		operation string
+		exitCode  int
+	}{{"plan", 0}, {"plan", 2}, {"apply", 0}} {
+		payload.Operation, payload.ExitCode = report.operation, report.exitCode
+		payload.Timestamp = time.Date(2025, 1, 31, 10+i, 0, 0, 0, time.UTC).Format(time.RFC3339)
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+	}
+
+	series, err := service.GetExitCodeSeries(ctx, "test-repo", "production")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []repository.ExitCodePoint{
+			{Timestamp: time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC), Operation: "plan", ExitCode: 2},
+			{Timestamp: time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC), Operation: "apply", ExitCode: 0},
+		}, series.Points, "Only the newest points are kept")
+	}
+
+	cfg.ExitCodeSeriesLength = 0
+	_, err = service.GetExitCodeSeries(ctx, "test-repo", "production")
+	assert.ErrorIs(t, err, ErrExitCodeSeriesDisabled)
+}
+
+// TestProcessDriftDetection_PlanChecksum tests reporting drift unchanged when the plan checksum repeats
+func TestProcessDriftDetection_PlanChecksum(t *testing.T) {
+	ctx := context.Background()
+
+	var descriptions []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if json.NewDecoder(r.Body).Decode(&body) == nil && body["description"] != nil {
+			descriptions = append(descriptions, body["description"].(string))
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/123/issues":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123, "web_url": "https://gitlab.example.com/issues/10"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/123/issues/10":
+			_, _ = w.Write([]byte(`{"iid": 10, "state": "opened"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 2}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	checksum := strings.Repeat("a", 64)
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		PlanOutput:      "Plan: 0 to add, 1 to change, 0 to destroy.",
+		PlanChecksum:    checksum,
+	}
+
+	// The first detection has nothing to compare against
+	result, err := service.ProcessDriftDetection(ctx, payload)
+	if assert.NoError(t, err) {
+		assert.Equal(t, checksum, result.PlanChecksum)
+		assert.False(t, result.DriftUnchanged)
+	}
+
+	result, err = service.ProcessDriftDetection(ctx, payload)
+	if assert.NoError(t, err) {
+		assert.True(t, result.DriftUnchanged)
+	}
+	if assert.Len(t, descriptions, 1, "The breach should create an issue") {
+		assert.Contains(t, descriptions[0], "Drift unchanged since last run.")
+	}
+
+	payload.PlanChecksum = strings.Repeat("b", 64)
+	result, err = service.ProcessDriftDetection(ctx, payload)
+	if assert.NoError(t, err) {
+		assert.Equal(t, payload.PlanChecksum, result.PlanChecksum)
+		assert.False(t, result.DriftUnchanged)
+	}
+	if assert.Len(t, descriptions, 2, "The open issue should be updated") {
+		assert.NotContains(t, descriptions[1], "Drift unchanged since last run.")
+	}
+
+	// Resolved drift is never unchanged, and the next detection starts afresh
+	_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "apply"})
+	assert.NoError(t, err)
+	result, err = service.ProcessDriftDetection(ctx, payload)
+	if assert.NoError(t, err) {
+		assert.False(t, result.DriftUnchanged)
+	}
+}
+
+// TestProcessDriftDetection_ResourceLabel tests that new drift issues carry the CLI's resource type label
+func TestProcessDriftDetection_ResourceLabel(t *testing.T) {
+	ctx := context.Background()
+
+	var issueBody map[string]interface{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/projects/123/issues" {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&issueBody))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123, "web_url": "https://gitlab.example.com/issues/10"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 1}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	_, err = service.ProcessDriftDetection(ctx, Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+		ResourceLabel:   "drift:network",
+	})
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, issueBody, "The breach should create an issue") {
+		assert.Contains(t, issueBody["labels"], "drift:network")
+	}
+}
+
+// TestProcessDriftDetection_ProjectOverview tests creating and updating a project's Drift Overview issue
+func TestProcessDriftDetection_ProjectOverview(t *testing.T) {
+	ctx := context.Background()
+
+	var created, updated []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/123/issues":
+			assert.Equal(t, "Drift Overview", body["title"])
+			created = append(created, body["description"].(string))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"iid": 10, "project_id": 123, "web_url": "https://gitlab.example.com/issues/10"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/123/issues/10":
+			_, _ = w.Write([]byte(`{"iid": 10, "state": "opened"}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/projects/123/issues/10":
+			updated = append(updated, body["description"].(string))
+			_, _ = w.Write([]byte(`{"iid": 10}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 10, ProjectOverview: true}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	if assert.Len(t, created, 1, "The first report should create the overview issue") {
+		assert.Contains(t, created[0], "| `test-repo` | `production` | 1 |")
+	}
+
+	payload.Environment, payload.ExitCode = "staging", 0
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	if assert.Len(t, updated, 1, "A new environment should update the overview issue") {
+		assert.Contains(t, updated[0], "1 of 2 environments")
+		assert.Contains(t, updated[0], "| `test-repo` | `production` | 1 |\n| `test-repo` | `staging` | 0 |")
+	}
+
+	// An unchanged count leaves the issue alone
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Len(t, created, 1)
+	assert.Len(t, updated, 1)
+
+	stored, err := storage.GetEnvironmentData(ctx, OverviewKey(123))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "10", stored["issueID"])
+	}
+	assert.NotEqual(t, service.GenerateKey("overview", "123"), OverviewKey(123), "The overview is not an environment's key")
+}
+
+// TestGenerateKey_Hashed tests fixed-length keys with HASH_KEYS
+func TestGenerateKey_Hashed(t *testing.T) {
+	service := &DriftServiceImpl{config: &config.Config{HashKeys: true}}
+
+	key := service.GenerateKey("my-terraform-repo", "production")
+	assert.Equal(t, "sha256:", key[:7])
+	assert.Len(t, key, 71, "Hashed keys have a fixed length")
+	assert.Equal(t, key, service.GenerateKey("my-terraform-repo", "production"), "Hashing is deterministic")
+
+	long := service.GenerateKey(strings.Repeat("repo", 500), strings.Repeat("env", 500))
+	assert.Len(t, long, 71, "Long names do not lengthen the key")
+
+	assert.NotEqual(t, service.GenerateKey("repo:a", "prod"), service.GenerateKey("repo", "a:prod"), "Escaping keeps names distinct before hashing")
+}
+
+// TestProcessDriftDetection_HashKeys tests storing environments under hashed keys and looking them up by name
+func TestProcessDriftDetection_HashKeys(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{
+		ComparisonBranch:  "main",
+		DriftThreshold:    5,
+		HashKeys:          true,
+		EnvironmentGroups: map[string][]string{"shop": {"shop-*/prod"}},
+	}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, &MockIssueTracker{}, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "shop-eu",
+		Branch:          "main",
+		Environment:     "prod",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	key := service.GenerateKey("shop-eu", "prod")
+	data, err := storage.GetEnvironmentData(ctx, key)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", data["driftIncrement"])
+		assert.Equal(t, "shop-eu", data["repoName"], "Names are stored alongside hashed state")
+		assert.Equal(t, "prod", data["environment"])
+	}
+	indexed, err := storage.GetField(ctx, nameIndexKey, "shop-eu:prod")
+	assert.NoError(t, err)
+	assert.Equal(t, key, indexed, "The name index points at the hashed key")
+
+	result, err := service.GetEnvironmentState(ctx, "shop-eu", "prod")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", result.DriftIncrement, "Lookups by name find the hashed key")
+	}
+
+	group, err := service.GetGroupDrift(ctx, "shop", "")
+	if assert.NoError(t, err) && assert.Len(t, group.Members, 1) {
+		assert.Equal(t, "shop-eu", group.Members[0].RepoName, "Group members are named from the stored fields")
+		assert.Equal(t, "prod", group.Members[0].Environment)
+	}
+
+	// State stored by name before HASH_KEYS was enabled moves to the hashed key
+	_, err = storage.InitializeEnvironment(ctx, "shop-us:prod", "prod", "456", "5", "main")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.SetField(ctx, "shop-us:prod", "driftIncrement", "3"))
+
+	result, err = service.GetEnvironmentState(ctx, "shop-us", "prod")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "3", result.DriftIncrement, "Lookups read state not yet migrated")
+	}
+
+	payload.RepoName = "shop-us"
+	result, err = service.ProcessDriftDetection(ctx, payload)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "4", result.DriftIncrement, "The next report carries the state over")
+	}
+	_, err = storage.GetEnvironmentData(ctx, "shop-us:prod")
+	assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound, "The unhashed key is removed")
+	indexed, err = storage.GetField(ctx, nameIndexKey, "shop-us:prod")
+	assert.NoError(t, err)
+	assert.Equal(t, service.GenerateKey("shop-us", "prod"), indexed)
+
+	_, err = service.GetEnvironmentState(ctx, "shop-ca", "prod")
+	assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+	// An environment named like the index does not migrate it away
+	payload.RepoName, payload.Environment = "index", "names"
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	indexed, err = storage.GetField(ctx, nameIndexKey, "shop-eu:prod")
+	assert.NoError(t, err)
+	assert.Equal(t, key, indexed, "The name index survives an environment with a colliding name")
+}
+
+// TestProcessDriftDetection_HashKeysOpenIssue tests that an issue opened before HASH_KEYS was enabled
+// is still closed by its environment once the state has moved to the hashed key
+func TestProcessDriftDetection_HashKeysOpenIssue(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 1, HashKeys: true}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	mockTracker := new(MockIssueTracker)
+	service := NewDriftService(storage, mockTracker, NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	_, err = storage.InitializeEnvironment(ctx, "test-repo:production", "prod", "123", "1", "main")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.SetField(ctx, "test-repo:production", "driftIncrement", "2"))
+	assert.NoError(t, storage.SetField(ctx, "test-repo:production", "issueID", "7"))
+	assert.NoError(t, storage.AddOpenIssue(ctx, "test-repo:production"))
+	assert.NoError(t, storage.SetField(ctx, IssueOwnerKey(123, 7), "environmentKey", "test-repo:production"))
+
+	mockTracker.On("GetIssueStatus", mock.Anything, 123, 7).Return(true, nil).Once()
+	mockTracker.On("CloseIssue", mock.Anything, 123, 7, "apply", mock.Anything).Return(nil).Once()
+
+	_, err = service.ProcessDriftDetection(ctx, Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "apply",
+		ExitCode:        0,
+	})
+	assert.NoError(t, err)
+	mockTracker.AssertExpectations(t)
+
+	key := service.GenerateKey("test-repo", "production")
+	data, err := storage.GetEnvironmentData(ctx, key)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0", data["driftIncrement"])
+		assert.Empty(t, data["issueID"], "The closed issue is no longer referenced")
+	}
+	owner, err := storage.GetField(ctx, IssueOwnerKey(123, 7), "environmentKey")
+	assert.NoError(t, err)
+	assert.Empty(t, owner, "Reverse index is cleared once the issue is closed")
+	keys, err := storage.ListOpenIssues(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// TestProcessDriftDetection_GitHubIssues tests filing and updating drift issues through GitHub Issues
+func TestProcessDriftDetection_GitHubIssues(t *testing.T) {
+	ctx := context.Background()
+
+	var created, updated int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repositories/123":
+			_, _ = w.Write([]byte(`{"full_name": "acme/infra"}`))
+		case "POST /repos/acme/infra/issues":
+			created++
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/infra/issues/7", "state": "open"}`))
+		case "GET /repos/acme/infra/issues/7":
+			_, _ = w.Write([]byte(`{"number": 7, "state": "open"}`))
+		case "PATCH /repos/acme/infra/issues/7":
+			updated++
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{IssueProvider: "github", GitHubBaseURL: mockServer.URL, GitHubToken: "gh-token", ComparisonBranch: "main", DriftThreshold: 1}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitHubClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+	payload := Payload{
+		RepoName:        "infra",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+	result, err := service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created, "The breach should create a GitHub issue")
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "7", result.IssueID)
+		assert.Equal(t, "https://github.com/acme/infra/issues/7", result.IssueURL)
+	}
+
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created, "Continued drift updates the open issue")
+	assert.Equal(t, 1, updated)
+}
+
+// TestProcessDriftDetection_VerifyProject tests checking the payload's projectId against the reported repository
+func TestProcessDriftDetection_VerifyProject(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mode          string
+		projectID     string
+		expectedError bool
+		verified      bool
+	}{
+		{name: "match", mode: "true", projectID: "123", verified: true},
+		{name: "mismatch rejected", mode: "true", projectID: "456", expectedError: true},
+		{name: "mismatch warned", mode: "warn", projectID: "456"},
+		{name: "unknown project rejected", mode: "true", projectID: "789", expectedError: true},
+		{name: "non-numeric project rejected", mode: "true", projectID: "default", expectedError: true},
+		{name: "disabled", mode: "false", projectID: "456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/projects/123":
+					lookups++
+					_, _ = w.Write([]byte(`{"id": 123, "name": "Infrastructure Repo", "path_with_namespace": "platform/infrastructure-repo"}`))
+				case "/projects/456":
+					lookups++
+					_, _ = w.Write([]byte(`{"id": 456, "name": "Payments", "path_with_namespace": "apps/payments"}`))
+				case "/projects/789":
+					lookups++
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					_, _ = w.Write([]byte(`{}`))
+				}
+			}))
+			defer mockServer.Close()
+
+			cfg := &config.Config{GitLabBaseURL: mockServer.URL, GitLabToken: "test-token", ComparisonBranch: "main", DriftThreshold: 5, VerifyProject: tt.mode}
+			storage, err := repository.NewMemoryRepository("", 1)
+			assert.NoError(t, err)
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			payload := Payload{
+				RepoName:        "Infrastructure-Repo",
+				Branch:          "main",
+				Environment:     "production",
+				EnvironmentTier: "prod",
+				ProjectID:       tt.projectID,
+				Operation:       "plan",
+			}
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrProjectMismatch)
+				_, err = storage.GetEnvironmentData(ctx, "Infrastructure-Repo:production")
+				assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound, "A rejected report should not create state")
+				return
+			}
+			assert.NoError(t, err)
+
+			// A verified project is not looked up again
+			_, err = service.ProcessDriftDetection(ctx, payload)
+			assert.NoError(t, err)
+			switch {
+			case tt.mode == "false":
+				assert.Zero(t, lookups)
+			case tt.verified:
+				assert.Equal(t, 1, lookups)
+			default:
+				assert.Equal(t, 2, lookups, "A mismatched project is checked on every report")
+			}
+		})
+	}
+}
+
+// recordingAuditLogger records audit entries for assertions
+type recordingAuditLogger struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (l *recordingAuditLogger) Record(ctx context.Context, entry audit.Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.Principal = audit.PrincipalFrom(ctx)
+	l.entries = append(l.entries, entry)
+}
+
+// TestProcessDriftDetection_AuditLog tests that mutating operations are audited against the caller
+func TestProcessDriftDetection_AuditLog(t *testing.T) {
+	ctx := audit.WithPrincipal(context.Background(), "token:0a1b2c3d4e5f")
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repositories/123":
+			_, _ = w.Write([]byte(`{"full_name": "acme/infra"}`))
+		case "POST /repos/acme/infra/issues":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/infra/issues/7", "state": "open"}`))
+		case "GET /repos/acme/infra/issues/7":
+			_, _ = w.Write([]byte(`{"number": 7, "state": "open"}`))
+		case "POST /repos/acme/infra/issues/7/comments", "PATCH /repos/acme/infra/issues/7":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{IssueProvider: "github", GitHubBaseURL: mockServer.URL, GitHubToken: "gh-token", ComparisonBranch: "main", DriftThreshold: 1, AckMaxDuration: 24 * time.Hour}
+	storage, err := repository.NewMemoryRepository("", 1)
+	assert.NoError(t, err)
+	auditor := &recordingAuditLogger{}
+	service := NewDriftService(storage, client.NewGitHubClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg).WithAuditLogger(auditor)
+
+	payload := Payload{
+		RepoName:        "infra",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	payload.Operation = "apply"
+	payload.ExitCode = 0
+	_, err = service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+
+	err = service.AcknowledgeDrift(ctx, Acknowledgement{RepoName: "infra", Environment: "production", AckUntil: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+
+	var actions []string
+	for _, entry := range auditor.entries {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, "token:0a1b2c3d4e5f", entry.Principal, "Entries are attributed to the caller")
+		assert.Equal(t, "infra", entry.RepoName)
+		assert.Equal(t, "production", entry.Environment)
+	}
+	assert.Equal(t, []string{
+		audit.ActionDriftIncrement,
+		audit.ActionIssueCreate,
+		audit.ActionDriftReset,
+		audit.ActionIssueClose,
+		audit.ActionAcknowledge,
+	}, actions)
+	if assert.Len(t, auditor.entries, 5) {
+		assert.Equal(t, "7", auditor.entries[1].Details["issueID"])
+		assert.Equal(t, "apply", auditor.entries[2].Details["operation"])
+	}
+}
+
+// recordingNotifier records drift notifications for assertions, failing with err when set
+type recordingNotifier struct {
+	notifications []client.DriftNotification
+	err           error
+}
+
+func (n *recordingNotifier) NotifyDrift(ctx context.Context, notification client.DriftNotification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
+
+// TestHandleThresholdBreach_Notifier tests announcing breaches after the drift issue is created or updated
+func TestHandleThresholdBreach_Notifier(t *testing.T) {
+	ctx := context.Background()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repositories/123":
+			_, _ = w.Write([]byte(`{"full_name": "acme/infra"}`))
+		case "POST /repos/acme/infra/issues":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/infra/issues/7", "state": "open"}`))
+		case "GET /repos/acme/infra/issues/7":
+			_, _ = w.Write([]byte(`{"number": 7, "state": "open"}`))
+		case "PATCH /repos/acme/infra/issues/7":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	cfg := &config.Config{IssueProvider: "github", GitHubBaseURL: mockServer.URL, GitHubToken: "gh-token", ComparisonBranch: "main", DriftThreshold: 2}
+	storage, err := repository.NewMemoryRepository("", 2)
+	assert.NoError(t, err)
+	notifier := &recordingNotifier{}
+	service := NewDriftService(storage, client.NewGitHubClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg).WithNotifier(notifier)
+
+	payload := Payload{
+		RepoName:        "infra",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+	for i := 0; i < 3; i++ {
+		_, err = service.ProcessDriftDetection(ctx, payload)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []client.DriftNotification{
+		{RepoName: "infra", Environment: "production", DriftCount: 2, Threshold: 2, IssueURL: "https://github.com/acme/infra/issues/7"},
+		{RepoName: "infra", Environment: "production", DriftCount: 3, Threshold: 2, IssueURL: "https://github.com/acme/infra/issues/7", Updated: true},
+	}, notifier.notifications, "Drift below the threshold is not announced")
+
+	// A failing notifier does not fail the report
+	notifier.err = fmt.Errorf("webhook unavailable")
+	result, err := service.ProcessDriftDetection(ctx, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", result.DriftIncrement)
+	assert.Len(t, notifier.notifications, 3)
+}
+
+// TestGetEnvironmentStats tests recording successful processing and listing environments that stopped reporting
+func TestGetEnvironmentStats(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled without a staleness window", func(t *testing.T) {
+		cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5}
+		storage, err := repository.NewMemoryRepository("", 5)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+		result, err := service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "plan"})
+		assert.NoError(t, err)
+		assert.Empty(t, result.LastSuccess, "Successful processing is only recorded with STALE_AFTER")
+
+		_, err = service.GetEnvironmentStats(ctx)
+		assert.ErrorIs(t, err, ErrStalenessDisabled)
+	})
+
+	t.Run("lists environments silent for longer than the window", func(t *testing.T) {
+		cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, StaleAfter: 48 * time.Hour, HashKeys: true}
+		storage, err := repository.NewMemoryRepository("", 5)
+		assert.NoError(t, err)
+		service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+		for _, environment := range []string{"production", "staging", "review-41", "review-42"} {
+			result, err := service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: environment, EnvironmentTier: "nonprod", ProjectID: "123", Operation: "plan"})
+			assert.NoError(t, err)
+			assert.NotEmpty(t, result.LastSuccess, "Successful processing should be recorded")
+		}
+
+		// The review apps' schedules broke days ago; staging reported just inside the window
+		lastSuccesses := map[string]time.Duration{"staging": 47 * time.Hour, "review-41": 72 * time.Hour, "review-42": 96 * time.Hour}
+		for environment, age := range lastSuccesses {
+			key := service.GenerateKey("test-repo", environment)
+			assert.NoError(t, storage.SetField(ctx, key, "lastSuccessfulProcessing", time.Now().Add(-age).UTC().Format(time.RFC3339)))
+		}
+
+		// Environments not yet processed since tracking began are not reported
+		_, err = storage.InitializeEnvironment(ctx, service.GenerateKey("legacy-repo", "production"), "prod", "456", "5", "main")
+		assert.NoError(t, err)
+
+		stats, err := service.GetEnvironmentStats(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, stats.Environments)
+		assert.Equal(t, "48h0m0s", stats.StaleAfter)
+		if assert.Len(t, stats.Stale, 2) {
+			assert.Equal(t, "review-42", stats.Stale[0].Environment, "Longest silent environment comes first")
+			assert.Equal(t, "review-41", stats.Stale[1].Environment)
+			assert.Equal(t, "test-repo", stats.Stale[0].RepoName, "Names should be read back for hashed keys")
+			assert.Equal(t, "nonprod", stats.Stale[0].EnvironmentTier)
+		}
+
+		// A new successful report takes the environment off the list
+		_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "review-42", EnvironmentTier: "nonprod", ProjectID: "123", Operation: "plan"})
+		assert.NoError(t, err)
+		stats, err = service.GetEnvironmentStats(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, stats.Stale, 1) {
+			assert.Equal(t, "review-41", stats.Stale[0].Environment)
+		}
+	})
+}
+
+// TestProcessDriftDetection_Heartbeat tests that heartbeats only mark an environment as seen
+func TestProcessDriftDetection_Heartbeat(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, StaleAfter: 48 * time.Hour}
+	storage, err := repository.NewMemoryRepository("", 5)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+	heartbeat := Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "validate", Heartbeat: true}
+	key := service.GenerateKey("test-repo", "production")
+
+	// A heartbeat never creates an environment
+	result, err := service.ProcessDriftDetection(ctx, heartbeat)
+	assert.NoError(t, err)
+	assert.Empty(t, result.LastSeen)
+	_, err = storage.GetEnvironmentData(ctx, key)
+	assert.ErrorIs(t, err, repository.ErrEnvironmentNotFound)
+
+	_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "plan", ExitCode: 2, Scheduled: true})
+	assert.NoError(t, err)
+
+	// The last successful report was three days ago, so the environment is stale
+	assert.NoError(t, storage.SetField(ctx, key, "lastSuccessfulProcessing", time.Now().Add(-72*time.Hour).UTC().Format(time.RFC3339)))
+	before, err := storage.GetEnvironmentData(ctx, key)
+	assert.NoError(t, err)
+	stats, err := service.GetEnvironmentStats(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, stats.Stale, 1)
+
+	result, err = service.ProcessDriftDetection(ctx, heartbeat)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.LastSeen, "The heartbeat should be recorded")
+	assert.Equal(t, "1", result.DriftIncrement, "A heartbeat does not count drift")
+
+	// Nothing but lastSeen changes, not even the operation log
+	after, err := storage.GetEnvironmentData(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, result.LastSeen, after["lastSeen"])
+	delete(after, "lastSeen")
+	assert.Equal(t, before, after)
+
+	stats, err = service.GetEnvironmentStats(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, stats.Stale, "A recent heartbeat keeps the environment off the stale list")
+}
+
+// TestProcessDriftDetection_HeartbeatRetention tests that heartbeats keep an environment from expiring
+func TestProcessDriftDetection_HeartbeatRetention(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 5, RetentionProd: 200 * time.Millisecond}
+	storage, err := repository.NewMemoryRepository("", 5)
+	assert.NoError(t, err)
+	service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+	key := service.GenerateKey("test-repo", "production")
+
+	_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "plan", ExitCode: 2, Scheduled: true})
+	assert.NoError(t, err)
+
+	time.Sleep(120 * time.Millisecond)
+	_, err = service.ProcessDriftDetection(ctx, Payload{RepoName: "test-repo", Branch: "main", Environment: "production", EnvironmentTier: "prod", ProjectID: "123", Operation: "validate", Heartbeat: true})
+	assert.NoError(t, err)
+	time.Sleep(120 * time.Millisecond)
+
+	data, err := storage.GetEnvironmentData(ctx, key)
+	if assert.NoError(t, err, "The heartbeat should slide the retention forward") {
+		assert.Equal(t, "1", data["driftIncrement"])
+	}
+}
+
+// blockingStorage holds each environment initialization until release is closed, counting them
+type blockingStorage struct {
+	repository.StorageRepository
+	mu          sync.Mutex
+	initialized int
+	release     chan struct{}
+}
+
+func (s *blockingStorage) InitializeEnvironment(ctx context.Context, key, tier, projectID, threshold, comparisonBranch string) (bool, error) {
+	s.mu.Lock()
+	s.initialized++
+	s.mu.Unlock()
+	<-s.release
+	return s.StorageRepository.InitializeEnvironment(ctx, key, tier, projectID, threshold, comparisonBranch)
+}
+
+// TestProcessDriftDetection_DedupeReports tests that identical concurrent reports share one processing
+func TestProcessDriftDetection_DedupeReports(t *testing.T) {
+	ctx := context.Background()
+	payload := Payload{
+		RepoName:        "test-repo",
+		Branch:          "main",
+		Environment:     "production",
+		EnvironmentTier: "prod",
+		ProjectID:       "123",
+		Operation:       "plan",
+		ExitCode:        2,
+		Scheduled:       true,
+	}
+
+	tests := []struct {
+		name                string
+		dedupe              bool
+		exitCodes           []int
+		expectedProcessings int
+		expectedDrift       int
+	}{
+		{name: "identical reports share one processing", dedupe: true, exitCodes: []int{2, 2, 2, 2}, expectedProcessings: 1, expectedDrift: 1},
+		{name: "different reports are each processed", dedupe: true, exitCodes: []int{2, 0}, expectedProcessings: 2, expectedDrift: 1},
+		{name: "identical reports without deduplication", dedupe: false, exitCodes: []int{2, 2, 2, 2}, expectedProcessings: 4, expectedDrift: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ComparisonBranch: "main", DriftThreshold: 100, DedupeReports: tt.dedupe}
+			memory, err := repository.NewMemoryRepository("", 100)
+			assert.NoError(t, err)
+			storage := &blockingStorage{StorageRepository: memory, release: make(chan struct{})}
+			service := NewDriftService(storage, client.NewGitLabClient(cfg), NewThresholdManager(storage, cfg), noopMetrics, cfg)
+
+			var wg sync.WaitGroup
+			results := make([]*DriftResult, len(tt.exitCodes))
+			for i, exitCode := range tt.exitCodes {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					report := payload
+					report.ExitCode = exitCode
+					report.Timestamp = time.Now().Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+					result, err := service.ProcessDriftDetection(ctx, report)
+					assert.NoError(t, err)
+					results[i] = result
+				}()
+			}
+
+			// Give every report time to reach the service before the first processing completes
+			time.Sleep(100 * time.Millisecond)
+			close(storage.release)
+			wg.Wait()
+
+			assert.Equal(t, tt.expectedProcessings, storage.initialized)
+			drift, err := memory.GetField(ctx, "test-repo:production", "driftIncrement")
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(tt.expectedDrift), drift)
+			if tt.expectedProcessings == 1 {
+				for _, result := range results {
+					assert.Same(t, results[0], result, "Identical reports should share the result")
+				}
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
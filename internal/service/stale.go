@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"drift-guardian/internal/repository"
+)
+
+// recordSuccessfulProcessing stamps the environment with the time its report was processed, with
+// STALE_AFTER; a failure is logged since it only delays the environment being reported stale
+func (d *DriftServiceImpl) recordSuccessfulProcessing(ctx context.Context, key string) {
+	if d.config.StaleAfter <= 0 {
+		return
+	}
+
+	if err := d.storage.SetField(ctx, key, "lastSuccessfulProcessing", time.Now().UTC().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record successful processing", "error", err, "key", key)
+	}
+}
+
+// processHeartbeat records that the CLI ran for an environment without changing its drift state,
+// with STALE_AFTER, and refreshes its retention. Only environments created by an earlier report are
+// marked as seen, so a heartbeat has no other side effects.
+func (d *DriftServiceImpl) processHeartbeat(ctx context.Context, payload Payload) (*DriftResult, error) {
+	key := d.environmentKey(ctx, payload.RepoName, payload.Environment)
+
+	result, err := d.environmentResult(ctx, key)
+	if errors.Is(err, repository.ErrEnvironmentNotFound) {
+		slog.Info("Ignoring heartbeat for unknown environment", "repo", payload.RepoName, "environment", payload.Environment)
+		return &DriftResult{EnvironmentTier: payload.EnvironmentTier, ProjectID: payload.ProjectID, Log: map[string]string{}}, nil
+	}
+	if err != nil {
+		slog.Error("Failed to get environment data", "error", err, "repo", payload.RepoName, "environment", payload.Environment)
+		return nil, fmt.Errorf("failed to get environment data: %w", err)
+	}
+
+	// A heartbeat is activity too, so it keeps the environment from expiring
+	d.refreshRetention(ctx, key, payload.EnvironmentTier)
+
+	if d.config.StaleAfter <= 0 {
+		return result, nil
+	}
+
+	lastSeen := time.Now().UTC().Format(time.RFC3339)
+	if err := d.storage.SetField(ctx, key, "lastSeen", lastSeen); err != nil {
+		slog.Error("Failed to record heartbeat", "error", err, "key", key)
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	result.LastSeen = lastSeen
+
+	slog.Info("Heartbeat recorded", "repo", payload.RepoName, "environment", payload.Environment, "operation", payload.Operation)
+	return result, nil
+}
+
+// lastActivity returns when the environment last reported successfully or sent a heartbeat
+func lastActivity(data map[string]string) (time.Time, bool) {
+	var last time.Time
+	for _, field := range []string{"lastSuccessfulProcessing", "lastSeen"} {
+		if seen, err := time.Parse(time.RFC3339, data[field]); err == nil && seen.After(last) {
+			last = seen
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// GetEnvironmentStats scans stored environments for those that have neither reported successfully
+// nor sent a heartbeat within STALE_AFTER, such as environments whose CI schedule broke.
+// Environments are tracked from their first successful report after STALE_AFTER is set.
+func (d *DriftServiceImpl) GetEnvironmentStats(ctx context.Context) (*EnvironmentStats, error) {
+	if d.config.StaleAfter <= 0 {
+		return nil, ErrStalenessDisabled
+	}
+
+	// The scan is read-only, so it can be served by a read replica
+	ctx = repository.WithReplicaReads(ctx)
+
+	keys, err := d.storage.ListEnvironments(ctx)
+	if err != nil {
+		slog.Error("Failed to list environments", "error", err)
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+
+	type staleEntry struct {
+		environment StaleEnvironment
+		lastActive  time.Time
+	}
+	var stale []staleEntry
+
+	stats := &EnvironmentStats{StaleAfter: d.config.StaleAfter.String(), Stale: []StaleEnvironment{}}
+	cutoff := time.Now().Add(-d.config.StaleAfter)
+	for _, key := range keys {
+		data, err := d.storage.GetEnvironmentData(ctx, key)
+		if errors.Is(err, repository.ErrEnvironmentNotFound) {
+			continue // Expired since the scan
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get environment data: %w", err)
+		}
+		stats.Environments++
+
+		lastActive, ok := lastActivity(data)
+		if !ok || lastActive.After(cutoff) {
+			continue
+		}
+
+		repoName, environment := environmentNames(key, data)
+		stale = append(stale, staleEntry{
+			environment: StaleEnvironment{
+				RepoName:        repoName,
+				Environment:     environment,
+				EnvironmentTier: data["environmentTier"],
+				LastSuccess:     data["lastSuccessfulProcessing"],
+				LastSeen:        data["lastSeen"],
+			},
+			lastActive: lastActive,
+		})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].lastActive.Before(stale[j].lastActive) })
+	for _, entry := range stale {
+		stats.Stale = append(stats.Stale, entry.environment)
+	}
+	return stats, nil
+}
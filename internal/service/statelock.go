@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"drift-guardian/internal/client"
+)
+
+// stateLockedLabel marks a drift issue whose latest run could not acquire the terraform state lock
+const stateLockedLabel = "state-locked"
+
+// recordStateLock handles a run that failed to acquire the state lock. The run says nothing about
+// drift, so the counter is left alone; an open issue is labelled so responders see the lock.
+func (d *DriftServiceImpl) recordStateLock(ctx context.Context, payload Payload, key string) error {
+	slog.Warn("Terraform could not acquire the state lock, skipping drift counting",
+		"key", key,
+		"operation", payload.Operation,
+		"repo", payload.RepoName,
+		"environment", payload.Environment,
+	)
+
+	issueIDStr, err := d.storage.GetField(ctx, key, "issueID")
+	if err != nil || issueIDStr == "" {
+		return nil // No issue to label
+	}
+
+	issueID, err := strconv.Atoi(issueIDStr)
+	if err != nil || issueID <= 0 {
+		return nil
+	}
+
+	env := EnvironmentInfo{
+		RepoName:        payload.RepoName,
+		Environment:     payload.Environment,
+		EnvironmentTier: payload.EnvironmentTier,
+		ProjectID:       payload.ProjectID,
+		IssueProjectID:  payload.IssueProjectID,
+		Key:             key,
+	}
+
+	projectID, err := d.storedIssueProject(ctx, env)
+	if err != nil {
+		slog.Warn("Invalid issue project ID, skipping state lock label", "error", err, "key", key)
+		return nil
+	}
+
+	annotator, ok := d.issueTracker.(client.IssueAnnotator)
+	if !ok {
+		return nil
+	}
+
+	// The label is advisory, so a failure to apply it must not fail the report
+	if err := annotator.AddIssueLabels(ctx, projectID, issueID, []string{stateLockedLabel}); err != nil {
+		slog.Warn("Failed to add state lock label", "error", err, "key", key, "issue_id", issueID)
+		return nil
+	}
+
+	slog.Info("Drift issue labelled as state-locked", "key", key, "issue_id", issueID)
+	return nil
+}
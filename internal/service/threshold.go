@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strconv"
 
 	"drift-guardian/internal/config"
@@ -49,5 +50,15 @@ func (t *ThresholdManagerImpl) GetThreshold(ctx context.Context, key string) (in
 		return 0, fmt.Errorf("invalid threshold value: %w", err)
 	}
 
+	// Thresholds below 1 would alert on every run, so fall back to the configured default
+	if threshold < 1 {
+		slog.Warn("Stored drift threshold below minimum, using configured default",
+			"key", key,
+			"stored_threshold", threshold,
+			"default_threshold", t.config.DriftThreshold,
+		)
+		return t.config.DriftThreshold, nil
+	}
+
 	return threshold, nil
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// notificationThrottled reports whether the environment already notified within NOTIFICATION_THROTTLE.
+// Detections weighted at CRITICAL_DRIFT_WEIGHT or above always notify.
+func (d *DriftServiceImpl) notificationThrottled(ctx context.Context, env EnvironmentInfo) bool {
+	if d.config.NotificationThrottle <= 0 {
+		return false
+	}
+
+	if d.config.CriticalDriftWeight > 0 && env.DriftWeight >= d.config.CriticalDriftWeight {
+		slog.Info("Critical drift bypasses the notification throttle", "key", env.Key, "weight", env.DriftWeight)
+		return false
+	}
+
+	stored, _ := d.storage.GetField(ctx, env.Key, "lastNotifiedAt")
+	lastNotified, err := time.Parse(time.RFC3339, stored)
+	if err != nil {
+		return false
+	}
+
+	since := time.Since(lastNotified)
+	if since >= d.config.NotificationThrottle {
+		return false
+	}
+
+	slog.Info("Notification throttled",
+		"key", env.Key,
+		"last_notified", stored,
+		"next_allowed_in", (d.config.NotificationThrottle - since).Round(time.Second).String(),
+		"repo", env.RepoName,
+		"environment", env.Environment,
+	)
+	d.metrics.Count("notification.throttled", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+	return true
+}
+
+// notifyDriftThrottled announces a breach unless the environment already notified within
+// NOTIFICATION_THROTTLE; the drift issue is created or updated either way
+func (d *DriftServiceImpl) notifyDriftThrottled(ctx context.Context, env EnvironmentInfo, driftCount, threshold int, issueURL string, updated bool) {
+	if d.notificationThrottled(ctx, env) {
+		return
+	}
+	d.recordNotification(ctx, env.Key)
+	d.notifyDrift(ctx, env, driftCount, threshold, issueURL, updated)
+}
+
+// recordNotification starts the environment's throttle window
+func (d *DriftServiceImpl) recordNotification(ctx context.Context, key string) {
+	if d.config.NotificationThrottle <= 0 {
+		return
+	}
+	if err := d.storage.SetField(ctx, key, "lastNotifiedAt", time.Now().Format(time.RFC3339)); err != nil {
+		slog.Warn("Failed to record notification time", "error", err, "key", key)
+	}
+}
+
+// resetNotificationThrottle ends the environment's throttle window, so drift returning after it
+// was resolved notifies straight away
+func (d *DriftServiceImpl) resetNotificationThrottle(ctx context.Context, key string) {
+	if d.config.NotificationThrottle <= 0 {
+		return
+	}
+	if err := d.storage.SetField(ctx, key, "lastNotifiedAt", ""); err != nil {
+		slog.Warn("Failed to reset notification time", "error", err, "key", key)
+	}
+}
+
+// claimIssueUpdate reports whether this replica may update the environment's issue. The claim is an
+// atomic storage lock held for ISSUE_UPDATE_MIN_INTERVAL, so replicas behind a load balancer cannot
+// each update the same issue within one interval. A failed claim lets the update through.
+func (d *DriftServiceImpl) claimIssueUpdate(ctx context.Context, env EnvironmentInfo, issueID int) bool {
+	if d.config.IssueUpdateInterval <= 0 {
+		return true
+	}
+
+	acquired, err := d.storage.AcquireLock(ctx, "issue-update:"+env.Key, d.config.IssueUpdateInterval)
+	if err != nil {
+		slog.Warn("Failed to claim issue update, updating anyway", "error", err, "key", env.Key, "issue_id", issueID)
+		return true
+	}
+	if acquired {
+		return true
+	}
+
+	slog.Info("Issue updated within the minimum update interval, skipping update",
+		"key", env.Key,
+		"issue_id", issueID,
+		"interval", d.config.IssueUpdateInterval.String(),
+	)
+	d.metrics.Count("issue.update_skipped", 1, metricTags(env.RepoName, env.Environment, env.EnvironmentTier))
+	return false
+}
@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 
-	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 
+	"drift-guardian/internal/audit"
 	"drift-guardian/internal/client"
 	"drift-guardian/internal/config"
 	"drift-guardian/internal/handler"
+	"drift-guardian/internal/metrics"
 	"drift-guardian/internal/middleware"
 	"drift-guardian/internal/repository"
 	"drift-guardian/internal/service"
@@ -39,35 +42,154 @@ func main() {
 	// Log configuration (sanitized)
 	slog.Info("Configuration loaded",
 		"log_level", cfg.LogLevel,
+		"storage_backend", cfg.StorageBackend,
+		"issue_provider", cfg.IssueProvider,
+		"hash_keys", cfg.HashKeys,
+		"compress_plan_output", cfg.CompressPlans,
+		"environment_ttl", cfg.EnvironmentTTL,
+		"tier_drift_weights", cfg.TierDriftWeights,
+		"deduplicate_reports", cfg.DedupeReports,
+		"stale_after", cfg.StaleAfter,
+		"plan_summary", cfg.PlanSummary,
 		"authentication_enabled", cfg.EnableAuthentication,
+		"named_tokens", len(cfg.TokenIdentities),
 		"comparison_branch", cfg.ComparisonBranch,
 		"drift_threshold", cfg.DriftThreshold,
+		"issue_tiers", cfg.IssueTiers,
+		"digest_mode", cfg.DigestMode,
+		"project_overview", cfg.ProjectOverview,
+		"escalation_after", cfg.EscalationAfter,
+		"statsd_enabled", cfg.StatsdAddr != "",
+		"audit_log", cfg.AuditLog,
+		"slack_enabled", cfg.SlackWebhookURL != "",
+		"maintenance_mode", cfg.MaintenanceMode,
+		"min_cli_version", cfg.MinCLIVersion,
 		"port", cfg.Port,
+		"grpc_port", cfg.GRPCPort,
 	)
 
-	// Initialize Redis/Valkey client
-	slog.Info("Initializing Redis connection...")
-	opt, err := redis.ParseURL(cfg.RedisURL)
-	if err != nil {
-		slog.Error("Failed to parse Redis URL", "error", err)
-		panic(err) // Exit if Redis URL is invalid
+	if cfg.BearerToken != "" {
+		slog.Warn("BEARER_TOKEN is deprecated, use BEARER_TOKENS (comma-separated) instead")
+	}
+
+	if cfg.MaintenanceMode {
+		slog.Warn("Maintenance mode enabled, drift reports will be rejected with 503", "retry_after", cfg.MaintenanceRetryAfter)
 	}
 
-	// Create context and Redis client
+	// Create context
 	ctx := context.Background()
-	rdb := redis.NewClient(opt)
+
+	// Initialize storage backend
+	var storage repository.StorageRepository
+	switch cfg.StorageBackend {
+	case "memory":
+		slog.Info("Initializing in-memory storage...", "file", cfg.MemoryStorageFile)
+		memoryRepo, err := repository.NewMemoryRepository(cfg.MemoryStorageFile, cfg.DriftThreshold)
+		if err != nil {
+			slog.Error("Failed to initialize in-memory storage", "error", err)
+			panic(err)
+		}
+		storage = memoryRepo
+	case "postgres":
+		slog.Info("Initializing Postgres connection...")
+		postgresRepo, err := repository.NewPostgresRepository(ctx, cfg.PostgresDSN, cfg.DriftThreshold)
+		if err != nil {
+			slog.Error("Failed to initialize Postgres storage", "error", err)
+			panic(err)
+		}
+		storage = postgresRepo
+	default:
+		// Initialize Redis/Valkey client
+		slog.Info("Initializing Redis connection...")
+		redisClient, err := repository.NewRedisClient(cfg.RedisURL, cfg.RedisOpTimeout)
+		if err != nil {
+			slog.Error("Failed to parse Redis URL", "error", err)
+			panic(err) // Exit if Redis URL is invalid
+		}
+		redisRepo := repository.NewRedisRepository(redisClient, cfg.DriftThreshold)
+		if cfg.CompressPlans {
+			redisRepo.WithPlanCompression()
+		}
+
+		// Serve read-only lookups from a replica to take dashboard load off the primary
+		if cfg.RedisReplicaURL != "" {
+			replicaClient, err := repository.NewRedisClient(cfg.RedisReplicaURL, cfg.RedisOpTimeout)
+			if err != nil {
+				slog.Error("Failed to parse Redis replica URL", "error", err)
+				panic(err)
+			}
+			redisRepo.WithReplica(replicaClient)
+			slog.Info("Serving read-only queries from Redis replica")
+		}
+		storage = redisRepo
+	}
 
 	// Initialize service layer dependencies
 	slog.Debug("Initializing service layer dependencies")
-	redisRepo := repository.NewRedisRepository(rdb)
-	gitlabClient := client.NewGitLabClient(cfg)
-	thresholdManager := service.NewThresholdManager(redisRepo, cfg)
-	driftService := service.NewDriftService(redisRepo, gitlabClient, thresholdManager, cfg)
+	var issueTracker client.IssueTracker
+	if cfg.IssueProvider == "github" {
+		issueTracker = client.NewGitHubClient(cfg)
+	} else {
+		issueTracker = client.NewGitLabClient(cfg)
+	}
+	thresholdManager := service.NewThresholdManager(storage, cfg)
+	statsdClient, err := metrics.NewStatsdClient(cfg.StatsdAddr, cfg.StatsdPrefix)
+	if err != nil {
+		// Metrics are best effort, so carry on without them
+		slog.Error("Failed to initialize statsd client, metrics disabled", "error", err)
+		statsdClient, _ = metrics.NewStatsdClient("", "")
+	}
+	driftService := service.NewDriftService(storage, issueTracker, thresholdManager, statsdClient, cfg)
+
+	// Announce threshold breaches in Slack alongside the drift issues
+	if cfg.SlackWebhookURL != "" {
+		driftService.WithNotifier(client.NewSlackNotifier(cfg))
+	}
+
+	// Record mutating operations in the audit log, separately from the operational logs
+	var auditor audit.Logger = audit.NopLogger{}
+	switch cfg.AuditLog {
+	case "stdout":
+		auditor = audit.NewWriterLogger(os.Stdout)
+	case "file":
+		fileLogger, err := audit.NewFileLogger(cfg.AuditLogFile)
+		if err != nil {
+			slog.Error("Failed to open audit log file", "error", err, "file", cfg.AuditLogFile)
+			panic(err)
+		}
+		auditor = fileLogger
+	case "redis":
+		auditClient, err := repository.NewRedisClient(cfg.RedisURL, cfg.RedisOpTimeout)
+		if err != nil {
+			slog.Error("Failed to parse Redis URL for the audit log", "error", err)
+			panic(err)
+		}
+		auditor = audit.NewRedisStreamLogger(auditClient, cfg.AuditRedisStream)
+	}
+	driftService.WithAuditLogger(auditor)
 	slog.Info("Service layer dependencies initialized successfully")
 
+	// Start escalation checker for long-running drift issues
+	if cfg.EscalationAfter > 0 {
+		escalationChecker := service.NewEscalationChecker(storage, issueTracker, cfg).WithAuditLogger(auditor)
+		go escalationChecker.Start(ctx)
+	}
+
+	// Start escalation of acknowledged drift that misses its resolve-by time
+	if cfg.EscalationCheckInterval > 0 {
+		ackChecker := service.NewAckChecker(storage, issueTracker, cfg).WithAuditLogger(auditor)
+		go ackChecker.Start(ctx)
+	}
+
+	// Start reconciliation of issue references deleted or closed outside Drift Guardian
+	if cfg.IssueReconcileInterval > 0 {
+		issueReconciler := service.NewIssueReconciler(storage, issueTracker, statsdClient, cfg)
+		go issueReconciler.Start(ctx)
+	}
+
 	// Initialize handler layer
 	responseWriter := handler.NewResponseWriter()
-	environmentHandler := handler.NewEnvironmentHandler(driftService, responseWriter)
+	environmentHandler := handler.NewEnvironmentHandler(driftService, responseWriter, cfg.MaxAcceptedPlanOutput)
 	healthHandler := handler.NewHealthHandler()
 
 	// Create HTTP router with middleware
@@ -78,22 +200,109 @@ func main() {
 		healthHandler.HandleHealth(w, r)
 	}))
 	readyWithSecurity := middleware.SecurityHeadersMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		healthHandler.HandleReady(w, r, rdb, ctx)
+		healthHandler.HandleReady(w, r, storage, cfg.StorageBackend, r.Context())
 	}))
 
 	mux.Handle("/health", healthWithSecurity)
 	mux.Handle("/ready", readyWithSecurity)
 
-	// Environment endpoint with authentication, logging, and security middleware
+	// Environment endpoint with authentication, logging, maintenance, CLI version, and security middleware
 	envHandler := middleware.SecurityHeadersMiddleware()(
 		middleware.AuthenticationMiddleware(cfg)(
-			middleware.LoggingMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				environmentHandler.HandleEnvironments(w, r, ctx)
-			})),
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(
+					middleware.CLIVersionMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						// A client disconnect must not abort a half-applied update, so storage calls use the
+						// server context and are bounded by the storage timeouts instead
+						environmentHandler.HandleEnvironments(w, r, audit.FromRequest(ctx, r))
+					})),
+				),
+			),
 		),
 	)
 	mux.Handle("/environments", envHandler)
 
+	// Read-only environment state endpoint shares the environment endpoint's middleware, so dashboards
+	// can poll drift counts without reporting
+	stateHandler := middleware.SecurityHeadersMiddleware()(
+		middleware.AuthenticationMiddleware(cfg)(
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					environmentHandler.HandleEnvironmentState(w, r, ctx)
+				})),
+			),
+		),
+	)
+	mux.Handle("/environments/{repo}/{environment}", stateHandler)
+
+	// Acknowledgement endpoint shares the environment endpoint's middleware
+	ackHandler := middleware.SecurityHeadersMiddleware()(
+		middleware.AuthenticationMiddleware(cfg)(
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					environmentHandler.HandleAcknowledge(w, r, audit.FromRequest(ctx, r))
+				})),
+			),
+		),
+	)
+	mux.Handle("/environments/ack", ackHandler)
+
+	// Environment group endpoint shares the environment endpoint's middleware
+	groupHandler := middleware.SecurityHeadersMiddleware()(
+		middleware.AuthenticationMiddleware(cfg)(
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					environmentHandler.HandleGroupDrift(w, r, ctx)
+				})),
+			),
+		),
+	)
+	mux.Handle("/groups/drift", groupHandler)
+
+	// Exit code series endpoint shares the environment endpoint's middleware
+	exitCodesHandler := middleware.SecurityHeadersMiddleware()(
+		middleware.AuthenticationMiddleware(cfg)(
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					environmentHandler.HandleExitCodes(w, r, ctx)
+				})),
+			),
+		),
+	)
+	mux.Handle("/environments/exit-codes", exitCodesHandler)
+
+	// Stale environment listing shares the environment endpoint's middleware
+	statsHandler := middleware.SecurityHeadersMiddleware()(
+		middleware.AuthenticationMiddleware(cfg)(
+			middleware.LoggingMiddleware(cfg)(
+				middleware.MaintenanceMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					environmentHandler.HandleStats(w, r, ctx)
+				})),
+			),
+		),
+	)
+	mux.Handle("/stats", statsHandler)
+
+	// Start the gRPC server for internal tooling, sharing the HTTP handler's report processing
+	if cfg.GRPCPort != "" {
+		grpcServer := handler.NewGRPCServer(environmentHandler, grpc.ChainUnaryInterceptor(
+			middleware.GRPCAuthenticationInterceptor(cfg),
+			middleware.GRPCMaintenanceInterceptor(cfg),
+		))
+		grpcAddr := ":" + cfg.GRPCPort
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			slog.Error("Failed to listen for gRPC", "error", err, "address", grpcAddr)
+			panic(err)
+		}
+		slog.Info("gRPC server listening", "address", grpcAddr)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Error("gRPC server error", "error", err)
+			}
+		}()
+	}
+
 	// Start the HTTP server (blocking call)
 	serverAddr := ":" + cfg.Port
 	slog.Info("Server listening", "address", serverAddr)